@@ -13,8 +13,26 @@ import (
 	"time"
 )
 
-const UserID = "userID"
-const RequestID = "requestID"
+// 不再用字符串常量做 key：不同包的同名字符串会冲突，取出的值也要自己做类型断言。
+// key 是带类型的指针，只有拿到同一个变量才能取到值，Value 直接返回 T
+type key[T any] struct{ name string }
+
+func (k *key[T]) With(ctx context.Context, v T) context.Context {
+	return context.WithValue(ctx, k, v)
+}
+
+// Value 在 ctx 中没有这个 key 时返回 T 的零值
+func (k *key[T]) Value(ctx context.Context) T {
+	v, _ := ctx.Value(k).(T)
+	return v
+}
+
+func (k *key[T]) String() string { return k.name }
+
+var (
+	UserID    = &key[string]{"userID"}
+	RequestID = &key[string]{"requestID"}
+)
 
 func Caller(ctx context.Context, wg *sync.WaitGroup) {
 	defer wg.Done()
@@ -24,7 +42,7 @@ func Caller(ctx context.Context, wg *sync.WaitGroup) {
 			fmt.Println("Caller out...")
 			return
 		default:
-			fmt.Println("User ID: ", ctx.Value(UserID), "is working")
+			fmt.Println("User ID: ", UserID.Value(ctx), "is working")
 			time.Sleep(time.Second)
 		}
 	}
@@ -38,7 +56,7 @@ func Responder(ctx context.Context, wg *sync.WaitGroup) {
 			fmt.Println("Responder out...")
 			return
 		default:
-			fmt.Println("Request ID: ", ctx.Value(RequestID), "is working")
+			fmt.Println("Request ID: ", RequestID.Value(ctx), "is working")
 			time.Sleep(time.Second)
 		}
 	}
@@ -47,8 +65,8 @@ func Responder(ctx context.Context, wg *sync.WaitGroup) {
 func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
-	ctx = context.WithValue(ctx, UserID, "123")
-	ctx = context.WithValue(ctx, RequestID, "abc123")
+	ctx = UserID.With(ctx, "123")
+	ctx = RequestID.With(ctx, "abc123")
 	var wg sync.WaitGroup
 	wg.Add(2)
 	go Caller(ctx, &wg)
@@ -1 +1,3 @@
 module PracticeProject
+
+go 1.22.5
@@ -0,0 +1,306 @@
+/**
+ * @File : pipeline.go
+ * @Description : 由单向 channel 串起来的流水线：每个阶段是一个函数，读 <-chan 写 chan<-，任一阶段出错时整条流水线停止
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package pipeline
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Pipeline 管理一条流水线里所有阶段的 goroutine。各阶段共享一个 ctx，
+// 某个阶段返回错误、调用 Stop 或父 ctx 取消时，所有阶段停止读写并关闭输出
+type Pipeline struct {
+	parent context.Context
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	once sync.Once
+	err  error
+}
+
+// New 创建一条流水线，用法：
+//
+//	p := pipeline.New(ctx)
+//	out := pipeline.Map(p, pipeline.Source(p, 1, 2, 3), square)
+//	for v := range out { ... }
+//	err := p.Wait()
+func New(ctx context.Context) *Pipeline {
+	inner, cancel := context.WithCancel(ctx)
+	return &Pipeline{parent: ctx, ctx: inner, cancel: cancel}
+}
+
+// Context 在流水线停止时结束，阶段里的函数应当把它传给阻塞的调用
+func (p *Pipeline) Context() context.Context {
+	return p.ctx
+}
+
+// Stop 让所有阶段停止，不算作错误。消费方提前退出、不再读输出时调用，否则上游的 goroutine 会一直阻塞
+func (p *Pipeline) Stop() {
+	p.cancel()
+}
+
+// Wait 等待所有阶段的 goroutine 退出，返回第一个阶段错误；没有阶段出错但父 ctx 被取消时返回父 ctx 的错误
+func (p *Pipeline) Wait() error {
+	p.wg.Wait()
+	p.cancel()
+	// 阶段在 wg.Done 之前调用 fail，wg.Wait 返回后读 err 是安全的
+	if p.err != nil {
+		return p.err
+	}
+	return p.parent.Err()
+}
+
+// fail 记录第一个错误并停止流水线
+func (p *Pipeline) fail(err error) {
+	p.once.Do(func() { p.err = err })
+	p.cancel()
+}
+
+// goStage 启动一个阶段，阶段返回后关闭 out。流水线停止后阶段返回的错误多半是 ctx 的错误，不再记录
+func goStage[T any](p *Pipeline, out chan T, stage func() error) <-chan T {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer close(out)
+		if err := stage(); err != nil && p.ctx.Err() == nil {
+			p.fail(err)
+		}
+	}()
+	return out
+}
+
+// send 在流水线停止时放弃发送，返回 false
+func send[T any](ctx context.Context, out chan<- T, v T) bool {
+	select {
+	case out <- v:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// recv 在 in 关闭或流水线停止时返回 false
+func recv[T any](ctx context.Context, in <-chan T) (T, bool) {
+	select {
+	case v, ok := <-in:
+		return v, ok
+	case <-ctx.Done():
+		var zero T
+		return zero, false
+	}
+}
+
+// Source 依次输出 values
+func Source[T any](p *Pipeline, values ...T) <-chan T {
+	out := make(chan T)
+	return goStage(p, out, func() error {
+		for _, v := range values {
+			if !send(p.ctx, out, v) {
+				return nil
+			}
+		}
+		return nil
+	})
+}
+
+// Generate 用 next 产生数据，emit 返回 false 时流水线已停止，next 应当返回。next 返回错误时流水线停止
+func Generate[T any](p *Pipeline, next func(ctx context.Context, emit func(T) bool) error) <-chan T {
+	out := make(chan T)
+	return goStage(p, out, func() error {
+		return next(p.ctx, func(v T) bool { return send(p.ctx, out, v) })
+	})
+}
+
+// Map 对每个值调用 f，f 返回错误时流水线停止
+func Map[In, Out any](p *Pipeline, in <-chan In, f func(ctx context.Context, v In) (Out, error)) <-chan Out {
+	out := make(chan Out)
+	return goStage(p, out, func() error {
+		for {
+			v, ok := recv(p.ctx, in)
+			if !ok {
+				return nil
+			}
+			r, err := f(p.ctx, v)
+			if err != nil {
+				return err
+			}
+			if !send(p.ctx, out, r) {
+				return nil
+			}
+		}
+	})
+}
+
+// Filter 只输出 keep 返回 true 的值，keep 返回错误时流水线停止
+func Filter[T any](p *Pipeline, in <-chan T, keep func(ctx context.Context, v T) (bool, error)) <-chan T {
+	out := make(chan T)
+	return goStage(p, out, func() error {
+		for {
+			v, ok := recv(p.ctx, in)
+			if !ok {
+				return nil
+			}
+			k, err := keep(p.ctx, v)
+			if err != nil {
+				return err
+			}
+			if k && !send(p.ctx, out, v) {
+				return nil
+			}
+		}
+	})
+}
+
+// FanOut 把 in 分给 n 个输出，每个值只交给一个输出：n 个 goroutine 抢着读 in，读方空闲的输出拿到的值多。
+// 常见用法是每个输出接一个 Map，再用 FanIn 合并，结果的顺序不再保证
+func FanOut[T any](p *Pipeline, in <-chan T, n int) []<-chan T {
+	outs := make([]<-chan T, n)
+	for i := range outs {
+		out := make(chan T)
+		outs[i] = goStage(p, out, func() error {
+			for {
+				v, ok := recv(p.ctx, in)
+				if !ok || !send(p.ctx, out, v) {
+					return nil
+				}
+			}
+		})
+	}
+	return outs
+}
+
+// FanIn 把多个输入合并成一个输出，所有输入都关闭后关闭输出
+func FanIn[T any](p *Pipeline, ins ...<-chan T) <-chan T {
+	out := make(chan T)
+	var wg sync.WaitGroup
+	wg.Add(len(ins))
+	for _, in := range ins {
+		p.wg.Add(1)
+		go func(in <-chan T) {
+			defer p.wg.Done()
+			defer wg.Done()
+			for {
+				v, ok := recv(p.ctx, in)
+				if !ok || !send(p.ctx, out, v) {
+					return
+				}
+			}
+		}(in)
+	}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// Batch 把值攒成最多 size 个一批输出。批次中第一个值到达后 timeout 内没有攒满也输出，in 关闭时输出剩下的值
+func Batch[T any](p *Pipeline, in <-chan T, size int, timeout time.Duration) <-chan []T {
+	out := make(chan []T)
+	return goStage(p, out, func() error {
+		var batch []T
+		timer := time.NewTimer(timeout)
+		stopTimer(timer)
+		defer timer.Stop()
+		flush := func() bool {
+			stopTimer(timer)
+			b := batch
+			batch = nil
+			return send(p.ctx, out, b)
+		}
+		for {
+			select {
+			case v, ok := <-in:
+				if !ok {
+					if len(batch) > 0 {
+						flush()
+					}
+					return nil
+				}
+				batch = append(batch, v)
+				if len(batch) == 1 {
+					timer.Reset(timeout)
+				}
+				if len(batch) == size && !flush() {
+					return nil
+				}
+			case <-timer.C:
+				if len(batch) > 0 && !flush() {
+					return nil
+				}
+			case <-p.ctx.Done():
+				return nil
+			}
+		}
+	})
+}
+
+// stopTimer 停止 timer 并清掉已经触发的值，之后 Reset 不会立刻收到旧的触发
+func stopTimer(t *time.Timer) {
+	if !t.Stop() {
+		select {
+		case <-t.C:
+		default:
+		}
+	}
+}
+
+// Tee 把每个值同时交给两个输出，两个输出都要读，慢的一方会拖慢另一方
+func Tee[T any](p *Pipeline, in <-chan T) (<-chan T, <-chan T) {
+	a, b := make(chan T), make(chan T)
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer close(a)
+		defer close(b)
+		for {
+			v, ok := recv(p.ctx, in)
+			if !ok {
+				return
+			}
+			// 谁先读就先给谁，给过的一方置为 nil，select 不再选它
+			a, b := a, b
+			for a != nil || b != nil {
+				select {
+				case a <- v:
+					a = nil
+				case b <- v:
+					b = nil
+				case <-p.ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return a, b
+}
+
+// Buffer 在两个阶段之间加一个容量为 size 的缓冲，上游可以比下游多跑 size 个值
+func Buffer[T any](p *Pipeline, in <-chan T, size int) <-chan T {
+	out := make(chan T, size)
+	return goStage(p, out, func() error {
+		for {
+			v, ok := recv(p.ctx, in)
+			if !ok || !send(p.ctx, out, v) {
+				return nil
+			}
+		}
+	})
+}
+
+// Collect 读完 in 并返回所有值，然后等待流水线结束
+func Collect[T any](p *Pipeline, in <-chan T) ([]T, error) {
+	var values []T
+	for v := range in {
+		values = append(values, v)
+	}
+	return values, p.Wait()
+}
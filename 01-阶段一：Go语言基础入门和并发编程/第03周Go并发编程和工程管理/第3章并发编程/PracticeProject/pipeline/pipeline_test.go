@@ -0,0 +1,183 @@
+/**
+ * @File : pipeline_test.go
+ * @Description : 测试各阶段的输出、出错时整条流水线停止，并通过 goroutine 数量确认结束后没有泄漏
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// checkLeaks 在测试结束时确认 goroutine 数量回到开始时的水平。
+// 退出的 goroutine 需要一点时间才会从计数中消失，所以轮询一段时间
+func checkLeaks(t *testing.T) {
+	t.Helper()
+	before := runtime.NumGoroutine()
+	t.Cleanup(func() {
+		deadline := time.Now().Add(time.Second)
+		for runtime.NumGoroutine() > before {
+			if time.Now().After(deadline) {
+				buf := make([]byte, 1<<16)
+				t.Errorf("%d goroutines leaked:\n%s", runtime.NumGoroutine()-before, buf[:runtime.Stack(buf, true)])
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+	})
+}
+
+func square(ctx context.Context, v int) (int, error) {
+	return v * v, nil
+}
+
+func even(ctx context.Context, v int) (bool, error) {
+	return v%2 == 0, nil
+}
+
+func TestStages(t *testing.T) {
+	tests := []struct {
+		name   string
+		build  func(p *Pipeline) <-chan int
+		sorted bool // 结果顺序不确定，比较前先排序
+		expect []int
+	}{
+		{"map and filter", func(p *Pipeline) <-chan int {
+			return Filter(p, Map(p, Source(p, 1, 2, 3, 4, 5, 6), square), even)
+		}, false, []int{4, 16, 36}},
+		{"fan out and fan in", func(p *Pipeline) <-chan int {
+			outs := FanOut(p, Source(p, 1, 2, 3, 4, 5, 6, 7, 8), 3)
+			for i, out := range outs {
+				outs[i] = Map(p, out, square)
+			}
+			return FanIn(p, outs...)
+		}, true, []int{1, 4, 9, 16, 25, 36, 49, 64}},
+		{"buffer", func(p *Pipeline) <-chan int {
+			return Buffer(p, Source(p, 1, 2, 3), 2)
+		}, false, []int{1, 2, 3}},
+		{"tee", func(p *Pipeline) <-chan int {
+			a, b := Tee(p, Source(p, 1, 2, 3))
+			return FanIn(p, a, Map(p, b, square))
+		}, true, []int{1, 1, 2, 3, 4, 9}},
+		{"empty source", func(p *Pipeline) <-chan int {
+			return Map(p, Source[int](p), square)
+		}, false, nil},
+	}
+	for _, tt := range tests {
+		checkLeaks(t)
+		p := New(context.Background())
+		got, err := Collect(p, tt.build(p))
+		if err != nil {
+			t.Errorf("%s: Collect() error = %v", tt.name, err)
+		}
+		if tt.sorted {
+			sort.Ints(got)
+		}
+		if !reflect.DeepEqual(got, tt.expect) {
+			t.Errorf("%s: got %v, expect %v", tt.name, got, tt.expect)
+		}
+	}
+}
+
+func TestBatch(t *testing.T) {
+	checkLeaks(t)
+	p := New(context.Background())
+	got, err := Collect(p, Batch(p, Source(p, 1, 2, 3, 4, 5, 6, 7), 3, time.Hour))
+	if err != nil || !reflect.DeepEqual(got, [][]int{{1, 2, 3}, {4, 5, 6}, {7}}) {
+		t.Errorf("Batch() by size = %v, %v", got, err)
+	}
+
+	// 1、2 到达后停顿，超过 timeout 时先输出不满的一批
+	p = New(context.Background())
+	in := Generate(p, func(ctx context.Context, emit func(int) bool) error {
+		for _, v := range []int{1, 2, 0, 3} {
+			if v == 0 {
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			if !emit(v) {
+				return nil
+			}
+		}
+		return nil
+	})
+	got, err = Collect(p, Batch(p, in, 3, 20*time.Millisecond))
+	if err != nil || !reflect.DeepEqual(got, [][]int{{1, 2}, {3}}) {
+		t.Errorf("Batch() by timeout = %v, %v", got, err)
+	}
+}
+
+// naturals 无限输出自然数，只有流水线停止才会结束
+func naturals(p *Pipeline) <-chan int {
+	return Generate(p, func(ctx context.Context, emit func(int) bool) error {
+		for i := 1; emit(i); i++ {
+		}
+		return nil
+	})
+}
+
+func TestErrorShortCircuits(t *testing.T) {
+	checkLeaks(t)
+	boom := errors.New("boom")
+	var calls atomic.Int64
+	p := New(context.Background())
+	failAt3 := func(ctx context.Context, v int) (int, error) {
+		calls.Add(1)
+		if v == 3 {
+			return 0, fmt.Errorf("value %d: %w", v, boom)
+		}
+		return v, nil
+	}
+	// 出错的阶段在中间，上游的无限数据源和下游的 Tee、FanIn 都要停下
+	a, b := Tee(p, Map(p, Buffer(p, naturals(p), 4), failAt3))
+	got, err := Collect(p, FanIn(p, a, b))
+	if !errors.Is(err, boom) {
+		t.Errorf("Collect() error = %v, expect boom", err)
+	}
+	// 出错后下游还没转发的值会被丢掉，只能确定出错的值和之后的值都没有到达
+	for _, v := range got {
+		if v >= 3 {
+			t.Errorf("Collect() got %v, expect only values before the failing one", got)
+			break
+		}
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("map function ran %d times, expect it to stop at the failing value", n)
+	}
+}
+
+func TestStop(t *testing.T) {
+	checkLeaks(t)
+	p := New(context.Background())
+	out := Batch(p, FanIn(p, FanOut(p, naturals(p), 4)...), 2, time.Hour)
+	<-out
+	<-out
+	// 消费方不再读，Stop 之后所有阶段都要退出，Wait 不报错
+	p.Stop()
+	if err := p.Wait(); err != nil {
+		t.Errorf("Wait() after Stop error = %v", err)
+	}
+	for range out {
+	}
+}
+
+func TestParentCanceled(t *testing.T) {
+	checkLeaks(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	p := New(ctx)
+	out := Filter(p, naturals(p), even)
+	<-out
+	cancel()
+	if _, err := Collect(p, out); !errors.Is(err, context.Canceled) {
+		t.Errorf("Collect() error = %v, expect Canceled", err)
+	}
+}
@@ -1,40 +1,50 @@
 package main
 
 import (
+	"PracticeProject/turnstile"
+	"context"
 	"fmt"
 )
 
-// 打印数字
-func PrintNum(chNum chan struct{}, chLetter chan struct{}, done chan struct{}) {
+// 打印数字：数字比字母多一轮，打印完最后一轮就结束整个轮转
+func PrintNum(p *turnstile.Participant, done chan struct{}) {
+	defer close(done)
 	for i := 1; i <= 28; i += 2 {
-		<-chNum                    // 等待数字信号
+		if p.Wait() != nil { // 等待轮到数字
+			return
+		}
 		fmt.Printf("%v%v", i, i+1) // 输出两个数字
-		chLetter <- struct{}{}     // 发送字母信号
+		if i+2 > 28 {
+			p.Finish() // 字母的 Wait 会返回，不需要两边各自数轮数
+			return
+		}
+		p.Done() // 轮到字母
 	}
-	done <- struct{}{}
 }
 
 // 打印字母
-func PrintLetter(chNum chan struct{}, chLetter chan struct{}) {
+func PrintLetter(p *turnstile.Participant) {
 	for i := 0; i < 26; i += 2 {
-		<-chLetter                         // 等待字母信号
+		if p.Wait() != nil { // 等待轮到字母
+			return
+		}
 		fmt.Printf("%c%c", 'A'+i, 'A'+i+1) // 输出两个字母
-		chNum <- struct{}{}                // 发送数字信号
+		p.Done()                           // 轮到数字
 	}
 }
 
 func main() {
-
-	chNum := make(chan struct{})       // 数字信号通道
-	chLetter := make(chan struct{}, 1) // 字母信号通道
+	// 两个参与者按加入顺序轮流，先加入的数字先打印
+	ts := turnstile.New(context.Background(), turnstile.RoundRobin())
+	num, _ := ts.Join("num")
+	letter, _ := ts.Join("letter")
 	done := make(chan struct{})
 
 	// 启动两个 Goroutine，分别用于打印数字和字母
-	go PrintNum(chNum, chLetter, done)
-	go PrintLetter(chNum, chLetter)
+	go PrintNum(num, done)
+	go PrintLetter(letter)
 
-	// 首先发送一个数字信号，开始打印数字
-	chNum <- struct{}{}
+	ts.Start()
 	<-done
-
+	fmt.Println()
 }
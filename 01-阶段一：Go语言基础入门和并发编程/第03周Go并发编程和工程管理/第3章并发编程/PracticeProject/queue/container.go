@@ -0,0 +1,96 @@
+/**
+ * @File : container.go
+ * @Description : 队列内部的存储：先进先出的环形缓冲区和按优先级出队的二叉堆
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package queue
+
+import "container/heap"
+
+// container 只在持有队列的锁时访问
+type container[T any] interface {
+	push(v T)
+	pop() T
+	len() int
+}
+
+// ring 是容量固定的环形缓冲区，出队不移动元素
+type ring[T any] struct {
+	buf  []T
+	head int
+	n    int
+}
+
+func newRing[T any](capacity int) *ring[T] {
+	return &ring[T]{buf: make([]T, capacity)}
+}
+
+func (r *ring[T]) push(v T) {
+	r.buf[(r.head+r.n)%len(r.buf)] = v
+	r.n++
+}
+
+func (r *ring[T]) pop() T {
+	v := r.buf[r.head]
+	var zero T
+	r.buf[r.head] = zero // 不再引用已出队的值
+	r.head = (r.head + 1) % len(r.buf)
+	r.n--
+	return v
+}
+
+func (r *ring[T]) len() int {
+	return r.n
+}
+
+// priority 用 container/heap 实现，less(a, b) 为 true 时 a 先出队。
+// 优先级相同的值按入队顺序出队，seq 记录入队顺序
+type priority[T any] struct {
+	items []entry[T]
+	less  func(a, b T) bool
+	seq   uint64
+}
+
+type entry[T any] struct {
+	v   T
+	seq uint64
+}
+
+func (p *priority[T]) Len() int { return len(p.items) }
+
+func (p *priority[T]) Less(i, j int) bool {
+	a, b := p.items[i], p.items[j]
+	if p.less(a.v, b.v) {
+		return true
+	}
+	if p.less(b.v, a.v) {
+		return false
+	}
+	return a.seq < b.seq
+}
+
+func (p *priority[T]) Swap(i, j int) { p.items[i], p.items[j] = p.items[j], p.items[i] }
+
+func (p *priority[T]) Push(x any) { p.items = append(p.items, x.(entry[T])) }
+
+func (p *priority[T]) Pop() any {
+	n := len(p.items) - 1
+	e := p.items[n]
+	p.items[n] = entry[T]{}
+	p.items = p.items[:n]
+	return e
+}
+
+func (p *priority[T]) push(v T) {
+	heap.Push(p, entry[T]{v: v, seq: p.seq})
+	p.seq++
+}
+
+func (p *priority[T]) pop() T {
+	return heap.Pop(p).(entry[T]).v
+}
+
+func (p *priority[T]) len() int {
+	return len(p.items)
+}
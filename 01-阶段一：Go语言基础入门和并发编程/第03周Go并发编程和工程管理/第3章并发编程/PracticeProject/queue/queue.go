@@ -0,0 +1,200 @@
+/**
+ * @File : queue.go
+ * @Description : 用 sync.Cond 实现的有界阻塞队列：Put/Take 支持 ctx 和超时，关闭后仍可取完剩余的值，记录等待时间
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrClosed 在队列关闭后 Put，或关闭且取空后 Take 时返回
+var ErrClosed = errors.New("queue: closed")
+
+// BlockingQueue 可以被多个生产者和消费者同时使用。
+// 满时 Put 等待 notFull，空时 Take 等待 notEmpty，两个条件变量共用一把锁
+type BlockingQueue[T any] struct {
+	mu       sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	items    container[T]
+	capacity int
+	closed   bool
+
+	put, take waitStats
+}
+
+// NewFIFO 创建先进先出的队列，capacity 必须大于 0
+func NewFIFO[T any](capacity int) *BlockingQueue[T] {
+	return newQueue[T](capacity, newRing[T](capacity))
+}
+
+// NewPriority 创建优先级队列，less(a, b) 为 true 时 a 先出队，优先级相同时先进先出
+func NewPriority[T any](capacity int, less func(a, b T) bool) *BlockingQueue[T] {
+	return newQueue[T](capacity, &priority[T]{less: less})
+}
+
+func newQueue[T any](capacity int, items container[T]) *BlockingQueue[T] {
+	if capacity <= 0 {
+		panic("queue: capacity must be positive")
+	}
+	q := &BlockingQueue[T]{items: items, capacity: capacity}
+	q.notEmpty = sync.NewCond(&q.mu)
+	q.notFull = sync.NewCond(&q.mu)
+	return q
+}
+
+// Put 在队列满时等待，ctx 结束时返回 ctx 的错误，队列关闭时返回 ErrClosed
+func (q *BlockingQueue[T]) Put(ctx context.Context, v T) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if err := q.wait(ctx, q.notFull, &q.put, func() bool { return q.closed || q.items.len() < q.capacity }); err != nil {
+		return err
+	}
+	if q.closed {
+		return ErrClosed
+	}
+	q.items.push(v)
+	q.notEmpty.Signal()
+	return nil
+}
+
+// Take 在队列空时等待。关闭后先取完剩余的值，取空后返回 ErrClosed
+func (q *BlockingQueue[T]) Take(ctx context.Context) (T, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var zero T
+	if err := q.wait(ctx, q.notEmpty, &q.take, func() bool { return q.closed || q.items.len() > 0 }); err != nil {
+		return zero, err
+	}
+	if q.items.len() == 0 {
+		return zero, ErrClosed
+	}
+	v := q.items.pop()
+	q.notFull.Signal()
+	return v, nil
+}
+
+// PutTimeout 最多等待 timeout，超时返回 context.DeadlineExceeded
+func (q *BlockingQueue[T]) PutTimeout(v T, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return q.Put(ctx, v)
+}
+
+// TakeTimeout 最多等待 timeout，超时返回 context.DeadlineExceeded
+func (q *BlockingQueue[T]) TakeTimeout(timeout time.Duration) (T, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return q.Take(ctx)
+}
+
+// TryPut 不等待，队列满或已关闭时返回 false
+func (q *BlockingQueue[T]) TryPut(v T) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed || q.items.len() >= q.capacity {
+		return false
+	}
+	q.items.push(v)
+	q.notEmpty.Signal()
+	return true
+}
+
+// TryTake 不等待，队列空时返回 false
+func (q *BlockingQueue[T]) TryTake() (T, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.items.len() == 0 {
+		var zero T
+		return zero, false
+	}
+	v := q.items.pop()
+	q.notFull.Signal()
+	return v, true
+}
+
+// Close 之后 Put 返回 ErrClosed，Take 取完剩余的值后返回 ErrClosed。可以多次调用
+func (q *BlockingQueue[T]) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.notEmpty.Broadcast()
+	q.notFull.Broadcast()
+}
+
+func (q *BlockingQueue[T]) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.items.len()
+}
+
+func (q *BlockingQueue[T]) Cap() int {
+	return q.capacity
+}
+
+// wait 在持有锁时调用，等到 ready 成立或 ctx 结束。
+// sync.Cond 不能和 ctx 一起 select，ctx 结束时由 AfterFunc 加锁后 Broadcast 唤醒等待者
+func (q *BlockingQueue[T]) wait(ctx context.Context, cond *sync.Cond, ws *waitStats, ready func() bool) error {
+	if ready() {
+		return nil
+	}
+	start := time.Now()
+	defer func() { ws.record(time.Since(start)) }()
+	stop := context.AfterFunc(ctx, func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		cond.Broadcast()
+	})
+	defer stop()
+	for !ready() {
+		if err := ctx.Err(); err != nil {
+			// 这次唤醒可能是 Signal 发给本 goroutine 的，放弃等待前转交给下一个等待者
+			cond.Signal()
+			return err
+		}
+		cond.Wait()
+	}
+	return nil
+}
+
+// Stats 是 Put 和 Take 的等待统计，只统计真正等待过的调用
+type Stats struct {
+	PutWaits     int64
+	PutWaitTime  time.Duration
+	MaxPutWait   time.Duration
+	TakeWaits    int64
+	TakeWaitTime time.Duration
+	MaxTakeWait  time.Duration
+}
+
+func (q *BlockingQueue[T]) Stats() Stats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return Stats{
+		PutWaits:     q.put.count,
+		PutWaitTime:  q.put.total,
+		MaxPutWait:   q.put.max,
+		TakeWaits:    q.take.count,
+		TakeWaitTime: q.take.total,
+		MaxTakeWait:  q.take.max,
+	}
+}
+
+// waitStats 只在持有队列的锁时访问
+type waitStats struct {
+	count int64
+	total time.Duration
+	max   time.Duration
+}
+
+func (w *waitStats) record(d time.Duration) {
+	w.count++
+	w.total += d
+	w.max = max(w.max, d)
+}
@@ -0,0 +1,263 @@
+/**
+ * @File : queue_test.go
+ * @Description : 测试出队顺序、阻塞与唤醒、超时和取消、关闭后取完剩余值、多生产者多消费者，并和带缓冲的 channel 做基准对比
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOrder(t *testing.T) {
+	tests := []struct {
+		name   string
+		q      *BlockingQueue[string]
+		expect []string
+	}{
+		{"fifo", NewFIFO[string](5), []string{"c1", "a2", "b1", "a1", "b2"}},
+		// 按首字母排序，首字母相同的按入队顺序
+		{"priority", NewPriority(5, func(a, b string) bool { return a[0] < b[0] }), []string{"a2", "a1", "b1", "b2", "c1"}},
+	}
+	for _, tt := range tests {
+		for _, v := range []string{"c1", "a2", "b1", "a1", "b2"} {
+			if !tt.q.TryPut(v) {
+				t.Fatalf("%s: TryPut(%s) failed", tt.name, v)
+			}
+		}
+		if tt.q.TryPut("x") {
+			t.Errorf("%s: TryPut() into a full queue succeeded", tt.name)
+		}
+		var got []string
+		for v, ok := tt.q.TryTake(); ok; v, ok = tt.q.TryTake() {
+			got = append(got, v)
+		}
+		if !reflect.DeepEqual(got, tt.expect) {
+			t.Errorf("%s: got %v, expect %v", tt.name, got, tt.expect)
+		}
+	}
+}
+
+// TestRingWraps 反复进出，让环形缓冲区的 head 绕回开头
+func TestRingWraps(t *testing.T) {
+	q := NewFIFO[int](3)
+	next := 0
+	for i := 0; i < 10; i++ {
+		q.TryPut(i)
+		if i%2 == 1 {
+			for j := 0; j < 2; j++ {
+				if v, ok := q.TryTake(); !ok || v != next {
+					t.Fatalf("TryTake() = %d, %v, expect %d", v, ok, next)
+				}
+				next++
+			}
+		}
+	}
+	if q.Len() != 0 {
+		t.Errorf("Len() = %d, expect 0", q.Len())
+	}
+}
+
+// blocked 确认 f 在 d 内没有返回，之后返回 f 的结果 channel
+func blocked(t *testing.T, what string, d time.Duration, f func() error) <-chan error {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() { done <- f() }()
+	select {
+	case err := <-done:
+		t.Fatalf("%s returned %v, expect it to block", what, err)
+	case <-time.After(d):
+	}
+	return done
+}
+
+func TestBlocking(t *testing.T) {
+	ctx := context.Background()
+	q := NewFIFO[int](1)
+	q.TryPut(1)
+	put := blocked(t, "Put() into a full queue", 20*time.Millisecond, func() error { return q.Put(ctx, 2) })
+	if v, err := q.Take(ctx); v != 1 || err != nil {
+		t.Fatalf("Take() = %d, %v", v, err)
+	}
+	if err := <-put; err != nil {
+		t.Fatal(err)
+	}
+	q.TryTake()
+
+	take := make(chan int)
+	go func() {
+		v, _ := q.Take(ctx)
+		take <- v
+	}()
+	time.Sleep(20 * time.Millisecond)
+	q.Put(ctx, 3)
+	if v := <-take; v != 3 {
+		t.Errorf("Take() = %d, expect 3", v)
+	}
+
+	s := q.Stats()
+	if s.PutWaits != 1 || s.TakeWaits != 1 || s.MaxPutWait < 10*time.Millisecond || s.TakeWaitTime < 10*time.Millisecond {
+		t.Errorf("Stats() = %+v, expect one put and one take that each waited about 20ms", s)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	q := NewFIFO[int](1)
+	if _, err := q.TakeTimeout(10 * time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("TakeTimeout() on an empty queue error = %v", err)
+	}
+	q.TryPut(1)
+	if err := q.PutTimeout(2, 10*time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("PutTimeout() into a full queue error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	put := blocked(t, "Put()", 10*time.Millisecond, func() error { return q.Put(ctx, 2) })
+	cancel()
+	if err := <-put; !errors.Is(err, context.Canceled) {
+		t.Errorf("Put() after cancel error = %v", err)
+	}
+	if q.Len() != 1 {
+		t.Errorf("Len() = %d, canceled Put should not add a value", q.Len())
+	}
+}
+
+func TestClose(t *testing.T) {
+	ctx := context.Background()
+	empty := NewFIFO[int](1)
+	take := blocked(t, "Take() on an empty queue", 10*time.Millisecond, func() error { _, err := empty.Take(ctx); return err })
+	full := NewFIFO[int](1)
+	full.TryPut(1)
+	put := blocked(t, "Put() into a full queue", 10*time.Millisecond, func() error { return full.Put(ctx, 2) })
+
+	empty.Close()
+	full.Close()
+	if err := <-take; !errors.Is(err, ErrClosed) {
+		t.Errorf("blocked Take() after Close error = %v", err)
+	}
+	if err := <-put; !errors.Is(err, ErrClosed) {
+		t.Errorf("blocked Put() after Close error = %v", err)
+	}
+	// 关闭前放进去的值仍然可以取出
+	if v, err := full.Take(ctx); v != 1 || err != nil {
+		t.Errorf("Take() after Close = %d, %v, expect the remaining value", v, err)
+	}
+	if _, err := full.Take(ctx); !errors.Is(err, ErrClosed) {
+		t.Errorf("Take() on a drained closed queue error = %v", err)
+	}
+	if full.TryPut(3) {
+		t.Errorf("TryPut() after Close succeeded")
+	}
+}
+
+func TestProducersConsumers(t *testing.T) {
+	const producers, consumers, perProducer = 8, 8, 1000
+	ctx := context.Background()
+	q := NewFIFO[[2]int](4)
+
+	var wg sync.WaitGroup
+	wg.Add(producers)
+	for p := 0; p < producers; p++ {
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < perProducer; i++ {
+				if err := q.Put(ctx, [2]int{p, i}); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(p)
+	}
+	go func() {
+		wg.Wait()
+		q.Close()
+	}()
+
+	// 每个消费者看到的同一个生产者的值必须是递增的
+	got := make([][][2]int, consumers)
+	var cwg sync.WaitGroup
+	cwg.Add(consumers)
+	for c := 0; c < consumers; c++ {
+		go func(c int) {
+			defer cwg.Done()
+			for {
+				v, err := q.Take(ctx)
+				if err != nil {
+					return
+				}
+				got[c] = append(got[c], v)
+			}
+		}(c)
+	}
+	cwg.Wait()
+
+	seen := make(map[[2]int]bool)
+	for c, values := range got {
+		last := make(map[int]int)
+		for _, v := range values {
+			if prev, ok := last[v[0]]; ok && v[1] <= prev {
+				t.Fatalf("consumer %d saw %d after %d from producer %d", c, v[1], prev, v[0])
+			}
+			last[v[0]] = v[1]
+			seen[v] = true
+		}
+	}
+	if len(seen) != producers*perProducer {
+		t.Errorf("consumers saw %d distinct values, expect %d", len(seen), producers*perProducer)
+	}
+}
+
+// 基准：n 个生产者和 n 个消费者共传递 b.N 个值，对比 BlockingQueue 和带缓冲的 channel
+var contention = []int{1, 4, 16}
+
+func BenchmarkBlockingQueue(b *testing.B) {
+	for _, n := range contention {
+		b.Run(fmt.Sprintf("%dx%d", n, n), func(b *testing.B) {
+			ctx := context.Background()
+			q := NewFIFO[int](64)
+			bench(b, n, func(v int) { q.Put(ctx, v) }, func() { q.Take(ctx) })
+		})
+	}
+}
+
+func BenchmarkChannel(b *testing.B) {
+	for _, n := range contention {
+		b.Run(fmt.Sprintf("%dx%d", n, n), func(b *testing.B) {
+			ch := make(chan int, 64)
+			bench(b, n, func(v int) { ch <- v }, func() { <-ch })
+		})
+	}
+}
+
+func bench(b *testing.B, n int, put func(int), take func()) {
+	var wg sync.WaitGroup
+	wg.Add(2 * n)
+	b.ResetTimer()
+	for i := 0; i < n; i++ {
+		// 把 b.N 尽量平均地分给 n 个生产者和 n 个消费者
+		count := b.N / n
+		if i < b.N%n {
+			count++
+		}
+		go func() {
+			defer wg.Done()
+			for j := 0; j < count; j++ {
+				put(j)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < count; j++ {
+				take()
+			}
+		}()
+	}
+	wg.Wait()
+}
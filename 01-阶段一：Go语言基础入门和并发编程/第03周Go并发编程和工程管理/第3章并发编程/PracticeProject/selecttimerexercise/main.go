@@ -7,12 +7,15 @@
 package main
 
 import (
+	"PracticeProject/watchdog"
 	"fmt"
 	"time"
 )
 
 func main() {
-	timer := time.NewTimer(3 * time.Second)
+	// 3 秒没有收到消息就超时。看门狗代替手动的 timer.Stop、清空 timer.C 和 Reset
+	idle := watchdog.New(3*time.Second, nil)
+	defer idle.Stop()
 	message := make(chan int)
 	go func() {
 		for i := 1; i <= 5; i++ {
@@ -28,11 +31,8 @@ func main() {
 				return
 			}
 			fmt.Println("Received message: Message ", mes)
-			if !timer.Stop() {
-				<-timer.C
-			}
-			timer.Reset(3 * time.Second)
-		case <-timer.C:
+			idle.Kick()
+		case <-idle.C():
 			fmt.Println("Timeout!")
 			return
 		}
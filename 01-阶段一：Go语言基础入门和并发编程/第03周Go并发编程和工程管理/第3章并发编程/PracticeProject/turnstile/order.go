@@ -0,0 +1,76 @@
+/**
+ * @File : order.go
+ * @Description : 轮转顺序：轮询、按权重连续多轮、自定义序列
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package turnstile
+
+// Member 是一个参与者，Weight 只有 Weighted 使用
+type Member struct {
+	Name   string
+	Weight int
+}
+
+// Order 决定下一个轮到谁，只在 Turnstile 持有锁时调用，可以有自己的状态。
+// members 按加入顺序排列且不为空；prev 是上一个持有者，还没有人轮到过时为空，它可能已经离开；
+// streak 是 prev 连续轮到的次数。返回空字符串表示暂时没有人可以轮到，成员变化时会再次调用
+type Order interface {
+	Next(members []Member, prev string, streak int) string
+}
+
+func indexOf(members []Member, name string) int {
+	for i, m := range members {
+		if m.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+type roundRobin struct{}
+
+// RoundRobin 按加入顺序轮流，每人一次
+func RoundRobin() Order {
+	return roundRobin{}
+}
+
+func (roundRobin) Next(members []Member, prev string, streak int) string {
+	return members[(indexOf(members, prev)+1)%len(members)].Name
+}
+
+type weighted struct{}
+
+// Weighted 按加入顺序轮流，每人连续轮到 Weight 次，Weight 小于 1 时按 1 次算
+func Weighted() Order {
+	return weighted{}
+}
+
+func (weighted) Next(members []Member, prev string, streak int) string {
+	if i := indexOf(members, prev); i >= 0 && streak < members[i].Weight {
+		return prev
+	}
+	return roundRobin{}.Next(members, prev, streak)
+}
+
+type sequence struct {
+	names []string
+	pos   int
+}
+
+// Sequence 按 names 的顺序循环，names 中可以重复出现同一个名字。
+// 轮到的名字不在成员中时跳过，整个序列都不在时没有人轮到
+func Sequence(names ...string) Order {
+	return &sequence{names: names}
+}
+
+func (s *sequence) Next(members []Member, prev string, streak int) string {
+	for range s.names {
+		name := s.names[s.pos]
+		s.pos = (s.pos + 1) % len(s.names)
+		if indexOf(members, name) >= 0 {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,196 @@
+/**
+ * @File : turnstile.go
+ * @Description : N 个 goroutine 按指定顺序轮流执行：参与者可以随时加入和离开，任一参与者结束或 ctx 取消时所有人退出
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package turnstile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+)
+
+var (
+	// ErrFinished 在某个参与者调用 Finish 之后由其他参与者的 Wait 返回
+	ErrFinished = errors.New("turnstile: finished")
+	// ErrLeft 在参与者离开之后调用它的方法时返回
+	ErrLeft = errors.New("turnstile: participant has left")
+	// ErrNotYourTurn 在没有轮到时调用 Done 返回
+	ErrNotYourTurn = errors.New("turnstile: not your turn")
+)
+
+// Turnstile 用一个 sync.Cond 协调所有参与者：轮次变化时 Broadcast，没轮到的参与者继续等待
+type Turnstile struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	order   Order
+	members []Member
+	started bool
+	current string // 当前轮到的参与者，为空表示没有人
+	prev    string
+	streak  int
+	err     error // 不为 nil 时已经结束
+	stop    func() bool
+}
+
+// New 创建一个还没开始的 Turnstile。参与者先 Join，调用 Start 后才开始轮转；ctx 取消时所有 Wait 返回 ctx 的错误
+func New(ctx context.Context, order Order) *Turnstile {
+	t := &Turnstile{order: order}
+	t.cond = sync.NewCond(&t.mu)
+	t.stop = context.AfterFunc(ctx, func() { t.end(ctx.Err()) })
+	return t
+}
+
+// Participant 是 Join 返回的句柄，只能由一个 goroutine 使用
+type Participant struct {
+	t    *Turnstile
+	name string
+}
+
+// Join 加入一个参与者，名字不能重复。开始后加入的参与者按 Order 的规则排进轮转
+func (t *Turnstile) Join(name string) (*Participant, error) {
+	return t.JoinWeighted(name, 1)
+}
+
+// JoinWeighted 加入一个带权重的参与者，权重只对 Weighted 有意义
+func (t *Turnstile) JoinWeighted(name string, weight int) (*Participant, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.err != nil {
+		return nil, t.err
+	}
+	if indexOf(t.members, name) >= 0 {
+		return nil, fmt.Errorf("turnstile: %s has already joined", name)
+	}
+	t.members = append(t.members, Member{Name: name, Weight: weight})
+	if t.started && t.current == "" {
+		t.advance()
+	}
+	return &Participant{t: t, name: name}, nil
+}
+
+// Start 开始轮转，第一个轮到的参与者由 Order 决定
+func (t *Turnstile) Start() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.started {
+		t.started = true
+		t.advance()
+	}
+}
+
+// advance 把轮次交给下一个参与者，在持有锁时调用
+func (t *Turnstile) advance() {
+	t.give(t.next())
+}
+
+func (t *Turnstile) next() string {
+	if len(t.members) == 0 {
+		return ""
+	}
+	return t.order.Next(t.members, t.prev, t.streak)
+}
+
+// give 把轮次交给 name 并唤醒所有等待者，name 为空表示暂时没有人轮到
+func (t *Turnstile) give(name string) {
+	t.current = name
+	if name != "" {
+		if name == t.prev {
+			t.streak++
+		} else {
+			t.prev, t.streak = name, 1
+		}
+	}
+	t.cond.Broadcast()
+}
+
+// end 结束轮转，只记录第一个原因
+func (t *Turnstile) end(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.err == nil {
+		t.err = err
+		t.cond.Broadcast()
+	}
+}
+
+func (p *Participant) Name() string {
+	return p.name
+}
+
+// Wait 等到轮到自己。轮转结束时返回 ErrFinished 或 ctx 的错误，已经离开时返回 ErrLeft
+func (p *Participant) Wait() error {
+	t := p.t
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for {
+		switch {
+		case t.err != nil:
+			return t.err
+		case indexOf(t.members, p.name) < 0:
+			return ErrLeft
+		case t.current == p.name:
+			return nil
+		}
+		t.cond.Wait()
+	}
+}
+
+// Done 结束自己这一轮，把轮次交给下一个参与者
+func (p *Participant) Done() error {
+	t := p.t
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.err != nil {
+		return t.err
+	}
+	if t.current != p.name {
+		return ErrNotYourTurn
+	}
+	t.advance()
+	return nil
+}
+
+// Turn 等到轮到自己后执行 fn，再把轮次交出去
+func (p *Participant) Turn(fn func()) error {
+	if err := p.Wait(); err != nil {
+		return err
+	}
+	fn()
+	return p.Done()
+}
+
+// Leave 退出轮转，其他参与者继续。正轮到自己时先把轮次交出去。可以多次调用
+func (p *Participant) Leave() {
+	t := p.t
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	i := indexOf(t.members, p.name)
+	if i < 0 {
+		return
+	}
+	if t.current != p.name {
+		t.members = append(t.members[:i], t.members[i+1:]...)
+		t.cond.Broadcast()
+		return
+	}
+	// 在删除之前计算下一个，轮询顺序才能从自己的位置继续；streak 设为最大，Weighted 不会再选自己
+	t.streak = math.MaxInt
+	next := t.next()
+	t.members = append(t.members[:i], t.members[i+1:]...)
+	if next == p.name {
+		// 只剩自己，或者 Sequence 下一个又是自己
+		next = t.next()
+	}
+	t.give(next)
+}
+
+// Finish 结束整个轮转，其他参与者的 Wait 返回 ErrFinished
+func (p *Participant) Finish() {
+	p.t.end(ErrFinished)
+	p.t.stop()
+}
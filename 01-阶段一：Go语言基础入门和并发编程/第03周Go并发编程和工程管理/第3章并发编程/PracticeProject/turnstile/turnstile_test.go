@@ -0,0 +1,178 @@
+/**
+ * @File : turnstile_test.go
+ * @Description : 按输出的交替顺序逐字比较，测试各种轮转顺序、动态加入和离开、Finish 和 ctx 取消
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package turnstile
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// player 是一个参与者：轮到时输出自己的名字，轮到 turns 次后离开
+type player struct {
+	name   string
+	weight int
+	turns  int
+}
+
+// play 让所有 player 并发地轮流输出，返回输出的顺序
+func play(t *testing.T, order Order, players ...player) string {
+	t.Helper()
+	ts := New(context.Background(), order)
+	var out strings.Builder
+	var wg sync.WaitGroup
+	for _, pl := range players {
+		p, err := ts.JoinWeighted(pl.name, pl.weight)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func(pl player) {
+			defer wg.Done()
+			defer p.Leave()
+			for i := 0; i < pl.turns; i++ {
+				// 最后一轮在轮次内离开，轮次直接交给下一个人
+				if err := p.Wait(); err != nil {
+					t.Errorf("%s: Wait() error = %v", pl.name, err)
+					return
+				}
+				out.WriteString(pl.name)
+				if i < pl.turns-1 {
+					p.Done()
+				}
+			}
+		}(pl)
+	}
+	ts.Start()
+	wg.Wait()
+	return out.String()
+}
+
+func TestOrders(t *testing.T) {
+	tests := []struct {
+		name    string
+		order   Order
+		players []player
+		expect  string
+	}{
+		{"round robin", RoundRobin(), []player{{"a", 1, 3}, {"b", 1, 3}, {"c", 1, 3}}, "abcabcabc"},
+		{"round robin continues after a player leaves", RoundRobin(), []player{{"a", 1, 3}, {"b", 1, 1}, {"c", 1, 3}}, "abcacac"},
+		{"weighted", Weighted(), []player{{"a", 2, 4}, {"b", 1, 2}, {"c", 3, 3}}, "aabcccaab"},
+		{"weighted player leaves inside its streak", Weighted(), []player{{"a", 3, 1}, {"b", 2, 4}}, "abbbb"},
+		{"sequence", Sequence("a", "b", "b", "c"), []player{{"a", 1, 2}, {"b", 1, 4}, {"c", 1, 2}}, "abbcabbc"},
+		{"sequence skips players that left", Sequence("a", "b", "c"), []player{{"a", 1, 1}, {"b", 1, 3}, {"c", 1, 3}}, "abcbcbc"},
+	}
+	for _, tt := range tests {
+		if got := play(t, tt.order, tt.players...); got != tt.expect {
+			t.Errorf("%s: got %q, expect %q", tt.name, got, tt.expect)
+		}
+	}
+}
+
+func TestJoinAfterStart(t *testing.T) {
+	ts := New(context.Background(), RoundRobin())
+	a, _ := ts.Join("a")
+	ts.Start()
+	var out strings.Builder
+	turn := func(p *Participant) {
+		if err := p.Turn(func() { out.WriteString(p.Name()) }); err != nil {
+			t.Fatalf("%s: Turn() error = %v", p.Name(), err)
+		}
+	}
+	turn(a)
+	turn(a)
+	// b 加入后排在 a 后面
+	b, _ := ts.Join("b")
+	turn(a)
+	turn(b)
+	turn(a)
+	if got := out.String(); got != "aaaba" {
+		t.Errorf("got %q, expect \"aaaba\"", got)
+	}
+	if err := a.Done(); !errors.Is(err, ErrNotYourTurn) {
+		t.Errorf("Done() out of turn error = %v, expect ErrNotYourTurn", err)
+	}
+	if _, err := ts.Join("a"); err == nil {
+		t.Errorf("Join() with a duplicate name should fail")
+	}
+
+	// 所有人都离开后没有人轮到，新加入的人立刻轮到
+	a.Leave()
+	b.Leave()
+	if err := a.Wait(); !errors.Is(err, ErrLeft) {
+		t.Errorf("Wait() after Leave error = %v, expect ErrLeft", err)
+	}
+	c, _ := ts.Join("c")
+	turn(c)
+}
+
+func TestFinish(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ts := New(ctx, RoundRobin())
+	num, _ := ts.Join("num")
+	letter, _ := ts.Join("letter")
+	ts.Start()
+
+	// 数字比字母多一轮：数字打印完就 Finish，字母的 Wait 返回 ErrFinished，不需要自己数轮数
+	var out strings.Builder
+	var letterErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; ; i++ {
+			if letterErr = letter.Turn(func() { out.WriteByte(byte('A' + i)) }); letterErr != nil {
+				return
+			}
+		}
+	}()
+	for i := 1; i <= 3; i++ {
+		num.Wait()
+		out.WriteByte(byte('0' + i))
+		if i == 3 {
+			num.Finish()
+		} else {
+			num.Done()
+		}
+	}
+	<-done
+	if got := out.String(); got != "1A2B3" {
+		t.Errorf("got %q, expect \"1A2B3\"", got)
+	}
+	if !errors.Is(letterErr, ErrFinished) {
+		t.Errorf("letter error = %v, expect ErrFinished", letterErr)
+	}
+	if _, err := ts.Join("late"); !errors.Is(err, ErrFinished) {
+		t.Errorf("Join() after Finish error = %v, expect ErrFinished", err)
+	}
+	// Finish 之后取消 ctx 不会覆盖结束原因
+	cancel()
+	if err := letter.Wait(); !errors.Is(err, ErrFinished) {
+		t.Errorf("Wait() after Finish and cancel error = %v, expect ErrFinished", err)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ts := New(ctx, RoundRobin())
+	a, _ := ts.Join("a")
+	b, _ := ts.Join("b")
+	ts.Start()
+	waited := make(chan error)
+	go func() { waited <- b.Wait() }()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if err := <-waited; !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait() after cancel error = %v, expect Canceled", err)
+	}
+	if err := a.Done(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Done() after cancel error = %v, expect Canceled", err)
+	}
+}
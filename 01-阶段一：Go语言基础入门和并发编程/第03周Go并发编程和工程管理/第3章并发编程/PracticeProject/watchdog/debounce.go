@@ -0,0 +1,116 @@
+/**
+ * @File : debounce.go
+ * @Description : 基于 Watchdog 的防抖和节流：防抖在最后一次触发后安静一段时间才执行，节流每个周期最多执行一次
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package watchdog
+
+import (
+	"sync"
+	"time"
+)
+
+// Debouncer 在连续的 Trigger 停下来 wait 之后调用一次 fn，每一串 Trigger 用一个 Watchdog，Trigger 就是 Kick
+type Debouncer struct {
+	wait time.Duration
+	fn   func()
+
+	mu  sync.Mutex
+	dog *Watchdog
+}
+
+func NewDebouncer(wait time.Duration, fn func()) *Debouncer {
+	return &Debouncer{wait: wait, fn: fn}
+}
+
+// Trigger 推迟 fn 的执行，没有等待中的调用时开始新的一串
+func (d *Debouncer) Trigger() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.dog == nil || !d.dog.Kick() {
+		d.dog = New(d.wait, d.fn)
+	}
+}
+
+// Stop 取消等待中的调用，之后的 Trigger 仍然有效。有调用被取消时返回 true
+func (d *Debouncer) Stop() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.dog != nil && d.dog.Stop()
+}
+
+// Throttler 让 fn 每 interval 最多执行一次：冷却期外的 Trigger 立即执行，
+// 冷却期内的 Trigger 合并成一次，在冷却期结束时执行并开始新的冷却期。fn 的调用不会重叠
+type Throttler struct {
+	interval time.Duration
+	fn       func()
+	run      sync.Mutex // 让 fn 串行执行，冷却结束时 fn 可能还在调用方的 goroutine 中执行
+
+	mu       sync.Mutex
+	cooldown *Watchdog
+	gen      uint64 // 每个冷却期的编号，trailing 用它判断自己是不是当前的冷却期
+	pending  bool
+	stopped  bool
+}
+
+func NewThrottler(interval time.Duration, fn func()) *Throttler {
+	return &Throttler{interval: interval, fn: fn}
+}
+
+// Trigger 在冷却期外同步调用 fn，返回 true；冷却期内只记下一次待执行的调用，返回 false
+func (t *Throttler) Trigger() bool {
+	t.mu.Lock()
+	if t.stopped {
+		t.mu.Unlock()
+		return false
+	}
+	if t.cooldown != nil && !t.cooldown.Expired() {
+		t.pending = true
+		t.mu.Unlock()
+		return false
+	}
+	// 冷却期刚结束、trailing 还没来得及执行时，待执行的调用由这次执行代替
+	t.pending = false
+	t.startCooldown()
+	t.mu.Unlock()
+	t.call()
+	return true
+}
+
+// startCooldown 在持有 mu 时调用
+func (t *Throttler) startCooldown() {
+	t.gen++
+	gen := t.gen
+	t.cooldown = New(t.interval, func() { t.trailing(gen) })
+}
+
+// trailing 在冷却期结束时执行合并的调用。gen 已经不是当前的冷却期时，说明 Trigger 抢先开始了新的冷却期
+func (t *Throttler) trailing(gen uint64) {
+	t.mu.Lock()
+	if t.gen != gen || !t.pending || t.stopped {
+		t.mu.Unlock()
+		return
+	}
+	t.pending = false
+	t.startCooldown()
+	t.mu.Unlock()
+	t.call()
+}
+
+func (t *Throttler) call() {
+	t.run.Lock()
+	defer t.run.Unlock()
+	t.fn()
+}
+
+// Stop 丢弃待执行的调用，之后的 Trigger 不再执行
+func (t *Throttler) Stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stopped = true
+	t.pending = false
+	if t.cooldown != nil {
+		t.cooldown.Stop()
+	}
+}
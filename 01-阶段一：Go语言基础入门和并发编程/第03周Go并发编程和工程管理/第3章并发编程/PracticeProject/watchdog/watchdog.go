@@ -0,0 +1,116 @@
+/**
+ * @File : watchdog.go
+ * @Description : 空闲看门狗：超过 timeout 没有 Kick 就触发一次，用于空闲流检测和会话过期
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrIdle 是 WithIdleTimeout 返回的 ctx 因为空闲而取消时的 cause
+var ErrIdle = errors.New("watchdog: idle timeout")
+
+// Watchdog 可以被多个 goroutine 同时使用，只触发一次，触发或 Stop 之后 Kick 不再有效。
+// Kick 只更新截止时间，不重置 timer；timer 到点时发现截止时间被推后了，再按剩余时间重新等待。
+// 这样频繁 Kick（比如每收到一条流消息）也不会反复停止和重置 timer，也不需要手动清空 timer.C
+type Watchdog struct {
+	timeout  time.Duration
+	onExpire func()
+	expired  chan struct{}
+
+	mu       sync.Mutex
+	timer    *time.Timer
+	deadline time.Time
+	done     bool // 已经触发或已经 Stop
+}
+
+// New 创建并立即启动一个看门狗。onExpire 可以为 nil，不为 nil 时在触发时由 timer 的 goroutine 调用
+func New(timeout time.Duration, onExpire func()) *Watchdog {
+	w := &Watchdog{
+		timeout:  timeout,
+		onExpire: onExpire,
+		expired:  make(chan struct{}),
+		deadline: time.Now().Add(timeout),
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.timer = time.AfterFunc(timeout, w.fire)
+	return w
+}
+
+// Kick 把截止时间推到 timeout 之后。已经触发或 Stop 时返回 false
+func (w *Watchdog) Kick() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.done {
+		return false
+	}
+	w.deadline = time.Now().Add(w.timeout)
+	return true
+}
+
+// Stop 停止看门狗且不触发。看门狗还在运行时返回 true
+func (w *Watchdog) Stop() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.done {
+		return false
+	}
+	w.done = true
+	w.timer.Stop()
+	return true
+}
+
+// C 在看门狗触发时关闭，Stop 之后永远不会关闭
+func (w *Watchdog) C() <-chan struct{} {
+	return w.expired
+}
+
+// Expired 返回看门狗是否已经触发
+func (w *Watchdog) Expired() bool {
+	select {
+	case <-w.expired:
+		return true
+	default:
+		return false
+	}
+}
+
+func (w *Watchdog) fire() {
+	w.mu.Lock()
+	if w.done {
+		w.mu.Unlock()
+		return
+	}
+	if left := time.Until(w.deadline); left > 0 {
+		// 等待期间被 Kick 过，按剩余时间再等
+		w.timer.Reset(left)
+		w.mu.Unlock()
+		return
+	}
+	w.done = true
+	close(w.expired)
+	w.mu.Unlock()
+	if w.onExpire != nil {
+		w.onExpire()
+	}
+}
+
+// WithIdleTimeout 返回一个在 timeout 内没有 Kick 就取消的 ctx，context.Cause 为 ErrIdle。
+// 父 ctx 结束或调用 cancel 时看门狗随之停止
+func WithIdleTimeout(parent context.Context, timeout time.Duration) (context.Context, *Watchdog, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(parent)
+	w := New(timeout, func() { cancel(ErrIdle) })
+	stop := context.AfterFunc(ctx, func() { w.Stop() })
+	return ctx, w, func() {
+		stop()
+		w.Stop()
+		cancel(context.Canceled)
+	}
+}
@@ -0,0 +1,196 @@
+/**
+ * @File : watchdog_test.go
+ * @Description : 测试看门狗的触发、Kick 推迟、Stop、并发 Kick、ctx 集成，以及防抖和节流的调用次数
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const timeout = 50 * time.Millisecond
+
+func TestExpire(t *testing.T) {
+	var calls atomic.Int32
+	start := time.Now()
+	w := New(timeout, func() { calls.Add(1) })
+	select {
+	case <-w.C():
+	case <-time.After(time.Second):
+		t.Fatal("watchdog did not expire")
+	}
+	if elapsed := time.Since(start); elapsed < timeout {
+		t.Errorf("expired after %v, expect at least %v", elapsed, timeout)
+	}
+	// onExpire 在关闭 C 之后调用
+	time.Sleep(10 * time.Millisecond)
+	if n := calls.Load(); n != 1 || !w.Expired() {
+		t.Errorf("onExpire called %d times, Expired() = %v", n, w.Expired())
+	}
+	if w.Kick() || w.Stop() {
+		t.Errorf("Kick() or Stop() after expiry should return false")
+	}
+}
+
+func TestKick(t *testing.T) {
+	w := New(timeout, nil)
+	// 持续 Kick 的时间是 timeout 的好几倍，期间不能触发
+	for i := 0; i < 20; i++ {
+		time.Sleep(timeout / 5)
+		if !w.Kick() {
+			t.Fatalf("Kick() %d returned false, watchdog expired while being kicked", i)
+		}
+	}
+	last := time.Now()
+	<-w.C()
+	if elapsed := time.Since(last); elapsed < timeout {
+		t.Errorf("expired %v after the last Kick, expect at least %v", elapsed, timeout)
+	}
+}
+
+func TestStop(t *testing.T) {
+	var calls atomic.Int32
+	w := New(timeout, func() { calls.Add(1) })
+	if !w.Stop() {
+		t.Errorf("first Stop() should return true")
+	}
+	if w.Stop() || w.Kick() {
+		t.Errorf("Stop() or Kick() after Stop should return false")
+	}
+	select {
+	case <-w.C():
+		t.Errorf("stopped watchdog expired")
+	case <-time.After(2 * timeout):
+	}
+	if calls.Load() != 0 {
+		t.Errorf("onExpire called after Stop")
+	}
+}
+
+func TestConcurrentKick(t *testing.T) {
+	var calls atomic.Int32
+	w := New(timeout, func() { calls.Add(1) })
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				w.Kick()
+				time.Sleep(time.Millisecond)
+			}
+		}()
+	}
+	wg.Wait()
+	<-w.C()
+	time.Sleep(10 * time.Millisecond)
+	if n := calls.Load(); n != 1 {
+		t.Errorf("onExpire called %d times, expect 1", n)
+	}
+}
+
+func TestWithIdleTimeout(t *testing.T) {
+	ctx, w, cancel := WithIdleTimeout(context.Background(), timeout)
+	defer cancel()
+	w.Kick()
+	<-ctx.Done()
+	if cause := context.Cause(ctx); !errors.Is(cause, ErrIdle) {
+		t.Errorf("Cause() = %v, expect ErrIdle", cause)
+	}
+
+	// 父 ctx 先结束时看门狗停止，不会再触发
+	parent, cancelParent := context.WithCancel(context.Background())
+	ctx, w, cancel = WithIdleTimeout(parent, timeout)
+	defer cancel()
+	cancelParent()
+	<-ctx.Done()
+	time.Sleep(2 * timeout)
+	if w.Expired() || !errors.Is(context.Cause(ctx), context.Canceled) {
+		t.Errorf("after the parent is canceled: Expired() = %v, Cause() = %v", w.Expired(), context.Cause(ctx))
+	}
+
+	ctx, w, cancel = WithIdleTimeout(context.Background(), timeout)
+	cancel()
+	if ctx.Err() == nil || w.Kick() {
+		t.Errorf("cancel() should cancel ctx and stop the watchdog")
+	}
+}
+
+func TestDebouncer(t *testing.T) {
+	var calls atomic.Int32
+	d := NewDebouncer(timeout, func() { calls.Add(1) })
+	burst := func() {
+		for i := 0; i < 5; i++ {
+			d.Trigger()
+			time.Sleep(timeout / 5)
+		}
+	}
+	burst()
+	if n := calls.Load(); n != 0 {
+		t.Errorf("fn called %d times during a burst, expect 0", n)
+	}
+	time.Sleep(2 * timeout)
+	if n := calls.Load(); n != 1 {
+		t.Errorf("fn called %d times after the first burst, expect 1", n)
+	}
+	burst()
+	time.Sleep(2 * timeout)
+	if n := calls.Load(); n != 2 {
+		t.Errorf("fn called %d times after the second burst, expect 2", n)
+	}
+
+	d.Trigger()
+	if !d.Stop() {
+		t.Errorf("Stop() with a pending call should return true")
+	}
+	time.Sleep(2 * timeout)
+	if n := calls.Load(); n != 2 {
+		t.Errorf("fn called %d times after Stop, expect 2", n)
+	}
+}
+
+func TestThrottler(t *testing.T) {
+	const interval = 50 * time.Millisecond
+	var calls atomic.Int32
+	th := NewThrottler(interval, func() { calls.Add(1) })
+	if !th.Trigger() || calls.Load() != 1 {
+		t.Fatalf("first Trigger() should call fn immediately")
+	}
+	// 冷却期内的多次 Trigger 合并成冷却期结束时的一次
+	for i := 0; i < 3; i++ {
+		if th.Trigger() {
+			t.Errorf("Trigger() during the cooldown should not call fn")
+		}
+	}
+	time.Sleep(interval / 2)
+	if n := calls.Load(); n != 1 {
+		t.Errorf("fn called %d times during the cooldown, expect 1", n)
+	}
+	time.Sleep(interval)
+	if n := calls.Load(); n != 2 {
+		t.Errorf("fn called %d times after the cooldown, expect 2", n)
+	}
+	// 冷却期内没有新的 Trigger，不会有第三次调用
+	time.Sleep(2 * interval)
+	if n := calls.Load(); n != 2 {
+		t.Errorf("fn called %d times without new triggers, expect 2", n)
+	}
+
+	th.Trigger()
+	th.Trigger()
+	th.Stop()
+	time.Sleep(2 * interval)
+	if n := calls.Load(); n != 3 {
+		t.Errorf("fn called %d times, expect the pending call to be dropped by Stop", n)
+	}
+	if th.Trigger() {
+		t.Errorf("Trigger() after Stop should not call fn")
+	}
+}
@@ -0,0 +1,285 @@
+/**
+ * @File : workerpool.go
+ * @Description : 固定数量 worker 加有界任务队列的协程池：支持取消、单个任务超时、捕获 panic、按序或乱序输出结果，Wait 汇总所有错误
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrClosed 在 Close 或 Wait 之后提交任务时返回
+var ErrClosed = errors.New("workerpool: pool is closed")
+
+// Job 是一个任务，ctx 在池被取消或任务超时后结束，任务应当及时返回
+type Job[T any] func(ctx context.Context) (T, error)
+
+// Result 是一个任务的结果，Index 是任务的提交顺序，从 0 开始
+type Result[T any] struct {
+	Index int
+	Value T
+	Err   error
+}
+
+// PanicError 是任务 panic 时的错误，Stack 是 panic 时的调用栈
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("job panicked: %v", e.Value)
+}
+
+// Options 配置协程池，零值使用默认值
+type Options struct {
+	// Workers 是 worker 数量，默认 runtime.NumCPU()
+	Workers int
+	// QueueSize 是等待执行的任务数上限，队列满时 Submit 阻塞，默认等于 Workers
+	QueueSize int
+	// JobTimeout 是每个任务的默认超时，0 表示不限制，SubmitTimeout 可以单独指定
+	JobTimeout time.Duration
+	// Results 为 true 时结果从 Results() 读出，调用方必须一直读到 channel 关闭；为 false 时只保留错误
+	Results bool
+	// Ordered 为 true 时 Results() 按提交顺序输出，先完成的结果会等前面的任务；为 false 时按完成顺序输出
+	Ordered bool
+}
+
+// Stats 是某一时刻的任务计数
+type Stats struct {
+	Queued    int64 // 在队列中等待的任务
+	Running   int64 // 正在执行的任务
+	Succeeded int64
+	Failed    int64 // 返回错误、超时或 panic 的任务
+	Canceled  int64 // 池被取消后没有执行的任务
+}
+
+type task[T any] struct {
+	index   int
+	job     Job[T]
+	timeout time.Duration
+}
+
+type Pool[T any] struct {
+	ctx        context.Context
+	jobTimeout time.Duration
+
+	// mu 让提交串行进行：任务按 Index 的顺序进入队列，Close 不会在 Submit 发送时关闭队列
+	mu     sync.Mutex
+	closed bool
+	next   int
+	jobs   chan task[T]
+
+	wg       sync.WaitGroup
+	done     chan Result[T] // worker 把结果交给 collect，Results 为 false 时为 nil
+	results  chan Result[T]
+	finished chan struct{} // 所有 worker 退出并且结果都已输出后关闭
+
+	errMu sync.Mutex
+	errs  []Result[T]
+
+	queued, running, succeeded, failed, canceled atomic.Int64
+}
+
+// New 启动 worker。ctx 取消后队列中的任务不再执行，正在执行的任务从 ctx 得知取消。
+// 提交完任务后必须调用 Close 或 Wait，否则 worker 不会退出
+func New[T any](ctx context.Context, opts Options) *Pool[T] {
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.NumCPU()
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = opts.Workers
+	}
+	p := &Pool[T]{
+		ctx:        ctx,
+		jobTimeout: opts.JobTimeout,
+		jobs:       make(chan task[T], opts.QueueSize),
+		finished:   make(chan struct{}),
+	}
+	if opts.Results {
+		p.done = make(chan Result[T])
+		p.results = make(chan Result[T])
+	}
+	p.wg.Add(opts.Workers)
+	for i := 0; i < opts.Workers; i++ {
+		go p.work()
+	}
+	go func() {
+		p.wg.Wait()
+		if p.done == nil {
+			close(p.finished)
+			return
+		}
+		close(p.done)
+	}()
+	if p.done != nil {
+		go p.collect(opts.Ordered)
+	}
+	return p
+}
+
+// Submit 提交一个使用默认超时的任务，队列满时阻塞，池被取消时返回 ctx 的错误
+func (p *Pool[T]) Submit(job Job[T]) error {
+	return p.SubmitTimeout(job, p.jobTimeout)
+}
+
+// SubmitTimeout 提交一个任务，timeout 为 0 表示不限制
+func (p *Pool[T]) SubmitTimeout(job Job[T], timeout time.Duration) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return ErrClosed
+	}
+	if err := p.ctx.Err(); err != nil {
+		return err
+	}
+	// 先计入 Queued，worker 可能在 Submit 返回之前就取走任务
+	p.queued.Add(1)
+	select {
+	case p.jobs <- task[T]{index: p.next, job: job, timeout: timeout}:
+		p.next++
+		return nil
+	case <-p.ctx.Done():
+		p.queued.Add(-1)
+		return p.ctx.Err()
+	}
+}
+
+// Close 停止接收任务，已提交的任务继续执行。可以多次调用
+func (p *Pool[T]) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.closed {
+		p.closed = true
+		close(p.jobs)
+	}
+}
+
+// Results 在 Options.Results 为 true 时返回结果 channel，所有结果输出后关闭；否则返回 nil
+func (p *Pool[T]) Results() <-chan Result[T] {
+	return p.results
+}
+
+// Wait 调用 Close 并等待所有任务结束，返回按提交顺序合并的任务错误；
+// 池被取消导致有任务没有执行时，还包含 ctx 的错误
+func (p *Pool[T]) Wait() error {
+	p.Close()
+	<-p.finished
+	p.errMu.Lock()
+	defer p.errMu.Unlock()
+	sort.Slice(p.errs, func(i, j int) bool { return p.errs[i].Index < p.errs[j].Index })
+	var errs []error
+	for _, r := range p.errs {
+		errs = append(errs, fmt.Errorf("job %d: %w", r.Index, r.Err))
+	}
+	if p.canceled.Load() > 0 {
+		errs = append(errs, p.ctx.Err())
+	}
+	return errors.Join(errs...)
+}
+
+// Stats 返回当前的任务计数，可以在任务执行时随时调用
+func (p *Pool[T]) Stats() Stats {
+	return Stats{
+		Queued:    p.queued.Load(),
+		Running:   p.running.Load(),
+		Succeeded: p.succeeded.Load(),
+		Failed:    p.failed.Load(),
+		Canceled:  p.canceled.Load(),
+	}
+}
+
+func (p *Pool[T]) work() {
+	defer p.wg.Done()
+	for t := range p.jobs {
+		p.queued.Add(-1)
+		if err := p.ctx.Err(); err != nil {
+			// 取消后仍然输出一个结果，Ordered 时后面的结果不会一直等它
+			p.canceled.Add(1)
+			p.emit(Result[T]{Index: t.index, Err: err})
+			continue
+		}
+		p.running.Add(1)
+		r := p.run(t)
+		p.running.Add(-1)
+		if r.Err != nil {
+			p.failed.Add(1)
+			p.errMu.Lock()
+			p.errs = append(p.errs, r)
+			p.errMu.Unlock()
+		} else {
+			p.succeeded.Add(1)
+		}
+		p.emit(r)
+	}
+}
+
+func (p *Pool[T]) run(t task[T]) (r Result[T]) {
+	r.Index = t.index
+	ctx := p.ctx
+	if t.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.timeout)
+		defer cancel()
+	}
+	defer func() {
+		if v := recover(); v != nil {
+			r.Err = &PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+	r.Value, r.Err = t.job(ctx)
+	return r
+}
+
+func (p *Pool[T]) emit(r Result[T]) {
+	if p.done != nil {
+		p.done <- r
+	}
+}
+
+// collect 把 worker 的结果转发到 Results()。结果先放进 queue，worker 不会因为调用方读得慢而停下；
+// Ordered 时乱序到达的结果先放在 pending 中，等前面的结果都到了再输出
+func (p *Pool[T]) collect(ordered bool) {
+	defer close(p.finished)
+	defer close(p.results)
+	in := p.done
+	var queue []Result[T]
+	pending := make(map[int]Result[T])
+	next := 0
+	for in != nil || len(queue) > 0 {
+		var out chan Result[T]
+		var head Result[T]
+		if len(queue) > 0 {
+			out, head = p.results, queue[0]
+		}
+		select {
+		case r, ok := <-in:
+			if !ok {
+				in = nil
+				continue
+			}
+			if !ordered {
+				queue = append(queue, r)
+				continue
+			}
+			pending[r.Index] = r
+			for r, ok := pending[next]; ok; r, ok = pending[next] {
+				delete(pending, next)
+				queue = append(queue, r)
+				next++
+			}
+		case out <- head:
+			queue = queue[1:]
+		}
+	}
+}
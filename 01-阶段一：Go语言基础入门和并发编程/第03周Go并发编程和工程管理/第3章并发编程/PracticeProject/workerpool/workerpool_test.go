@@ -0,0 +1,197 @@
+/**
+ * @File : workerpool_test.go
+ * @Description : 测试结果顺序、错误汇总、panic 捕获、超时、取消、有界队列和实时计数
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package workerpool
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// square 让编号小的任务睡得久，先提交的任务反而后完成
+func square(i, n int) Job[int] {
+	return func(ctx context.Context) (int, error) {
+		time.Sleep(time.Duration(n-i) * time.Millisecond)
+		return i * i, nil
+	}
+}
+
+func TestResults(t *testing.T) {
+	tests := []struct {
+		name    string
+		ordered bool
+	}{
+		{"ordered", true},
+		{"unordered", false},
+	}
+	const n = 20
+	for _, tt := range tests {
+		p := New[int](context.Background(), Options{Workers: 4, Results: true, Ordered: tt.ordered})
+		go func() {
+			for i := 0; i < n; i++ {
+				if err := p.Submit(square(i, n)); err != nil {
+					t.Errorf("%s: Submit() error = %v", tt.name, err)
+				}
+			}
+			p.Close()
+		}()
+		var indexes []int
+		for r := range p.Results() {
+			if r.Err != nil || r.Value != r.Index*r.Index {
+				t.Errorf("%s: result %d = %d, %v", tt.name, r.Index, r.Value, r.Err)
+			}
+			indexes = append(indexes, r.Index)
+		}
+		if err := p.Wait(); err != nil {
+			t.Errorf("%s: Wait() error = %v", tt.name, err)
+		}
+		if len(indexes) != n {
+			t.Fatalf("%s: got %d results, expect %d", tt.name, len(indexes), n)
+		}
+		// 无序模式下到达顺序取决于调度，只检查每个任务的结果都恰好出现一次
+		inOrder := true
+		seen := make(map[int]bool)
+		for i, idx := range indexes {
+			inOrder = inOrder && idx == i
+			if idx < 0 || idx >= n || seen[idx] {
+				t.Errorf("%s: unexpected or duplicate index %d in %v", tt.name, idx, indexes)
+			}
+			seen[idx] = true
+		}
+		if tt.ordered && !inOrder {
+			t.Errorf("%s: results arrived out of order: %v", tt.name, indexes)
+		}
+	}
+}
+
+func TestWaitErrors(t *testing.T) {
+	p := New[int](context.Background(), Options{Workers: 2})
+	jobs := []Job[int]{
+		func(ctx context.Context) (int, error) { return 1, nil },
+		func(ctx context.Context) (int, error) { return 0, errors.New("boom") },
+		func(ctx context.Context) (int, error) { panic("bad input") },
+		func(ctx context.Context) (int, error) { <-ctx.Done(); return 0, ctx.Err() },
+	}
+	for i, job := range jobs {
+		var err error
+		if i == 3 {
+			err = p.SubmitTimeout(job, 10*time.Millisecond)
+		} else {
+			err = p.Submit(job)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := p.Wait()
+	var pe *PanicError
+	if !errors.As(err, &pe) || pe.Value != "bad input" || !strings.Contains(string(pe.Stack), "workerpool") {
+		t.Errorf("Wait() error = %v, expect a PanicError with a stack", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait() error = %v, expect it to contain DeadlineExceeded", err)
+	}
+	expect := "job 1: boom\njob 2: job panicked: bad input\njob 3: context deadline exceeded"
+	if err == nil || err.Error() != expect {
+		t.Errorf("Wait() error = %q, expect %q", err, expect)
+	}
+	if s := p.Stats(); s != (Stats{Succeeded: 1, Failed: 3}) {
+		t.Errorf("Stats() = %+v, expect 1 succeeded and 3 failed", s)
+	}
+	if err := p.Submit(jobs[0]); !errors.Is(err, ErrClosed) {
+		t.Errorf("Submit() after Wait error = %v, expect ErrClosed", err)
+	}
+}
+
+// waitFor 轮询直到 cond 成立，Stats 由 worker 异步更新
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBoundedQueue(t *testing.T) {
+	release := make(chan struct{})
+	block := func(ctx context.Context) (int, error) {
+		<-release
+		return 0, nil
+	}
+	p := New[int](context.Background(), Options{Workers: 1, QueueSize: 2})
+	for i := 0; i < 3; i++ {
+		if err := p.Submit(block); err != nil {
+			t.Fatal(err)
+		}
+	}
+	waitFor(t, "one running and two queued jobs", func() bool { return p.Stats() == Stats{Queued: 2, Running: 1} })
+
+	submitted := make(chan error)
+	go func() { submitted <- p.Submit(block) }()
+	select {
+	case err := <-submitted:
+		t.Fatalf("Submit() into a full queue returned %v, expect it to block", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	if err := <-submitted; err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if s := p.Stats(); s != (Stats{Succeeded: 4}) {
+		t.Errorf("Stats() = %+v, expect 4 succeeded", s)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	p := New[int](ctx, Options{Workers: 1, QueueSize: 3, Results: true, Ordered: true})
+	if err := p.Submit(func(ctx context.Context) (int, error) {
+		close(started)
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := p.Submit(square(0, 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-started
+	cancel()
+	if err := p.Submit(square(0, 0)); !errors.Is(err, context.Canceled) {
+		t.Errorf("Submit() after cancel error = %v, expect Canceled", err)
+	}
+	p.Close()
+
+	var n int
+	for r := range p.Results() {
+		if r.Index != n || !errors.Is(r.Err, context.Canceled) {
+			t.Errorf("result %d = %+v, expect index %d canceled", n, r, n)
+		}
+		n++
+	}
+	if n != 4 {
+		t.Errorf("got %d results, expect 4", n)
+	}
+	err := p.Wait()
+	if !errors.Is(err, context.Canceled) || !strings.HasPrefix(err.Error(), "job 0: context canceled") {
+		t.Errorf("Wait() error = %v, expect job 0 and the pool to be canceled", err)
+	}
+	if s := p.Stats(); s != (Stats{Failed: 1, Canceled: 3}) {
+		t.Errorf("Stats() = %+v, expect 1 failed and 3 canceled", s)
+	}
+}
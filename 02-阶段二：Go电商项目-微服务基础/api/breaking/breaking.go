@@ -0,0 +1,254 @@
+/**
+ * @File : breaking.go
+ * @Description : 比较两个版本的 proto 定义，找出会让旧客户端或旧服务端出错的修改
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package breaking
+
+import (
+	"fmt"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"sort"
+)
+
+// Change 是一处不兼容的修改，位置指向新版本中相关的定义，删除整个文件时指向旧文件
+type Change struct {
+	File    string
+	Line    int // 从 1 开始，0 表示没有位置信息
+	Message string
+}
+
+func (c Change) String() string {
+	if c.Line == 0 {
+		return fmt.Sprintf("%s: %s", c.File, c.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s", c.File, c.Line, c.Message)
+}
+
+// Compare 检查从 old 到 cur 的修改。规则大致对应 buf 的 WIRE_JSON 级别，另外保护生成代码的 import 路径：
+//
+//   - 删除文件、修改 package 或 go_package
+//   - 删除 message、enum、service、method
+//   - 删除字段或枚举值而没有 reserved 对应的编号
+//   - 修改字段的名字、类型、repeated/map、optional 或所属的 oneof
+//   - 修改枚举值的名字，修改方法的请求、响应类型或流式类型
+//
+// 新增任何东西都是兼容的
+func Compare(old, cur []protoreflect.FileDescriptor) []Change {
+	c := &comparer{}
+	curFiles := make(map[string]protoreflect.FileDescriptor)
+	for _, f := range cur {
+		curFiles[f.Path()] = f
+		c.index(f)
+	}
+	for _, of := range old {
+		nf, ok := curFiles[of.Path()]
+		if !ok {
+			c.add(of, nil, "file %s was deleted", of.Path())
+			continue
+		}
+		if of.Package() != nf.Package() {
+			c.add(nf, nil, "package changed from %q to %q", of.Package(), nf.Package())
+		}
+		if og, ng := goPackage(of), goPackage(nf); og != ng {
+			c.add(nf, nil, "go_package changed from %q to %q", og, ng)
+		}
+		c.messages(of, of.Messages())
+		c.enums(of, of.Enums())
+		c.services(of)
+	}
+	sort.SliceStable(c.changes, func(i, j int) bool {
+		a, b := c.changes[i], c.changes[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	return c.changes
+}
+
+type comparer struct {
+	changes []Change
+	// 新版本中按全名索引的定义，类型从一个文件移到另一个文件时仍然能找到
+	byName map[protoreflect.FullName]protoreflect.Descriptor
+}
+
+func (c *comparer) index(f protoreflect.FileDescriptor) {
+	if c.byName == nil {
+		c.byName = make(map[protoreflect.FullName]protoreflect.Descriptor)
+	}
+	var walk func(protoreflect.MessageDescriptors)
+	walk = func(msgs protoreflect.MessageDescriptors) {
+		for i := 0; i < msgs.Len(); i++ {
+			m := msgs.Get(i)
+			c.byName[m.FullName()] = m
+			for j := 0; j < m.Enums().Len(); j++ {
+				c.byName[m.Enums().Get(j).FullName()] = m.Enums().Get(j)
+			}
+			walk(m.Messages())
+		}
+	}
+	walk(f.Messages())
+	for i := 0; i < f.Enums().Len(); i++ {
+		c.byName[f.Enums().Get(i).FullName()] = f.Enums().Get(i)
+	}
+	for i := 0; i < f.Services().Len(); i++ {
+		c.byName[f.Services().Get(i).FullName()] = f.Services().Get(i)
+	}
+}
+
+// add 记录一处修改，at 不为 nil 时报告它在新版本中的位置，否则只报告文件
+func (c *comparer) add(file protoreflect.FileDescriptor, at protoreflect.Descriptor, format string, args ...any) {
+	ch := Change{File: file.Path(), Message: fmt.Sprintf(format, args...)}
+	if at != nil {
+		file = at.ParentFile()
+		ch.File = file.Path()
+		if loc := file.SourceLocations().ByDescriptor(at); loc.Path != nil {
+			ch.Line = loc.StartLine + 1
+		}
+	}
+	c.changes = append(c.changes, ch)
+}
+
+func goPackage(f protoreflect.FileDescriptor) string {
+	type goPackager interface{ GetGoPackage() string }
+	if opts, ok := f.Options().(goPackager); ok {
+		return opts.GetGoPackage()
+	}
+	return ""
+}
+
+func (c *comparer) messages(oldFile protoreflect.FileDescriptor, msgs protoreflect.MessageDescriptors) {
+	for i := 0; i < msgs.Len(); i++ {
+		om := msgs.Get(i)
+		if om.IsMapEntry() {
+			// map 的 entry 是生成的 message，修改已经在 map 字段上报告过
+			continue
+		}
+		nm, ok := c.byName[om.FullName()].(protoreflect.MessageDescriptor)
+		if !ok {
+			c.add(oldFile, nil, "message %s was deleted", om.FullName())
+			continue
+		}
+		c.fields(om, nm)
+		c.messages(oldFile, om.Messages())
+		c.enums(oldFile, om.Enums())
+	}
+}
+
+func (c *comparer) fields(om, nm protoreflect.MessageDescriptor) {
+	for i := 0; i < om.Fields().Len(); i++ {
+		of := om.Fields().Get(i)
+		nf := nm.Fields().ByNumber(of.Number())
+		if nf == nil {
+			if !nm.ReservedRanges().Has(of.Number()) {
+				c.add(nm.ParentFile(), nm, "field %d %q of %s was deleted without reserving its number", of.Number(), of.Name(), om.FullName())
+			}
+			continue
+		}
+		if of.Name() != nf.Name() {
+			c.add(nm.ParentFile(), nf, "field %d of %s was renamed from %q to %q", of.Number(), om.FullName(), of.Name(), nf.Name())
+		}
+		if ot, nt := fieldType(of), fieldType(nf); ot != nt {
+			c.add(nm.ParentFile(), nf, "field %s changed type from %s to %s", nf.FullName(), ot, nt)
+		}
+		switch {
+		case oneofName(of) != oneofName(nf):
+			c.add(nm.ParentFile(), nf, "field %s moved from oneof %q to %q", nf.FullName(), oneofName(of), oneofName(nf))
+		case of.HasPresence() != nf.HasPresence() && !of.IsList() && !nf.IsList() && of.Message() == nil && nf.Message() == nil:
+			// 标量字段加上或去掉 optional 不影响 wire 格式，但生成代码的字段类型会在 T 和 *T 之间变化
+			c.add(nm.ParentFile(), nf, "field %s changed presence (optional added or removed)", nf.FullName())
+		}
+	}
+}
+
+// fieldType 描述字段在 wire 和生成代码中的形状，比如 "repeated string"、"map<string, int64>"、"message greeter.v1.HelloRequest"
+func fieldType(f protoreflect.FieldDescriptor) string {
+	if f.IsMap() {
+		return fmt.Sprintf("map<%s, %s>", fieldType(f.MapKey()), fieldType(f.MapValue()))
+	}
+	t := f.Kind().String()
+	switch f.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		t += " " + string(f.Message().FullName())
+	case protoreflect.EnumKind:
+		t += " " + string(f.Enum().FullName())
+	}
+	if f.IsList() {
+		t = "repeated " + t
+	}
+	return t
+}
+
+func oneofName(f protoreflect.FieldDescriptor) string {
+	// proto3 optional 在描述符里是一个合成的 oneof，不算真正的 oneof
+	if o := f.ContainingOneof(); o != nil && !o.IsSynthetic() {
+		return string(o.Name())
+	}
+	return ""
+}
+
+func (c *comparer) enums(oldFile protoreflect.FileDescriptor, enums protoreflect.EnumDescriptors) {
+	for i := 0; i < enums.Len(); i++ {
+		oe := enums.Get(i)
+		ne, ok := c.byName[oe.FullName()].(protoreflect.EnumDescriptor)
+		if !ok {
+			c.add(oldFile, nil, "enum %s was deleted", oe.FullName())
+			continue
+		}
+		for j := 0; j < oe.Values().Len(); j++ {
+			ov := oe.Values().Get(j)
+			nv := ne.Values().ByNumber(ov.Number())
+			if nv == nil {
+				if !ne.ReservedRanges().Has(ov.Number()) {
+					c.add(ne.ParentFile(), ne, "enum value %d %q of %s was deleted without reserving its number", ov.Number(), ov.Name(), oe.FullName())
+				}
+				continue
+			}
+			if ov.Name() != nv.Name() {
+				c.add(ne.ParentFile(), nv, "enum value %d of %s was renamed from %q to %q", ov.Number(), oe.FullName(), ov.Name(), nv.Name())
+			}
+		}
+	}
+}
+
+func (c *comparer) services(oldFile protoreflect.FileDescriptor) {
+	for i := 0; i < oldFile.Services().Len(); i++ {
+		osvc := oldFile.Services().Get(i)
+		ns, ok := c.byName[osvc.FullName()].(protoreflect.ServiceDescriptor)
+		if !ok {
+			c.add(oldFile, nil, "service %s was deleted", osvc.FullName())
+			continue
+		}
+		for j := 0; j < osvc.Methods().Len(); j++ {
+			om := osvc.Methods().Get(j)
+			nm := ns.Methods().ByName(om.Name())
+			if nm == nil {
+				c.add(ns.ParentFile(), ns, "method %s was deleted", om.FullName())
+				continue
+			}
+			if om.Input().FullName() != nm.Input().FullName() {
+				c.add(ns.ParentFile(), nm, "method %s changed request type from %s to %s", nm.FullName(), om.Input().FullName(), nm.Input().FullName())
+			}
+			if om.Output().FullName() != nm.Output().FullName() {
+				c.add(ns.ParentFile(), nm, "method %s changed response type from %s to %s", nm.FullName(), om.Output().FullName(), nm.Output().FullName())
+			}
+			if om.IsStreamingClient() != nm.IsStreamingClient() || om.IsStreamingServer() != nm.IsStreamingServer() {
+				c.add(ns.ParentFile(), nm, "method %s changed streaming from %s to %s", nm.FullName(), streaming(om), streaming(nm))
+			}
+		}
+	}
+}
+
+func streaming(m protoreflect.MethodDescriptor) string {
+	switch {
+	case m.IsStreamingClient() && m.IsStreamingServer():
+		return "bidi streaming"
+	case m.IsStreamingClient():
+		return "client streaming"
+	case m.IsStreamingServer():
+		return "server streaming"
+	}
+	return "unary"
+}
@@ -0,0 +1,143 @@
+/**
+ * @File : breaking_test.go
+ * @Description : 每条规则一个用例：把基准 proto 改一处，检查报告的修改
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package breaking
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const base = `syntax = "proto3";
+
+package shop.v1;
+
+option go_package = "api/shop/v1;shopv1";
+
+service OrderService {
+  rpc Create(CreateRequest) returns (Order);
+  rpc Watch(WatchRequest) returns (stream Order);
+}
+
+message CreateRequest {
+  string sku = 1;
+  int32 count = 2;
+  repeated string tags = 3;
+  oneof payment {
+    string card = 4;
+    string wallet = 5;
+  }
+  optional string note = 6;
+}
+
+message WatchRequest {
+  string id = 1;
+}
+
+message Order {
+  string id = 1;
+  Status status = 2;
+  map<string, int64> items = 3;
+}
+
+enum Status {
+  STATUS_UNSPECIFIED = 0;
+  STATUS_PAID = 1;
+  STATUS_SHIPPED = 2;
+}
+`
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name string
+		// edit 把基准中的 old 替换成 new
+		old, new string
+		want     []string
+	}{
+		{"add field", "int32 count = 2;", "int32 count = 2;\n  string coupon = 7;", nil},
+		{"add method", "rpc Create(CreateRequest) returns (Order);", "rpc Create(CreateRequest) returns (Order);\n  rpc Cancel(WatchRequest) returns (Order);", nil},
+		{"delete field with reserved number", "int32 count = 2;", "reserved 2;", nil},
+		{"delete field", "int32 count = 2;", "", []string{`shop.proto:12: field 2 "count" of shop.v1.CreateRequest was deleted without reserving its number`}},
+		{"rename field", "string sku = 1;", "string product = 1;", []string{`shop.proto:13: field 1 of shop.v1.CreateRequest was renamed from "sku" to "product"`}},
+		{"change field type", "int32 count = 2;", "int64 count = 2;", []string{"shop.proto:14: field shop.v1.CreateRequest.count changed type from int32 to int64"}},
+		{"make field repeated", "string id = 1;\n}\n\nmessage Order", "repeated string id = 1;\n}\n\nmessage Order", []string{"shop.proto:24: field shop.v1.WatchRequest.id changed type from string to repeated string"}},
+		{"change map value", "map<string, int64> items", "map<string, int32> items", []string{"shop.proto:30: field shop.v1.Order.items changed type from map<string, int64> to map<string, int32>"}},
+		{"drop optional", "optional string note", "string note", []string{"shop.proto:20: field shop.v1.CreateRequest.note changed presence (optional added or removed)"}},
+		{"move out of oneof", "    string wallet = 5;\n  }", "  }\n  string wallet = 5;", []string{`shop.proto:19: field shop.v1.CreateRequest.wallet moved from oneof "payment" to ""`}},
+		{"delete enum value", "  STATUS_SHIPPED = 2;\n", "", []string{`shop.proto:33: enum value 2 "STATUS_SHIPPED" of shop.v1.Status was deleted without reserving its number`}},
+		{"rename enum value", "STATUS_SHIPPED = 2", "STATUS_SENT = 2", []string{`shop.proto:36: enum value 2 of shop.v1.Status was renamed from "STATUS_SHIPPED" to "STATUS_SENT"`}},
+		{"change streaming", "returns (stream Order)", "returns (Order)", []string{"shop.proto:9: method shop.v1.OrderService.Watch changed streaming from server streaming to unary"}},
+		{"change request type", "rpc Watch(WatchRequest)", "rpc Watch(CreateRequest)", []string{"shop.proto:9: method shop.v1.OrderService.Watch changed request type from shop.v1.WatchRequest to shop.v1.CreateRequest"}},
+		{"delete method", "  rpc Watch(WatchRequest) returns (stream Order);\n", "", []string{"shop.proto:7: method shop.v1.OrderService.Watch was deleted"}},
+		{"change package", "package shop.v1;", "package shop.v2;", []string{
+			`shop.proto: package changed from "shop.v1" to "shop.v2"`,
+			"shop.proto: message shop.v1.CreateRequest was deleted",
+			"shop.proto: message shop.v1.WatchRequest was deleted",
+			"shop.proto: message shop.v1.Order was deleted",
+			"shop.proto: enum shop.v1.Status was deleted",
+			"shop.proto: service shop.v1.OrderService was deleted",
+		}},
+		{"change go_package", `"api/shop/v1;shopv1"`, `"api/shop;shop"`, []string{`shop.proto: go_package changed from "api/shop/v1;shopv1" to "api/shop;shop"`}},
+	}
+	ctx := context.Background()
+	old, err := Compile(ctx, map[string][]byte{"shop.proto": []byte(base)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		if !strings.Contains(base, tt.old) {
+			t.Fatalf("%s: %q is not in the base proto", tt.name, tt.old)
+		}
+		cur, err := Compile(ctx, map[string][]byte{"shop.proto": []byte(strings.Replace(base, tt.old, tt.new, 1))})
+		if err != nil {
+			t.Fatalf("%s: Compile() error = %v", tt.name, err)
+		}
+		var got []string
+		for _, c := range Compare(old, cur) {
+			got = append(got, c.String())
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Compare() =\n%s\nexpect\n%s", tt.name, strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+		}
+	}
+}
+
+func TestCompareMovedFile(t *testing.T) {
+	ctx := context.Background()
+	old, err := Compile(ctx, map[string][]byte{"shop.proto": []byte(base)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 文件改名但内容不变：生成代码的文件名变了，但类型的全名和 go_package 都没变
+	cur, err := Compile(ctx, map[string][]byte{"order.proto": []byte(base)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := Compare(old, cur)
+	if len(got) != 1 || got[0].String() != "shop.proto: file shop.proto was deleted" {
+		t.Errorf("Compare() = %v, expect only the deleted file", got)
+	}
+}
+
+// TestCurrentProtos 确认 api 模块中的 proto 都能编译，和自己比较没有任何修改
+func TestCurrentProtos(t *testing.T) {
+	files, err := ReadDir("..")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no proto files found in the api module")
+	}
+	fds, err := Compile(context.Background(), files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes := Compare(fds, fds); len(changes) != 0 {
+		t.Errorf("Compare() of the same files = %v, expect none", changes)
+	}
+}
@@ -0,0 +1,78 @@
+/**
+ * @File : compile.go
+ * @Description : 在内存中编译一组 proto 文件，不需要 protoc，旧版本的文件直接从 git 读出来编译
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package breaking
+
+import (
+	"bytes"
+	"context"
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Compile 编译 files（key 是相对于 import 根目录的路径），google/protobuf 下的标准文件不需要提供
+func Compile(ctx context.Context, files map[string][]byte) ([]protoreflect.FileDescriptor, error) {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	c := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: func(path string) (io.ReadCloser, error) {
+				data, ok := files[path]
+				if !ok {
+					return nil, fs.ErrNotExist
+				}
+				return io.NopCloser(bytes.NewReader(data)), nil
+			},
+		}),
+		// 保留源码位置，报告问题时可以指出行号
+		SourceInfoMode: protocompile.SourceInfoStandard,
+	}
+	compiled, err := c.Compile(ctx, names...)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]protoreflect.FileDescriptor, len(compiled))
+	for i, f := range compiled {
+		out[i] = f
+	}
+	return out, nil
+}
+
+// ReadDir 读取 root 下所有的 proto 文件，跳过 testdata 目录
+func ReadDir(root string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == "testdata" {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".proto") {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = data
+		return nil
+	})
+	return files, err
+}
@@ -0,0 +1,184 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        v5.27.5
+// source: cache/v1/cache.proto
+
+// 方法级缓存规则，以自定义 option 的形式挂在 rpc 上
+// 缓存逻辑在 rpckit/cache 中，生成代码用 api 目录下的 gen.sh
+
+package cachev1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	descriptorpb "google.golang.org/protobuf/types/descriptorpb"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type CacheRules struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// 响应最多缓存多久，不设置或为 0 表示不缓存
+	Ttl *durationpb.Duration `protobuf:"bytes,1,opt,name=ttl,proto3" json:"ttl,omitempty"`
+}
+
+func (x *CacheRules) Reset() {
+	*x = CacheRules{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_cache_v1_cache_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CacheRules) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CacheRules) ProtoMessage() {}
+
+func (x *CacheRules) ProtoReflect() protoreflect.Message {
+	mi := &file_cache_v1_cache_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CacheRules.ProtoReflect.Descriptor instead.
+func (*CacheRules) Descriptor() ([]byte, []int) {
+	return file_cache_v1_cache_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *CacheRules) GetTtl() *durationpb.Duration {
+	if x != nil {
+		return x.Ttl
+	}
+	return nil
+}
+
+var file_cache_v1_cache_proto_extTypes = []protoimpl.ExtensionInfo{
+	{
+		ExtendedType:  (*descriptorpb.MethodOptions)(nil),
+		ExtensionType: (*CacheRules)(nil),
+		Field:         51002,
+		Name:          "cache.v1.cache",
+		Tag:           "bytes,51002,opt,name=cache",
+		Filename:      "cache/v1/cache.proto",
+	},
+}
+
+// Extension fields to descriptorpb.MethodOptions.
+var (
+	// 只有幂等、并且结果只取决于请求内容的方法才能打开缓存
+	//
+	// optional cache.v1.CacheRules cache = 51002;
+	E_Cache = &file_cache_v1_cache_proto_extTypes[0]
+)
+
+var File_cache_v1_cache_proto protoreflect.FileDescriptor
+
+var file_cache_v1_cache_proto_rawDesc = []byte{
+	0x0a, 0x14, 0x63, 0x61, 0x63, 0x68, 0x65, 0x2f, 0x76, 0x31, 0x2f, 0x63, 0x61, 0x63, 0x68, 0x65,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x08, 0x63, 0x61, 0x63, 0x68, 0x65, 0x2e, 0x76, 0x31,
+	0x1a, 0x20, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
+	0x66, 0x2f, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x6f, 0x72, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x1a, 0x1e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f,
+	0x62, 0x75, 0x66, 0x2f, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x22, 0x39, 0x0a, 0x0a, 0x43, 0x61, 0x63, 0x68, 0x65, 0x52, 0x75, 0x6c, 0x65, 0x73,
+	0x12, 0x2b, 0x0a, 0x03, 0x74, 0x74, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e,
+	0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e,
+	0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x03, 0x74, 0x74, 0x6c, 0x3a, 0x4c, 0x0a,
+	0x05, 0x63, 0x61, 0x63, 0x68, 0x65, 0x12, 0x1e, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x4d, 0x65, 0x74, 0x68, 0x6f, 0x64, 0x4f,
+	0x70, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0xba, 0x8e, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14,
+	0x2e, 0x63, 0x61, 0x63, 0x68, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x43, 0x61, 0x63, 0x68, 0x65, 0x52,
+	0x75, 0x6c, 0x65, 0x73, 0x52, 0x05, 0x63, 0x61, 0x63, 0x68, 0x65, 0x42, 0x16, 0x5a, 0x14, 0x61,
+	0x70, 0x69, 0x2f, 0x63, 0x61, 0x63, 0x68, 0x65, 0x2f, 0x76, 0x31, 0x3b, 0x63, 0x61, 0x63, 0x68,
+	0x65, 0x76, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_cache_v1_cache_proto_rawDescOnce sync.Once
+	file_cache_v1_cache_proto_rawDescData = file_cache_v1_cache_proto_rawDesc
+)
+
+func file_cache_v1_cache_proto_rawDescGZIP() []byte {
+	file_cache_v1_cache_proto_rawDescOnce.Do(func() {
+		file_cache_v1_cache_proto_rawDescData = protoimpl.X.CompressGZIP(file_cache_v1_cache_proto_rawDescData)
+	})
+	return file_cache_v1_cache_proto_rawDescData
+}
+
+var file_cache_v1_cache_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_cache_v1_cache_proto_goTypes = []any{
+	(*CacheRules)(nil),                 // 0: cache.v1.CacheRules
+	(*durationpb.Duration)(nil),        // 1: google.protobuf.Duration
+	(*descriptorpb.MethodOptions)(nil), // 2: google.protobuf.MethodOptions
+}
+var file_cache_v1_cache_proto_depIdxs = []int32{
+	1, // 0: cache.v1.CacheRules.ttl:type_name -> google.protobuf.Duration
+	2, // 1: cache.v1.cache:extendee -> google.protobuf.MethodOptions
+	0, // 2: cache.v1.cache:type_name -> cache.v1.CacheRules
+	3, // [3:3] is the sub-list for method output_type
+	3, // [3:3] is the sub-list for method input_type
+	2, // [2:3] is the sub-list for extension type_name
+	1, // [1:2] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_cache_v1_cache_proto_init() }
+func file_cache_v1_cache_proto_init() {
+	if File_cache_v1_cache_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_cache_v1_cache_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*CacheRules); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_cache_v1_cache_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 1,
+			NumServices:   0,
+		},
+		GoTypes:           file_cache_v1_cache_proto_goTypes,
+		DependencyIndexes: file_cache_v1_cache_proto_depIdxs,
+		MessageInfos:      file_cache_v1_cache_proto_msgTypes,
+		ExtensionInfos:    file_cache_v1_cache_proto_extTypes,
+	}.Build()
+	File_cache_v1_cache_proto = out.File
+	file_cache_v1_cache_proto_rawDesc = nil
+	file_cache_v1_cache_proto_goTypes = nil
+	file_cache_v1_cache_proto_depIdxs = nil
+}
@@ -0,0 +1,107 @@
+/**
+ * @File : main.go
+ * @Description : 检查当前的 proto 相对于上一个发布版本（git tag api/v*）有没有不兼容的修改
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package main
+
+import (
+	"api/breaking"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+)
+
+// 用法：在 api 目录下执行
+//
+//	go run ./cmd/protobreak                    和最近的 api/v* tag 比较
+//	go run ./cmd/protobreak -against main      和任意 git 版本比较
+//
+// 发现不兼容的修改时逐条打印并以状态码 1 退出。确实要做不兼容修改时应该新建 v2 包，而不是改 v1
+func main() {
+	against := flag.String("against", "", "git revision to compare with, default is the latest api/v* tag")
+	dir := flag.String("dir", ".", "root of the proto files, the same as protoc -I")
+	flag.Parse()
+
+	ref := *against
+	if ref == "" {
+		out, err := git(*dir, "describe", "--tags", "--abbrev=0", "--match", "api/v*")
+		if err != nil {
+			log.Fatalf("没有找到 api/v* tag，先用 git tag api/v1.0.0 标记一个版本，或者用 -against 指定: %v", err)
+		}
+		ref = out
+	}
+
+	ctx := context.Background()
+	oldFiles, err := filesAt(*dir, ref)
+	if err != nil {
+		log.Fatalf("读取 %s 的 proto 失败: %v", ref, err)
+	}
+	curFiles, err := breaking.ReadDir(*dir)
+	if err != nil {
+		log.Fatalf("读取当前的 proto 失败: %v", err)
+	}
+	old, err := breaking.Compile(ctx, oldFiles)
+	if err != nil {
+		log.Fatalf("编译 %s 的 proto 失败: %v", ref, err)
+	}
+	cur, err := breaking.Compile(ctx, curFiles)
+	if err != nil {
+		log.Fatalf("编译当前的 proto 失败: %v", err)
+	}
+
+	changes := breaking.Compare(old, cur)
+	for _, c := range changes {
+		fmt.Println(c)
+	}
+	if len(changes) > 0 {
+		fmt.Printf("%d breaking changes against %s\n", len(changes), ref)
+		os.Exit(1)
+	}
+	fmt.Printf("no breaking changes against %s (%d files)\n", ref, len(oldFiles))
+}
+
+func git(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(ee.Stderr)))
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// filesAt 从 git 中读出 dir 在 ref 版本时的所有 proto 文件，不需要切换工作区
+func filesAt(dir, ref string) (map[string][]byte, error) {
+	// ls-tree 列出的路径相对于仓库根目录，prefix 是 dir 在仓库中的位置
+	prefix, err := git(dir, "rev-parse", "--show-prefix")
+	if err != nil {
+		return nil, err
+	}
+	list, err := git(dir, "-c", "core.quotePath=false", "ls-tree", "-r", "--name-only", "--full-tree", ref, "--", ":/"+prefix)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]byte)
+	for _, name := range strings.Split(list, "\n") {
+		rel := strings.TrimPrefix(name, prefix)
+		if !strings.HasSuffix(name, ".proto") || strings.HasPrefix(rel, "testdata/") || strings.Contains(rel, "/testdata/") {
+			continue
+		}
+		data, err := git(dir, "show", ref+":"+name)
+		if err != nil {
+			return nil, err
+		}
+		files[path.Clean(rel)] = []byte(data + "\n")
+	}
+	return files, nil
+}
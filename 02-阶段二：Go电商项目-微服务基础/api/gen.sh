@@ -0,0 +1,12 @@
+#!/usr/bin/env bash
+# 重新生成 api 模块中所有 proto 的 Go 代码，生成的文件和 proto 放在同一个目录
+# 需要 protoc、protoc-gen-go v1.34.2 和 protoc-gen-go-grpc v1.5.1，在任意目录执行：bash api/gen.sh
+set -euo pipefail
+
+cd "$(dirname "$0")"
+protos=$(find . -name '*.proto' -not -path './testdata/*' | sed 's|^\./||' | sort)
+protoc -I . \
+	--go_out=. --go_opt=paths=source_relative \
+	--go-grpc_out=. --go-grpc_opt=paths=source_relative \
+	$protos
+echo "generated: $(echo $protos)"
@@ -2,9 +2,11 @@
 // versions:
 // - protoc-gen-go-grpc v1.5.1
 // - protoc             v5.27.5
-// source: serverStream.proto
+// source: greeter/v1/greeter.proto
 
-package proto
+// Greeter 合并了原来 protobuf_test 中的 SayHello 和 grpc_server_streaming 中的 StreamNumbers
+
+package greeterv1
 
 import (
 	context "context"
@@ -19,14 +21,20 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	Greeter_StreamNumbers_FullMethodName = "/Greeter/StreamNumbers"
+	Greeter_SayHello_FullMethodName      = "/greeter.v1.Greeter/SayHello"
+	Greeter_StreamNumbers_FullMethodName = "/greeter.v1.Greeter/StreamNumbers"
+	Greeter_GetGreeting_FullMethodName   = "/greeter.v1.Greeter/GetGreeting"
 )
 
 // GreeterClient is the client API for Greeter service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type GreeterClient interface {
+	SayHello(ctx context.Context, in *HelloRequest, opts ...grpc.CallOption) (*HelloReply, error)
+	// 依次发送 1 到 10，服务端配置了 SumService 时最后一条消息是总和
 	StreamNumbers(ctx context.Context, in *StreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StreamResponse], error)
+	// 按 read_mask 只返回需要的字段，read_mask 为空时返回全部字段
+	GetGreeting(ctx context.Context, in *GetGreetingRequest, opts ...grpc.CallOption) (*Greeting, error)
 }
 
 type greeterClient struct {
@@ -37,6 +45,16 @@ func NewGreeterClient(cc grpc.ClientConnInterface) GreeterClient {
 	return &greeterClient{cc}
 }
 
+func (c *greeterClient) SayHello(ctx context.Context, in *HelloRequest, opts ...grpc.CallOption) (*HelloReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HelloReply)
+	err := c.cc.Invoke(ctx, Greeter_SayHello_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *greeterClient) StreamNumbers(ctx context.Context, in *StreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StreamResponse], error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	stream, err := c.cc.NewStream(ctx, &Greeter_ServiceDesc.Streams[0], Greeter_StreamNumbers_FullMethodName, cOpts...)
@@ -56,11 +74,25 @@ func (c *greeterClient) StreamNumbers(ctx context.Context, in *StreamRequest, op
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type Greeter_StreamNumbersClient = grpc.ServerStreamingClient[StreamResponse]
 
+func (c *greeterClient) GetGreeting(ctx context.Context, in *GetGreetingRequest, opts ...grpc.CallOption) (*Greeting, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Greeting)
+	err := c.cc.Invoke(ctx, Greeter_GetGreeting_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // GreeterServer is the server API for Greeter service.
 // All implementations must embed UnimplementedGreeterServer
 // for forward compatibility.
 type GreeterServer interface {
+	SayHello(context.Context, *HelloRequest) (*HelloReply, error)
+	// 依次发送 1 到 10，服务端配置了 SumService 时最后一条消息是总和
 	StreamNumbers(*StreamRequest, grpc.ServerStreamingServer[StreamResponse]) error
+	// 按 read_mask 只返回需要的字段，read_mask 为空时返回全部字段
+	GetGreeting(context.Context, *GetGreetingRequest) (*Greeting, error)
 	mustEmbedUnimplementedGreeterServer()
 }
 
@@ -71,9 +103,15 @@ type GreeterServer interface {
 // pointer dereference when methods are called.
 type UnimplementedGreeterServer struct{}
 
+func (UnimplementedGreeterServer) SayHello(context.Context, *HelloRequest) (*HelloReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SayHello not implemented")
+}
 func (UnimplementedGreeterServer) StreamNumbers(*StreamRequest, grpc.ServerStreamingServer[StreamResponse]) error {
 	return status.Errorf(codes.Unimplemented, "method StreamNumbers not implemented")
 }
+func (UnimplementedGreeterServer) GetGreeting(context.Context, *GetGreetingRequest) (*Greeting, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetGreeting not implemented")
+}
 func (UnimplementedGreeterServer) mustEmbedUnimplementedGreeterServer() {}
 func (UnimplementedGreeterServer) testEmbeddedByValue()                 {}
 
@@ -95,6 +133,24 @@ func RegisterGreeterServer(s grpc.ServiceRegistrar, srv GreeterServer) {
 	s.RegisterService(&Greeter_ServiceDesc, srv)
 }
 
+func _Greeter_SayHello_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HelloRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GreeterServer).SayHello(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Greeter_SayHello_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GreeterServer).SayHello(ctx, req.(*HelloRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Greeter_StreamNumbers_Handler(srv interface{}, stream grpc.ServerStream) error {
 	m := new(StreamRequest)
 	if err := stream.RecvMsg(m); err != nil {
@@ -106,13 +162,40 @@ func _Greeter_StreamNumbers_Handler(srv interface{}, stream grpc.ServerStream) e
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type Greeter_StreamNumbersServer = grpc.ServerStreamingServer[StreamResponse]
 
+func _Greeter_GetGreeting_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetGreetingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GreeterServer).GetGreeting(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Greeter_GetGreeting_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GreeterServer).GetGreeting(ctx, req.(*GetGreetingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Greeter_ServiceDesc is the grpc.ServiceDesc for Greeter service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
 var Greeter_ServiceDesc = grpc.ServiceDesc{
-	ServiceName: "Greeter",
+	ServiceName: "greeter.v1.Greeter",
 	HandlerType: (*GreeterServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SayHello",
+			Handler:    _Greeter_SayHello_Handler,
+		},
+		{
+			MethodName: "GetGreeting",
+			Handler:    _Greeter_GetGreeting_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "StreamNumbers",
@@ -120,5 +203,5 @@ var Greeter_ServiceDesc = grpc.ServiceDesc{
 			ServerStreams: true,
 		},
 	},
-	Metadata: "serverStream.proto",
+	Metadata: "greeter/v1/greeter.proto",
 }
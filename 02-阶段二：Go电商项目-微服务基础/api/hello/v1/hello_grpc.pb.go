@@ -2,9 +2,11 @@
 // versions:
 // - protoc-gen-go-grpc v1.5.1
 // - protoc             v5.27.5
-// source: helloworld.proto
+// source: hello/v1/hello.proto
 
-package proto
+// HelloService 原来在 grpc_protoc/hello.proto 和 grpc_test/proto/helloworld.proto 中各有一份
+
+package hellov1
 
 import (
 	context "context"
@@ -19,13 +21,14 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	HelloService_SayHello_FullMethodName = "/HelloService/SayHello"
+	HelloService_SayHello_FullMethodName = "/hello.v1.HelloService/SayHello"
 )
 
 // HelloServiceClient is the client API for HelloService service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type HelloServiceClient interface {
+	// 结果只取决于 name 和调用方身份，可以在服务端缓存
 	SayHello(ctx context.Context, in *HelloRequest, opts ...grpc.CallOption) (*HelloResponse, error)
 }
 
@@ -51,6 +54,7 @@ func (c *helloServiceClient) SayHello(ctx context.Context, in *HelloRequest, opt
 // All implementations must embed UnimplementedHelloServiceServer
 // for forward compatibility.
 type HelloServiceServer interface {
+	// 结果只取决于 name 和调用方身份，可以在服务端缓存
 	SayHello(context.Context, *HelloRequest) (*HelloResponse, error)
 	mustEmbedUnimplementedHelloServiceServer()
 }
@@ -108,7 +112,7 @@ func _HelloService_SayHello_Handler(srv interface{}, ctx context.Context, dec fu
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
 var HelloService_ServiceDesc = grpc.ServiceDesc{
-	ServiceName: "HelloService",
+	ServiceName: "hello.v1.HelloService",
 	HandlerType: (*HelloServiceServer)(nil),
 	Methods: []grpc.MethodDesc{
 		{
@@ -117,5 +121,5 @@ var HelloService_ServiceDesc = grpc.ServiceDesc{
 		},
 	},
 	Streams:  []grpc.StreamDesc{},
-	Metadata: "helloworld.proto",
+	Metadata: "hello/v1/hello.proto",
 }
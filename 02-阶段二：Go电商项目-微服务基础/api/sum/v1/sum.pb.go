@@ -2,9 +2,9 @@
 // versions:
 // 	protoc-gen-go v1.34.2
 // 	protoc        v5.27.5
-// source: clientStream.proto
+// source: sum/v1/sum.proto
 
-package proto
+package sumv1
 
 import (
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
@@ -31,7 +31,7 @@ type SumRequest struct {
 func (x *SumRequest) Reset() {
 	*x = SumRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_clientStream_proto_msgTypes[0]
+		mi := &file_sum_v1_sum_proto_msgTypes[0]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -44,7 +44,7 @@ func (x *SumRequest) String() string {
 func (*SumRequest) ProtoMessage() {}
 
 func (x *SumRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_clientStream_proto_msgTypes[0]
+	mi := &file_sum_v1_sum_proto_msgTypes[0]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -57,7 +57,7 @@ func (x *SumRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SumRequest.ProtoReflect.Descriptor instead.
 func (*SumRequest) Descriptor() ([]byte, []int) {
-	return file_clientStream_proto_rawDescGZIP(), []int{0}
+	return file_sum_v1_sum_proto_rawDescGZIP(), []int{0}
 }
 
 func (x *SumRequest) GetNumber() int32 {
@@ -78,7 +78,7 @@ type SumResponse struct {
 func (x *SumResponse) Reset() {
 	*x = SumResponse{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_clientStream_proto_msgTypes[1]
+		mi := &file_sum_v1_sum_proto_msgTypes[1]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -91,7 +91,7 @@ func (x *SumResponse) String() string {
 func (*SumResponse) ProtoMessage() {}
 
 func (x *SumResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_clientStream_proto_msgTypes[1]
+	mi := &file_sum_v1_sum_proto_msgTypes[1]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -104,7 +104,7 @@ func (x *SumResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use SumResponse.ProtoReflect.Descriptor instead.
 func (*SumResponse) Descriptor() ([]byte, []int) {
-	return file_clientStream_proto_rawDescGZIP(), []int{1}
+	return file_sum_v1_sum_proto_rawDescGZIP(), []int{1}
 }
 
 func (x *SumResponse) GetSum() int32 {
@@ -114,42 +114,44 @@ func (x *SumResponse) GetSum() int32 {
 	return 0
 }
 
-var File_clientStream_proto protoreflect.FileDescriptor
+var File_sum_v1_sum_proto protoreflect.FileDescriptor
 
-var file_clientStream_proto_rawDesc = []byte{
-	0x0a, 0x12, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x2e, 0x70,
-	0x72, 0x6f, 0x74, 0x6f, 0x22, 0x24, 0x0a, 0x0a, 0x53, 0x75, 0x6d, 0x52, 0x65, 0x71, 0x75, 0x65,
-	0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x6e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01,
-	0x28, 0x05, 0x52, 0x06, 0x6e, 0x75, 0x6d, 0x62, 0x65, 0x72, 0x22, 0x1f, 0x0a, 0x0b, 0x53, 0x75,
-	0x6d, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x73, 0x75, 0x6d,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x03, 0x73, 0x75, 0x6d, 0x32, 0x36, 0x0a, 0x0a, 0x53,
-	0x75, 0x6d, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x28, 0x0a, 0x09, 0x53, 0x74, 0x72,
-	0x65, 0x61, 0x6d, 0x53, 0x75, 0x6d, 0x12, 0x0b, 0x2e, 0x53, 0x75, 0x6d, 0x52, 0x65, 0x71, 0x75,
-	0x65, 0x73, 0x74, 0x1a, 0x0c, 0x2e, 0x53, 0x75, 0x6d, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
-	0x65, 0x28, 0x01, 0x42, 0x09, 0x5a, 0x07, 0x2e, 0x3b, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x06,
-	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+var file_sum_v1_sum_proto_rawDesc = []byte{
+	0x0a, 0x10, 0x73, 0x75, 0x6d, 0x2f, 0x76, 0x31, 0x2f, 0x73, 0x75, 0x6d, 0x2e, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x12, 0x06, 0x73, 0x75, 0x6d, 0x2e, 0x76, 0x31, 0x22, 0x24, 0x0a, 0x0a, 0x53, 0x75,
+	0x6d, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x6e, 0x75, 0x6d, 0x62,
+	0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x6e, 0x75, 0x6d, 0x62, 0x65, 0x72,
+	0x22, 0x1f, 0x0a, 0x0b, 0x53, 0x75, 0x6d, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
+	0x10, 0x0a, 0x03, 0x73, 0x75, 0x6d, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x03, 0x73, 0x75,
+	0x6d, 0x32, 0x44, 0x0a, 0x0a, 0x53, 0x75, 0x6d, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12,
+	0x36, 0x0a, 0x09, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x53, 0x75, 0x6d, 0x12, 0x12, 0x2e, 0x73,
+	0x75, 0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x75, 0x6d, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x13, 0x2e, 0x73, 0x75, 0x6d, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x75, 0x6d, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x28, 0x01, 0x42, 0x12, 0x5a, 0x10, 0x61, 0x70, 0x69, 0x2f, 0x73,
+	0x75, 0x6d, 0x2f, 0x76, 0x31, 0x3b, 0x73, 0x75, 0x6d, 0x76, 0x31, 0x62, 0x06, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x33,
 }
 
 var (
-	file_clientStream_proto_rawDescOnce sync.Once
-	file_clientStream_proto_rawDescData = file_clientStream_proto_rawDesc
+	file_sum_v1_sum_proto_rawDescOnce sync.Once
+	file_sum_v1_sum_proto_rawDescData = file_sum_v1_sum_proto_rawDesc
 )
 
-func file_clientStream_proto_rawDescGZIP() []byte {
-	file_clientStream_proto_rawDescOnce.Do(func() {
-		file_clientStream_proto_rawDescData = protoimpl.X.CompressGZIP(file_clientStream_proto_rawDescData)
+func file_sum_v1_sum_proto_rawDescGZIP() []byte {
+	file_sum_v1_sum_proto_rawDescOnce.Do(func() {
+		file_sum_v1_sum_proto_rawDescData = protoimpl.X.CompressGZIP(file_sum_v1_sum_proto_rawDescData)
 	})
-	return file_clientStream_proto_rawDescData
+	return file_sum_v1_sum_proto_rawDescData
 }
 
-var file_clientStream_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
-var file_clientStream_proto_goTypes = []any{
-	(*SumRequest)(nil),  // 0: SumRequest
-	(*SumResponse)(nil), // 1: SumResponse
+var file_sum_v1_sum_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_sum_v1_sum_proto_goTypes = []any{
+	(*SumRequest)(nil),  // 0: sum.v1.SumRequest
+	(*SumResponse)(nil), // 1: sum.v1.SumResponse
 }
-var file_clientStream_proto_depIdxs = []int32{
-	0, // 0: SumService.StreamSum:input_type -> SumRequest
-	1, // 1: SumService.StreamSum:output_type -> SumResponse
+var file_sum_v1_sum_proto_depIdxs = []int32{
+	0, // 0: sum.v1.SumService.StreamSum:input_type -> sum.v1.SumRequest
+	1, // 1: sum.v1.SumService.StreamSum:output_type -> sum.v1.SumResponse
 	1, // [1:2] is the sub-list for method output_type
 	0, // [0:1] is the sub-list for method input_type
 	0, // [0:0] is the sub-list for extension type_name
@@ -157,13 +159,13 @@ var file_clientStream_proto_depIdxs = []int32{
 	0, // [0:0] is the sub-list for field type_name
 }
 
-func init() { file_clientStream_proto_init() }
-func file_clientStream_proto_init() {
-	if File_clientStream_proto != nil {
+func init() { file_sum_v1_sum_proto_init() }
+func file_sum_v1_sum_proto_init() {
+	if File_sum_v1_sum_proto != nil {
 		return
 	}
 	if !protoimpl.UnsafeEnabled {
-		file_clientStream_proto_msgTypes[0].Exporter = func(v any, i int) any {
+		file_sum_v1_sum_proto_msgTypes[0].Exporter = func(v any, i int) any {
 			switch v := v.(*SumRequest); i {
 			case 0:
 				return &v.state
@@ -175,7 +177,7 @@ func file_clientStream_proto_init() {
 				return nil
 			}
 		}
-		file_clientStream_proto_msgTypes[1].Exporter = func(v any, i int) any {
+		file_sum_v1_sum_proto_msgTypes[1].Exporter = func(v any, i int) any {
 			switch v := v.(*SumResponse); i {
 			case 0:
 				return &v.state
@@ -192,18 +194,18 @@ func file_clientStream_proto_init() {
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
-			RawDescriptor: file_clientStream_proto_rawDesc,
+			RawDescriptor: file_sum_v1_sum_proto_rawDesc,
 			NumEnums:      0,
 			NumMessages:   2,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
-		GoTypes:           file_clientStream_proto_goTypes,
-		DependencyIndexes: file_clientStream_proto_depIdxs,
-		MessageInfos:      file_clientStream_proto_msgTypes,
+		GoTypes:           file_sum_v1_sum_proto_goTypes,
+		DependencyIndexes: file_sum_v1_sum_proto_depIdxs,
+		MessageInfos:      file_sum_v1_sum_proto_msgTypes,
 	}.Build()
-	File_clientStream_proto = out.File
-	file_clientStream_proto_rawDesc = nil
-	file_clientStream_proto_goTypes = nil
-	file_clientStream_proto_depIdxs = nil
+	File_sum_v1_sum_proto = out.File
+	file_sum_v1_sum_proto_rawDesc = nil
+	file_sum_v1_sum_proto_goTypes = nil
+	file_sum_v1_sum_proto_depIdxs = nil
 }
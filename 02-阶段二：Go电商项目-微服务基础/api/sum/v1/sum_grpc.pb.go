@@ -2,9 +2,9 @@
 // versions:
 // - protoc-gen-go-grpc v1.5.1
 // - protoc             v5.27.5
-// source: clientStream.proto
+// source: sum/v1/sum.proto
 
-package proto
+package sumv1
 
 import (
 	context "context"
@@ -19,12 +19,14 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	SumService_StreamSum_FullMethodName = "/SumService/StreamSum"
+	SumService_StreamSum_FullMethodName = "/sum.v1.SumService/StreamSum"
 )
 
 // SumServiceClient is the client API for SumService service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// SumService 是客户端流：客户端逐个发送数字，发送完毕后服务端返回总和
 type SumServiceClient interface {
 	StreamSum(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[SumRequest, SumResponse], error)
 }
@@ -53,6 +55,8 @@ type SumService_StreamSumClient = grpc.ClientStreamingClient[SumRequest, SumResp
 // SumServiceServer is the server API for SumService service.
 // All implementations must embed UnimplementedSumServiceServer
 // for forward compatibility.
+//
+// SumService 是客户端流：客户端逐个发送数字，发送完毕后服务端返回总和
 type SumServiceServer interface {
 	StreamSum(grpc.ClientStreamingServer[SumRequest, SumResponse]) error
 	mustEmbedUnimplementedSumServiceServer()
@@ -100,7 +104,7 @@ type SumService_StreamSumServer = grpc.ClientStreamingServer[SumRequest, SumResp
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
 var SumService_ServiceDesc = grpc.ServiceDesc{
-	ServiceName: "SumService",
+	ServiceName: "sum.v1.SumService",
 	HandlerType: (*SumServiceServer)(nil),
 	Methods:     []grpc.MethodDesc{},
 	Streams: []grpc.StreamDesc{
@@ -110,5 +114,5 @@ var SumService_ServiceDesc = grpc.ServiceDesc{
 			ClientStreams: true,
 		},
 	},
-	Metadata: "clientStream.proto",
+	Metadata: "sum/v1/sum.proto",
 }
@@ -0,0 +1,204 @@
+/**
+ * @File : cache.go
+ * @Description : 服务端一元调用的响应缓存：方法通过 (cache.v1.cache) option 开启，相同的并发请求只执行一次 handler
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package cache
+
+import (
+	"api/cache/v1"
+	"context"
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"rpckit/metrics"
+	"rpckit/tlsutil"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultMaxEntries 是 Options.MaxEntries 为 0 时最多缓存的响应数
+	DefaultMaxEntries = 1000
+	// StatusKey 是响应 header 中标明结果来源的 metadata：hit、miss、coalesced 或 bypass
+	StatusKey = "x-cache"
+	// AgeKey 是命中缓存时响应 header 中的缓存时长，单位秒
+	AgeKey = "age"
+)
+
+// Options 配置缓存。零值可用
+type Options struct {
+	MaxEntries int                        // 所有方法共用的条目上限
+	Registry   *metrics.Registry          // 不为 nil 时把命中情况记录到 Prometheus 指标
+	TTL        func(string) time.Duration // 按完整方法名返回缓存时间，为 nil 时读取 proto option
+}
+
+// Stats 是缓存的累计统计
+type Stats struct {
+	Hits      uint64 // 直接返回缓存的结果
+	Misses    uint64 // 执行了 handler
+	Coalesced uint64 // 等待同一请求正在执行的 handler，和它共享结果
+	Bypassed  uint64 // 客户端要求 no-store
+	Evictions uint64 // 因为超出条目上限被淘汰
+	Entries   int
+}
+
+// Cache 并发安全，零值不可用，请使用 New。
+// 缓存的 key 由方法名、调用方的 mTLS 身份和确定性序列化后的请求组成，
+// 不同身份的调用方不会拿到彼此的结果；只有成功的响应会被缓存
+type Cache struct {
+	entries *lru
+	group   singleflight.Group
+	ttl     func(string) time.Duration
+	now     func() time.Time
+
+	hits, misses, coalesced, bypassed atomic.Uint64
+	requests                          *metrics.Counter
+	size                              *metrics.Gauge
+}
+
+func New(opts Options) *Cache {
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = DefaultMaxEntries
+	}
+	c := &Cache{entries: newLRU(opts.MaxEntries), ttl: opts.TTL, now: time.Now}
+	if c.ttl == nil {
+		c.ttl = optionTTL()
+	}
+	if opts.Registry != nil {
+		c.requests = opts.Registry.NewCounter("grpc_server_cache_requests_total", "Cacheable unary RPCs by cache result.", "grpc_method", "result")
+		c.size = opts.Registry.NewGauge("grpc_server_cache_entries", "Responses currently held in the cache.")
+	}
+	return c
+}
+
+// optionTTL 从方法的 (cache.v1.cache) option 读取缓存时间，结果按方法名记住。
+// 只能找到已经链接进程序的 proto，也就是注册到 server 上的服务
+func optionTTL() func(string) time.Duration {
+	var known sync.Map
+	return func(fullMethod string) time.Duration {
+		if ttl, ok := known.Load(fullMethod); ok {
+			return ttl.(time.Duration)
+		}
+		var ttl time.Duration
+		// "/hello.v1.HelloService/SayHello" -> "hello.v1.HelloService.SayHello"
+		name := strings.Replace(strings.TrimPrefix(fullMethod, "/"), "/", ".", 1)
+		if d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name)); err == nil {
+			if md, ok := d.(protoreflect.MethodDescriptor); ok {
+				rules, _ := proto.GetExtension(md.Options(), cachev1.E_Cache).(*cachev1.CacheRules)
+				ttl = rules.GetTtl().AsDuration()
+			}
+		}
+		known.Store(fullMethod, ttl)
+		return ttl
+	}
+}
+
+// UnaryServerInterceptor 缓存开启了缓存的方法的响应，其他方法直接调用 handler
+func (c *Cache) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ttl := c.ttl(info.FullMethod)
+		msg, ok := req.(proto.Message)
+		if ttl <= 0 || !ok {
+			return handler(ctx, req)
+		}
+		ctl := parseControl(ctx)
+		if ctl.noStore {
+			c.observe(info.FullMethod, "bypass", &c.bypassed)
+			grpc.SetHeader(ctx, metadata.Pairs(StatusKey, "bypass"))
+			return handler(ctx, req)
+		}
+		key, err := c.key(ctx, info.FullMethod, msg)
+		if err != nil {
+			return handler(ctx, req)
+		}
+
+		now := c.now()
+		if e, ok := c.entries.get(key, now); ok && ctl.accepts(now.Sub(e.stored)) {
+			c.observe(info.FullMethod, "hit", &c.hits)
+			age := int64(now.Sub(e.stored) / time.Second)
+			grpc.SetHeader(ctx, metadata.Pairs(StatusKey, "hit", AgeKey, strconv.FormatInt(age, 10)))
+			return proto.Clone(e.resp), nil
+		}
+
+		// 第一个请求执行 handler，同时到达的相同请求等它的结果。
+		// handler 不随第一个调用方取消，否则一个客户端放弃会让所有等待的请求一起失败
+		leader := false
+		ch := c.group.DoChan(key, func() (any, error) {
+			leader = true
+			resp, err := handler(context.WithoutCancel(ctx), req)
+			if err != nil {
+				return nil, err
+			}
+			m, ok := resp.(proto.Message)
+			if !ok {
+				return resp, nil
+			}
+			stored := c.now()
+			c.entries.add(&entry{key: key, resp: proto.Clone(m), stored: stored, expires: stored.Add(ttl)})
+			if c.size != nil {
+				n, _ := c.entries.stats()
+				c.size.Set(float64(n))
+			}
+			return m, nil
+		})
+		select {
+		case <-ctx.Done():
+			return nil, status.FromContextError(ctx.Err()).Err()
+		case r := <-ch:
+			// leader 只在执行 handler 的 goroutine 里写入，读取之前 channel 已经建立了先后关系
+			result, counter := "coalesced", &c.coalesced
+			if leader {
+				result, counter = "miss", &c.misses
+			}
+			c.observe(info.FullMethod, result, counter)
+			grpc.SetHeader(ctx, metadata.Pairs(StatusKey, result))
+			if r.Err != nil {
+				return nil, r.Err
+			}
+			// 每个调用方拿到自己的副本，后面的拦截器修改响应不会影响别人
+			if m, ok := r.Val.(proto.Message); ok {
+				return proto.Clone(m), nil
+			}
+			return r.Val, nil
+		}
+	}
+}
+
+// key 由方法名、调用方身份和确定性序列化的请求组成，用 0 字节分隔
+func (c *Cache) key(ctx context.Context, method string, req proto.Message) (string, error) {
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	id, _ := tlsutil.PeerIdentity(ctx)
+	return method + "\x00" + id.Name + "\x00" + string(b), nil
+}
+
+func (c *Cache) observe(method, result string, counter *atomic.Uint64) {
+	counter.Add(1)
+	if c.requests != nil {
+		c.requests.Inc(method, result)
+	}
+}
+
+// Stats 返回从创建以来的统计
+func (c *Cache) Stats() Stats {
+	entries, evictions := c.entries.stats()
+	return Stats{
+		Hits:      c.hits.Load(),
+		Misses:    c.misses.Load(),
+		Coalesced: c.coalesced.Load(),
+		Bypassed:  c.bypassed.Load(),
+		Evictions: evictions,
+		Entries:   entries,
+	}
+}
@@ -0,0 +1,350 @@
+/**
+ * @File : cache_test.go
+ * @Description : 测试 proto option 开启缓存、cache-control 指令、过期和淘汰、并发请求合并以及命中指标
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package cache
+
+import (
+	"api/hello/v1"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	testpb "google.golang.org/grpc/interop/grpc_testing"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"rpckit/grpctest"
+	"rpckit/metrics"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// helloServer 记录 handler 的执行次数；block 不为 nil 时每次调用都等它关闭
+type helloServer struct {
+	hellov1.UnimplementedHelloServiceServer
+	calls atomic.Int64
+	block chan struct{}
+}
+
+func (s *helloServer) SayHello(ctx context.Context, req *hellov1.HelloRequest) (*hellov1.HelloResponse, error) {
+	n := s.calls.Add(1)
+	if s.block != nil {
+		<-s.block
+	}
+	if req.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+	return &hellov1.HelloResponse{Message: fmt.Sprintf("Hello, %s #%d", req.GetName(), n)}, nil
+}
+
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func start(t *testing.T, srv *helloServer, c *Cache, interceptors ...grpc.UnaryServerInterceptor) hellov1.HelloServiceClient {
+	t.Helper()
+	interceptors = append(interceptors, c.UnaryServerInterceptor())
+	conn := grpctest.Start(t, func(s *grpc.Server) { hellov1.RegisterHelloServiceServer(s, srv) },
+		grpctest.WithServerOptions(grpc.ChainUnaryInterceptor(interceptors...)))
+	return hellov1.NewHelloServiceClient(conn)
+}
+
+func TestOptionTTL(t *testing.T) {
+	ttl := optionTTL()
+	tests := []struct {
+		method string
+		expect time.Duration
+	}{
+		{"/hello.v1.HelloService/SayHello", 30 * time.Second},
+		{"/grpc.testing.TestService/UnaryCall", 0},
+		{"/no.such.Service/Method", 0},
+		{"malformed", 0},
+	}
+	for _, tt := range tests {
+		// 第二次读的是记住的结果
+		for i := 0; i < 2; i++ {
+			if got := ttl(tt.method); got != tt.expect {
+				t.Errorf("ttl(%s) = %v, expect %v", tt.method, got, tt.expect)
+			}
+		}
+	}
+}
+
+func TestParseControl(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []string
+		noCache bool
+		noStore bool
+		maxAge  time.Duration
+	}{
+		{"nothing", nil, false, false, -1},
+		{"no-cache", []string{"no-cache"}, true, false, -1},
+		{"combined with spaces and case", []string{" No-Store , max-age=10"}, false, true, 10 * time.Second},
+		{"several values keep the smallest max-age", []string{"max-age=60", "max-age=5, max-age=30"}, false, false, 5 * time.Second},
+		{"invalid max-age is ignored", []string{"max-age=-1, max-age=abc, max-age="}, false, false, -1},
+		{"unknown directives are ignored", []string{"private, must-revalidate"}, false, false, -1},
+	}
+	for _, tt := range tests {
+		ctx := context.Background()
+		if tt.values != nil {
+			ctx = metadata.NewIncomingContext(ctx, metadata.MD{ControlKey: tt.values})
+		}
+		got := parseControl(ctx)
+		if got != (control{noCache: tt.noCache, noStore: tt.noStore, maxAge: tt.maxAge}) {
+			t.Errorf("%s: parseControl(%q) = %+v", tt.name, tt.values, got)
+		}
+	}
+}
+
+func TestCache(t *testing.T) {
+	clk := &clock{now: time.Unix(1700000000, 0)}
+	reg := metrics.NewRegistry()
+	c := New(Options{Registry: reg})
+	c.now = clk.Now
+	srv := &helloServer{}
+	client := start(t, srv, c)
+
+	tests := []struct {
+		name    string
+		req     string
+		control string
+		advance time.Duration
+		result  string // x-cache header
+		calls   int64  // 到这一步为止 handler 的执行次数
+	}{
+		{"first call runs the handler", "a", "", 0, "miss", 1},
+		{"identical request is served from the cache", "a", "", 0, "hit", 1},
+		{"different request is a different key", "b", "", 0, "miss", 2},
+		{"still cached after 10s", "a", "", 10 * time.Second, "hit", 2},
+		{"max-age rejects an older entry", "a", "max-age=5", 0, "miss", 3},
+		{"refreshed entry is accepted", "a", "max-age=5", time.Second, "hit", 3},
+		{"no-cache runs the handler", "a", "no-cache", 0, "miss", 4},
+		{"no-store bypasses the cache", "a", "no-store", 0, "bypass", 5},
+		{"entry expires after the ttl", "a", "", 31 * time.Second, "miss", 6},
+		{"errors are not cached", "", "", 0, "miss", 7},
+		{"errors are not cached, again", "", "", 0, "miss", 8},
+	}
+	for _, tt := range tests {
+		clk.Advance(tt.advance)
+		ctx := context.Background()
+		if tt.control != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, ControlKey, tt.control)
+		}
+		var header metadata.MD
+		_, err := client.SayHello(ctx, &hellov1.HelloRequest{Name: tt.req}, grpc.Header(&header))
+		if (err != nil) != (tt.req == "") {
+			t.Errorf("%s: SayHello() error = %v", tt.name, err)
+		}
+		if got := header.Get(StatusKey); len(got) != 1 || got[0] != tt.result {
+			t.Errorf("%s: %s = %v, expect %s", tt.name, StatusKey, got, tt.result)
+		}
+		if got := srv.calls.Load(); got != tt.calls {
+			t.Errorf("%s: handler ran %d times, expect %d", tt.name, got, tt.calls)
+		}
+	}
+
+	// 命中时带上缓存时长
+	clk.Advance(3 * time.Second)
+	var header metadata.MD
+	if _, err := client.SayHello(context.Background(), &hellov1.HelloRequest{Name: "a"}, grpc.Header(&header)); err != nil {
+		t.Fatal(err)
+	}
+	if got := header.Get(AgeKey); len(got) != 1 || got[0] != "3" {
+		t.Errorf("%s = %v, expect 3", AgeKey, got)
+	}
+
+	expect := Stats{Hits: 4, Misses: 7, Bypassed: 1, Entries: 2}
+	if got := c.Stats(); got != expect {
+		t.Errorf("Stats() = %+v, expect %+v", got, expect)
+	}
+	var buf bytes.Buffer
+	if _, err := reg.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`grpc_server_cache_requests_total{grpc_method="/hello.v1.HelloService/SayHello",result="hit"} 4`,
+		`grpc_server_cache_requests_total{grpc_method="/hello.v1.HelloService/SayHello",result="miss"} 7`,
+		`grpc_server_cache_requests_total{grpc_method="/hello.v1.HelloService/SayHello",result="bypass"} 1`,
+		`grpc_server_cache_entries 2`,
+	} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("metrics do not contain %s:\n%s", line, buf.String())
+		}
+	}
+}
+
+type testServer struct {
+	testpb.UnimplementedTestServiceServer
+	calls atomic.Int64
+}
+
+func (s *testServer) UnaryCall(context.Context, *testpb.SimpleRequest) (*testpb.SimpleResponse, error) {
+	s.calls.Add(1)
+	return &testpb.SimpleResponse{}, nil
+}
+
+func TestMethodWithoutOption(t *testing.T) {
+	c := New(Options{})
+	srv := &testServer{}
+	conn := grpctest.Start(t, func(s *grpc.Server) { testpb.RegisterTestServiceServer(s, srv) },
+		grpctest.WithServerOptions(grpc.UnaryInterceptor(c.UnaryServerInterceptor())))
+	client := testpb.NewTestServiceClient(conn)
+	for i := 0; i < 3; i++ {
+		var header metadata.MD
+		if _, err := client.UnaryCall(context.Background(), &testpb.SimpleRequest{}, grpc.Header(&header)); err != nil {
+			t.Fatal(err)
+		}
+		if got := header.Get(StatusKey); got != nil {
+			t.Errorf("%s = %v on a method without the cache option", StatusKey, got)
+		}
+	}
+	if n := srv.calls.Load(); n != 3 {
+		t.Errorf("handler ran %d times, expect 3", n)
+	}
+	if got := c.Stats(); got != (Stats{}) {
+		t.Errorf("Stats() = %+v, expect nothing recorded", got)
+	}
+}
+
+func TestEviction(t *testing.T) {
+	c := New(Options{MaxEntries: 2})
+	srv := &helloServer{}
+	client := start(t, srv, c)
+	call := func(name string) string {
+		var header metadata.MD
+		if _, err := client.SayHello(context.Background(), &hellov1.HelloRequest{Name: name}, grpc.Header(&header)); err != nil {
+			t.Fatal(err)
+		}
+		return header.Get(StatusKey)[0]
+	}
+	for _, step := range []struct{ name, result string }{
+		{"a", "miss"}, {"b", "miss"}, {"a", "hit"},
+		// c 挤掉最久没用的 b
+		{"c", "miss"}, {"a", "hit"}, {"b", "miss"},
+	} {
+		if got := call(step.name); got != step.result {
+			t.Errorf("call(%s) = %s, expect %s", step.name, got, step.result)
+		}
+	}
+	if got := c.Stats(); got.Entries != 2 || got.Evictions != 2 {
+		t.Errorf("Stats() = %+v, expect 2 entries and 2 evictions", got)
+	}
+}
+
+func TestCoalesce(t *testing.T) {
+	const callers = 10
+	c := New(Options{})
+	srv := &helloServer{block: make(chan struct{})}
+	var entered atomic.Int64
+	count := func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		entered.Add(1)
+		return handler(ctx, req)
+	}
+	client := start(t, srv, c, count)
+
+	var wg sync.WaitGroup
+	replies := make([]string, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := client.SayHello(context.Background(), &hellov1.HelloRequest{Name: "a"})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			replies[i] = resp.GetMessage()
+		}(i)
+	}
+	for deadline := time.Now().Add(5 * time.Second); entered.Load() < callers || srv.calls.Load() == 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d callers entered, handler ran %d times", entered.Load(), srv.calls.Load())
+		}
+	}
+	// 调用方放弃等待不影响 handler 执行完并写入缓存
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.SayHello(ctx, &hellov1.HelloRequest{Name: "a"}); status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("SayHello() with a short deadline = %v, expect DeadlineExceeded", err)
+	}
+	close(srv.block)
+	wg.Wait()
+
+	if n := srv.calls.Load(); n != 1 {
+		t.Errorf("handler ran %d times for identical concurrent requests, expect 1", n)
+	}
+	for i, r := range replies {
+		if r != replies[0] {
+			t.Errorf("caller %d got %q, caller 0 got %q", i, r, replies[0])
+		}
+	}
+	// 进入拦截器较晚的调用方可能已经能直接命中缓存；超时的调用在服务端的 deadline 比客户端稍晚，
+	// 可能在那之前就拿到了结果，所以它可能记一次也可能不记
+	st := c.Stats()
+	if shared := st.Hits + st.Coalesced; st.Misses != 1 || shared < callers-1 || shared > callers {
+		t.Errorf("Stats() = %+v, expect 1 miss and %d or %d hits or coalesced", st, callers-1, callers)
+	}
+	if _, err := client.SayHello(context.Background(), &hellov1.HelloRequest{Name: "a"}); err != nil || srv.calls.Load() != 1 {
+		t.Errorf("SayHello() after the flight = %v, handler ran %d times, expect a cache hit", err, srv.calls.Load())
+	}
+}
+
+// withIdentity 模拟 mTLS 校验通过的对端
+func withIdentity(name string) context.Context {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: name}}
+	info := credentials.TLSInfo{State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}}
+	return peer.NewContext(context.Background(), &peer.Peer{AuthInfo: info})
+}
+
+func TestKey(t *testing.T) {
+	c := New(Options{})
+	key := func(ctx context.Context, method, name string) string {
+		k, err := c.key(ctx, method, &hellov1.HelloRequest{Name: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+	base := key(withIdentity("alice"), "/hello.v1.HelloService/SayHello", "a")
+	tests := []struct {
+		name string
+		key  string
+		same bool
+	}{
+		{"same caller and request", key(withIdentity("alice"), "/hello.v1.HelloService/SayHello", "a"), true},
+		{"another caller", key(withIdentity("bob"), "/hello.v1.HelloService/SayHello", "a"), false},
+		{"anonymous caller", key(context.Background(), "/hello.v1.HelloService/SayHello", "a"), false},
+		{"another request", key(withIdentity("alice"), "/hello.v1.HelloService/SayHello", "b"), false},
+		{"another method", key(withIdentity("alice"), "/hello.v1.HelloService/Other", "a"), false},
+	}
+	for _, tt := range tests {
+		if (tt.key == base) != tt.same {
+			t.Errorf("%s: key equal to the base key = %v, expect %v", tt.name, tt.key == base, tt.same)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+/**
+ * @File : control.go
+ * @Description : 解析请求 metadata 中类似 HTTP Cache-Control 的指令
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package cache
+
+import (
+	"context"
+	"google.golang.org/grpc/metadata"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ControlKey 是客户端控制缓存行为的 metadata，支持的指令（可以用逗号组合）：
+//
+//	no-cache    不读缓存，调用 handler 并用新结果更新缓存
+//	no-store    完全绕过缓存，既不读也不写
+//	max-age=N   只接受缓存了不超过 N 秒的结果
+//
+// 不认识的指令和取值不合法的 max-age 会被忽略
+const ControlKey = "cache-control"
+
+type control struct {
+	noCache bool
+	noStore bool
+	maxAge  time.Duration // 小于 0 表示没有限制
+}
+
+func parseControl(ctx context.Context) control {
+	c := control{maxAge: -1}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get(ControlKey) {
+		for _, d := range strings.Split(v, ",") {
+			name, value, _ := strings.Cut(strings.ToLower(strings.TrimSpace(d)), "=")
+			switch name {
+			case "no-cache":
+				c.noCache = true
+			case "no-store":
+				c.noStore = true
+			case "max-age":
+				if n, err := strconv.ParseUint(value, 10, 32); err == nil {
+					age := time.Duration(n) * time.Second
+					// 同时给了多个 max-age 时取最严格的
+					if c.maxAge < 0 || age < c.maxAge {
+						c.maxAge = age
+					}
+				}
+			}
+		}
+	}
+	return c
+}
+
+// accepts 报告缓存了 age 的结果是否满足请求
+func (c control) accepts(age time.Duration) bool {
+	return !c.noCache && (c.maxAge < 0 || age <= c.maxAge)
+}
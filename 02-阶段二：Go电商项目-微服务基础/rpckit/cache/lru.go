@@ -0,0 +1,76 @@
+/**
+ * @File : lru.go
+ * @Description : 带过期时间的 LRU，保存一元调用的响应
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package cache
+
+import (
+	"container/list"
+	"google.golang.org/protobuf/proto"
+	"sync"
+	"time"
+)
+
+type entry struct {
+	key     string
+	resp    proto.Message
+	stored  time.Time
+	expires time.Time
+}
+
+// lru 并发安全。过期的条目在下次被读到或者被挤出时删除，条目数不会超过 max
+type lru struct {
+	mu    sync.Mutex
+	max   int
+	items map[string]*list.Element
+	// ll 的元素是 *entry，最近用过的在前面
+	ll        *list.List
+	evictions uint64
+}
+
+func newLRU(max int) *lru {
+	return &lru{max: max, items: make(map[string]*list.Element), ll: list.New()}
+}
+
+// get 返回 now 时仍然有效的条目
+func (c *lru) get(key string, now time.Time) (*entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*entry)
+	if !now.Before(e.expires) {
+		c.ll.Remove(el)
+		delete(c.items, key)
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return e, true
+}
+
+func (c *lru) add(e *entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.items[e.key]; ok {
+		el.Value = e
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[e.key] = c.ll.PushFront(e)
+	for c.ll.Len() > c.max {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*entry).key)
+		c.evictions++
+	}
+}
+
+func (c *lru) stats() (entries int, evictions uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len(), c.evictions
+}
@@ -0,0 +1,42 @@
+/**
+ * @File : main.go
+ * @Description : 读取各服务写出的 span 文件，在终端中以瀑布图显示每条 trace
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"rpckit/trace"
+	"strings"
+)
+
+// 用法：
+//
+//	go run rpckit/cmd/traceview -file spans.jsonl [-trace <trace-id 前缀>]
+func main() {
+	file := flag.String("file", "spans.jsonl", "span file written by the services with -trace-file")
+	traceID := flag.String("trace", "", "only show traces whose id starts with this prefix")
+	flag.Parse()
+
+	spans, err := trace.ReadFile(*file)
+	if err != nil {
+		log.Fatalf("读取 span 文件失败: %v", err)
+	}
+	if *traceID != "" {
+		filtered := spans[:0]
+		for _, s := range spans {
+			if strings.HasPrefix(s.TraceID, *traceID) {
+				filtered = append(filtered, s)
+			}
+		}
+		spans = filtered
+	}
+	if len(spans) == 0 {
+		log.Fatalf("没有找到 span")
+	}
+	trace.Waterfall(os.Stdout, spans)
+}
@@ -0,0 +1,293 @@
+/**
+ * @File : compress_test.go
+ * @Description : 压缩连接和 gRPC 压缩协商的测试，以及不同消息大小下 CPU 与字节数的基准测试
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package compress
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"google.golang.org/grpc"
+	testpb "google.golang.org/grpc/interop/grpc_testing"
+	"google.golang.org/grpc/stats"
+	"io"
+	"rpckit/grpctest"
+	"sync"
+	"testing"
+)
+
+// payload 生成和仓库里常见消息相近的 JSON 文本：重复的键名、递增的数字
+func payload(n int) []byte {
+	var b bytes.Buffer
+	for i := 0; b.Len() < n; i++ {
+		fmt.Fprintf(&b, `{"id":%d,"name":"user-%d","message":"hello user-%d","sum":%d}`, i, i*7919%10007, i, i*(i+1)/2)
+	}
+	return b.Bytes()[:n]
+}
+
+// rwc 把写入记到 wire 里，读取来自 in，用来直接检查帧内容
+type rwc struct {
+	in   io.Reader
+	wire bytes.Buffer
+}
+
+func (c *rwc) Read(p []byte) (int, error)  { return c.in.Read(p) }
+func (c *rwc) Write(p []byte) (int, error) { return c.wire.Write(p) }
+func (c *rwc) Close() error                { return nil }
+
+func TestConnRoundTrip(t *testing.T) {
+	tests := []struct {
+		name       string
+		compressor string
+		size       int
+		compressed bool // 线上的帧是否被压缩
+	}{
+		{"identity", Identity, 4096, false},
+		{"gzip below threshold", Gzip, 100, false},
+		{"gzip", Gzip, 4096, true},
+		{"zstd", Zstd, 4096, true},
+		{"zstd large", Zstd, 1 << 20, true},
+		{"empty write", Zstd, 0, false},
+	}
+	for _, tt := range tests {
+		client := &rwc{}
+		c, err := NewConn(client, tt.compressor, 512)
+		if err != nil {
+			t.Fatalf("%s: NewConn() error = %v", tt.name, err)
+		}
+		want := payload(tt.size)
+		if _, err := c.Write(want); err != nil {
+			t.Fatalf("%s: Write() error = %v", tt.name, err)
+		}
+		if got := client.wire.Len() < len(want); got != tt.compressed {
+			t.Errorf("%s: %d bytes on the wire for %d bytes of data, compressed = %v, expect %v",
+				tt.name, client.wire.Len(), len(want), got, tt.compressed)
+		}
+
+		// 服务端读出原文，并且回复时跟随客户端的算法
+		server := &rwc{in: bytes.NewReader(client.wire.Bytes())}
+		s := NewServerConn(server, 512)
+		got := make([]byte, len(want))
+		if _, err := io.ReadFull(s, got); err != nil {
+			t.Fatalf("%s: server Read() error = %v", tt.name, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: server read %d bytes that differ from what the client wrote", tt.name, len(got))
+		}
+		if _, err := s.Write(want); err != nil {
+			t.Fatalf("%s: server Write() error = %v", tt.name, err)
+		}
+		if server.wire.Len() != client.wire.Len() {
+			t.Errorf("%s: reply is %d bytes on the wire, expect the same encoding as the request (%d bytes)",
+				tt.name, server.wire.Len(), client.wire.Len())
+		}
+	}
+}
+
+func TestConnUnknownCompressor(t *testing.T) {
+	if _, err := NewConn(&rwc{}, "brotli", 0); err == nil {
+		t.Errorf("NewConn(brotli) error = nil, expect unknown algorithm")
+	}
+}
+
+func TestConnTruncatedFrame(t *testing.T) {
+	client := &rwc{}
+	c, _ := NewConn(client, Zstd, 0)
+	c.Write(payload(4096))
+	frame := client.wire.Bytes()
+	s := NewServerConn(&rwc{in: bytes.NewReader(frame[:len(frame)/2])}, 0)
+	if _, err := s.Read(make([]byte, 10)); err != io.ErrUnexpectedEOF {
+		t.Errorf("Read() of a truncated frame = %v, expect %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+// payloadStats 记录每条收到的消息压缩前后的大小
+type payloadStats struct {
+	mu       sync.Mutex
+	payloads []*stats.InPayload
+}
+
+func (h *payloadStats) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context   { return ctx }
+func (h *payloadStats) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context { return ctx }
+func (h *payloadStats) HandleConn(context.Context, stats.ConnStats)                       {}
+func (h *payloadStats) HandleRPC(_ context.Context, s stats.RPCStats) {
+	if p, ok := s.(*stats.InPayload); ok {
+		h.mu.Lock()
+		h.payloads = append(h.payloads, p)
+		h.mu.Unlock()
+	}
+}
+
+// last 报告最后一条消息是否被压缩
+func (h *payloadStats) last() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	p := h.payloads[len(h.payloads)-1]
+	return p.CompressedLength < p.Length
+}
+
+type testServer struct {
+	testpb.UnimplementedTestServiceServer
+}
+
+func (testServer) UnaryCall(_ context.Context, req *testpb.SimpleRequest) (*testpb.SimpleResponse, error) {
+	return &testpb.SimpleResponse{Payload: &testpb.Payload{Body: payload(int(req.ResponseSize))}}, nil
+}
+
+func (testServer) StreamingOutputCall(req *testpb.StreamingOutputCallRequest, stream testpb.TestService_StreamingOutputCallServer) error {
+	for _, p := range req.ResponseParameters {
+		if err := stream.Send(&testpb.StreamingOutputCallResponse{Payload: &testpb.Payload{Body: payload(int(p.Size))}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestGRPCNegotiation(t *testing.T) {
+	tests := []struct {
+		name           string
+		compressor     string // 客户端拦截器使用的算法
+		callOpts       []grpc.CallOption
+		prefer         []string // 服务端的回复偏好
+		requestSize    int
+		responseSize   int32
+		requestZipped  bool
+		responseZipped bool
+	}{
+		{"small request and response", Zstd, nil, []string{Zstd}, 100, 100, false, false},
+		{"large request and response", Zstd, nil, []string{Zstd}, 8192, 8192, true, true},
+		{"large request, small response", Gzip, nil, []string{Gzip}, 8192, 100, true, false},
+		{"server compresses although the client did not", "", nil, []string{Zstd}, 100, 8192, false, true},
+		{"no server preference mirrors the request", Gzip, nil, nil, 8192, 8192, true, true},
+		{"per-call option overrides the interceptor", Zstd, []grpc.CallOption{grpc.UseCompressor(Identity)}, nil, 8192, 8192, false, false},
+		{"per-call option enables compression", "", []grpc.CallOption{grpc.UseCompressor(Gzip)}, nil, 8192, 8192, true, true},
+	}
+	for _, tt := range tests {
+		serverStats, clientStats := &payloadStats{}, &payloadStats{}
+		conn := grpctest.Start(t, func(s *grpc.Server) { testpb.RegisterTestServiceServer(s, testServer{}) },
+			grpctest.WithServerOptions(grpc.StatsHandler(serverStats), grpc.UnaryInterceptor(UnaryServerInterceptor(1024, tt.prefer...))),
+			grpctest.WithDialOptions(grpc.WithStatsHandler(clientStats), grpc.WithUnaryInterceptor(UnaryClientInterceptor(tt.compressor, 1024))))
+
+		req := &testpb.SimpleRequest{ResponseSize: tt.responseSize, Payload: &testpb.Payload{Body: payload(tt.requestSize)}}
+		resp, err := testpb.NewTestServiceClient(conn).UnaryCall(context.Background(), req, tt.callOpts...)
+		if err != nil {
+			t.Fatalf("%s: UnaryCall() error = %v", tt.name, err)
+		}
+		if len(resp.Payload.Body) != int(tt.responseSize) {
+			t.Errorf("%s: response body is %d bytes, expect %d", tt.name, len(resp.Payload.Body), tt.responseSize)
+		}
+		if got := serverStats.last(); got != tt.requestZipped {
+			t.Errorf("%s: request compressed = %v, expect %v", tt.name, got, tt.requestZipped)
+		}
+		if got := clientStats.last(); got != tt.responseZipped {
+			t.Errorf("%s: response compressed = %v, expect %v", tt.name, got, tt.responseZipped)
+		}
+	}
+}
+
+func TestGRPCStreamNegotiation(t *testing.T) {
+	tests := []struct {
+		name      string
+		firstSize int32
+		zipped    bool
+	}{
+		{"small first message keeps the stream uncompressed", 100, false},
+		{"large first message compresses the stream", 8192, true},
+	}
+	for _, tt := range tests {
+		clientStats := &payloadStats{}
+		conn := grpctest.Start(t, func(s *grpc.Server) { testpb.RegisterTestServiceServer(s, testServer{}) },
+			grpctest.WithServerOptions(grpc.StreamInterceptor(StreamServerInterceptor(1024, Zstd))),
+			grpctest.WithDialOptions(grpc.WithStatsHandler(clientStats)))
+
+		req := &testpb.StreamingOutputCallRequest{ResponseParameters: []*testpb.ResponseParameters{{Size: tt.firstSize}, {Size: 8192}}}
+		stream, err := testpb.NewTestServiceClient(conn).StreamingOutputCall(context.Background(), req)
+		if err != nil {
+			t.Fatalf("%s: StreamingOutputCall() error = %v", tt.name, err)
+		}
+		for {
+			if _, err := stream.Recv(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s: Recv() error = %v", tt.name, err)
+			}
+		}
+		// 整个流沿用第一条消息时的决定，所以看最后一条 8KB 的消息
+		if got := clientStats.last(); got != tt.zipped {
+			t.Errorf("%s: last message compressed = %v, expect %v", tt.name, got, tt.zipped)
+		}
+	}
+}
+
+// 典型消息大小：一元的 HelloRequest、一批流式数字、较大的 JSON 文档、文件分片
+var benchSizes = []int{64, 1 << 10, 16 << 10, 256 << 10}
+
+func BenchmarkCompress(b *testing.B) {
+	for _, name := range []string{Gzip, Zstd} {
+		for _, n := range benchSizes {
+			p := payload(n)
+			b.Run(fmt.Sprintf("%s/%dB", name, n), func(b *testing.B) {
+				b.SetBytes(int64(n))
+				b.ReportAllocs()
+				var out []byte
+				for i := 0; i < b.N; i++ {
+					var err error
+					if out, err = compress(name, p); err != nil {
+						b.Fatal(err)
+					}
+				}
+				// 压缩比 = 压缩后 / 压缩前，越小越省带宽
+				b.ReportMetric(float64(len(out)), "wire-bytes")
+				b.ReportMetric(float64(len(out))/float64(n), "ratio")
+			})
+		}
+	}
+}
+
+func BenchmarkDecompress(b *testing.B) {
+	for _, name := range []string{Gzip, Zstd} {
+		for _, n := range benchSizes {
+			p, err := compress(name, payload(n))
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.Run(fmt.Sprintf("%s/%dB", name, n), func(b *testing.B) {
+				b.SetBytes(int64(n))
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if _, err := decompress(name, p); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkConn 测 net/rpc 连接层的完整开销（分帧 + 压缩），identity 作为对照
+func BenchmarkConn(b *testing.B) {
+	for _, name := range []string{Identity, Gzip, Zstd} {
+		for _, n := range benchSizes {
+			p := payload(n)
+			b.Run(fmt.Sprintf("%s/%dB", name, n), func(b *testing.B) {
+				w := &rwc{}
+				c, err := NewConn(w, name, DefaultThreshold)
+				if err != nil {
+					b.Fatal(err)
+				}
+				b.SetBytes(int64(n))
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					w.wire.Reset()
+					if _, err := c.Write(p); err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(w.wire.Len()), "wire-bytes")
+			})
+		}
+	}
+}
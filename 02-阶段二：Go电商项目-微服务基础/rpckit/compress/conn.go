@@ -0,0 +1,188 @@
+/**
+ * @File : conn.go
+ * @Description : 给 net/rpc 用的压缩连接：把每次写入包成一帧，达到阈值才压缩，并在帧头里协商双方使用的算法
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package compress
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"google.golang.org/grpc/encoding"
+	"io"
+	"sync/atomic"
+)
+
+// 帧格式：
+//
+//	1 字节标志 | uvarint 长度 | 数据
+//
+// 标志的高 4 位是发送方希望对方使用的算法，低 4 位是这一帧实际使用的算法。
+// 服务端跟随客户端的偏好，所以客户端换算法不需要服务端改配置
+var algorithms = []string{Identity, Gzip, Zstd} // 下标就是线上的编号
+
+// maxFrameSize 是单帧（压缩前后）允许的最大字节数
+const maxFrameSize = 64 << 20
+
+var errFrameTooLarge = errors.New("compress: frame exceeds 64MB")
+
+func algorithmID(name string) (byte, error) {
+	if name == "" {
+		return 0, nil
+	}
+	for i, a := range algorithms {
+		if a == name {
+			return byte(i), nil
+		}
+	}
+	return 0, fmt.Errorf("compress: unknown algorithm %q, expect one of %v", name, algorithms)
+}
+
+// Conn 实现 io.ReadWriteCloser，可以直接交给 gob 等 net/rpc 编解码器。
+// 读和写可以在不同的 goroutine 中进行，但不能有多个 goroutine 同时写
+type Conn struct {
+	rwc       io.ReadWriteCloser
+	r         *bufio.Reader
+	threshold int
+	follow    bool          // 服务端：跟随对方在帧头里声明的偏好
+	send      atomic.Uint32 // 写入时使用的算法编号
+	pending   []byte        // 已解压但还没被读走的数据
+	buf       bytes.Buffer
+}
+
+// NewConn 创建客户端连接：不小于 threshold 字节的写入用 name 压缩，并请求对方也这样回复
+func NewConn(rwc io.ReadWriteCloser, name string, threshold int) (*Conn, error) {
+	id, err := algorithmID(name)
+	if err != nil {
+		return nil, err
+	}
+	c := &Conn{rwc: rwc, r: bufio.NewReader(rwc), threshold: threshold}
+	c.send.Store(uint32(id))
+	return c, nil
+}
+
+// NewServerConn 创建服务端连接：回复使用客户端最近一帧里声明的算法，收到第一帧之前不压缩
+func NewServerConn(rwc io.ReadWriteCloser, threshold int) *Conn {
+	return &Conn{rwc: rwc, r: bufio.NewReader(rwc), threshold: threshold, follow: true}
+}
+
+func (c *Conn) Read(p []byte) (int, error) {
+	for len(c.pending) == 0 {
+		if err := c.readFrame(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+func (c *Conn) readFrame() error {
+	flags, err := c.r.ReadByte()
+	if err != nil {
+		return err
+	}
+	n, err := binary.ReadUvarint(c.r)
+	if err != nil {
+		return unexpected(err)
+	}
+	if n > maxFrameSize {
+		return errFrameTooLarge
+	}
+	prefer, used := flags>>4, flags&0x0f
+	if int(prefer) >= len(algorithms) || int(used) >= len(algorithms) {
+		return fmt.Errorf("compress: bad frame flags %#x", flags)
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(c.r, data); err != nil {
+		return unexpected(err)
+	}
+	if used != 0 {
+		if data, err = decompress(algorithms[used], data); err != nil {
+			return err
+		}
+	}
+	if c.follow {
+		c.send.Store(uint32(prefer))
+	}
+	c.pending = data
+	return nil
+}
+
+// unexpected 把帧中间断开的 EOF 换成 ErrUnexpectedEOF，只有帧边界上的 EOF 才表示连接正常关闭
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func (c *Conn) Write(p []byte) (int, error) {
+	if len(p) > maxFrameSize {
+		return 0, errFrameTooLarge
+	}
+	prefer := byte(c.send.Load())
+	used, data := byte(0), p
+	if prefer != 0 && len(p) >= c.threshold {
+		compressed, err := compress(algorithms[prefer], p)
+		if err != nil {
+			return 0, err
+		}
+		// 压缩后反而更大（例如已经压缩过的数据）就直接发原文
+		if len(compressed) < len(p) {
+			used, data = prefer, compressed
+		}
+	}
+
+	// 帧头和数据一次写出，避免在连接上产生两个小包
+	c.buf.Reset()
+	c.buf.WriteByte(prefer<<4 | used)
+	var hdr [binary.MaxVarintLen64]byte
+	c.buf.Write(hdr[:binary.PutUvarint(hdr[:], uint64(len(data)))])
+	c.buf.Write(data)
+	if _, err := c.rwc.Write(c.buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *Conn) Close() error {
+	return c.rwc.Close()
+}
+
+// compress 和 decompress 复用 gRPC 注册的压缩器，两边的实现和池是同一份
+func compress(name string, p []byte) ([]byte, error) {
+	var b bytes.Buffer
+	w, err := encoding.GetCompressor(name).Compress(&b)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(p); err != nil {
+		w.Close()
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func decompress(name string, p []byte) ([]byte, error) {
+	r, err := encoding.GetCompressor(name).Decompress(bytes.NewReader(p))
+	if err != nil {
+		return nil, err
+	}
+	// 多读一个字节用来判断是否超过上限
+	data, err := io.ReadAll(io.LimitReader(r, maxFrameSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxFrameSize {
+		return nil, errFrameTooLarge
+	}
+	return data, nil
+}
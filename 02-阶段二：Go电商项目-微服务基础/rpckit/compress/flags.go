@@ -0,0 +1,70 @@
+/**
+ * @File : flags.go
+ * @Description : 通过命令行参数为 gRPC 服务端和客户端配置压缩算法和压缩阈值
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package compress
+
+import (
+	"flag"
+	"google.golang.org/grpc"
+	"strings"
+)
+
+// DefaultThreshold 是默认的压缩阈值，再小的消息压缩省下的字节抵不上 CPU 和帧头开销
+const DefaultThreshold = 1024
+
+// Flags 保存与压缩相关的命令行参数
+//
+//	服务端：-compressors 是回复时的算法偏好顺序，-compress-threshold 以下的回复不压缩
+//	客户端：-compressor 是请求使用的算法，-compress-threshold 以下的一元请求不压缩
+type Flags struct {
+	Compressor  string
+	Compressors string
+	Threshold   int
+}
+
+// RegisterServerFlags 注册服务端参数，需要在 flag.Parse 之前调用
+func RegisterServerFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{}
+	fs.StringVar(&f.Compressors, "compressors", Zstd+","+Gzip, "comma separated compressors for responses in order of preference, picked from what the client accepts")
+	fs.IntVar(&f.Threshold, "compress-threshold", DefaultThreshold, "responses smaller than this many bytes are sent uncompressed")
+	return f
+}
+
+// RegisterClientFlags 注册客户端参数，需要在 flag.Parse 之前调用
+func RegisterClientFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{}
+	fs.StringVar(&f.Compressor, "compressor", "", "compress requests with gzip or zstd, empty sends them uncompressed")
+	fs.IntVar(&f.Threshold, "compress-threshold", DefaultThreshold, "unary requests smaller than this many bytes are sent uncompressed")
+	return f
+}
+
+func (f *Flags) prefer() []string {
+	var names []string
+	for _, name := range strings.Split(f.Compressors, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// UnaryServerInterceptor 返回按参数配置的一元服务端压缩拦截器
+func (f *Flags) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return UnaryServerInterceptor(f.Threshold, f.prefer()...)
+}
+
+// StreamServerInterceptor 返回按参数配置的流式服务端压缩拦截器
+func (f *Flags) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return StreamServerInterceptor(f.Threshold, f.prefer()...)
+}
+
+// DialOptions 返回客户端的压缩拦截器，单次调用仍可以用 grpc.UseCompressor 覆盖
+func (f *Flags) DialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(UnaryClientInterceptor(f.Compressor, f.Threshold)),
+		grpc.WithChainStreamInterceptor(StreamClientInterceptor(f.Compressor)),
+	}
+}
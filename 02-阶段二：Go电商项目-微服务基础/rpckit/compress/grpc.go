@@ -0,0 +1,99 @@
+/**
+ * @File : grpc.go
+ * @Description : gRPC 压缩协商：客户端按请求大小决定是否压缩，服务端按回复大小和客户端声明支持的算法选择回复的压缩方式
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package compress
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+	"slices"
+)
+
+// size 返回消息编码后的大小，不是 proto 消息时返回 -1，表示大小未知
+func size(m any) int {
+	if pm, ok := m.(proto.Message); ok {
+		return proto.Size(pm)
+	}
+	return -1
+}
+
+// UnaryClientInterceptor 在请求不小于 threshold 字节时用 name 压缩。
+// 调用方自己传入的 grpc.UseCompressor 排在后面，会覆盖这里的选择
+func UnaryClientInterceptor(name string, threshold int) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if name != "" && name != Identity && size(req) >= threshold {
+			opts = append([]grpc.CallOption{grpc.UseCompressor(name)}, opts...)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor 让流上的所有消息都用 name 压缩。
+// 建流时还不知道消息大小，所以流不做阈值判断
+func StreamClientInterceptor(name string) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if name != "" && name != Identity {
+			opts = append([]grpc.CallOption{grpc.UseCompressor(name)}, opts...)
+		}
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
+
+// choose 决定回复的压缩方式：小于 threshold 不压缩；否则按 prefer 的顺序选第一个客户端也支持的算法，
+// 都不支持时返回空串，沿用 gRPC 默认行为（和请求使用相同的压缩方式）
+func choose(ctx context.Context, n, threshold int, prefer []string) string {
+	if n >= 0 && n < threshold {
+		return Identity
+	}
+	supported, _ := grpc.ClientSupportedCompressors(ctx)
+	for _, name := range prefer {
+		if slices.Contains(supported, name) {
+			return name
+		}
+	}
+	return ""
+}
+
+func setSendCompressor(ctx context.Context, msg any, threshold int, prefer []string) {
+	if name := choose(ctx, size(msg), threshold, prefer); name != "" {
+		// 只有 handler 已经发送过响应头时才会失败，此时保持原来的压缩方式即可
+		_ = grpc.SetSendCompressor(ctx, name)
+	}
+}
+
+// UnaryServerInterceptor 在 handler 返回后根据回复大小选择压缩方式
+func UnaryServerInterceptor(threshold int, prefer ...string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		if err == nil {
+			setSendCompressor(ctx, resp, threshold, prefer)
+		}
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor 根据流上第一条回复的大小为整个流选择压缩方式
+func StreamServerInterceptor(threshold int, prefer ...string) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &serverStream{ServerStream: ss, threshold: threshold, prefer: prefer})
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	threshold int
+	prefer    []string
+	decided   bool
+}
+
+func (s *serverStream) SendMsg(m any) error {
+	if !s.decided {
+		s.decided = true
+		setSendCompressor(s.Context(), m, s.threshold, s.prefer)
+	}
+	return s.ServerStream.SendMsg(m)
+}
@@ -0,0 +1,97 @@
+/**
+ * @File : zstd.go
+ * @Description : 注册 gRPC 的 zstd 压缩器（gzip 由 grpc/encoding/gzip 注册），编码器和解码器都放在池里复用
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package compress
+
+import (
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/encoding/gzip"
+	"io"
+	"sync"
+)
+
+// 压缩算法名称，即 grpc-encoding 头里的值
+const (
+	Identity = encoding.Identity
+	Gzip     = gzip.Name
+	Zstd     = "zstd"
+)
+
+// maxDecoderMemory 限制单条消息解压时的内存，防止压缩炸弹
+const maxDecoderMemory = 64 << 20
+
+func init() {
+	encoding.RegisterCompressor(&zstdCompressor{})
+}
+
+type zstdCompressor struct {
+	encoders sync.Pool
+	decoders sync.Pool
+}
+
+func (c *zstdCompressor) Name() string {
+	return Zstd
+}
+
+func (c *zstdCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	enc, ok := c.encoders.Get().(*zstd.Encoder)
+	if !ok {
+		var err error
+		// 并发度为 1：每条消息由一个 goroutine 同步压缩，不额外起后台协程
+		enc, err = zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		enc.Reset(w)
+	}
+	return &zstdWriter{Encoder: enc, pool: &c.encoders}, nil
+}
+
+type zstdWriter struct {
+	*zstd.Encoder
+	pool *sync.Pool
+}
+
+func (w *zstdWriter) Close() error {
+	err := w.Encoder.Close()
+	w.pool.Put(w.Encoder)
+	return err
+}
+
+func (c *zstdCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	dec, ok := c.decoders.Get().(*zstd.Decoder)
+	if !ok {
+		var err error
+		dec, err = zstd.NewReader(r, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(maxDecoderMemory))
+		if err != nil {
+			return nil, err
+		}
+	} else if err := dec.Reset(r); err != nil {
+		c.decoders.Put(dec)
+		return nil, err
+	}
+	return &zstdReader{dec: dec, pool: &c.decoders}, nil
+}
+
+// zstdReader 读到 EOF 后把解码器还回池里，之后的读取不再碰解码器
+type zstdReader struct {
+	dec  *zstd.Decoder
+	pool *sync.Pool
+}
+
+func (r *zstdReader) Read(p []byte) (int, error) {
+	if r.dec == nil {
+		return 0, io.EOF
+	}
+	n, err := r.dec.Read(p)
+	if err == io.EOF {
+		r.pool.Put(r.dec)
+		r.dec = nil
+	}
+	return n, err
+}
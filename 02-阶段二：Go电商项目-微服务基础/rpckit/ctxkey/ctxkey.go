@@ -0,0 +1,155 @@
+/**
+ * @File : ctxkey.go
+ * @Description : 带类型的 context key：每个 Key 是一个独立的指针，不会和其他包的 key 冲突，取值时不需要类型断言
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package ctxkey
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"golang.org/x/text/language"
+	"strings"
+	"sync"
+)
+
+// Key 是 T 类型值的 context key。用 New 创建只在进程内使用的 key，
+// 用 NewBridged 或 NewString 创建会随 gRPC metadata 和 net/rpc envelope 传给下游的 key
+type Key[T any] struct {
+	name   string
+	format func(T) string
+	parse  func(string) (T, error)
+}
+
+// New 创建一个只在进程内使用的 key，name 只用于调试输出
+func New[T any](name string) *Key[T] {
+	return &Key[T]{name: name}
+}
+
+// NewBridged 创建一个会传给下游的 key，name 是 metadata 的名字（小写，不能重复）。
+// 出站时用 format 把值编码成字符串，入站时用 parse 解码
+func NewBridged[T any](name string, format func(T) string, parse func(string) (T, error)) *Key[T] {
+	k := &Key[T]{name: strings.ToLower(name), format: format, parse: parse}
+	register(k)
+	return k
+}
+
+// NewString 创建一个会传给下游的字符串 key
+func NewString(name string) *Key[string] {
+	return NewBridged(name, func(s string) string { return s }, func(s string) (string, error) { return s, nil })
+}
+
+// Name 返回 key 的名字，桥接的 key 就是 metadata 的名字
+func (k *Key[T]) Name() string {
+	return k.name
+}
+
+func (k *Key[T]) String() string {
+	return "ctxkey." + k.name
+}
+
+// With 返回带有 k=v 的 ctx
+func (k *Key[T]) With(ctx context.Context, v T) context.Context {
+	return context.WithValue(ctx, k, v)
+}
+
+// From 返回 ctx 中 k 的值，没有设置时 ok 为 false
+func (k *Key[T]) From(ctx context.Context) (v T, ok bool) {
+	v, ok = ctx.Value(k).(T)
+	return v, ok
+}
+
+// Value 返回 ctx 中 k 的值，没有设置时返回零值
+func (k *Key[T]) Value(ctx context.Context) T {
+	v, _ := k.From(ctx)
+	return v
+}
+
+func (k *Key[T]) export(ctx context.Context) (string, bool) {
+	v, ok := k.From(ctx)
+	if !ok {
+		return "", false
+	}
+	return k.format(v), true
+}
+
+func (k *Key[T]) inject(ctx context.Context, s string) (context.Context, error) {
+	v, err := k.parse(s)
+	if err != nil {
+		return ctx, fmt.Errorf("ctxkey: invalid %s %q: %v", k.name, s, err)
+	}
+	return k.With(ctx, v), nil
+}
+
+// NewRequestID 生成一个随机的 request id
+func NewRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// bridge 是 Key[T] 去掉类型参数后的样子，桥接代码只需要按字符串读写
+type bridge interface {
+	Name() string
+	export(ctx context.Context) (string, bool)
+	inject(ctx context.Context, s string) (context.Context, error)
+}
+
+var (
+	mu      sync.RWMutex
+	bridged []bridge
+)
+
+// register 和 flag 包一样，名字重复时 panic，这是程序错误
+func register(b bridge) {
+	mu.Lock()
+	defer mu.Unlock()
+	for _, old := range bridged {
+		if old.Name() == b.Name() {
+			panic(fmt.Sprintf("ctxkey: %s registered twice", b.Name()))
+		}
+	}
+	bridged = append(bridged, b)
+}
+
+func bridges() []bridge {
+	mu.RLock()
+	defer mu.RUnlock()
+	return bridged
+}
+
+// fromStrings 用 lookup 读取每个桥接 key 的值放进 ctx，gRPC 和 net/rpc 的桥接共用。
+// 没有 request id 时生成一个，说明这个服务就是请求的入口
+func fromStrings(ctx context.Context, lookup func(name string) (string, bool)) (context.Context, error) {
+	for _, b := range bridges() {
+		s, ok := lookup(b.Name())
+		if !ok {
+			continue
+		}
+		var err error
+		if ctx, err = b.inject(ctx, s); err != nil {
+			return ctx, err
+		}
+	}
+	if _, ok := RequestID.From(ctx); !ok {
+		ctx = RequestID.With(ctx, NewRequestID())
+	}
+	return ctx, nil
+}
+
+// 预定义的 key，所有服务使用同一套 metadata 名字
+var (
+	// RequestID 标识一次外部请求，服务端拦截器在请求没有带时生成一个
+	RequestID = NewString("x-request-id")
+	// UserID 是发起请求的用户。它由客户端填写，边缘服务要先鉴权再设置，不能直接信任外部请求带来的值
+	UserID = NewString("x-user-id")
+	// Tenant 是多租户服务中请求所属的租户
+	Tenant = NewString("x-tenant-id")
+	// Locale 是响应使用的语言，按 BCP 47 解析，例如 zh-CN
+	Locale = NewBridged("x-locale", language.Tag.String, language.Parse)
+)
@@ -0,0 +1,198 @@
+/**
+ * @File : ctxkey_test.go
+ * @Description : 测试带类型的 key 互不冲突，以及桥接 key 经过 gRPC metadata 和 net/rpc envelope 后在服务端还原
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package ctxkey
+
+import (
+	"context"
+	"fmt"
+	"golang.org/x/text/language"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	testpb "google.golang.org/grpc/interop/grpc_testing"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net"
+	"net/rpc"
+	"rpckit/grpctest"
+	"rpckit/trace"
+	"strconv"
+	"testing"
+)
+
+func TestKey(t *testing.T) {
+	// 名字相同的两个 key 也互不影响，字符串 key 就做不到
+	a, b := New[string]("name"), New[string]("name")
+	count := New[int]("count")
+	ctx := a.With(context.Background(), "a")
+	ctx = count.With(ctx, 3)
+
+	if v, ok := a.From(ctx); !ok || v != "a" {
+		t.Errorf("a.From() = %q, %v, expect \"a\", true", v, ok)
+	}
+	if v, ok := b.From(ctx); ok {
+		t.Errorf("b.From() = %q, %v, expect a missing value", v, ok)
+	}
+	if v := count.Value(ctx); v != 3 {
+		t.Errorf("count.Value() = %d, expect 3", v)
+	}
+	if v := count.Value(context.Background()); v != 0 {
+		t.Errorf("count.Value() without a value = %d, expect 0", v)
+	}
+	ctx = a.With(ctx, "inner")
+	if v := a.Value(ctx); v != "inner" {
+		t.Errorf("a.Value() after overriding = %q, expect \"inner\"", v)
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("registering x-request-id twice should panic")
+		}
+	}()
+	NewString("X-Request-ID")
+}
+
+// describe 把预定义 key 的值拼成一个字符串，方便比较
+func describe(ctx context.Context) string {
+	return fmt.Sprintf("request=%s user=%s tenant=%s locale=%s",
+		RequestID.Value(ctx), UserID.Value(ctx), Tenant.Value(ctx), Locale.Value(ctx))
+}
+
+type testServer struct {
+	testpb.UnimplementedTestServiceServer
+}
+
+func (testServer) UnaryCall(ctx context.Context, req *testpb.SimpleRequest) (*testpb.SimpleResponse, error) {
+	return &testpb.SimpleResponse{Username: describe(ctx)}, nil
+}
+
+func (testServer) StreamingOutputCall(req *testpb.StreamingOutputCallRequest, stream testpb.TestService_StreamingOutputCallServer) error {
+	return stream.Send(&testpb.StreamingOutputCallResponse{Payload: &testpb.Payload{Body: []byte(describe(stream.Context()))}})
+}
+
+func TestGRPC(t *testing.T) {
+	conn := grpctest.Start(t, func(s *grpc.Server) { testpb.RegisterTestServiceServer(s, testServer{}) },
+		grpctest.WithServerOptions(grpc.UnaryInterceptor(UnaryServerInterceptor()), grpc.StreamInterceptor(StreamServerInterceptor())),
+		grpctest.WithDialOptions(grpc.WithUnaryInterceptor(UnaryClientInterceptor()), grpc.WithStreamInterceptor(StreamClientInterceptor())))
+	client := testpb.NewTestServiceClient(conn)
+
+	full := RequestID.With(context.Background(), "r1")
+	full = UserID.With(full, "u1")
+	full = Tenant.With(full, "t1")
+	full = Locale.With(full, language.MustParse("zh-CN"))
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		expect string
+		code   codes.Code
+	}{
+		{"all keys", full, "request=r1 user=u1 tenant=t1 locale=zh-CN", codes.OK},
+		{"values set directly in metadata", metadata.AppendToOutgoingContext(context.Background(),
+			"x-request-id", "r2", "x-locale", "en-US"), "request=r2 user= tenant= locale=en-US", codes.OK},
+		{"invalid locale", metadata.AppendToOutgoingContext(context.Background(), "x-locale", "not a locale!"), "", codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		var header metadata.MD
+		resp, err := client.UnaryCall(tt.ctx, &testpb.SimpleRequest{}, grpc.Header(&header))
+		if status.Code(err) != tt.code {
+			t.Errorf("%s: UnaryCall() error = %v, expect %s", tt.name, err, tt.code)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if got := resp.GetUsername(); got != tt.expect {
+			t.Errorf("%s: UnaryCall() saw %q, expect %q", tt.name, got, tt.expect)
+		}
+		if got, want := header.Get(RequestID.Name()), RequestID.Value(tt.ctx); len(got) != 1 || (want != "" && got[0] != want) {
+			t.Errorf("%s: response header %s = %v, expect [%s]", tt.name, RequestID.Name(), got, want)
+		}
+
+		stream, err := client.StreamingOutputCall(tt.ctx, &testpb.StreamingOutputCallRequest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg, err := stream.Recv()
+		if err != nil {
+			t.Errorf("%s: StreamingOutputCall() error = %v", tt.name, err)
+			continue
+		}
+		if got := string(msg.GetPayload().GetBody()); got != tt.expect {
+			t.Errorf("%s: StreamingOutputCall() saw %q, expect %q", tt.name, got, tt.expect)
+		}
+	}
+
+	// 没有 request id 时服务端生成一个，并在响应 header 中返回
+	var header metadata.MD
+	resp, err := client.UnaryCall(context.Background(), &testpb.SimpleRequest{}, grpc.Header(&header))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := header.Get(RequestID.Name()); len(ids) != 1 || len(ids[0]) != 32 || resp.GetUsername() != "request="+ids[0]+" user= tenant= locale=und" {
+		t.Errorf("generated request id: header %v, handler saw %q", ids, resp.GetUsername())
+	}
+}
+
+// Args 通过 Receiver 拿到 envelope 中的值
+type Args struct {
+	N   int
+	ctx context.Context
+}
+
+func (a *Args) SetContext(ctx context.Context) { a.ctx = ctx }
+
+type Greeter struct{}
+
+func (Greeter) Describe(args *Args, reply *string) error {
+	*reply = strconv.Itoa(args.N) + " " + describe(args.ctx)
+	return nil
+}
+
+func TestNetRPC(t *testing.T) {
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("Greeter", Greeter{}); err != nil {
+		t.Fatal(err)
+	}
+	serverConn, clientConn := net.Pipe()
+	go srv.ServeCodec(WrapServerCodec(trace.NewServerCodec(serverConn, trace.NewTracer("server", nil))))
+	client := trace.NewClient(clientConn)
+	defer client.Close()
+	tracer := trace.NewTracer("client", nil)
+
+	ctx := RequestID.With(context.Background(), "r1")
+	ctx = UserID.With(ctx, "u1")
+	ctx = Locale.With(ctx, language.MustParse("fr"))
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		expect string
+		err    bool
+	}{
+		{"values travel in the envelope", ctx, "1 request=r1 user=u1 tenant= locale=fr", false},
+		{"invalid locale", trace.WithMetadata(context.Background(), "x-locale", "not a locale!"), "", true},
+	}
+	for _, tt := range tests {
+		var reply string
+		err := Call(tt.ctx, tracer, client, "Greeter.Describe", &Args{N: 1}, &reply)
+		if (err != nil) != tt.err {
+			t.Errorf("%s: Call() error = %v, expect error %v", tt.name, err, tt.err)
+		}
+		if reply != tt.expect {
+			t.Errorf("%s: reply = %q, expect %q", tt.name, reply, tt.expect)
+		}
+	}
+
+	// 没有 request id 时 codec 生成一个
+	var reply string
+	if err := Call(context.Background(), tracer, client, "Greeter.Describe", &Args{N: 2}, &reply); err != nil {
+		t.Fatal(err)
+	}
+	if len(reply) != len("2 request= user= tenant= locale=und")+32 {
+		t.Errorf("reply without a request id = %q, expect a generated one", reply)
+	}
+}
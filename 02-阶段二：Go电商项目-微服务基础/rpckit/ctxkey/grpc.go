@@ -0,0 +1,90 @@
+/**
+ * @File : grpc.go
+ * @Description : gRPC 桥接：客户端把桥接 key 的值写进请求 metadata，服务端再从 metadata 读回 ctx
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package ctxkey
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// ToOutgoing 把 ctx 中所有桥接 key 的值写进出站 metadata，覆盖 metadata 中同名的值
+func ToOutgoing(ctx context.Context) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	for _, b := range bridges() {
+		if v, ok := b.export(ctx); ok {
+			md.Set(b.Name(), v)
+		}
+	}
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// FromIncoming 把入站 metadata 中桥接 key 的值放进 ctx，值无法解析时返回错误
+func FromIncoming(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	return fromStrings(ctx, func(name string) (string, bool) {
+		if vs := md.Get(name); len(vs) > 0 {
+			return vs[0], true
+		}
+		return "", false
+	})
+}
+
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(ToOutgoing(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(ToOutgoing(ctx), desc, cc, method, opts...)
+	}
+}
+
+// UnaryServerInterceptor 把 metadata 中的值放进 handler 的 ctx，并在响应 header 中返回 request id
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := incoming(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := incoming(ss.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func incoming(ctx context.Context) (context.Context, error) {
+	ctx, err := FromIncoming(ctx)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	grpc.SetHeader(ctx, metadata.Pairs(RequestID.Name(), RequestID.Value(ctx)))
+	return ctx, nil
+}
+
+// serverStream 替换 Context，handler 通过 stream.Context() 读到桥接的值
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
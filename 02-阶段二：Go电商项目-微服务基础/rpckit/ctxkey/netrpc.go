@@ -0,0 +1,85 @@
+/**
+ * @File : netrpc.go
+ * @Description : net/rpc 桥接：客户端把桥接 key 的值放进 envelope，服务端 codec 读出来交给实现了 Receiver 的参数
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package ctxkey
+
+import (
+	"context"
+	"net/rpc"
+	"rpckit/trace"
+)
+
+// ToEnvelope 把 ctx 中所有桥接 key 的值放进 trace metadata，用 trace.Tracer.Call 调用时随 envelope 发送
+func ToEnvelope(ctx context.Context) context.Context {
+	for _, b := range bridges() {
+		if v, ok := b.export(ctx); ok {
+			ctx = trace.WithMetadata(ctx, b.Name(), v)
+		}
+	}
+	return ctx
+}
+
+// Call 等同于 t.Call(ToEnvelope(ctx), ...)
+func Call(ctx context.Context, t *trace.Tracer, client *trace.Client, serviceMethod string, args, reply any) error {
+	return t.Call(ToEnvelope(ctx), client, serviceMethod, args, reply)
+}
+
+// Receiver 由 net/rpc 的参数类型实现。net/rpc 的方法没有 ctx 参数，codec 解码参数后调用 SetContext
+// 把带有桥接值的 ctx 交给方法。ctx 要保存在未导出的字段里，gob 和 JSON 都会忽略它：
+//
+//	type OrderArgs struct {
+//		ID  string
+//		ctx context.Context
+//	}
+//
+//	func (a *OrderArgs) SetContext(ctx context.Context) { a.ctx = ctx }
+type Receiver interface {
+	SetContext(ctx context.Context)
+}
+
+type serverCodec struct {
+	trace.MetadataCodec
+
+	// net/rpc 在同一个 goroutine 里依次调用 ReadRequestHeader 和 ReadRequestBody
+	md map[string]string
+}
+
+// WrapServerCodec 用法：rpc.ServeCodec(ctxkey.WrapServerCodec(trace.NewServerCodec(conn, tracer)))。
+// 返回值仍是 trace.MetadataCodec，可以继续被 idempotency.Guard.WrapServerCodec 包装
+func WrapServerCodec(codec trace.MetadataCodec) trace.MetadataCodec {
+	return &serverCodec{MetadataCodec: codec}
+}
+
+func (c *serverCodec) ReadRequestHeader(r *rpc.Request) error {
+	c.md = nil
+	if err := c.MetadataCodec.ReadRequestHeader(r); err != nil {
+		return err
+	}
+	c.md = c.RequestMetadata(r.Seq)
+	return nil
+}
+
+// ReadRequestBody 返回错误时 net/rpc 不调用方法，把错误写回给客户端
+func (c *serverCodec) ReadRequestBody(body any) error {
+	md := c.md
+	c.md = nil
+	if err := c.MetadataCodec.ReadRequestBody(body); err != nil {
+		return err
+	}
+	recv, ok := body.(Receiver)
+	if !ok {
+		return nil
+	}
+	ctx, err := fromStrings(context.Background(), func(name string) (string, bool) {
+		v, ok := md[name]
+		return v, ok
+	})
+	if err != nil {
+		return err
+	}
+	recv.SetContext(ctx)
+	return nil
+}
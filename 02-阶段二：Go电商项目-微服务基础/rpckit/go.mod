@@ -0,0 +1,21 @@
+module rpckit
+
+go 1.22.5
+
+require (
+	api v0.0.0
+	github.com/klauspost/compress v1.18.0
+	go.etcd.io/bbolt v1.3.11
+	golang.org/x/sync v0.8.0
+	golang.org/x/text v0.17.0
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240814211410-ddb44dafa142
+	google.golang.org/grpc v1.67.1
+	google.golang.org/protobuf v1.34.2
+)
+
+require (
+	golang.org/x/net v0.28.0 // indirect
+	golang.org/x/sys v0.24.0 // indirect
+)
+
+replace api => ../api
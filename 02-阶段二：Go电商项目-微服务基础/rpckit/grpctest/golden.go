@@ -0,0 +1,65 @@
+/**
+ * @File : golden.go
+ * @Description : 黄金文件断言：把输出与 testdata 中保存的期望值比较，go test -update 时重写期望值
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package grpctest
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"google.golang.org/grpc/metadata"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files with the current output")
+
+// Golden 比较 got 与 testdata/<name>.golden
+func Golden(t testing.TB, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden file (run go test -update to create it): %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s mismatch (run go test -update if the change is expected)\n--- got\n%s--- want\n%s", path, got, want)
+	}
+}
+
+// FormatMetadata 把元数据按键排序后逐行输出，ignore 中的键（如每次都不同的时间戳）会被跳过
+func FormatMetadata(md metadata.MD, ignore ...string) []byte {
+	skip := make(map[string]bool, len(ignore))
+	for _, k := range ignore {
+		skip[strings.ToLower(k)] = true
+	}
+	keys := make([]string, 0, len(md))
+	for k := range md {
+		if !skip[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	var b bytes.Buffer
+	for _, k := range keys {
+		for _, v := range md[k] {
+			fmt.Fprintf(&b, "%s: %s\n", k, v)
+		}
+	}
+	return b.Bytes()
+}
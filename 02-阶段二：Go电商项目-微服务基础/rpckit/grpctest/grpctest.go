@@ -0,0 +1,70 @@
+/**
+ * @File : grpctest.go
+ * @Description : 测试辅助：在进程内通过 bufconn 启动 gRPC 服务并返回连好的客户端连接，不占用真实端口
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package grpctest
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"testing"
+)
+
+// bufSize 是内存连接的缓冲区大小，测试中的消息都很小
+const bufSize = 1 << 20
+
+type config struct {
+	serverOpts []grpc.ServerOption
+	dialOpts   []grpc.DialOption
+}
+
+type Option func(*config)
+
+// WithServerOptions 追加服务端选项，例如拦截器
+func WithServerOptions(opts ...grpc.ServerOption) Option {
+	return func(c *config) { c.serverOpts = append(c.serverOpts, opts...) }
+}
+
+// WithDialOptions 追加客户端选项
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(c *config) { c.dialOpts = append(c.dialOpts, opts...) }
+}
+
+// Start 创建 gRPC 服务，由 register 注册服务实现，然后返回连到它的客户端连接。
+// 服务和连接会在测试结束时自动关闭：
+//
+//	conn := grpctest.Start(t, func(s *grpc.Server) { proto.RegisterGreeterServer(s, &server{}) })
+//	client := proto.NewGreeterClient(conn)
+func Start(t testing.TB, register func(*grpc.Server), opts ...Option) *grpc.ClientConn {
+	t.Helper()
+	var c config
+	for _, o := range opts {
+		o(&c)
+	}
+
+	lis := bufconn.Listen(bufSize)
+	s := grpc.NewServer(c.serverOpts...)
+	register(s)
+	go s.Serve(lis)
+
+	dialOpts := append([]grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	}, c.dialOpts...)
+	// passthrough 让 gRPC 不去解析地址，直接交给上面的 dialer
+	conn, err := grpc.NewClient("passthrough:///bufconn", dialOpts...)
+	if err != nil {
+		s.Stop()
+		t.Fatalf("grpctest: dial: %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		s.Stop()
+	})
+	return conn
+}
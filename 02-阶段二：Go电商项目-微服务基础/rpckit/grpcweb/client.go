@@ -0,0 +1,259 @@
+/**
+ * @File : client.go
+ * @Description : 最小的 Go gRPC-Web 客户端，和浏览器一样通过 HTTP/1.1 调用一元和服务端流方法，用于测试网关的兼容性
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package grpcweb
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/metadata"
+	grpcstatus "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxMessageSize 限制单条响应消息的大小，和 gRPC 默认的接收上限一致
+const maxMessageSize = 4 << 20
+
+// Client 调用 baseURL 上的 gRPC-Web 服务，Text 为 true 时使用 base64 文本格式
+type Client struct {
+	baseURL string
+	text    bool
+	http    *http.Client
+}
+
+func NewClient(baseURL string, text bool) *Client {
+	return &Client{baseURL: strings.TrimSuffix(baseURL, "/"), text: text, http: http.DefaultClient}
+}
+
+// Stream 是一次调用的响应流
+type Stream struct {
+	body     io.ReadCloser
+	r        *bufio.Reader
+	header   metadata.MD
+	trailer  metadata.MD
+	encoding string
+	done     error // 读到 trailer 后的结果，io.EOF 表示成功
+}
+
+// Invoke 调用一元方法
+func (c *Client) Invoke(ctx context.Context, method string, req, resp proto.Message) (header, trailer metadata.MD, err error) {
+	s, err := c.NewStream(ctx, method, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer s.Close()
+	if err := s.Recv(resp); err != nil {
+		return s.Header(), s.Trailer(), err
+	}
+	if err := s.Recv(resp); err != io.EOF {
+		if err == nil {
+			err = grpcstatus.Error(codes.Internal, "grpcweb: unary call returned more than one message")
+		}
+		return s.Header(), s.Trailer(), err
+	}
+	return s.Header(), s.Trailer(), nil
+}
+
+// NewStream 发送请求并返回响应流，ctx 里的 outgoing metadata 作为请求头发送，deadline 转换成 grpc-timeout
+func (c *Client) NewStream(ctx context.Context, method string, req proto.Message) (*Stream, error) {
+	msg, err := proto.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	frame := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+	frame = append(frame, msg...)
+	contentType := ContentType + "+proto"
+	if c.text {
+		contentType = ContentTypeText + "+proto"
+		frame = []byte(base64.StdEncoding.EncodeToString(frame))
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+method, bytes.NewReader(frame))
+	if err != nil {
+		return nil, err
+	}
+	md, _ := metadata.FromOutgoingContext(ctx)
+	for k, vv := range md {
+		for _, v := range vv {
+			httpReq.Header.Add(k, v)
+		}
+	}
+	httpReq.Header.Set("Content-Type", contentType)
+	httpReq.Header.Set("X-Grpc-Web", "1")
+	if deadline, ok := ctx.Deadline(); ok {
+		httpReq.Header.Set("Grpc-Timeout", strconv.FormatInt(max(time.Until(deadline).Milliseconds(), 1), 10)+"m")
+	}
+
+	resp, err := c.http.Do(httpReq)
+	if err != nil {
+		return nil, grpcstatus.FromContextError(err).Err()
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, grpcstatus.Errorf(httpStatusCode(resp.StatusCode), "grpcweb: unexpected HTTP status %s", resp.Status)
+	}
+	s := &Stream{body: resp.Body, header: toMD(resp.Header), encoding: resp.Header.Get("Grpc-Encoding")}
+	var body io.Reader = resp.Body
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), ContentTypeText) {
+		body = &textReader{r: resp.Body}
+	}
+	s.r = bufio.NewReader(body)
+	// 服务端没有进入 gRPC 处理就返回错误时，状态码在 HTTP 头里
+	if code := resp.Header.Get("Grpc-Status"); code != "" {
+		s.done = statusFromHeader(resp.Header)
+	}
+	return s, nil
+}
+
+func (s *Stream) Header() metadata.MD  { return s.header }
+func (s *Stream) Trailer() metadata.MD { return s.trailer }
+func (s *Stream) Close() error         { return s.body.Close() }
+
+// Recv 读取下一条消息，流正常结束时返回 io.EOF，否则返回服务端的 status 错误
+func (s *Stream) Recv(m proto.Message) error {
+	if s.done != nil {
+		return s.done
+	}
+	var hdr [5]byte
+	if _, err := io.ReadFull(s.r, hdr[:]); err != nil {
+		s.done = grpcstatus.Errorf(codes.Internal, "grpcweb: stream ended without trailers: %v", err)
+		return s.done
+	}
+	n := binary.BigEndian.Uint32(hdr[1:])
+	if n > maxMessageSize {
+		s.done = grpcstatus.Errorf(codes.ResourceExhausted, "grpcweb: message of %d bytes exceeds %d", n, maxMessageSize)
+		return s.done
+	}
+	data := make([]byte, n)
+	if _, err := io.ReadFull(s.r, data); err != nil {
+		s.done = grpcstatus.Errorf(codes.Internal, "grpcweb: truncated message: %v", err)
+		return s.done
+	}
+
+	if hdr[0]&trailerFlag != 0 {
+		tp := textproto.NewReader(bufio.NewReader(io.MultiReader(bytes.NewReader(data), strings.NewReader("\r\n"))))
+		h, err := tp.ReadMIMEHeader()
+		if err != nil && err != io.EOF {
+			s.done = grpcstatus.Errorf(codes.Internal, "grpcweb: invalid trailers: %v", err)
+			return s.done
+		}
+		s.trailer = toMD(http.Header(h))
+		s.done = statusFromHeader(http.Header(h))
+		return s.done
+	}
+	if hdr[0]&1 != 0 {
+		var err error
+		if data, err = decompress(s.encoding, data); err != nil {
+			s.done = grpcstatus.Errorf(codes.Internal, "grpcweb: %v", err)
+			return s.done
+		}
+	}
+	if err := proto.Unmarshal(data, m); err != nil {
+		s.done = grpcstatus.Errorf(codes.Internal, "grpcweb: unmarshal response: %v", err)
+		return s.done
+	}
+	return nil
+}
+
+func decompress(name string, data []byte) ([]byte, error) {
+	c := encoding.GetCompressor(name)
+	if c == nil {
+		return nil, fmt.Errorf("unknown grpc-encoding %q", name)
+	}
+	r, err := c.Decompress(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(io.LimitReader(r, maxMessageSize))
+}
+
+// statusFromHeader 把 grpc-status、grpc-message 和 grpc-status-details-bin 还原成 status 错误，成功时返回 io.EOF
+func statusFromHeader(h http.Header) error {
+	code, err := strconv.Atoi(h.Get("Grpc-Status"))
+	if err != nil {
+		return grpcstatus.Errorf(codes.Internal, "grpcweb: invalid grpc-status %q", h.Get("Grpc-Status"))
+	}
+	if codes.Code(code) == codes.OK {
+		return io.EOF
+	}
+	if bin := h.Get("Grpc-Status-Details-Bin"); bin != "" {
+		if b, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(bin, "=")); err == nil {
+			st := &status.Status{}
+			if proto.Unmarshal(b, st) == nil {
+				return grpcstatus.ErrorProto(st)
+			}
+		}
+	}
+	msg, _ := decodeGrpcMessage(h.Get("Grpc-Message"))
+	return grpcstatus.Error(codes.Code(code), msg)
+}
+
+// decodeGrpcMessage 还原 grpc-message 中按百分号编码的字符
+func decodeGrpcMessage(msg string) (string, error) {
+	if !strings.Contains(msg, "%") {
+		return msg, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(msg); i++ {
+		if msg[i] == '%' && i+2 < len(msg) {
+			v, err := strconv.ParseUint(msg[i+1:i+3], 16, 8)
+			if err != nil {
+				return msg, err
+			}
+			b.WriteByte(byte(v))
+			i += 2
+			continue
+		}
+		b.WriteByte(msg[i])
+	}
+	return b.String(), nil
+}
+
+// toMD 把 HTTP 头转换成 metadata，去掉 HTTP 本身的头和跨域相关的头
+func toMD(h http.Header) metadata.MD {
+	md := metadata.MD{}
+	for k, vv := range h {
+		k = strings.ToLower(k)
+		switch {
+		case k == "content-type", k == "content-length", k == "date", k == "vary", k == "transfer-encoding",
+			strings.HasPrefix(k, "grpc-"), strings.HasPrefix(k, "access-control-"):
+			continue
+		}
+		md[k] = append(md[k], vv...)
+	}
+	return md
+}
+
+// httpStatusCode 按 gRPC 的约定把 HTTP 状态码映射成 gRPC 状态码
+func httpStatusCode(code int) codes.Code {
+	switch code {
+	case http.StatusBadRequest:
+		return codes.Internal
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.Unimplemented
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return codes.Unavailable
+	}
+	return codes.Unknown
+}
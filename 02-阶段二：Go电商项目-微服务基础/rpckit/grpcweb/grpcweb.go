@@ -0,0 +1,132 @@
+/**
+ * @File : grpcweb.go
+ * @Description : gRPC-Web 网关：把浏览器发来的 gRPC-Web 请求（二进制和 base64 文本两种格式）交给已有的 grpc.Server 处理
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package grpcweb
+
+import (
+	"google.golang.org/grpc"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// gRPC-Web 的 content-type，+proto 后缀可以省略
+const (
+	ContentType     = "application/grpc-web"
+	ContentTypeText = "application/grpc-web-text"
+)
+
+// 浏览器的跨域预检结果缓存时间（秒）
+const preflightMaxAge = "600"
+
+// Handler 把 gRPC-Web 请求转换成 grpc.Server.ServeHTTP 能处理的 gRPC 请求，
+// 服务端的拦截器、stats handler、限流等都和原生 gRPC 调用一样生效
+type Handler struct {
+	server  *grpc.Server
+	origins []string
+}
+
+type Option func(*Handler)
+
+// WithOrigins 限制允许跨域调用的页面来源，例如 "http://localhost:3000"，默认允许所有来源
+func WithOrigins(origins ...string) Option {
+	return func(h *Handler) { h.origins = append(h.origins, origins...) }
+}
+
+// Wrap 返回处理 s 上所有服务的 gRPC-Web handler
+func Wrap(s *grpc.Server, opts ...Option) *Handler {
+	h := &Handler{server: s}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Serve 在 addr 上启动 gRPC-Web 的 HTTP 服务，addr 为空时不启动
+func Serve(addr string, s *grpc.Server, opts ...Option) {
+	if addr == "" {
+		return
+	}
+	h := Wrap(s, opts...)
+	go func() {
+		log.Printf("grpcweb: serving http://%s", addr)
+		log.Printf("grpcweb: %v", http.ListenAndServe(addr, h))
+	}()
+}
+
+// IsGRPCWebRequest 判断是不是 gRPC-Web 请求（不包括跨域预检）
+func IsGRPCWebRequest(r *http.Request) bool {
+	return r.Method == http.MethodPost && strings.HasPrefix(r.Header.Get("Content-Type"), ContentType)
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin != "" && !h.allowOrigin(origin) {
+		http.Error(w, "grpcweb: origin not allowed", http.StatusForbidden)
+		return
+	}
+	if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+		h.preflight(w, r)
+		return
+	}
+	if IsGRPCWebRequest(r) {
+		h.serveWeb(w, r)
+		return
+	}
+	// 通过 TLS 或 h2c 的 HTTP/2 连接过来的原生 gRPC 请求直接交给 grpc.Server
+	if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		h.server.ServeHTTP(w, r)
+		return
+	}
+	http.Error(w, "grpcweb: expect a gRPC-Web request", http.StatusUnsupportedMediaType)
+}
+
+func (h *Handler) allowOrigin(origin string) bool {
+	return len(h.origins) == 0 || slices.Contains(h.origins, origin)
+}
+
+// preflight 回应浏览器的跨域预检。gRPC 的 metadata 就是请求头，名字由业务决定，所以请求什么头就允许什么头
+func (h *Handler) preflight(w http.ResponseWriter, r *http.Request) {
+	hdr := w.Header()
+	setAllowOrigin(hdr, r.Header.Get("Origin"))
+	hdr.Set("Access-Control-Allow-Methods", http.MethodPost)
+	if req := r.Header.Get("Access-Control-Request-Headers"); req != "" {
+		hdr.Set("Access-Control-Allow-Headers", req)
+	}
+	hdr.Set("Access-Control-Max-Age", preflightMaxAge)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func setAllowOrigin(hdr http.Header, origin string) {
+	if origin == "" {
+		return
+	}
+	hdr.Set("Access-Control-Allow-Origin", origin)
+	hdr.Add("Vary", "Origin")
+}
+
+// serveWeb 改写请求让 grpc.Server 当作 HTTP/2 的 gRPC 请求处理，响应由 responseWriter 转换回 gRPC-Web
+func (h *Handler) serveWeb(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get("Content-Type")
+	text := strings.HasPrefix(contentType, ContentTypeText)
+	webType, subtype := ContentType, strings.TrimPrefix(contentType, ContentType)
+	if text {
+		webType, subtype = ContentTypeText, strings.TrimPrefix(contentType, ContentTypeText)
+	}
+
+	req := r.Clone(r.Context())
+	req.ProtoMajor, req.ProtoMinor, req.Proto = 2, 0, "HTTP/2.0"
+	req.Header.Set("Content-Type", "application/grpc"+subtype)
+	req.Header.Del("Content-Length")
+	if text {
+		req.Body = newTextReader(r.Body)
+	}
+
+	rw := newResponseWriter(w, webType+subtype, r.Header.Get("Origin"), text)
+	h.server.ServeHTTP(rw, req)
+	rw.finish()
+}
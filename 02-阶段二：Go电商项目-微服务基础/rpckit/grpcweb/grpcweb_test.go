@@ -0,0 +1,241 @@
+/**
+ * @File : grpcweb_test.go
+ * @Description : 用 gRPC-Web 客户端通过 HTTP/1.1 测试网关：二进制和文本格式、服务端流、错误状态、跨域
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package grpcweb
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	testpb "google.golang.org/grpc/interop/grpc_testing"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type testServer struct {
+	testpb.UnimplementedTestServiceServer
+}
+
+// UnaryCall 把请求里的 client-id 放回 header 的 server-id，ResponseStatus 不为空时返回带 details 的错误
+func (testServer) UnaryCall(ctx context.Context, req *testpb.SimpleRequest) (*testpb.SimpleResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	grpc.SetHeader(ctx, metadata.MD{"server-id": md.Get("client-id")})
+	grpc.SetTrailer(ctx, metadata.Pairs("server-trailer", "done"))
+	if s := req.ResponseStatus; s != nil {
+		st, _ := status.New(codes.Code(s.Code), s.Message).WithDetails(&errdetails.ErrorInfo{Reason: "OUT_OF_STOCK"})
+		return nil, st.Err()
+	}
+	return &testpb.SimpleResponse{Payload: &testpb.Payload{Body: bytes.Repeat([]byte("x"), int(req.ResponseSize))}}, nil
+}
+
+func (testServer) StreamingOutputCall(req *testpb.StreamingOutputCallRequest, stream testpb.TestService_StreamingOutputCallServer) error {
+	for _, p := range req.ResponseParameters {
+		if err := stream.Send(&testpb.StreamingOutputCallResponse{Payload: &testpb.Payload{Body: make([]byte, p.Size)}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func startWeb(t *testing.T, opts ...Option) *httptest.Server {
+	s := grpc.NewServer()
+	testpb.RegisterTestServiceServer(s, testServer{})
+	ts := httptest.NewServer(Wrap(s, opts...))
+	t.Cleanup(func() {
+		ts.Close()
+		s.Stop()
+	})
+	return ts
+}
+
+var modes = []struct {
+	name string
+	text bool
+}{{"binary", false}, {"text", true}}
+
+func TestUnary(t *testing.T) {
+	ts := startWeb(t)
+	for _, m := range modes {
+		c := NewClient(ts.URL, m.text)
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "client-id", "browser-1")
+		resp := &testpb.SimpleResponse{}
+		// 1000 字节不是 3 的倍数，文本模式的 base64 在消息中间留下 padding
+		header, trailer, err := c.Invoke(ctx, "/grpc.testing.TestService/UnaryCall", &testpb.SimpleRequest{ResponseSize: 1000}, resp)
+		if err != nil {
+			t.Errorf("%s: Invoke() error = %v", m.name, err)
+			continue
+		}
+		if len(resp.Payload.Body) != 1000 {
+			t.Errorf("%s: response body is %d bytes, expect 1000", m.name, len(resp.Payload.Body))
+		}
+		if got := header.Get("server-id"); len(got) != 1 || got[0] != "browser-1" {
+			t.Errorf("%s: header server-id = %v, expect [browser-1]", m.name, got)
+		}
+		if got := trailer.Get("server-trailer"); len(got) != 1 || got[0] != "done" {
+			t.Errorf("%s: trailer server-trailer = %v, expect [done]", m.name, got)
+		}
+	}
+}
+
+func TestServerStreaming(t *testing.T) {
+	ts := startWeb(t)
+	sizes := []int32{1, 10, 100, 1000, 0, 65536}
+	var params []*testpb.ResponseParameters
+	for _, size := range sizes {
+		params = append(params, &testpb.ResponseParameters{Size: size})
+	}
+	for _, m := range modes {
+		stream, err := NewClient(ts.URL, m.text).NewStream(context.Background(), "/grpc.testing.TestService/StreamingOutputCall",
+			&testpb.StreamingOutputCallRequest{ResponseParameters: params})
+		if err != nil {
+			t.Fatalf("%s: NewStream() error = %v", m.name, err)
+		}
+		var got []int32
+		for {
+			resp := &testpb.StreamingOutputCallResponse{}
+			err := stream.Recv(resp)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s: Recv() error = %v", m.name, err)
+			}
+			got = append(got, int32(len(resp.Payload.GetBody())))
+		}
+		stream.Close()
+		if len(got) != len(sizes) {
+			t.Errorf("%s: received %v, expect %v", m.name, got, sizes)
+			continue
+		}
+		for i := range sizes {
+			if got[i] != sizes[i] {
+				t.Errorf("%s: message %d is %d bytes, expect %d", m.name, i, got[i], sizes[i])
+			}
+		}
+	}
+}
+
+func TestStatus(t *testing.T) {
+	ts := startWeb(t)
+	tests := []struct {
+		name    string
+		method  string
+		req     *testpb.SimpleRequest
+		code    codes.Code
+		message string
+		reason  string
+	}{
+		{"error with details", "/grpc.testing.TestService/UnaryCall",
+			&testpb.SimpleRequest{ResponseStatus: &testpb.EchoStatus{Code: int32(codes.FailedPrecondition), Message: "库存不足: 100%"}},
+			codes.FailedPrecondition, "库存不足: 100%", "OUT_OF_STOCK"},
+		{"unknown method", "/grpc.testing.TestService/Missing", &testpb.SimpleRequest{}, codes.Unimplemented, "", ""},
+		{"unknown service", "/Missing/Call", &testpb.SimpleRequest{}, codes.Unimplemented, "", ""},
+	}
+	for _, m := range modes {
+		for _, tt := range tests {
+			_, _, err := NewClient(ts.URL, m.text).Invoke(context.Background(), tt.method, tt.req, &testpb.SimpleResponse{})
+			st := status.Convert(err)
+			if st.Code() != tt.code {
+				t.Errorf("%s/%s: error = %v, expect %v", m.name, tt.name, err, tt.code)
+				continue
+			}
+			if tt.message != "" && st.Message() != tt.message {
+				t.Errorf("%s/%s: message = %q, expect %q", m.name, tt.name, st.Message(), tt.message)
+			}
+			if tt.reason != "" {
+				if details := st.Details(); len(details) != 1 || details[0].(*errdetails.ErrorInfo).Reason != tt.reason {
+					t.Errorf("%s/%s: details = %v, expect ErrorInfo %s", m.name, tt.name, details, tt.reason)
+				}
+			}
+		}
+	}
+}
+
+func TestCORS(t *testing.T) {
+	ts := startWeb(t, WithOrigins("http://shop.example"))
+	tests := []struct {
+		name   string
+		method string
+		origin string
+		status int
+		header map[string]string // 期望的响应头，值为空表示不应出现
+	}{
+		{"preflight from allowed origin", http.MethodOptions, "http://shop.example", http.StatusNoContent, map[string]string{
+			"Access-Control-Allow-Origin":  "http://shop.example",
+			"Access-Control-Allow-Methods": "POST",
+			"Access-Control-Allow-Headers": "content-type,x-grpc-web,client-id",
+		}},
+		{"preflight from other origin", http.MethodOptions, "http://evil.example", http.StatusForbidden, map[string]string{
+			"Access-Control-Allow-Origin": "",
+		}},
+		{"call from allowed origin", http.MethodPost, "http://shop.example", http.StatusOK, map[string]string{
+			"Access-Control-Allow-Origin":   "http://shop.example",
+			"Access-Control-Expose-Headers": "Server-Id, Grpc-Status, Grpc-Message, Grpc-Status-Details-Bin",
+			"Content-Type":                  "application/grpc-web+proto",
+		}},
+		{"call from other origin", http.MethodPost, "http://evil.example", http.StatusForbidden, nil},
+	}
+	for _, tt := range tests {
+		// 空请求体相当于一条空的 SimpleRequest 都没有发，服务端仍然会返回状态，足够检查响应头
+		body := []byte{0, 0, 0, 0, 0}
+		req, _ := http.NewRequest(tt.method, ts.URL+"/grpc.testing.TestService/UnaryCall", bytes.NewReader(body))
+		req.Header.Set("Origin", tt.origin)
+		if tt.method == http.MethodOptions {
+			req.Header.Set("Access-Control-Request-Method", "POST")
+			req.Header.Set("Access-Control-Request-Headers", "content-type,x-grpc-web,client-id")
+		} else {
+			req.Header.Set("Content-Type", "application/grpc-web+proto")
+			req.Header.Set("Client-Id", "browser-1")
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: status = %d, expect %d", tt.name, resp.StatusCode, tt.status)
+		}
+		for k, want := range tt.header {
+			if got := resp.Header.Get(k); got != want {
+				t.Errorf("%s: %s = %q, expect %q", tt.name, k, got, want)
+			}
+		}
+	}
+}
+
+func TestTextReader(t *testing.T) {
+	enc := base64.StdEncoding.EncodeToString
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"single chunk", enc([]byte("hello grpc-web")), "hello grpc-web", false},
+		{"padded chunks", enc([]byte("a")) + enc([]byte("bc")) + enc([]byte("def")), "abcdef", false},
+		{"line breaks", "aGVs\r\nbG8=\n", "hello", false},
+		{"truncated", "aGVsbG", "", true},
+		{"invalid", "a*Vs", "", true},
+	}
+	for _, tt := range tests {
+		got, err := io.ReadAll(newTextReader(io.NopCloser(strings.NewReader(tt.in))))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && string(got) != tt.want {
+			t.Errorf("%s: got %q, expect %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+/**
+ * @File : writer.go
+ * @Description : 把 grpc.Server 写出的 HTTP/2 响应转换成 gRPC-Web 响应：trailer 变成 body 里的最后一帧
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package grpcweb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// trailerFlag 是 trailer 帧的标志位，普通消息帧的标志位是 0（未压缩）或 1（压缩）
+const trailerFlag = 0x80
+
+// grpc.Server 在写完 body 后直接设置的 trailer，其余 trailer 带 http.TrailerPrefix 前缀
+var statusTrailers = []string{"Grpc-Status", "Grpc-Message", "Grpc-Status-Details-Bin"}
+
+type responseWriter struct {
+	w           http.ResponseWriter
+	out         io.Writer // 二进制模式就是 w，文本模式先做 base64 编码
+	text        *textWriter
+	header      http.Header // grpc.Server 看到的 header，第一次写 body 时复制到 w
+	contentType string
+	origin      string
+	wroteHeader bool
+}
+
+func newResponseWriter(w http.ResponseWriter, contentType, origin string, text bool) *responseWriter {
+	rw := &responseWriter{w: w, out: w, header: make(http.Header), contentType: contentType, origin: origin}
+	if text {
+		rw.text = &textWriter{w: w}
+		rw.out = rw.text
+	}
+	return rw
+}
+
+func (rw *responseWriter) Header() http.Header { return rw.header }
+
+// WriteHeader 把 header 复制给浏览器。gRPC 用 Trailer 头预告的 trailer 在 gRPC-Web 里放在 body 中，不再声明
+func (rw *responseWriter) WriteHeader(code int) {
+	if rw.wroteHeader {
+		return
+	}
+	rw.wroteHeader = true
+	hdr := rw.w.Header()
+	var expose []string
+	for k, vv := range rw.header {
+		if k == "Trailer" || strings.HasPrefix(k, http.TrailerPrefix) {
+			continue
+		}
+		hdr[k] = vv
+		// 值为 nil 的头（grpc.Server 用它去掉 Date）只是为了不发送
+		if k != "Content-Type" && len(vv) > 0 {
+			expose = append(expose, k)
+		}
+	}
+	hdr.Set("Content-Type", rw.contentType)
+	if rw.origin != "" {
+		// 浏览器只允许页面脚本读取列在 Expose-Headers 里的响应头，服务端用 SetHeader 设置的 metadata 都要列出来
+		setAllowOrigin(hdr, rw.origin)
+		slices.Sort(expose)
+		hdr.Set("Access-Control-Expose-Headers", strings.Join(append(expose, statusTrailers...), ", "))
+	}
+	rw.w.WriteHeader(code)
+}
+
+func (rw *responseWriter) Write(p []byte) (int, error) {
+	rw.WriteHeader(http.StatusOK)
+	return rw.out.Write(p)
+}
+
+func (rw *responseWriter) Flush() {
+	rw.WriteHeader(http.StatusOK)
+	if rw.text != nil {
+		rw.text.flush()
+	}
+	if f, ok := rw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// finish 在 grpc.Server 处理完请求后调用，把 trailer 编码成 "key: value\r\n" 形式的最后一帧
+func (rw *responseWriter) finish() {
+	trailer := make(http.Header)
+	for k, vv := range rw.header {
+		if name, ok := strings.CutPrefix(k, http.TrailerPrefix); ok {
+			trailer[http.CanonicalHeaderKey(name)] = vv
+		} else if slices.Contains(statusTrailers, k) {
+			trailer[k] = vv
+		}
+	}
+	if len(trailer) == 0 {
+		// grpc.Server 在进入 gRPC 处理之前就拒绝了请求，它已经写了普通的 HTTP 错误
+		return
+	}
+	var body bytes.Buffer
+	keys := make([]string, 0, len(trailer))
+	for k := range trailer {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		for _, v := range trailer[k] {
+			fmt.Fprintf(&body, "%s: %s\r\n", strings.ToLower(k), v)
+		}
+	}
+	frame := make([]byte, 5, 5+body.Len())
+	frame[0] = trailerFlag
+	binary.BigEndian.PutUint32(frame[1:], uint32(body.Len()))
+	rw.Write(append(frame, body.Bytes()...))
+	rw.Flush()
+}
+
+// textWriter 把输出编码成 base64。凑不满 3 字节的部分留到下次写入，Flush 时才补齐 padding，
+// 所以 padding 只会出现在消息边界上，浏览器按 4 个字符一组解码
+type textWriter struct {
+	w       io.Writer
+	pending []byte
+}
+
+func (t *textWriter) Write(p []byte) (int, error) {
+	t.pending = append(t.pending, p...)
+	n := len(t.pending) / 3 * 3
+	if n == 0 {
+		return len(p), nil
+	}
+	if err := t.encode(t.pending[:n]); err != nil {
+		return 0, err
+	}
+	t.pending = append(t.pending[:0], t.pending[n:]...)
+	return len(p), nil
+}
+
+func (t *textWriter) flush() error {
+	if len(t.pending) == 0 {
+		return nil
+	}
+	err := t.encode(t.pending)
+	t.pending = t.pending[:0]
+	return err
+}
+
+func (t *textWriter) encode(p []byte) error {
+	buf := make([]byte, base64.StdEncoding.EncodedLen(len(p)))
+	base64.StdEncoding.Encode(buf, p)
+	_, err := t.w.Write(buf)
+	return err
+}
+
+// textReader 解码 base64 文本格式的请求体。请求可能由多段分别编码的 base64 拼接而成，
+// 中间会出现 padding，所以按 4 个字符一组解码
+type textReader struct {
+	r       io.Reader
+	in      []byte // 还没凑满 4 个字符的输入
+	out     []byte // 已解码还没被读走的数据
+	buf     [4096]byte
+	readErr error
+}
+
+func newTextReader(r io.ReadCloser) io.ReadCloser {
+	return struct {
+		io.Reader
+		io.Closer
+	}{&textReader{r: r}, r}
+}
+
+func (t *textReader) Read(p []byte) (int, error) {
+	for len(t.out) == 0 {
+		if t.readErr != nil {
+			if t.readErr == io.EOF && len(t.in) > 0 {
+				return 0, fmt.Errorf("grpcweb: truncated base64 body")
+			}
+			return 0, t.readErr
+		}
+		n, err := t.r.Read(t.buf[:])
+		t.readErr = err
+		for _, c := range t.buf[:n] {
+			// 忽略换行等空白字符
+			if c == '\r' || c == '\n' || c == ' ' || c == '\t' {
+				continue
+			}
+			t.in = append(t.in, c)
+			if len(t.in) < 4 {
+				continue
+			}
+			var dst [3]byte
+			m, err := base64.StdEncoding.Decode(dst[:], t.in)
+			if err != nil {
+				return 0, fmt.Errorf("grpcweb: invalid base64 body: %w", err)
+			}
+			t.out = append(t.out, dst[:m]...)
+			t.in = t.in[:0]
+		}
+	}
+	n := copy(p, t.out)
+	t.out = t.out[n:]
+	return n, nil
+}
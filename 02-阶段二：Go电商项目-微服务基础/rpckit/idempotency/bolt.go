@@ -0,0 +1,110 @@
+/**
+ * @File : bolt.go
+ * @Description : 基于 BoltDB 单文件数据库的 Store，服务重启后记录仍然有效
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package idempotency
+
+import (
+	"encoding/json"
+	"go.etcd.io/bbolt"
+	"time"
+)
+
+var bucket = []byte("idempotency")
+
+// Bolt 把记录以 JSON 保存在一个 bucket 里。同一个文件同时只能被一个进程打开
+type Bolt struct {
+	db        *bbolt.DB
+	lastSweep time.Time // 只在 Update 事务中读写，Bolt 的写事务是串行的
+}
+
+// OpenBolt 打开或创建数据库文件；另一个进程占用文件时等待 timeout 后返回错误
+func OpenBolt(path string, timeout time.Duration) (*Bolt, error) {
+	db, err := bbolt.Open(path, 0o600, &bbolt.Options{Timeout: timeout})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Bolt{db: db}, nil
+}
+
+func (b *Bolt) Claim(key string, pending Record, now time.Time) (Record, bool, error) {
+	var (
+		existing Record
+		claimed  bool
+	)
+	err := b.db.Update(func(tx *bbolt.Tx) error {
+		bk := tx.Bucket(bucket)
+		if now.Sub(b.lastSweep) >= sweepInterval {
+			if err := sweep(bk, now); err != nil {
+				return err
+			}
+			b.lastSweep = now
+		}
+		if v := bk.Get([]byte(key)); v != nil {
+			if err := json.Unmarshal(v, &existing); err == nil && now.Before(existing.Expires) {
+				return nil
+			}
+		}
+		claimed, existing = true, pending
+		return put(bk, key, pending)
+	})
+	if err != nil {
+		return Record{}, false, err
+	}
+	return existing, claimed, nil
+}
+
+// sweep 删除过期的记录，删除前先收集 key，避免在遍历时修改 bucket
+func sweep(bk *bbolt.Bucket, now time.Time) error {
+	var expired [][]byte
+	err := bk.ForEach(func(k, v []byte) error {
+		var r Record
+		if json.Unmarshal(v, &r) != nil || !now.Before(r.Expires) {
+			expired = append(expired, append([]byte(nil), k...))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range expired {
+		if err := bk.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func put(bk *bbolt.Bucket, key string, r Record) error {
+	v, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return bk.Put([]byte(key), v)
+}
+
+func (b *Bolt) Complete(key string, done Record) error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		return put(tx.Bucket(bucket), key, done)
+	})
+}
+
+func (b *Bolt) Release(key string) error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(bucket).Delete([]byte(key))
+	})
+}
+
+func (b *Bolt) Close() error {
+	return b.db.Close()
+}
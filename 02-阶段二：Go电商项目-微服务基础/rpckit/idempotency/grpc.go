@@ -0,0 +1,101 @@
+/**
+ * @File : grpc.go
+ * @Description : gRPC 一元调用的幂等拦截器：第一次执行并记录结果，重复的请求直接重放
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"log"
+	"rpckit/tlsutil"
+)
+
+// UnaryServerInterceptor 只处理带 idempotency-key 的请求，没有带键的请求照常执行。
+// 同一个键的请求内容不同时返回 FailedPrecondition，第一次请求还没执行完时返回 Aborted
+func (g *Guard) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		keys := md.Get(Key)
+		msg, ok := req.(proto.Message)
+		if len(keys) == 0 || !ok {
+			return handler(ctx, req)
+		}
+		body, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "idempotency: %v", err)
+		}
+		id, _ := tlsutil.PeerIdentity(ctx)
+		storeKey, rec, claimed, err := g.claim(id.Name, keys[0], fingerprint(info.FullMethod, body))
+		if err != nil {
+			return nil, grpcError(err)
+		}
+		if !claimed {
+			grpc.SetHeader(ctx, metadata.Pairs(ReplayedKey, "true"))
+			return replay(rec)
+		}
+
+		// handler panic 时释放 key，否则重试要等到 pending 记录过期
+		defer func() {
+			if p := recover(); p != nil {
+				g.store.Release(storeKey)
+				panic(p)
+			}
+		}()
+		resp, err := handler(ctx, req)
+		st := status.Convert(err)
+		if transient(st.Code()) {
+			g.store.Release(storeKey)
+			return resp, err
+		}
+		done := Record{Fingerprint: rec.Fingerprint, Code: uint32(st.Code()), Message: st.Message()}
+		if m, ok := resp.(proto.Message); ok && err == nil {
+			if done.Response, err = proto.Marshal(m); err != nil {
+				g.store.Release(storeKey)
+				return nil, status.Errorf(codes.Internal, "idempotency: %v", err)
+			}
+			done.Type = string(m.ProtoReflect().Descriptor().FullName())
+		}
+		if err := g.complete(storeKey, done); err != nil {
+			log.Printf("idempotency: saving the result of %s: %v", info.FullMethod, err)
+		}
+		return resp, st.Err()
+	}
+}
+
+// replay 按保存的类型名重建响应
+func replay(rec Record) (any, error) {
+	if codes.Code(rec.Code) != codes.OK {
+		return nil, status.Error(codes.Code(rec.Code), rec.Message)
+	}
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(rec.Type))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "idempotency: replaying %s: %v", rec.Type, err)
+	}
+	m := mt.New().Interface()
+	if err := proto.Unmarshal(rec.Response, m); err != nil {
+		return nil, status.Errorf(codes.Internal, "idempotency: replaying %s: %v", rec.Type, err)
+	}
+	return m, nil
+}
+
+func grpcError(err error) error {
+	switch {
+	case errors.Is(err, errKeyTooLong):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, errMismatch):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, errInProgress):
+		return status.Error(codes.Aborted, err.Error())
+	}
+	return status.Errorf(codes.Unavailable, "idempotency store: %v", err)
+}
@@ -0,0 +1,141 @@
+/**
+ * @File : idempotency.go
+ * @Description : 幂等键约定：客户端为每个写操作生成一个键，重试时带同一个键，服务端只执行一次并重放第一次的结果
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package idempotency
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"reflect"
+	"rpckit/trace"
+	"sync"
+	"time"
+)
+
+const (
+	// Key 是携带幂等键的 metadata，gRPC 放在请求 metadata 中，net/rpc 放在 envelope 中
+	Key = "idempotency-key"
+	// ReplayedKey 出现在 gRPC 响应 header 中时，表示结果是重放的，handler 没有再次执行
+	ReplayedKey = "idempotency-replayed"
+	// DefaultTTL 是完成的记录默认保留多久，客户端的重试必须在这段时间内完成
+	DefaultTTL = 24 * time.Hour
+	// DefaultLockTimeout 是 pending 记录默认保留多久。进程在 handler 执行中崩溃时，
+	// 这个 key 在超时之前一直返回“正在执行”，之后可以重试
+	DefaultLockTimeout = time.Minute
+	// MaxKeyLength 是幂等键的最大长度
+	MaxKeyLength = 128
+)
+
+var (
+	errKeyTooLong = fmt.Errorf("idempotency key is longer than %d bytes", MaxKeyLength)
+	errInProgress = errors.New("a request with the same idempotency key is still in progress")
+	errMismatch   = errors.New("idempotency key was already used for a different request")
+)
+
+// NewKey 生成一个随机的幂等键
+func NewKey() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// WithKey 把幂等键放进 ctx：gRPC 调用通过请求 metadata 发送，net/rpc 用 trace.Tracer.Call 调用时通过 envelope 发送。
+// 同一个操作的所有重试都要使用同一个 ctx 或同一个键
+func WithKey(ctx context.Context, key string) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	md.Set(Key, key)
+	return trace.WithMetadata(metadata.NewOutgoingContext(ctx, md), Key, key)
+}
+
+// Options 配置 Guard，零值使用默认值
+type Options struct {
+	TTL         time.Duration
+	LockTimeout time.Duration
+}
+
+// Guard 对带幂等键的请求去重，可以同时用于 gRPC 拦截器和 net/rpc codec。
+// 记录按调用方身份（mTLS 时为证书身份）和键保存，不同调用方使用相同的键互不影响
+type Guard struct {
+	store       Store
+	ttl         time.Duration
+	lockTimeout time.Duration
+	now         func() time.Time
+
+	// replies 是 net/rpc 方法的返回值类型，重放时用来解码保存的响应
+	mu      sync.RWMutex
+	replies map[string]reflect.Type
+}
+
+func New(store Store, opts Options) *Guard {
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultTTL
+	}
+	if opts.LockTimeout <= 0 {
+		opts.LockTimeout = DefaultLockTimeout
+	}
+	return &Guard{
+		store:       store,
+		ttl:         opts.TTL,
+		lockTimeout: opts.LockTimeout,
+		now:         time.Now,
+		replies:     make(map[string]reflect.Type),
+	}
+}
+
+func fingerprint(method string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// claim 占用 key。claimed 为 true 时调用方执行请求，之后必须调用 complete 或 release；
+// 为 false 时 rec 是第一次请求的结果，需要重放
+func (g *Guard) claim(caller, key, fp string) (storeKey string, rec Record, claimed bool, err error) {
+	if len(key) > MaxKeyLength {
+		return "", Record{}, false, errKeyTooLong
+	}
+	storeKey = caller + "\x00" + key
+	now := g.now()
+	rec, claimed, err = g.store.Claim(storeKey, Record{Fingerprint: fp, Expires: now.Add(g.lockTimeout)}, now)
+	switch {
+	case err != nil:
+		return "", Record{}, false, err
+	case claimed:
+		return storeKey, rec, true, nil
+	case rec.Fingerprint != fp:
+		return "", Record{}, false, errMismatch
+	case !rec.Done:
+		return "", Record{}, false, errInProgress
+	}
+	return storeKey, rec, false, nil
+}
+
+// complete 保存结果。保存失败时请求已经执行过，只能等 pending 记录过期，期间的重试会收到“正在执行”
+func (g *Guard) complete(storeKey string, rec Record) error {
+	rec.Done = true
+	rec.Expires = g.now().Add(g.ttl)
+	return g.store.Complete(storeKey, rec)
+}
+
+// transient 是重试有可能成功的状态码，出现时不记录结果，让客户端重试时重新执行
+func transient(code codes.Code) bool {
+	switch code {
+	case codes.Canceled, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted, codes.Unavailable:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,341 @@
+/**
+ * @File : idempotency_test.go
+ * @Description : 测试内存和 Bolt 存储、gRPC 拦截器和 net/rpc codec 的去重、重放和指纹校验
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	testpb "google.golang.org/grpc/interop/grpc_testing"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net"
+	"net/rpc"
+	"path/filepath"
+	"rpckit/grpctest"
+	"rpckit/trace"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func openBolt(t *testing.T, path string) *Bolt {
+	t.Helper()
+	b, err := OpenBolt(path, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestStores(t *testing.T) {
+	stores := []struct {
+		name  string
+		store Store
+	}{
+		{"memory", NewMemory()},
+		{"bolt", openBolt(t, filepath.Join(t.TempDir(), "idempotency.db"))},
+	}
+	now := time.Unix(1700000000, 0)
+	pending := Record{Fingerprint: "fp", Expires: now.Add(time.Minute)}
+	done := Record{Fingerprint: "fp", Done: true, Type: "T", Response: []byte("resp"), Expires: now.Add(time.Hour)}
+	for _, s := range stores {
+		steps := []struct {
+			name    string
+			op      func() (Record, bool, error)
+			claimed bool
+			expect  Record
+		}{
+			{"new key is claimed", func() (Record, bool, error) { return s.store.Claim("k", pending, now) }, true, pending},
+			{"pending key is not claimed twice", func() (Record, bool, error) { return s.store.Claim("k", pending, now) }, false, pending},
+			{"completed record is returned", func() (Record, bool, error) {
+				if err := s.store.Complete("k", done); err != nil {
+					return Record{}, false, err
+				}
+				return s.store.Claim("k", pending, now.Add(30*time.Minute))
+			}, false, done},
+			{"expired record is replaced", func() (Record, bool, error) { return s.store.Claim("k", pending, now.Add(time.Hour)) }, true, pending},
+			{"released key can be claimed again", func() (Record, bool, error) {
+				if err := s.store.Release("k"); err != nil {
+					return Record{}, false, err
+				}
+				return s.store.Claim("k", pending, now)
+			}, true, pending},
+			{"keys are independent", func() (Record, bool, error) { return s.store.Claim("other", pending, now) }, true, pending},
+		}
+		for _, st := range steps {
+			rec, claimed, err := st.op()
+			if err != nil {
+				t.Errorf("%s/%s: %v", s.name, st.name, err)
+				continue
+			}
+			if claimed != st.claimed || rec.Fingerprint != st.expect.Fingerprint || rec.Done != st.expect.Done ||
+				string(rec.Response) != string(st.expect.Response) || !rec.Expires.Equal(st.expect.Expires) {
+				t.Errorf("%s/%s: Claim() = %+v, %v, expect %+v, %v", s.name, st.name, rec, claimed, st.expect, st.claimed)
+			}
+		}
+		if err := s.store.Close(); err != nil {
+			t.Errorf("%s: Close() = %v", s.name, err)
+		}
+	}
+}
+
+func TestMemorySweep(t *testing.T) {
+	m := NewMemory()
+	now := time.Unix(1700000000, 0)
+	for i := 0; i < 10; i++ {
+		m.Claim(fmt.Sprint(i), Record{Expires: now.Add(time.Second)}, now)
+	}
+	// 过了清理间隔后，下一次 Claim 顺带删掉过期的记录
+	m.Claim("new", Record{Expires: now.Add(time.Hour)}, now.Add(sweepInterval))
+	if n := m.Len(); n != 1 {
+		t.Errorf("%d records after the sweep, expect 1", n)
+	}
+}
+
+// testServer 的 UnaryCall 按请求决定成功或失败，成功时返回这是第几次执行
+type testServer struct {
+	testpb.UnimplementedTestServiceServer
+	calls atomic.Int64
+	block chan struct{}
+}
+
+func (s *testServer) UnaryCall(ctx context.Context, req *testpb.SimpleRequest) (*testpb.SimpleResponse, error) {
+	n := s.calls.Add(1)
+	if s.block != nil {
+		<-s.block
+	}
+	if req.GetResponseSize() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "negative size")
+	}
+	if code := codes.Code(req.GetResponseStatus().GetCode()); code != codes.OK {
+		return nil, status.Error(code, req.GetResponseStatus().GetMessage())
+	}
+	return &testpb.SimpleResponse{Username: fmt.Sprintf("call %d", n)}, nil
+}
+
+func startGRPC(t *testing.T, srv *testServer, g *Guard) testpb.TestServiceClient {
+	t.Helper()
+	conn := grpctest.Start(t, func(s *grpc.Server) { testpb.RegisterTestServiceServer(s, srv) },
+		grpctest.WithServerOptions(grpc.UnaryInterceptor(g.UnaryServerInterceptor())))
+	return testpb.NewTestServiceClient(conn)
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	srv := &testServer{}
+	client := startGRPC(t, srv, New(NewMemory(), Options{}))
+	unavailable := &testpb.EchoStatus{Code: int32(codes.Unavailable), Message: "try again"}
+	tests := []struct {
+		name     string
+		key      string
+		req      *testpb.SimpleRequest
+		reply    string
+		code     codes.Code
+		replayed bool
+		calls    int64 // 到这一步为止 handler 的执行次数
+	}{
+		{"without a key", "", &testpb.SimpleRequest{}, "call 1", codes.OK, false, 1},
+		{"without a key runs again", "", &testpb.SimpleRequest{}, "call 2", codes.OK, false, 2},
+		{"first request with a key", "k1", &testpb.SimpleRequest{ResponseSize: 1}, "call 3", codes.OK, false, 3},
+		{"duplicate is replayed", "k1", &testpb.SimpleRequest{ResponseSize: 1}, "call 3", codes.OK, true, 3},
+		{"same key with another payload", "k1", &testpb.SimpleRequest{ResponseSize: 2}, "", codes.FailedPrecondition, false, 3},
+		{"another key runs the handler", "k2", &testpb.SimpleRequest{ResponseSize: 1}, "call 4", codes.OK, false, 4},
+		{"error is recorded", "k3", &testpb.SimpleRequest{ResponseSize: -1}, "", codes.InvalidArgument, false, 5},
+		{"recorded error is replayed", "k3", &testpb.SimpleRequest{ResponseSize: -1}, "", codes.InvalidArgument, true, 5},
+		{"transient error is not recorded", "k4", &testpb.SimpleRequest{ResponseStatus: unavailable}, "", codes.Unavailable, false, 6},
+		{"retry after a transient error runs again", "k4", &testpb.SimpleRequest{ResponseStatus: unavailable}, "", codes.Unavailable, false, 7},
+		{"key too long", strings.Repeat("k", MaxKeyLength+1), &testpb.SimpleRequest{}, "", codes.InvalidArgument, false, 7},
+	}
+	for _, tt := range tests {
+		ctx := context.Background()
+		if tt.key != "" {
+			ctx = WithKey(ctx, tt.key)
+		}
+		var header metadata.MD
+		resp, err := client.UnaryCall(ctx, tt.req, grpc.Header(&header))
+		if status.Code(err) != tt.code || resp.GetUsername() != tt.reply {
+			t.Errorf("%s: UnaryCall() = %q, %v, expect %q, %v", tt.name, resp.GetUsername(), err, tt.reply, tt.code)
+		}
+		if replayed := len(header.Get(ReplayedKey)) > 0; replayed != tt.replayed {
+			t.Errorf("%s: replayed = %v, expect %v", tt.name, replayed, tt.replayed)
+		}
+		if n := srv.calls.Load(); n != tt.calls {
+			t.Errorf("%s: handler ran %d times, expect %d", tt.name, n, tt.calls)
+		}
+	}
+}
+
+func TestInProgress(t *testing.T) {
+	srv := &testServer{block: make(chan struct{})}
+	client := startGRPC(t, srv, New(NewMemory(), Options{}))
+	ctx := WithKey(context.Background(), "k")
+	first := make(chan error, 1)
+	go func() {
+		_, err := client.UnaryCall(ctx, &testpb.SimpleRequest{})
+		first <- err
+	}()
+	for deadline := time.Now().Add(5 * time.Second); srv.calls.Load() == 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("first request never reached the handler")
+		}
+	}
+	// 第一次请求还没完成，重试的请求不会再执行一次
+	if _, err := client.UnaryCall(ctx, &testpb.SimpleRequest{}); status.Code(err) != codes.Aborted {
+		t.Errorf("UnaryCall() while the first is running = %v, expect Aborted", err)
+	}
+	close(srv.block)
+	if err := <-first; err != nil {
+		t.Fatal(err)
+	}
+	if resp, err := client.UnaryCall(ctx, &testpb.SimpleRequest{}); err != nil || resp.GetUsername() != "call 1" {
+		t.Errorf("UnaryCall() after the first finished = %q, %v, expect the replayed call 1", resp.GetUsername(), err)
+	}
+	if n := srv.calls.Load(); n != 1 {
+		t.Errorf("handler ran %d times, expect 1", n)
+	}
+}
+
+func TestBoltSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "idempotency.db")
+	ctx := WithKey(context.Background(), "order-42")
+	req := &testpb.SimpleRequest{ResponseSize: 42}
+
+	store := openBolt(t, path)
+	first, err := startGRPC(t, &testServer{}, New(store, Options{})).UnaryCall(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+
+	// 模拟服务重启：新的进程状态，同一个数据库文件
+	store = openBolt(t, path)
+	defer store.Close()
+	srv := &testServer{}
+	var header metadata.MD
+	second, err := startGRPC(t, srv, New(store, Options{})).UnaryCall(ctx, req, grpc.Header(&header))
+	if err != nil || second.GetUsername() != first.GetUsername() || len(header.Get(ReplayedKey)) == 0 {
+		t.Errorf("UnaryCall() after restart = %q, %v, expect the replayed %q", second.GetUsername(), err, first.GetUsername())
+	}
+	if n := srv.calls.Load(); n != 0 {
+		t.Errorf("handler ran %d times after restart, expect 0", n)
+	}
+}
+
+func TestCallerScope(t *testing.T) {
+	g := New(NewMemory(), Options{})
+	if _, _, claimed, err := g.claim("alice", "k", "fp1"); err != nil || !claimed {
+		t.Fatalf("claim(alice) = %v, %v", claimed, err)
+	}
+	// 另一个调用方用同样的键不受影响
+	if _, _, claimed, err := g.claim("bob", "k", "fp2"); err != nil || !claimed {
+		t.Errorf("claim(bob) = %v, %v, expect a separate record", claimed, err)
+	}
+	if _, _, _, err := g.claim("alice", "k", "fp2"); !errors.Is(err, errMismatch) {
+		t.Errorf("claim(alice) with another fingerprint = %v, expect %v", err, errMismatch)
+	}
+}
+
+type Order struct {
+	Item  string
+	Count int
+}
+
+// Orders 是一个非幂等的 net/rpc 服务，每次调用都会生成新的订单号
+type Orders struct {
+	created atomic.Int64
+}
+
+func (o *Orders) Create(args Order, reply *string) error {
+	n := o.created.Add(1)
+	if args.Count <= 0 {
+		return errors.New("count must be positive")
+	}
+	*reply = fmt.Sprintf("order-%d: %d x %s", n, args.Count, args.Item)
+	return nil
+}
+
+// Admin 和 Orders 共用计数，但没有在 Guard 上登记
+type Admin struct {
+	orders *Orders
+}
+
+func (a *Admin) Cancel(id string, reply *bool) error {
+	a.orders.created.Add(1)
+	*reply = true
+	return nil
+}
+
+func TestNetRPC(t *testing.T) {
+	orders := &Orders{}
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("Orders", orders); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.RegisterName("Admin", &Admin{orders}); err != nil {
+		t.Fatal(err)
+	}
+	g := New(NewMemory(), Options{})
+	if err := g.RegisterName("Orders", orders); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.RegisterName("Nothing", struct{}{}); err == nil {
+		t.Errorf("RegisterName() of a type without methods should fail")
+	}
+
+	serverConn, clientConn := net.Pipe()
+	go srv.ServeCodec(g.WrapServerCodec(trace.NewServerCodec(serverConn, trace.NewTracer("server", nil))))
+	client := trace.NewClient(clientConn)
+	defer client.Close()
+	tracer := trace.NewTracer("client", nil)
+
+	var cancelled bool
+	if err := tracer.Call(WithKey(context.Background(), "c1"), client, "Admin.Cancel", "order-1", &cancelled); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		key     string
+		method  string
+		args    any
+		reply   string
+		err     string
+		created int64 // 到这一步为止方法的执行次数
+	}{
+		{"first request", "k1", "Orders.Create", Order{"hat", 2}, "order-2: 2 x hat", "", 2},
+		{"duplicate is replayed", "k1", "Orders.Create", Order{"hat", 2}, "order-2: 2 x hat", "", 2},
+		{"same key with another payload", "k1", "Orders.Create", Order{"hat", 3}, "", "different request", 2},
+		{"without a key", "", "Orders.Create", Order{"hat", 2}, "order-3: 2 x hat", "", 3},
+		{"error is recorded", "k2", "Orders.Create", Order{"hat", 0}, "", "count must be positive", 4},
+		{"recorded error is replayed", "k2", "Orders.Create", Order{"hat", 0}, "", "count must be positive", 4},
+		{"unregistered reply type cannot be replayed", "c1", "Admin.Cancel", "order-1", "", "was not registered", 4},
+	}
+	for _, tt := range tests {
+		ctx := context.Background()
+		if tt.key != "" {
+			ctx = WithKey(ctx, tt.key)
+		}
+		var err error
+		var reply string
+		if tt.method == "Admin.Cancel" {
+			err = tracer.Call(ctx, client, tt.method, tt.args, &cancelled)
+		} else {
+			err = tracer.Call(ctx, client, tt.method, tt.args, &reply)
+		}
+		if (err == nil) != (tt.err == "") || (err != nil && !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%s: %s error = %v, expect %q", tt.name, tt.method, err, tt.err)
+		}
+		if reply != tt.reply {
+			t.Errorf("%s: %s reply = %q, expect %q", tt.name, tt.method, reply, tt.reply)
+		}
+		if n := orders.created.Load(); n != tt.created {
+			t.Errorf("%s: method ran %d times, expect %d", tt.name, n, tt.created)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+/**
+ * @File : netrpc.go
+ * @Description : net/rpc 的幂等 codec：从 envelope 读取幂等键，重复的请求不调用方法，直接写回第一次的结果
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package idempotency
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"google.golang.org/grpc/codes"
+	"log"
+	"net/rpc"
+	"reflect"
+	"rpckit/trace"
+	"sync"
+)
+
+// errReplay 让 net/rpc 跳过方法调用，WriteResponse 再把它换成保存的结果
+var errReplay = errors.New("idempotency: replaying the recorded response")
+
+var typeOfError = reflect.TypeOf((*error)(nil)).Elem()
+
+// RegisterName 记下 rcvr 中 net/rpc 方法的返回值类型，服务本身仍需用 rpc.RegisterName 注册。
+// net/rpc 不公开方法信息，重放时只能靠这里的类型解码保存的响应
+func (g *Guard) RegisterName(name string, rcvr any) error {
+	t := reflect.TypeOf(rcvr)
+	found := false
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for i := 0; i < t.NumMethod(); i++ {
+		m := t.Method(i)
+		mt := m.Type
+		// 和 net/rpc 的规则一样：func (t *T) Method(args T1, reply *T2) error
+		if !m.IsExported() || mt.NumIn() != 3 || mt.NumOut() != 1 || mt.Out(0) != typeOfError || mt.In(2).Kind() != reflect.Pointer {
+			continue
+		}
+		g.replies[name+"."+m.Name] = mt.In(2).Elem()
+		found = true
+	}
+	if !found {
+		return fmt.Errorf("idempotency: %s has no net/rpc methods", name)
+	}
+	return nil
+}
+
+// rpcCall 是一个带幂等键的 net/rpc 请求
+type rpcCall struct {
+	method   string
+	key      string
+	storeKey string // 不为空表示这次请求执行了方法，结果需要保存
+	fp       string
+	replay   *Record // 不为 nil 表示重放
+}
+
+type serverCodec struct {
+	trace.MetadataCodec
+	g *Guard
+
+	// net/rpc 在同一个 goroutine 里依次调用 ReadRequestHeader 和 ReadRequestBody，
+	// current 是刚读到请求头、还没读请求体的请求
+	current *rpcCall
+
+	mu    sync.Mutex
+	calls map[uint64]*rpcCall
+}
+
+// WrapServerCodec 用法：rpc.ServeCodec(guard.WrapServerCodec(trace.NewServerCodec(conn, tracer)))。
+// 客户端用 idempotency.WithKey 和 trace.Tracer.Call 发送幂等键
+func (g *Guard) WrapServerCodec(codec trace.MetadataCodec) rpc.ServerCodec {
+	return &serverCodec{MetadataCodec: codec, g: g, calls: make(map[uint64]*rpcCall)}
+}
+
+func (c *serverCodec) ReadRequestHeader(r *rpc.Request) error {
+	c.current = nil
+	if err := c.MetadataCodec.ReadRequestHeader(r); err != nil {
+		return err
+	}
+	if key := c.RequestMetadata(r.Seq)[Key]; key != "" {
+		c.current = &rpcCall{method: r.ServiceMethod, key: key}
+		c.mu.Lock()
+		c.calls[r.Seq] = c.current
+		c.mu.Unlock()
+	}
+	return nil
+}
+
+// ReadRequestBody 返回错误时 net/rpc 不调用方法，把错误写回给客户端
+func (c *serverCodec) ReadRequestBody(body any) error {
+	call := c.current
+	c.current = nil
+	if err := c.MetadataCodec.ReadRequestBody(body); err != nil || call == nil || body == nil {
+		return err
+	}
+	// JSON 会对 map 的 key 排序，相同的参数总是得到相同的指纹
+	b, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("idempotency: %v", err)
+	}
+	call.fp = fingerprint(call.method, b)
+	storeKey, rec, claimed, err := c.g.claim("", call.key, call.fp)
+	switch {
+	case err != nil:
+		return err
+	case claimed:
+		call.storeKey = storeKey
+		return nil
+	}
+	call.replay = &rec
+	return errReplay
+}
+
+func (c *serverCodec) WriteResponse(r *rpc.Response, body any) error {
+	c.mu.Lock()
+	call := c.calls[r.Seq]
+	delete(c.calls, r.Seq)
+	c.mu.Unlock()
+
+	switch {
+	case call == nil:
+	case call.replay != nil:
+		resp := *r
+		r, body = &resp, c.replayBody(&resp, call.replay)
+	case call.storeKey != "":
+		done := Record{Fingerprint: call.fp, Type: call.method}
+		if r.Error != "" {
+			done.Code, done.Message = uint32(codes.Unknown), r.Error
+		} else if b, err := json.Marshal(body); err == nil {
+			done.Response = b
+		} else {
+			c.g.store.Release(call.storeKey)
+			return c.MetadataCodec.WriteResponse(r, body)
+		}
+		if err := c.g.complete(call.storeKey, done); err != nil {
+			log.Printf("idempotency: saving the result of %s: %v", call.method, err)
+		}
+	}
+	return c.MetadataCodec.WriteResponse(r, body)
+}
+
+// replayBody 把 r 改成第一次请求的结果，返回要写回的响应体
+func (c *serverCodec) replayBody(r *rpc.Response, rec *Record) any {
+	r.Error = ""
+	if codes.Code(rec.Code) != codes.OK {
+		r.Error = rec.Message
+		return struct{}{}
+	}
+	c.g.mu.RLock()
+	t, ok := c.g.replies[r.ServiceMethod]
+	c.g.mu.RUnlock()
+	if !ok {
+		r.Error = fmt.Sprintf("idempotency: cannot replay %s, its reply type was not registered with Guard.RegisterName", r.ServiceMethod)
+		return struct{}{}
+	}
+	reply := reflect.New(t)
+	if err := json.Unmarshal(rec.Response, reply.Interface()); err != nil {
+		r.Error = fmt.Sprintf("idempotency: replaying %s: %v", r.ServiceMethod, err)
+		return struct{}{}
+	}
+	return reply.Interface()
+}
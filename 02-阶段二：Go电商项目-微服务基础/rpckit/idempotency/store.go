@@ -0,0 +1,91 @@
+/**
+ * @File : store.go
+ * @Description : 幂等键的存储接口和内存实现，记录每个键第一次请求的指纹和结果
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package idempotency
+
+import (
+	"sync"
+	"time"
+)
+
+// Record 是一个幂等键对应的记录。Done 为 false 表示第一次请求还在执行
+type Record struct {
+	// Fingerprint 是方法名和请求内容的 SHA-256，同一个键换了请求内容会被拒绝
+	Fingerprint string    `json:"fingerprint"`
+	Done        bool      `json:"done"`
+	Code        uint32    `json:"code"`              // gRPC 状态码，net/rpc 调用失败时为 codes.Unknown
+	Message     string    `json:"message,omitempty"` // 错误信息
+	Type        string    `json:"type,omitempty"`    // 响应的类型名，gRPC 是 proto 全名，net/rpc 是方法名
+	Response    []byte    `json:"response,omitempty"`
+	Expires     time.Time `json:"expires"`
+}
+
+// Store 保存幂等记录，实现必须并发安全。过期的记录视为不存在
+type Store interface {
+	// Claim 在 key 不存在时写入 pending 并返回 (pending, true)，否则返回已有记录和 false。
+	// 检查和写入必须是原子的，同时到达的两个请求只有一个能占到 key
+	Claim(key string, pending Record, now time.Time) (Record, bool, error)
+	// Complete 用最终结果替换 pending 记录
+	Complete(key string, done Record) error
+	// Release 删除 pending 记录，之后用同一个 key 重试会重新执行
+	Release(key string) error
+	Close() error
+}
+
+// sweepInterval 是清理过期记录的最小间隔，清理在 Claim 中顺带进行
+const sweepInterval = time.Minute
+
+// Memory 是进程内的 Store，重启后记录丢失
+type Memory struct {
+	mu        sync.Mutex
+	records   map[string]Record
+	lastSweep time.Time
+}
+
+func NewMemory() *Memory {
+	return &Memory{records: make(map[string]Record)}
+}
+
+func (m *Memory) Claim(key string, pending Record, now time.Time) (Record, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if now.Sub(m.lastSweep) >= sweepInterval {
+		for k, r := range m.records {
+			if !now.Before(r.Expires) {
+				delete(m.records, k)
+			}
+		}
+		m.lastSweep = now
+	}
+	if r, ok := m.records[key]; ok && now.Before(r.Expires) {
+		return r, false, nil
+	}
+	m.records[key] = pending
+	return pending, true, nil
+}
+
+func (m *Memory) Complete(key string, done Record) error {
+	m.mu.Lock()
+	m.records[key] = done
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *Memory) Release(key string) error {
+	m.mu.Lock()
+	delete(m.records, key)
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *Memory) Close() error { return nil }
+
+// Len 返回当前保存的记录数，包括还没有被清理的过期记录
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.records)
+}
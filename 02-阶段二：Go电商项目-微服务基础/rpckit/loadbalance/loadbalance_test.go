@@ -0,0 +1,210 @@
+/**
+ * @File : loadbalance_test.go
+ * @Description : 测试权重属性、picker 按 weight/(1+load) 的选择比例、Done 解析负载 trailer 和服务端上报的负载
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package loadbalance
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	testpb "google.golang.org/grpc/interop/grpc_testing"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/resolver"
+	"math"
+	"rpckit/grpctest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeSubConn 只用来区分不同的实例
+type fakeSubConn struct {
+	balancer.SubConn
+	name string
+}
+
+// build 用带权重的地址建一个 picker，返回的 SubConn 和 weights 一一对应
+func build(b *pickerBuilder, weights ...int) (*picker, []*fakeSubConn) {
+	ready := make(map[balancer.SubConn]base.SubConnInfo)
+	scs := make([]*fakeSubConn, len(weights))
+	for i, w := range weights {
+		scs[i] = &fakeSubConn{name: string(rune('a' + i))}
+		addr := resolver.Address{Addr: scs[i].name}
+		if w != 0 {
+			addr = SetAddrWeight(addr, w)
+		}
+		ready[scs[i]] = base.SubConnInfo{Address: addr}
+	}
+	return b.Build(base.PickerBuildInfo{ReadySCs: ready}).(*picker), scs
+}
+
+func newPickerBuilder() *pickerBuilder {
+	return &pickerBuilder{loads: make(map[balancer.SubConn]*atomic.Int64)}
+}
+
+func TestAddrWeight(t *testing.T) {
+	tests := []struct {
+		addr   resolver.Address
+		expect int
+	}{
+		{resolver.Address{Addr: "a"}, 1},
+		{SetAddrWeight(resolver.Address{Addr: "a"}, 5), 5},
+		{SetAddrWeight(resolver.Address{Addr: "a"}, 0), 1},
+		{SetAddrWeight(resolver.Address{Addr: "a"}, -3), 1},
+	}
+	for _, tt := range tests {
+		if got := AddrWeight(tt.addr); got != tt.expect {
+			t.Errorf("AddrWeight(%v) = %d, expect %d", tt.addr, got, tt.expect)
+		}
+	}
+
+	p, scs := build(newPickerBuilder(), 3, 0)
+	for _, it := range p.items {
+		expect := map[balancer.SubConn]float64{scs[0]: 3, scs[1]: 1}[it.sc]
+		if it.weight != expect {
+			t.Errorf("picker weight of %s = %g, expect %g", it.sc.(*fakeSubConn).name, it.weight, expect)
+		}
+	}
+}
+
+// shares 按 n 次 Pick 统计每个 SubConn 被选中的比例
+func shares(t *testing.T, p *picker, n int) map[balancer.SubConn]float64 {
+	t.Helper()
+	counts := make(map[balancer.SubConn]float64)
+	for i := 0; i < n; i++ {
+		res, err := p.Pick(balancer.PickInfo{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[res.SubConn]++
+	}
+	for sc := range counts {
+		counts[sc] /= float64(n)
+	}
+	return counts
+}
+
+func TestPick(t *testing.T) {
+	b := newPickerBuilder()
+	p, scs := build(b, 3, 1)
+	const n = 20000
+	check := func(name string, expect ...float64) {
+		t.Helper()
+		got := shares(t, p, n)
+		for i, sc := range scs {
+			// n 次选择的标准差不到 0.004，0.03 的误差足够宽
+			if math.Abs(got[sc]-expect[i]) > 0.03 {
+				t.Errorf("%s: share of %s = %.3f, expect %.3f", name, sc.name, got[sc], expect[i])
+			}
+		}
+	}
+	check("no load", 0.75, 0.25)
+
+	// 有效权重 3/(1+5)=0.5 和 1/(1+0)=1
+	done := func(sc *fakeSubConn, load string) {
+		for {
+			res, _ := p.Pick(balancer.PickInfo{})
+			if res.SubConn == sc {
+				res.Done(balancer.DoneInfo{Trailer: metadata.Pairs(LoadKey, load)})
+				return
+			}
+		}
+	}
+	done(scs[0], "5")
+	check("loaded", 1.0/3, 2.0/3)
+
+	// 重建 picker 时沿用已经上报的负载
+	p = b.Build(base.PickerBuildInfo{ReadySCs: map[balancer.SubConn]base.SubConnInfo{
+		scs[0]: {Address: SetAddrWeight(resolver.Address{Addr: "a"}, 3)},
+		scs[1]: {Address: resolver.Address{Addr: "b"}},
+	}}).(*picker)
+	check("rebuilt", 1.0/3, 2.0/3)
+
+	if _, err := b.Build(base.PickerBuildInfo{}).Pick(balancer.PickInfo{}); err != balancer.ErrNoSubConnAvailable {
+		t.Errorf("Pick without ready SubConns error = %v, expect ErrNoSubConnAvailable", err)
+	}
+}
+
+func TestDone(t *testing.T) {
+	p, _ := build(newPickerBuilder(), 1)
+	tests := []struct {
+		name    string
+		trailer metadata.MD
+		expect  int64
+	}{
+		{"valid load", metadata.Pairs(LoadKey, "7"), 7},
+		{"missing trailer keeps the last load", nil, 7},
+		{"invalid number is ignored", metadata.Pairs(LoadKey, "many"), 7},
+		{"negative load is ignored", metadata.Pairs(LoadKey, "-1"), 7},
+		{"idle replica", metadata.Pairs(LoadKey, "0"), 0},
+	}
+	for _, tt := range tests {
+		res, err := p.Pick(balancer.PickInfo{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Done(balancer.DoneInfo{Trailer: tt.trailer})
+		if got := p.items[0].load.Load(); got != tt.expect {
+			t.Errorf("%s: load = %d, expect %d", tt.name, got, tt.expect)
+		}
+	}
+}
+
+// blockingServer 的 UnaryCall 在 ResponseSize 为 1 时阻塞到 release 关闭
+type blockingServer struct {
+	testpb.UnimplementedTestServiceServer
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingServer) UnaryCall(_ context.Context, req *testpb.SimpleRequest) (*testpb.SimpleResponse, error) {
+	if req.ResponseSize == 1 {
+		s.started <- struct{}{}
+		<-s.release
+	}
+	return &testpb.SimpleResponse{}, nil
+}
+
+func TestReporter(t *testing.T) {
+	r := NewReporter()
+	srv := &blockingServer{started: make(chan struct{}), release: make(chan struct{})}
+	conn := grpctest.Start(t, func(s *grpc.Server) { testpb.RegisterTestServiceServer(s, srv) },
+		grpctest.WithServerOptions(grpc.UnaryInterceptor(r.UnaryServerInterceptor())))
+	client := testpb.NewTestServiceClient(conn)
+
+	call := func(size int32) string {
+		var trailer metadata.MD
+		if _, err := client.UnaryCall(context.Background(), &testpb.SimpleRequest{ResponseSize: size}, grpc.Trailer(&trailer)); err != nil {
+			t.Error(err)
+			return ""
+		}
+		return trailer.Get(LoadKey)[0]
+	}
+	// 空闲的副本上报 0，不把刚结束的请求算进去
+	if got := call(0); got != "0" {
+		t.Errorf("load of an idle server = %s, expect 0", got)
+	}
+
+	blocked := make(chan string)
+	go func() { blocked <- call(1) }()
+	<-srv.started
+	if got := call(0); got != "1" {
+		t.Errorf("load with one request in flight = %s, expect 1", got)
+	}
+	close(srv.release)
+	select {
+	case got := <-blocked:
+		if got != "0" {
+			t.Errorf("load reported by the last request = %s, expect 0", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("blocked call did not finish")
+	}
+	if r.Load() != 0 {
+		t.Errorf("Load() after all calls = %d, expect 0", r.Load())
+	}
+}
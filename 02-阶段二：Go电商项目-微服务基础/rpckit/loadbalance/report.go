@@ -0,0 +1,63 @@
+/**
+ * @File : report.go
+ * @Description : 服务端拦截器，在每次调用结束时通过 trailer 上报当前负载
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package loadbalance
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"strconv"
+	"sync/atomic"
+)
+
+// Reporter 统计正在处理的请求数，一元调用和流式调用共用同一个计数
+type Reporter struct {
+	inflight atomic.Int64
+}
+
+func NewReporter() *Reporter {
+	return &Reporter{}
+}
+
+// Load 返回当前正在处理的请求数
+func (r *Reporter) Load() int64 {
+	return r.inflight.Load()
+}
+
+func (r *Reporter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		r.inflight.Add(1)
+		defer r.recoverDone()
+		resp, err := handler(ctx, req)
+		// 先把本次请求减掉再上报，空闲的副本上报 0，而不是至少为 1
+		grpc.SetTrailer(ctx, r.done())
+		return resp, err
+	}
+}
+
+func (r *Reporter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		r.inflight.Add(1)
+		defer r.recoverDone()
+		err := handler(srv, ss)
+		ss.SetTrailer(r.done())
+		return err
+	}
+}
+
+// done 结束一个请求，返回上报剩余负载的 trailer
+func (r *Reporter) done() metadata.MD {
+	return metadata.Pairs(LoadKey, strconv.FormatInt(r.inflight.Add(-1), 10))
+}
+
+// recoverDone 在 handler panic、没有走到 done 时结束请求，然后继续 panic
+func (r *Reporter) recoverDone() {
+	if p := recover(); p != nil {
+		r.inflight.Add(-1)
+		panic(p)
+	}
+}
@@ -0,0 +1,123 @@
+/**
+ * @File : weighted.go
+ * @Description : 按静态权重和服务端在 trailer 中上报的负载做加权随机选择的负载均衡器
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package loadbalance
+
+import (
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/resolver"
+	"math/rand/v2"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// Name 是负载均衡策略名，在 service config 中使用：
+//
+//	{"loadBalancingConfig": [{"weighted_load": {}}]}
+const Name = "weighted_load"
+
+// LoadKey 是服务端在 trailer 中上报当前负载（正在处理的请求数）的 key
+const LoadKey = "x-server-load"
+
+type weightKey struct{}
+
+// SetAddrWeight 给地址附加静态权重，小于 1 的值按 1 处理
+func SetAddrWeight(addr resolver.Address, weight int) resolver.Address {
+	addr.BalancerAttributes = addr.BalancerAttributes.WithValue(weightKey{}, weight)
+	return addr
+}
+
+// AddrWeight 读取地址上的静态权重，没有设置时为 1
+func AddrWeight(addr resolver.Address) int {
+	w, _ := addr.BalancerAttributes.Value(weightKey{}).(int)
+	if w < 1 {
+		return 1
+	}
+	return w
+}
+
+func init() {
+	balancer.Register(builder{})
+}
+
+type builder struct{}
+
+func (builder) Name() string { return Name }
+
+// Build 为每个 ClientConn 创建独立的 pickerBuilder，这样负载数据不会在不同连接之间串用
+func (builder) Build(cc balancer.ClientConn, opts balancer.BuildOptions) balancer.Balancer {
+	pb := &pickerBuilder{loads: make(map[balancer.SubConn]*atomic.Int64)}
+	return base.NewBalancerBuilder(Name, pb, base.Config{HealthCheck: true}).Build(cc, opts)
+}
+
+type pickerBuilder struct {
+	mu    sync.Mutex
+	loads map[balancer.SubConn]*atomic.Int64
+}
+
+func (b *pickerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// 只保留仍然可用的 SubConn 的负载，重建 picker 时沿用之前上报的值
+	loads := make(map[balancer.SubConn]*atomic.Int64, len(info.ReadySCs))
+	items := make([]item, 0, len(info.ReadySCs))
+	for sc, sci := range info.ReadySCs {
+		load, ok := b.loads[sc]
+		if !ok {
+			load = new(atomic.Int64)
+		}
+		loads[sc] = load
+		items = append(items, item{sc: sc, weight: float64(AddrWeight(sci.Address)), load: load})
+	}
+	b.loads = loads
+	return &picker{items: items}
+}
+
+type item struct {
+	sc     balancer.SubConn
+	weight float64
+	load   *atomic.Int64
+}
+
+type picker struct {
+	items []item
+}
+
+// Pick 的有效权重为 weight / (1 + load)，负载越高被选中的概率越低
+func (p *picker) Pick(balancer.PickInfo) (balancer.PickResult, error) {
+	weights := make([]float64, len(p.items))
+	var total float64
+	for i, it := range p.items {
+		weights[i] = it.weight / float64(1+it.load.Load())
+		total += weights[i]
+	}
+	chosen := p.items[len(p.items)-1]
+	r := rand.Float64() * total
+	for i, w := range weights {
+		if r < w {
+			chosen = p.items[i]
+			break
+		}
+		r -= w
+	}
+	return balancer.PickResult{
+		SubConn: chosen.sc,
+		Done: func(di balancer.DoneInfo) {
+			v := di.Trailer.Get(LoadKey)
+			if len(v) == 0 {
+				return
+			}
+			if n, err := strconv.ParseInt(v[0], 10, 64); err == nil && n >= 0 {
+				chosen.load.Store(n)
+			}
+		},
+	}, nil
+}
@@ -0,0 +1,187 @@
+/**
+ * @File : loadgen_test.go
+ * @Description : 测试报告中的分位数和直方图计算、参数检查，以及超高 QPS 不会让限速器崩溃
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package loadgen
+
+import (
+	"context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// results 把毫秒数转换成成功的调用结果
+func results(latencies ...float64) []result {
+	out := make([]result, len(latencies))
+	for i, l := range latencies {
+		out[i] = result{latency: time.Duration(l * float64(time.Millisecond)), code: codes.OK.String()}
+	}
+	return out
+}
+
+func TestPercentiles(t *testing.T) {
+	oneToHundred := make([]float64, 100)
+	for i := range oneToHundred {
+		// 倒序传入，确认计算前会排序
+		oneToHundred[i] = float64(100 - i)
+	}
+	tests := []struct {
+		name      string
+		latencies []float64
+		// 依次对应 50, 75, 90, 95, 99, 99.9
+		expect []float64
+	}{
+		{"single sample", []float64{7}, []float64{7, 7, 7, 7, 7, 7}},
+		{"1 to 100 ms", oneToHundred, []float64{50, 75, 90, 95, 99, 100}},
+		// 最近秩法：p50 取第 ceil(0.5*4)=2 个，p75 取第 3 个，其余都是第 4 个
+		{"four samples", []float64{4, 1, 3, 2}, []float64{2, 3, 4, 4, 4, 4}},
+	}
+	for _, tt := range tests {
+		r := newReport(tt.name, Options{}, results(tt.latencies...), time.Second)
+		var got []float64
+		for _, p := range r.Latencies {
+			got = append(got, p.LatencyMs)
+		}
+		if !reflect.DeepEqual(got, tt.expect) {
+			t.Errorf("%s: percentiles = %v, expect %v", tt.name, got, tt.expect)
+		}
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	tests := []struct {
+		name      string
+		latencies []float64
+		uppers    []float64
+		counts    []int
+	}{
+		// 10ms 到 110ms 等分成 10 个 10ms 宽的桶，最慢的样本落在最后一个桶
+		{"evenly spread", []float64{10, 20, 30, 40, 50, 60, 70, 80, 90, 100, 110},
+			[]float64{20, 30, 40, 50, 60, 70, 80, 90, 100, 110},
+			[]int{1, 1, 1, 1, 1, 1, 1, 1, 1, 2}},
+		{"skewed", []float64{10, 10, 10, 11, 110},
+			[]float64{20, 30, 40, 50, 60, 70, 80, 90, 100, 110},
+			[]int{4, 0, 0, 0, 0, 0, 0, 0, 0, 1}},
+		// 所有样本相同时桶宽为 0，全部计入最后一个桶
+		{"identical samples", []float64{5, 5, 5},
+			[]float64{5, 5, 5, 5, 5, 5, 5, 5, 5, 5},
+			[]int{0, 0, 0, 0, 0, 0, 0, 0, 0, 3}},
+	}
+	for _, tt := range tests {
+		r := newReport(tt.name, Options{}, results(tt.latencies...), time.Second)
+		var uppers []float64
+		var counts []int
+		total := 0
+		for _, b := range r.Histogram {
+			uppers = append(uppers, b.UpperMs)
+			counts = append(counts, b.Count)
+			total += b.Count
+		}
+		if !reflect.DeepEqual(uppers, tt.uppers) {
+			t.Errorf("%s: bucket upper bounds = %v, expect %v", tt.name, uppers, tt.uppers)
+		}
+		if !reflect.DeepEqual(counts, tt.counts) {
+			t.Errorf("%s: bucket counts = %v, expect %v", tt.name, counts, tt.counts)
+		}
+		if total != len(tt.latencies) {
+			t.Errorf("%s: histogram holds %d samples, expect %d", tt.name, total, len(tt.latencies))
+		}
+	}
+}
+
+func TestReportSummary(t *testing.T) {
+	rs := results(10, 20, 30, 40)
+	rs[1] = result{latency: 20 * time.Millisecond, code: codes.Unavailable.String(), err: "connection refused"}
+	rs[2] = result{latency: 30 * time.Millisecond, code: codes.Unavailable.String(), err: "connection refused"}
+	r := newReport("summary", Options{Concurrency: 2, QPS: 5}, rs, 2*time.Second)
+
+	if r.Count != 4 || r.RPS != 2 || r.AverageMs != 25 || r.FastestMs != 10 || r.SlowestMs != 40 || r.TotalMs != 2000 {
+		t.Errorf("summary = count %d, rps %v, avg %v, fastest %v, slowest %v, total %v", r.Count, r.RPS, r.AverageMs, r.FastestMs, r.SlowestMs, r.TotalMs)
+	}
+	if expect := map[string]int{"OK": 2, "Unavailable": 2}; !reflect.DeepEqual(r.StatusCodes, expect) {
+		t.Errorf("status codes = %v, expect %v", r.StatusCodes, expect)
+	}
+	if expect := map[string]int{"connection refused": 2}; !reflect.DeepEqual(r.Errors, expect) {
+		t.Errorf("errors = %v, expect %v", r.Errors, expect)
+	}
+
+	empty := newReport("empty", Options{}, nil, 0)
+	if empty.Count != 0 || empty.Latencies != nil || empty.Histogram != nil || empty.RPS != 0 {
+		t.Errorf("empty report = %+v, expect no statistics", empty)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{"defaults", Options{}, false},
+		{"highest QPS", Options{QPS: MaxQPS}, false},
+		{"QPS over the limit", Options{QPS: 2e9}, true},
+		{"infinite QPS", Options{QPS: math.Inf(1)}, true},
+		{"NaN QPS", Options{QPS: math.NaN()}, true},
+		{"negative QPS", Options{QPS: -1}, true},
+		{"negative concurrency", Options{Concurrency: -1}, true},
+		{"negative total", Options{Total: -1}, true},
+		{"negative timeout", Options{Timeout: -time.Second}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.opts.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestInterval(t *testing.T) {
+	tests := []struct {
+		qps    float64
+		expect time.Duration
+	}{
+		{0, 0},
+		{-5, 0},
+		{math.NaN(), 0},
+		{math.Inf(1), 0},
+		{1e12, 0},
+		{4, 250 * time.Millisecond},
+		{MaxQPS, time.Nanosecond},
+	}
+	for _, tt := range tests {
+		if got := interval(tt.qps); got != tt.expect {
+			t.Errorf("interval(%v) = %v, expect %v", tt.qps, got, tt.expect)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"unlimited", Options{Concurrency: 4, Total: 50}},
+		{"paced", Options{Concurrency: 4, Total: 10, QPS: 1000}},
+		// 以前这里会把间隔截断成 0，NewTicker 直接 panic
+		{"QPS beyond what a ticker can pace", Options{Concurrency: 4, Total: 50, QPS: 1e12}},
+	}
+	for _, tt := range tests {
+		r := Run(context.Background(), tt.name, tt.opts, func(ctx context.Context, n int) error {
+			if n%5 == 0 {
+				return status.Error(codes.Unavailable, "down")
+			}
+			return nil
+		})
+		if r.Count != tt.opts.Total {
+			t.Errorf("%s: %d calls, expect %d", tt.name, r.Count, tt.opts.Total)
+		}
+		if n := r.StatusCodes["Unavailable"]; n != tt.opts.Total/5 {
+			t.Errorf("%s: %d Unavailable, expect %d", tt.name, n, tt.opts.Total/5)
+		}
+	}
+}
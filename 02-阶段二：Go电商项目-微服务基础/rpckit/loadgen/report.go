@@ -0,0 +1,270 @@
+/**
+ * @File : report.go
+ * @Description : 压测报告：延迟分位数、直方图、按状态码和错误信息分类的计数，可输出为文本、JSON、CSV、HTML
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package loadgen
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// percentiles 是报告中列出的延迟分位
+var percentiles = []float64{50, 75, 90, 95, 99, 99.9}
+
+// histogramBuckets 是直方图的桶数，在最快和最慢之间等分
+const histogramBuckets = 10
+
+// 报告中的时间统一以毫秒表示，方便不同次压测之间直接比较
+type Percentile struct {
+	Percentile float64 `json:"percentile"`
+	LatencyMs  float64 `json:"latency_ms"`
+}
+
+type Bucket struct {
+	UpperMs float64 `json:"upper_ms"` // 桶的上界
+	Count   int     `json:"count"`
+}
+
+type Report struct {
+	Name        string         `json:"name"`
+	Date        time.Time      `json:"date"`
+	Concurrency int            `json:"concurrency"`
+	TargetQPS   float64        `json:"target_qps"`
+	Count       int            `json:"count"`
+	TotalMs     float64        `json:"total_ms"`
+	RPS         float64        `json:"rps"`
+	AverageMs   float64        `json:"average_ms"`
+	FastestMs   float64        `json:"fastest_ms"`
+	SlowestMs   float64        `json:"slowest_ms"`
+	Latencies   []Percentile   `json:"latencies"`
+	Histogram   []Bucket       `json:"histogram"`
+	StatusCodes map[string]int `json:"status_codes"`
+	Errors      map[string]int `json:"errors,omitempty"` // 错误信息 -> 次数
+}
+
+func ms(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func newReport(name string, opts Options, results []result, total time.Duration) *Report {
+	r := &Report{
+		Name:        name,
+		Date:        time.Now(),
+		Concurrency: opts.Concurrency,
+		TargetQPS:   opts.QPS,
+		Count:       len(results),
+		TotalMs:     ms(total),
+		StatusCodes: make(map[string]int),
+		Errors:      make(map[string]int),
+	}
+	if len(results) == 0 {
+		return r
+	}
+	if total > 0 {
+		r.RPS = float64(len(results)) / total.Seconds()
+	}
+
+	latencies := make([]time.Duration, len(results))
+	var sum time.Duration
+	for i, res := range results {
+		latencies[i] = res.latency
+		sum += res.latency
+		r.StatusCodes[res.code]++
+		if res.err != "" {
+			r.Errors[res.err]++
+		}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	fastest, slowest := latencies[0], latencies[len(latencies)-1]
+	r.AverageMs = ms(sum / time.Duration(len(latencies)))
+	r.FastestMs = ms(fastest)
+	r.SlowestMs = ms(slowest)
+
+	for _, p := range percentiles {
+		// 最近秩法：取第 ceil(p% * n) 个样本
+		i := int(math.Ceil(p/100*float64(len(latencies)))) - 1
+		if i < 0 {
+			i = 0
+		}
+		r.Latencies = append(r.Latencies, Percentile{Percentile: p, LatencyMs: ms(latencies[i])})
+	}
+
+	step := (slowest - fastest) / histogramBuckets
+	for b := 1; b <= histogramBuckets; b++ {
+		r.Histogram = append(r.Histogram, Bucket{UpperMs: ms(fastest + step*time.Duration(b))})
+	}
+	r.Histogram[histogramBuckets-1].UpperMs = ms(slowest)
+	for _, l := range latencies {
+		b := histogramBuckets - 1
+		if step > 0 {
+			b = min(int((l-fastest)/step), histogramBuckets-1)
+		}
+		r.Histogram[b].Count++
+	}
+	return r
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// WriteText 输出给终端看的摘要
+func (r *Report) WriteText(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Summary: %s\n", r.Name)
+	fmt.Fprintf(&b, "  Count:\t%d\n  Total:\t%.2f ms\n  Slowest:\t%.2f ms\n  Fastest:\t%.2f ms\n  Average:\t%.2f ms\n  Requests/sec:\t%.2f\n",
+		r.Count, r.TotalMs, r.SlowestMs, r.FastestMs, r.AverageMs, r.RPS)
+	b.WriteString("\nLatency distribution:\n")
+	for _, p := range r.Latencies {
+		fmt.Fprintf(&b, "  %v %% in %.2f ms\n", p.Percentile, p.LatencyMs)
+	}
+	b.WriteString("\nResponse time histogram:\n")
+	maxCount := 0
+	for _, h := range r.Histogram {
+		maxCount = max(maxCount, h.Count)
+	}
+	for _, h := range r.Histogram {
+		bar := 0
+		if maxCount > 0 {
+			bar = h.Count * 40 / maxCount
+		}
+		fmt.Fprintf(&b, "  %8.2f [%d]\t|%s\n", h.UpperMs, h.Count, strings.Repeat("∎", bar))
+	}
+	b.WriteString("\nStatus code distribution:\n")
+	for _, code := range sortedKeys(r.StatusCodes) {
+		fmt.Fprintf(&b, "  [%s]\t%d responses\n", code, r.StatusCodes[code])
+	}
+	if len(r.Errors) > 0 {
+		b.WriteString("\nError distribution:\n")
+		for _, msg := range sortedKeys(r.Errors) {
+			fmt.Fprintf(&b, "  [%d]\t%s\n", r.Errors[msg], msg)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteCSV 按 metric,value 两列输出，多次压测的文件可以直接 join 或 diff
+func (r *Report) WriteCSV(w io.Writer) error {
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', 3, 64) }
+	rows := [][]string{
+		{"metric", "value"},
+		{"name", r.Name},
+		{"date", r.Date.Format(time.RFC3339)},
+		{"concurrency", strconv.Itoa(r.Concurrency)},
+		{"target_qps", f(r.TargetQPS)},
+		{"count", strconv.Itoa(r.Count)},
+		{"total_ms", f(r.TotalMs)},
+		{"rps", f(r.RPS)},
+		{"average_ms", f(r.AverageMs)},
+		{"fastest_ms", f(r.FastestMs)},
+		{"slowest_ms", f(r.SlowestMs)},
+	}
+	for _, p := range r.Latencies {
+		rows = append(rows, []string{"p" + strconv.FormatFloat(p.Percentile, 'f', -1, 64) + "_ms", f(p.LatencyMs)})
+	}
+	for _, code := range sortedKeys(r.StatusCodes) {
+		rows = append(rows, []string{"status_" + code, strconv.Itoa(r.StatusCodes[code])})
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+var htmlReport = template.Must(template.New("report").Funcs(template.FuncMap{
+	"pct": func(count, total int) float64 {
+		if total == 0 {
+			return 0
+		}
+		return float64(count) * 100 / float64(total)
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Name}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+td, th { border: 1px solid #ccc; padding: 4px 10px; text-align: right; }
+.bar { background: #4a90d9; height: 14px; }
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+<p>{{.Date.Format "2006-01-02 15:04:05"}} · concurrency {{.Concurrency}} · target QPS {{.TargetQPS}}</p>
+<h2>Summary</h2>
+<table>
+<tr><th>Count</th><td>{{.Count}}</td></tr>
+<tr><th>Total</th><td>{{printf "%.2f" .TotalMs}} ms</td></tr>
+<tr><th>Requests/sec</th><td>{{printf "%.2f" .RPS}}</td></tr>
+<tr><th>Average</th><td>{{printf "%.2f" .AverageMs}} ms</td></tr>
+<tr><th>Fastest</th><td>{{printf "%.2f" .FastestMs}} ms</td></tr>
+<tr><th>Slowest</th><td>{{printf "%.2f" .SlowestMs}} ms</td></tr>
+</table>
+<h2>Latency distribution</h2>
+<table>
+<tr><th>Percentile</th><th>Latency (ms)</th></tr>
+{{range .Latencies}}<tr><td>{{.Percentile}}</td><td>{{printf "%.2f" .LatencyMs}}</td></tr>
+{{end}}</table>
+<h2>Histogram</h2>
+<table>
+<tr><th>&le; ms</th><th>Count</th><th style="width:400px"></th></tr>
+{{range .Histogram}}<tr><td>{{printf "%.2f" .UpperMs}}</td><td>{{.Count}}</td><td style="text-align:left"><div class="bar" style="width:{{pct .Count $.Count}}%"></div></td></tr>
+{{end}}</table>
+<h2>Status codes</h2>
+<table>
+<tr><th>Code</th><th>Count</th></tr>
+{{range $code, $n := .StatusCodes}}<tr><td>{{$code}}</td><td>{{$n}}</td></tr>
+{{end}}</table>
+{{if .Errors}}<h2>Errors</h2>
+<table>
+<tr><th>Count</th><th>Message</th></tr>
+{{range $msg, $n := .Errors}}<tr><td>{{$n}}</td><td style="text-align:left">{{$msg}}</td></tr>
+{{end}}</table>{{end}}
+</body>
+</html>
+`))
+
+func (r *Report) WriteHTML(w io.Writer) error {
+	return htmlReport.Execute(w, r)
+}
+
+// Write 按格式名输出：text、json、csv、html
+func (r *Report) Write(w io.Writer, format string) error {
+	switch format {
+	case "", "text":
+		return r.WriteText(w)
+	case "json":
+		return r.WriteJSON(w)
+	case "csv":
+		return r.WriteCSV(w)
+	case "html":
+		return r.WriteHTML(w)
+	}
+	return fmt.Errorf("loadgen: unknown report format %q", format)
+}
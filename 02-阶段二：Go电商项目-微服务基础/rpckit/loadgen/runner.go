@@ -0,0 +1,140 @@
+/**
+ * @File : runner.go
+ * @Description : 压测引擎：按固定并发或目标 QPS 反复执行一次调用，并记录每次调用的耗时和状态码
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package loadgen
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/grpc/status"
+	"math"
+	"sync"
+	"time"
+)
+
+// Options 描述一次压测。Total 和 Duration 至少设置一个，两者都设置时先到者结束
+type Options struct {
+	Concurrency int           // 并发的 worker 数
+	QPS         float64       // 目标总 QPS，0 表示不限速，由并发数决定压力
+	Total       int           // 总请求数
+	Duration    time.Duration // 压测持续时间
+	Timeout     time.Duration // 单次调用超时，0 表示不限
+}
+
+// MaxQPS 是能够限速的最高 QPS：请求间隔最小为 1ns，再高就没有意义了
+const MaxQPS = float64(time.Second)
+
+// Validate 检查参数的取值范围，命令行工具应该在 Run 之前调用它，把错误报告给用户
+func (o Options) Validate() error {
+	switch {
+	case o.Concurrency < 0:
+		return fmt.Errorf("loadgen: concurrency %d is negative", o.Concurrency)
+	case math.IsNaN(o.QPS) || o.QPS < 0 || o.QPS > MaxQPS:
+		return fmt.Errorf("loadgen: QPS %v is out of range [0, %v]", o.QPS, MaxQPS)
+	case o.Total < 0:
+		return fmt.Errorf("loadgen: total %d is negative", o.Total)
+	case o.Duration < 0 || o.Timeout < 0:
+		return fmt.Errorf("loadgen: duration and timeout cannot be negative")
+	}
+	return nil
+}
+
+// interval 返回 QPS 对应的请求间隔，0 表示不限速；超出范围的 QPS 按不限速处理，NewTicker 不接受 0 间隔
+func interval(qps float64) time.Duration {
+	if !(qps > 0 && qps <= MaxQPS) {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / qps)
+}
+
+// CallFunc 执行一次调用，n 是从 0 开始的请求序号，返回的错误按 gRPC 状态码归类
+type CallFunc func(ctx context.Context, n int) error
+
+type result struct {
+	latency time.Duration
+	code    string
+	err     string
+}
+
+// Run 执行压测直到完成 Total 个请求或超过 Duration，然后汇总成报告。
+// ctx 被取消时不再发起新请求，已经发出的请求会带着 ctx 一起结束
+func Run(ctx context.Context, name string, opts Options, call CallFunc) *Report {
+	if opts.Concurrency < 1 {
+		opts.Concurrency = 1
+	}
+	if opts.Total <= 0 && opts.Duration <= 0 {
+		opts.Total = 200
+	}
+
+	// 持续时间只约束派发新请求，不能取消在途请求，否则结尾会多出一批 Canceled
+	dispatch := ctx
+	if opts.Duration > 0 {
+		var cancel context.CancelFunc
+		dispatch, cancel = context.WithTimeout(ctx, opts.Duration)
+		defer cancel()
+	}
+
+	jobs := make(chan int)
+	go func() {
+		defer close(jobs)
+		var tick <-chan time.Time
+		if d := interval(opts.QPS); d > 0 {
+			ticker := time.NewTicker(d)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
+		for n := 0; opts.Total <= 0 || n < opts.Total; n++ {
+			if tick != nil {
+				select {
+				case <-tick:
+				case <-dispatch.Done():
+					return
+				}
+			}
+			select {
+			case jobs <- n:
+			case <-dispatch.Done():
+				return
+			}
+		}
+	}()
+
+	// 每个 worker 写自己的切片，结束后再合并，避免热路径上加锁
+	results := make([][]result, opts.Concurrency)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < opts.Concurrency; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for n := range jobs {
+				results[w] = append(results[w], do(ctx, opts.Timeout, n, call))
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	var all []result
+	for _, rs := range results {
+		all = append(all, rs...)
+	}
+	return newReport(name, opts, all, time.Since(start))
+}
+
+func do(ctx context.Context, timeout time.Duration, n int, call CallFunc) result {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	err := call(ctx, n)
+	r := result{latency: time.Since(start), code: status.Code(err).String()}
+	if err != nil {
+		r.err = status.Convert(err).Message()
+	}
+	return r
+}
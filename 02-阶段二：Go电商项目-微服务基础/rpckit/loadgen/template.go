@@ -0,0 +1,59 @@
+/**
+ * @File : template.go
+ * @Description : 请求模板：用 text/template 渲染出 JSON，再按 protojson 解析成请求消息
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package loadgen
+
+import (
+	"bytes"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"text/template"
+	"time"
+)
+
+// TemplateData 是模板中可以引用的变量，例如 {"name": "user-{{.RequestNumber}}"}
+type TemplateData struct {
+	RequestNumber int    // 请求序号，从 0 开始
+	MessageNumber int    // 流中的消息序号，从 0 开始，一元调用恒为 0
+	Timestamp     string // RFC3339 格式的当前时间
+	TimestampUnix int64  // 当前 Unix 秒
+}
+
+// NewTemplateData 以当前时间填充时间戳字段
+func NewTemplateData(request, message int) TemplateData {
+	now := time.Now()
+	return TemplateData{
+		RequestNumber: request,
+		MessageNumber: message,
+		Timestamp:     now.Format(time.RFC3339),
+		TimestampUnix: now.Unix(),
+	}
+}
+
+type Template struct {
+	t *template.Template
+}
+
+// ParseTemplate 解析请求模板，空字符串等价于 "{}"
+func ParseTemplate(text string) (*Template, error) {
+	if text == "" {
+		text = "{}"
+	}
+	t, err := template.New("request").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return &Template{t: t}, nil
+}
+
+// Render 渲染模板并把结果解析到 msg 中
+func (t *Template) Render(data TemplateData, msg proto.Message) error {
+	var b bytes.Buffer
+	if err := t.t.Execute(&b, data); err != nil {
+		return err
+	}
+	return protojson.Unmarshal(b.Bytes(), msg)
+}
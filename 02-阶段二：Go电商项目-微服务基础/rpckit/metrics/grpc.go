@@ -0,0 +1,107 @@
+/**
+ * @File : grpc.go
+ * @Description : gRPC 服务端的 stats.Handler，记录请求数、耗时、正在处理的请求、流消息数和消息大小
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package metrics
+
+import (
+	"context"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+)
+
+// ServerHandler 通过 grpc.StatsHandler 安装。
+// 相比拦截器，stats.Handler 能看到每一条流消息和它在线路上的大小
+type ServerHandler struct {
+	started       *Counter
+	handled       *Counter
+	handling      *Histogram
+	inFlight      *Gauge
+	msgReceived   *Counter
+	msgSent       *Counter
+	receivedBytes *Histogram
+	sentBytes     *Histogram
+
+	methods *methodSet
+}
+
+// NewServerHandler 在 r 上注册 grpc_server_* 系列指标
+func NewServerHandler(r *Registry) *ServerHandler {
+	return &ServerHandler{
+		started:       r.NewCounter("grpc_server_started_total", "RPCs started on the server.", "grpc_method", "grpc_type"),
+		handled:       r.NewCounter("grpc_server_handled_total", "RPCs completed on the server, by status code.", "grpc_method", "grpc_type", "grpc_code"),
+		handling:      r.NewHistogram("grpc_server_handling_seconds", "Time from receiving the request headers to sending the status.", LatencyBuckets, "grpc_method"),
+		inFlight:      r.NewGauge("grpc_server_in_flight", "RPCs currently being handled.", "grpc_method"),
+		msgReceived:   r.NewCounter("grpc_server_msg_received_total", "Messages received from clients.", "grpc_method"),
+		msgSent:       r.NewCounter("grpc_server_msg_sent_total", "Messages sent to clients.", "grpc_method"),
+		receivedBytes: r.NewHistogram("grpc_server_msg_received_bytes", "Wire size of received messages.", SizeBuckets, "grpc_method"),
+		sentBytes:     r.NewHistogram("grpc_server_msg_sent_bytes", "Wire size of sent messages.", SizeBuckets, "grpc_method"),
+		methods:       newMethodSet(),
+	}
+}
+
+// WithMethodFilter 设置哪些方法单独记录，其余的记为 UnknownMethod，需要在服务启动前调用。
+// 普通服务不需要设置：调用未注册的方法时 gRPC 不会产生 Begin 事件。
+// 用 UnknownServiceHandler 接收任意方法的服务（比如代理）应该只放行自己认识的方法
+func (h *ServerHandler) WithMethodFilter(known func(fullMethod string) bool) *ServerHandler {
+	h.methods.known = known
+	return h
+}
+
+type rpcInfoKey struct{}
+
+// rpcInfo 在 TagRPC 中创建，同一次调用的后续事件都能拿到
+type rpcInfo struct {
+	method string
+	kind   string
+}
+
+func (h *ServerHandler) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	return context.WithValue(ctx, rpcInfoKey{}, &rpcInfo{method: info.FullMethodName})
+}
+
+func (h *ServerHandler) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	info, ok := ctx.Value(rpcInfoKey{}).(*rpcInfo)
+	if !ok {
+		return
+	}
+	switch s := s.(type) {
+	case *stats.Begin:
+		// TagRPC 对未注册的方法也会调用，到 Begin 才确定标签，避免把它们计入 maxMethods
+		info.method = h.methods.label(info.method)
+		info.kind = rpcType(s.IsClientStream, s.IsServerStream)
+		h.started.Inc(info.method, info.kind)
+		h.inFlight.Add(1, info.method)
+	case *stats.InPayload:
+		h.msgReceived.Inc(info.method)
+		h.receivedBytes.Observe(float64(s.WireLength), info.method)
+	case *stats.OutPayload:
+		h.msgSent.Inc(info.method)
+		h.sentBytes.Observe(float64(s.WireLength), info.method)
+	case *stats.End:
+		h.inFlight.Add(-1, info.method)
+		h.handled.Inc(info.method, info.kind, status.Code(s.Error).String())
+		h.handling.Observe(s.EndTime.Sub(s.BeginTime).Seconds(), info.method)
+	}
+}
+
+func (h *ServerHandler) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (h *ServerHandler) HandleConn(context.Context, stats.ConnStats) {}
+
+func rpcType(clientStream, serverStream bool) string {
+	switch {
+	case clientStream && serverStream:
+		return "bidi_stream"
+	case clientStream:
+		return "client_stream"
+	case serverStream:
+		return "server_stream"
+	default:
+		return "unary"
+	}
+}
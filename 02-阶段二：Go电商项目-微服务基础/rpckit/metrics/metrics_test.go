@@ -0,0 +1,153 @@
+/**
+ * @File : metrics_test.go
+ * @Description : 测试 Prometheus 文本格式输出，以及 gRPC 和 net/rpc 指标对方法名标签的限制
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"google.golang.org/grpc/stats"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"rpckit/grpctest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExposition(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("requests_total", "Requests.\nSecond line with a \\ backslash.", "method", "code")
+	g := r.NewGauge("in_flight", "Requests in flight.")
+	h := r.NewHistogram("latency_seconds", "Latency.", []float64{0.1, 1, 2.5}, "method")
+
+	c.Inc("/b", "OK")
+	c.Add(2, "/a", "OK")
+	c.Inc("quote\" backslash\\ newline\n", "Unknown")
+	g.Add(3)
+	g.Add(-1)
+	for _, v := range []float64{0.05, 0.1, 0.5, 2.5, 7} {
+		h.Observe(v, "/a")
+	}
+
+	var buf bytes.Buffer
+	n, err := r.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("WriteTo() = %d, expect %d bytes", n, buf.Len())
+	}
+	grpctest.Golden(t, "exposition", buf.Bytes())
+}
+
+func text(t *testing.T, r *Registry) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := r.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+// countSeries 统计输出中某个指标的时间序列数，以及是否有 unknown 标签
+func countSeries(t *testing.T, r *Registry, name string) (total int, unknown bool) {
+	t.Helper()
+	for _, line := range strings.Split(text(t, r), "\n") {
+		if strings.HasPrefix(line, name+"{") {
+			total++
+			unknown = unknown || strings.Contains(line, `="`+UnknownMethod+`"`)
+		}
+	}
+	return total, unknown
+}
+
+// simulate 模拟 gRPC 对一次一元调用产生的事件
+func simulate(h *ServerHandler, method string) {
+	ctx := h.TagRPC(context.Background(), &stats.RPCTagInfo{FullMethodName: method})
+	now := time.Now()
+	h.HandleRPC(ctx, &stats.Begin{BeginTime: now})
+	h.HandleRPC(ctx, &stats.End{BeginTime: now, EndTime: now})
+}
+
+func TestServerHandlerMethodLabels(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  func(string) bool
+		methods int
+		series  int
+		unknown bool
+	}{
+		{"few methods", nil, 3, 3, false},
+		{"too many methods fold into unknown", nil, maxMethods + 50, maxMethods + 1, true},
+		{"filtered methods fold into unknown", func(m string) bool { return m == "/svc/M0" }, 10, 2, true},
+	}
+	for _, tt := range tests {
+		r := NewRegistry()
+		h := NewServerHandler(r)
+		if tt.filter != nil {
+			h.WithMethodFilter(tt.filter)
+		}
+		for i := 0; i < tt.methods; i++ {
+			simulate(h, fmt.Sprintf("/svc/M%d", i))
+		}
+		// 只打了标签没有 Begin 的调用（未注册的方法）不占名额
+		h.TagRPC(context.Background(), &stats.RPCTagInfo{FullMethodName: "/never/Begins"})
+
+		total, unknown := countSeries(t, r, "grpc_server_started_total")
+		if total != tt.series || unknown != tt.unknown {
+			t.Errorf("%s: %d series (unknown %v), expect %d (unknown %v)", tt.name, total, unknown, tt.series, tt.unknown)
+		}
+		if total, _ := countSeries(t, r, "grpc_server_in_flight"); total != tt.series {
+			t.Errorf("%s: in_flight has %d series, expect %d", tt.name, total, tt.series)
+		}
+	}
+}
+
+type Echo struct{}
+
+func (Echo) Say(in string, out *string) error {
+	*out = in
+	return nil
+}
+
+func TestRPCServerMethodLabels(t *testing.T) {
+	r := NewRegistry()
+	m := NewRPCServer(r)
+	srv := rpc.NewServer()
+	if err := srv.Register(Echo{}); err != nil {
+		t.Fatal(err)
+	}
+	serverConn, clientConn := net.Pipe()
+	counter := NewByteCounter(serverConn)
+	go srv.ServeCodec(m.WrapServerCodec(jsonrpc.NewServerCodec(counter), counter))
+	client := jsonrpc.NewClient(clientConn)
+	defer client.Close()
+
+	var out string
+	if err := client.Call("Echo.Say", "hi", &out); err != nil || out != "hi" {
+		t.Fatalf("Echo.Say = %q, %v", out, err)
+	}
+	// 不存在的方法会收到错误响应，同样只占用有限的标签值
+	for i := 0; i < maxMethods+10; i++ {
+		if err := client.Call(fmt.Sprintf("Echo.Missing%d", i), "", &out); err == nil {
+			t.Fatal("calling a missing method should fail")
+		}
+	}
+
+	total, unknown := countSeries(t, r, "netrpc_server_started_total")
+	if total != maxMethods+1 || !unknown {
+		t.Errorf("%d series (unknown %v), expect %d with unknown", total, unknown, maxMethods+1)
+	}
+	if total, _ := countSeries(t, r, "netrpc_server_in_flight"); total != maxMethods+1 {
+		t.Errorf("in_flight has %d series, expect %d", total, maxMethods+1)
+	}
+	if !strings.Contains(text(t, r), `netrpc_server_handled_total{method="Echo.Say",status="ok"} 1`) {
+		t.Errorf("Echo.Say was not recorded:\n%s", text(t, r))
+	}
+}
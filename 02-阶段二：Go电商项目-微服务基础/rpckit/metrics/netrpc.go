@@ -0,0 +1,178 @@
+/**
+ * @File : netrpc.go
+ * @Description : net/rpc 服务端的 ServerCodec 包装，记录请求数、耗时、正在处理的请求和请求/响应大小
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"net/rpc"
+	"sync"
+	"time"
+)
+
+// RPCServer 保存 net/rpc 的指标，多个连接共用同一份
+type RPCServer struct {
+	started       *Counter
+	handled       *Counter
+	handling      *Histogram
+	inFlight      *Gauge
+	requestBytes  *Histogram
+	responseBytes *Histogram
+	methods       *methodSet
+}
+
+// NewRPCServer 在 r 上注册 netrpc_server_* 系列指标
+func NewRPCServer(r *Registry) *RPCServer {
+	return &RPCServer{
+		started:       r.NewCounter("netrpc_server_started_total", "net/rpc requests received.", "method"),
+		handled:       r.NewCounter("netrpc_server_handled_total", "net/rpc requests answered, status is ok or error.", "method", "status"),
+		handling:      r.NewHistogram("netrpc_server_handling_seconds", "Time from reading the request header to writing the response.", LatencyBuckets, "method"),
+		inFlight:      r.NewGauge("netrpc_server_in_flight", "net/rpc requests currently being handled.", "method"),
+		requestBytes:  r.NewHistogram("netrpc_server_request_bytes", "Wire size of requests including the header.", SizeBuckets, "method"),
+		responseBytes: r.NewHistogram("netrpc_server_response_bytes", "Wire size of responses including the header.", SizeBuckets, "method"),
+		methods:       newMethodSet(),
+	}
+}
+
+// WrapServerCodec 包装任意 ServerCodec。counter 是 codec 读写所用的连接，
+// 为 nil 时不记录大小：
+//
+//	counter := metrics.NewByteCounter(conn)
+//	rpc.ServeCodec(m.WrapServerCodec(someCodec(counter), counter))
+func (m *RPCServer) WrapServerCodec(codec rpc.ServerCodec, counter *ByteCounter) rpc.ServerCodec {
+	return &serverCodec{ServerCodec: codec, m: m, counter: counter, pending: make(map[uint64]call)}
+}
+
+type call struct {
+	method string
+	start  time.Time
+}
+
+type serverCodec struct {
+	rpc.ServerCodec
+	m       *RPCServer
+	counter *ByteCounter
+
+	// net/rpc 在同一个 goroutine 中依次读取请求，但会并发写响应，所以 pending 需要加锁
+	mu      sync.Mutex
+	pending map[uint64]call
+	// reading 是当前请求开始时已读取的字节数
+	reading int64
+	method  string
+}
+
+func (c *serverCodec) ReadRequestHeader(r *rpc.Request) error {
+	if c.counter != nil {
+		c.reading = c.counter.BytesRead()
+	}
+	if err := c.ServerCodec.ReadRequestHeader(r); err != nil {
+		return err
+	}
+	// 找不到的方法 net/rpc 也会回一个错误响应，所以这里同样要限制标签值的个数
+	c.method = c.m.methods.label(r.ServiceMethod)
+	c.mu.Lock()
+	c.pending[r.Seq] = call{method: c.method, start: time.Now()}
+	c.mu.Unlock()
+	c.m.started.Inc(c.method)
+	c.m.inFlight.Add(1, c.method)
+	return nil
+}
+
+func (c *serverCodec) ReadRequestBody(body any) error {
+	err := c.ServerCodec.ReadRequestBody(body)
+	if c.counter != nil {
+		c.m.requestBytes.Observe(float64(c.counter.BytesRead()-c.reading), c.method)
+	}
+	return err
+}
+
+func (c *serverCodec) WriteResponse(r *rpc.Response, body any) error {
+	c.mu.Lock()
+	started, ok := c.pending[r.Seq]
+	delete(c.pending, r.Seq)
+	c.mu.Unlock()
+
+	// net/rpc 串行调用 WriteResponse，写之前和写之后的差值就是这条响应的大小
+	var before int64
+	if c.counter != nil {
+		before = c.counter.BytesWritten()
+	}
+	err := c.ServerCodec.WriteResponse(r, body)
+	if !ok {
+		return err
+	}
+	status := "ok"
+	if r.Error != "" {
+		status = "error"
+	}
+	c.m.inFlight.Add(-1, started.method)
+	c.m.handled.Inc(started.method, status)
+	c.m.handling.Observe(time.Since(started.start).Seconds(), started.method)
+	if c.counter != nil {
+		c.m.responseBytes.Observe(float64(c.counter.BytesWritten()-before), started.method)
+	}
+	return err
+}
+
+// ByteCounter 统计连接上读写的字节数，本身也是一个连接，可以交给其他 codec 使用。
+// 它实现了 io.ByteReader，gob 就不会再套一层 bufio，读取的字节数和消息边界一致
+type ByteCounter struct {
+	r    *bufio.Reader
+	conn io.ReadWriteCloser
+
+	mu      sync.Mutex
+	read    int64
+	written int64
+}
+
+func NewByteCounter(conn io.ReadWriteCloser) *ByteCounter {
+	return &ByteCounter{r: bufio.NewReader(conn), conn: conn}
+}
+
+func (b *ByteCounter) ReadByte() (byte, error) {
+	c, err := b.r.ReadByte()
+	if err == nil {
+		b.add(&b.read, 1)
+	}
+	return c, err
+}
+
+func (b *ByteCounter) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	b.add(&b.read, n)
+	return n, err
+}
+
+func (b *ByteCounter) Write(p []byte) (int, error) {
+	n, err := b.conn.Write(p)
+	b.add(&b.written, n)
+	return n, err
+}
+
+func (b *ByteCounter) Close() error {
+	return b.conn.Close()
+}
+
+func (b *ByteCounter) add(field *int64, n int) {
+	b.mu.Lock()
+	*field += int64(n)
+	b.mu.Unlock()
+}
+
+// BytesRead 返回目前读取的字节数
+func (b *ByteCounter) BytesRead() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.read
+}
+
+// BytesWritten 返回目前写出的字节数
+func (b *ByteCounter) BytesWritten() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.written
+}
@@ -0,0 +1,315 @@
+/**
+ * @File : registry.go
+ * @Description : 最小的指标注册表：计数器、仪表和直方图，按 Prometheus 文本格式输出到 /metrics
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// LatencyBuckets 是耗时直方图的默认上界（秒）
+var LatencyBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// SizeBuckets 是消息大小直方图的默认上界（字节）
+var SizeBuckets = []float64{64, 256, 1024, 4096, 16384, 65536, 262144, 1048576, 4194304}
+
+// UnknownMethod 是不单独记录的方法统一使用的标签值
+const UnknownMethod = "unknown"
+
+// maxMethods 是一组指标最多单独记录的方法数，之后出现的新方法都记为 UnknownMethod。
+// 方法名来自客户端，不加限制的话调用方换着方法名调用就能制造无限多的时间序列
+const maxMethods = 500
+
+// Registry 持有所有指标，实现了 http.Handler，可以直接挂到 /metrics 上
+type Registry struct {
+	mu       sync.Mutex
+	families []*family
+	names    map[string]bool
+}
+
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+// family 是同名、同标签的一组时间序列
+type family struct {
+	name    string
+	help    string
+	kind    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// series 是一组标签值对应的数据，计数器和仪表只用 value，直方图用 counts/sum/count
+type series struct {
+	values []string
+
+	mu     sync.Mutex
+	value  float64
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func (r *Registry) register(name, help, kind string, buckets []float64, labels []string) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names[name] {
+		panic("metrics: duplicate metric " + name)
+	}
+	r.names[name] = true
+	f := &family{name: name, help: help, kind: kind, labels: labels, buckets: buckets, series: make(map[string]*series)}
+	r.families = append(r.families, f)
+	return f
+}
+
+func (f *family) with(values []string) *series {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s wants %d label values, got %d", f.name, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{values: append([]string(nil), values...)}
+		if f.buckets != nil {
+			s.counts = make([]uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+// Counter 只增不减
+type Counter struct{ f *family }
+
+// Gauge 可增可减，用来表示正在处理的请求数这类瞬时值
+type Gauge struct{ f *family }
+
+// Histogram 按上界统计分布，输出时换算成 Prometheus 的累积桶
+type Histogram struct{ f *family }
+
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	return &Counter{r.register(name, help, "counter", nil, labels)}
+}
+
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{r.register(name, help, "gauge", nil, labels)}
+}
+
+// NewHistogram 的 buckets 必须升序，最后隐含一个 +Inf 桶
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if !sort.Float64sAreSorted(buckets) {
+		panic("metrics: buckets of " + name + " are not sorted")
+	}
+	return &Histogram{r.register(name, help, "histogram", buckets, labels)}
+}
+
+func (c *Counter) Add(v float64, labels ...string) {
+	if v < 0 {
+		panic("metrics: counter " + c.f.name + " cannot decrease")
+	}
+	s := c.f.with(labels)
+	s.mu.Lock()
+	s.value += v
+	s.mu.Unlock()
+}
+
+func (c *Counter) Inc(labels ...string) { c.Add(1, labels...) }
+
+func (g *Gauge) Add(v float64, labels ...string) {
+	s := g.f.with(labels)
+	s.mu.Lock()
+	s.value += v
+	s.mu.Unlock()
+}
+
+func (g *Gauge) Set(v float64, labels ...string) {
+	s := g.f.with(labels)
+	s.mu.Lock()
+	s.value = v
+	s.mu.Unlock()
+}
+
+func (h *Histogram) Observe(v float64, labels ...string) {
+	s := h.f.with(labels)
+	i := sort.SearchFloat64s(h.f.buckets, v)
+	s.mu.Lock()
+	if i < len(s.counts) {
+		s.counts[i]++
+	}
+	s.sum += v
+	s.count++
+	s.mu.Unlock()
+}
+
+// WriteTo 按注册顺序输出所有指标，同一指标内按标签值排序，保证输出稳定
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	families := append([]*family(nil), r.families...)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	cw := &countingWriter{w: bw}
+	for _, f := range families {
+		f.write(cw)
+	}
+	if cw.err == nil {
+		cw.err = bw.Flush()
+	}
+	return cw.n, cw.err
+}
+
+func (f *family) write(w *countingWriter) {
+	f.mu.Lock()
+	keys := make([]string, 0, len(f.series))
+	for k := range f.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	all := make([]*series, len(keys))
+	for i, k := range keys {
+		all[i] = f.series[k]
+	}
+	f.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.name, f.kind)
+	for _, s := range all {
+		s.mu.Lock()
+		if f.kind != "histogram" {
+			fmt.Fprintf(w, "%s%s %s\n", f.name, formatLabels(f.labels, s.values, "", ""), formatFloat(s.value))
+			s.mu.Unlock()
+			continue
+		}
+		var cumulative uint64
+		for i, le := range f.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, formatLabels(f.labels, s.values, "le", formatFloat(le)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, formatLabels(f.labels, s.values, "le", "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", f.name, formatLabels(f.labels, s.values, "", ""), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", f.name, formatLabels(f.labels, s.values, "", ""), s.count)
+		s.mu.Unlock()
+	}
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteTo(w)
+}
+
+// Serve 在后台启动只包含 /metrics 的 HTTP 服务；addr 为空时不启动。
+// 指标端口被占用不应该影响业务，所以失败时只记录日志
+func Serve(addr string, r *Registry) {
+	if addr == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", r)
+	go func() {
+		log.Printf("metrics: serving http://%s/metrics", addr)
+		log.Printf("metrics: %v", http.ListenAndServe(addr, mux))
+	}()
+}
+
+func formatLabels(names, values []string, extraName, extraValue string) string {
+	if len(names) == 0 && extraName == "" {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, n := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=\"%s\"", n, escapeLabel(values[i]))
+	}
+	if extraName != "" {
+		if len(names) > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, "%s=\"%s\"", extraName, extraValue)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
+
+func escapeHelp(s string) string { return helpEscaper.Replace(s) }
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// methodSet 把客户端发来的方法名换成标签值，known 为 nil 时只受 maxMethods 限制
+type methodSet struct {
+	known func(method string) bool
+
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+func newMethodSet() *methodSet {
+	return &methodSet{seen: make(map[string]bool)}
+}
+
+func (m *methodSet) label(method string) string {
+	if m.known != nil && !m.known(method) {
+		return UnknownMethod
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.seen[method] {
+		if len(m.seen) >= maxMethods {
+			return UnknownMethod
+		}
+		m.seen[method] = true
+	}
+	return method
+}
+
+// countingWriter 记录写出的字节数和第一个错误，省得每次 Fprintf 都检查
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
@@ -0,0 +1,43 @@
+/**
+ * @File : codec.go
+ * @Description : 原样传递消息字节的编解码器，代理不需要生成代码也不需要知道消息类型
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package proxy
+
+import (
+	"fmt"
+	"google.golang.org/grpc/encoding"
+)
+
+// Frame 保存一条消息序列化后的字节，代理收到什么就转发什么
+type Frame struct {
+	payload []byte
+}
+
+type rawCodec struct{}
+
+// Codec 返回只接受 *Frame 的编解码器。名字沿用 "proto"，content-type 仍然是 application/grpc+proto，
+// 两端的客户端和服务端看不出中间经过了代理
+func Codec() encoding.Codec { return rawCodec{} }
+
+func (rawCodec) Marshal(v any) ([]byte, error) {
+	f, ok := v.(*Frame)
+	if !ok {
+		return nil, fmt.Errorf("proxy: cannot marshal %T, expect *proxy.Frame", v)
+	}
+	return f.payload, nil
+}
+
+// Unmarshal 直接保留 data：gRPC 传进来的是新分配的切片，调用结束后不会被复用
+func (rawCodec) Unmarshal(data []byte, v any) error {
+	f, ok := v.(*Frame)
+	if !ok {
+		return fmt.Errorf("proxy: cannot unmarshal into %T, expect *proxy.Frame", v)
+	}
+	f.payload = data
+	return nil
+}
+
+func (rawCodec) Name() string { return "proto" }
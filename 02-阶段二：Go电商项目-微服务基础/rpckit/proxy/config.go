@@ -0,0 +1,85 @@
+/**
+ * @File : config.go
+ * @Description : 代理的路由规则：按服务名选择后端地址
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Route 把一个服务的所有方法转发到 Backend。Service 是带包名的完整服务名：
+//
+//	"hello.HelloService"  proto 文件里有 package hello
+//	"Greeter"             proto 文件没有 package
+//	"*"                   兜底规则
+type Route struct {
+	Service string `json:"service"`
+	Backend string `json:"backend"`
+}
+
+// Config 对应路由配置文件：
+//
+//	{"routes": [{"service": "Greeter", "backend": "127.0.0.1:50052"}]}
+type Config struct {
+	Routes []Route `json:"routes"`
+}
+
+// LoadConfig 从文件读取配置
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(data)
+}
+
+// ParseConfig 解析并检查配置，同一个服务只能出现一次
+func ParseConfig(data []byte) (*Config, error) {
+	var c Config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("proxy: parse config: %w", err)
+	}
+	seen := make(map[string]bool)
+	for _, r := range c.Routes {
+		if r.Service == "" || strings.Contains(r.Service, "/") {
+			return nil, fmt.Errorf("proxy: service %q must be a service name like hello.HelloService or \"*\"", r.Service)
+		}
+		if r.Backend == "" {
+			return nil, fmt.Errorf("proxy: service %q has no backend", r.Service)
+		}
+		if seen[r.Service] {
+			return nil, fmt.Errorf("proxy: duplicate route for service %q", r.Service)
+		}
+		seen[r.Service] = true
+	}
+	return &c, nil
+}
+
+// backend 返回服务对应的后端地址，精确匹配优先于 "*"
+func (c *Config) backend(service string) (string, bool) {
+	fallback := ""
+	for _, r := range c.Routes {
+		switch r.Service {
+		case service:
+			return r.Backend, true
+		case "*":
+			fallback = r.Backend
+		}
+	}
+	return fallback, fallback != ""
+}
+
+// serviceName 从 "/hello.HelloService/SayHello" 中取出 "hello.HelloService"
+func serviceName(fullMethod string) string {
+	s := strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(s, "/"); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
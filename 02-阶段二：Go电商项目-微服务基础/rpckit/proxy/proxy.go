@@ -0,0 +1,135 @@
+/**
+ * @File : proxy.go
+ * @Description : 透明 gRPC 反向代理：接收任意方法，原样转发请求、响应、header、trailer 和状态码
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package proxy
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"io"
+)
+
+// ForwardedForKey 记录经过的代理看到的调用方地址，多级代理时按顺序追加
+const ForwardedForKey = "x-forwarded-for"
+
+// Director 为一次调用选择后端连接，返回的错误会作为调用的状态码返回给客户端
+type Director func(ctx context.Context, fullMethod string) (*grpc.ClientConn, error)
+
+// 代理不知道方法的类型，一律按双向流转发，一元和单向流只是其中消息数为 1 的特例
+var clientStreamDesc = &grpc.StreamDesc{ServerStreams: true, ClientStreams: true}
+
+// ServerOptions 返回把所有未注册的服务都转发出去的服务端选项。
+// ForceServerCodec 会作用于整个 Server，所以代理用的 Server 上不要再注册普通服务
+func ServerOptions(director Director) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ForceServerCodec(Codec()),
+		grpc.UnknownServiceHandler(Handler(director)),
+	}
+}
+
+// Handler 返回用于 grpc.UnknownServiceHandler 的转发函数
+func Handler(director Director) grpc.StreamHandler {
+	return func(_ any, ss grpc.ServerStream) error {
+		method, ok := grpc.MethodFromServerStream(ss)
+		if !ok {
+			return status.Error(codes.Internal, "proxy: no method in stream context")
+		}
+		conn, err := director(ss.Context(), method)
+		if err != nil {
+			return err
+		}
+		// 客户端的 deadline 和取消都通过 ss.Context() 传给后端
+		ctx, cancel := context.WithCancel(outgoingContext(ss.Context()))
+		defer cancel()
+		cs, err := grpc.NewClientStream(ctx, clientStreamDesc, conn, method, grpc.ForceCodec(Codec()))
+		if err != nil {
+			return err
+		}
+
+		requests := forwardRequests(ss, cs)
+		responses := forwardResponses(cs, ss)
+		for {
+			select {
+			case err := <-requests:
+				if err != io.EOF {
+					// 客户端取消或连接断开，取消发往后端的调用
+					return err
+				}
+				// 客户端发送完毕，通知后端，然后继续等待响应
+				cs.CloseSend()
+				requests = nil
+			case err := <-responses:
+				if err == io.EOF {
+					return nil
+				}
+				// 后端返回的是 status 错误，原样交给客户端，包括 details
+				return err
+			}
+		}
+	}
+}
+
+// outgoingContext 把客户端发来的 metadata 原样作为发往后端的 metadata，并追加调用方地址。
+// :authority、content-type 等保留字段会被 gRPC 在发送时过滤掉
+func outgoingContext(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	md = md.Copy()
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		md.Append(ForwardedForKey, p.Addr.String())
+	}
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// forwardRequests 把客户端的消息转发给后端，通道里返回客户端一侧的结束原因，io.EOF 表示客户端正常发送完毕
+func forwardRequests(src grpc.ServerStream, dst grpc.ClientStream) <-chan error {
+	ret := make(chan error, 1)
+	go func() {
+		for {
+			f := &Frame{}
+			if err := src.RecvMsg(f); err != nil {
+				ret <- err
+				return
+			}
+			if err := dst.SendMsg(f); err != nil {
+				// 后端已经结束了调用，真正的状态由 forwardResponses 从 RecvMsg 取回
+				return
+			}
+		}
+	}()
+	return ret
+}
+
+// forwardResponses 先转发后端的 header，再转发响应消息，通道里返回后端一侧的结束原因，io.EOF 表示调用成功
+func forwardResponses(src grpc.ClientStream, dst grpc.ServerStream) <-chan error {
+	ret := make(chan error, 1)
+	go func() {
+		// Header 会等到后端发出 header 为止；后端直接返回错误时没有 header，错误由下面的 RecvMsg 取回
+		if md, err := src.Header(); err == nil && len(md) > 0 {
+			if err := dst.SendHeader(md); err != nil {
+				ret <- err
+				return
+			}
+		}
+		for {
+			f := &Frame{}
+			if err := src.RecvMsg(f); err != nil {
+				// RecvMsg 返回错误后后端的 trailer 才完整
+				dst.SetTrailer(src.Trailer())
+				ret <- err
+				return
+			}
+			if err := dst.SendMsg(f); err != nil {
+				ret <- err
+				return
+			}
+		}
+	}()
+	return ret
+}
@@ -0,0 +1,336 @@
+/**
+ * @File : proxy_test.go
+ * @Description : 通过 bufconn 测试代理：四种调用方式、header/trailer 转发、错误状态和路由规则
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package proxy
+
+import (
+	"context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+	testpb "google.golang.org/grpc/interop/grpc_testing"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"io"
+	"rpckit/grpctest"
+	"testing"
+)
+
+// backend 把请求里的 client-id 放回 header 的 server-id，并在 trailer 里写上 server-trailer，
+// 和 grpcmetadata 示例里的 Client-ID/Server-ID 一样，用来检查代理是否原样转发 metadata
+type backend struct {
+	testpb.UnimplementedTestServiceServer
+	incoming chan metadata.MD
+}
+
+func (b *backend) metadata(ctx context.Context) (header, trailer metadata.MD) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	select {
+	case b.incoming <- md:
+	default:
+	}
+	return metadata.MD{"server-id": md.Get("client-id")}, metadata.Pairs("server-trailer", "done")
+}
+
+func (b *backend) UnaryCall(ctx context.Context, req *testpb.SimpleRequest) (*testpb.SimpleResponse, error) {
+	header, trailer := b.metadata(ctx)
+	grpc.SetHeader(ctx, header)
+	grpc.SetTrailer(ctx, trailer)
+	if s := req.ResponseStatus; s != nil {
+		st, _ := status.New(codes.Code(s.Code), s.Message).WithDetails(&errdetails.ErrorInfo{Reason: "BACKEND"})
+		return nil, st.Err()
+	}
+	return &testpb.SimpleResponse{Payload: req.Payload}, nil
+}
+
+func (b *backend) StreamingOutputCall(req *testpb.StreamingOutputCallRequest, stream testpb.TestService_StreamingOutputCallServer) error {
+	header, trailer := b.metadata(stream.Context())
+	stream.SetHeader(header)
+	stream.SetTrailer(trailer)
+	for _, p := range req.ResponseParameters {
+		if err := stream.Send(&testpb.StreamingOutputCallResponse{Payload: &testpb.Payload{Body: make([]byte, p.Size)}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *backend) StreamingInputCall(stream testpb.TestService_StreamingInputCallServer) error {
+	header, trailer := b.metadata(stream.Context())
+	stream.SetHeader(header)
+	stream.SetTrailer(trailer)
+	var total int32
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&testpb.StreamingInputCallResponse{AggregatedPayloadSize: total})
+		}
+		if err != nil {
+			return err
+		}
+		total += int32(len(req.Payload.GetBody()))
+	}
+}
+
+func (b *backend) FullDuplexCall(stream testpb.TestService_FullDuplexCallServer) error {
+	header, trailer := b.metadata(stream.Context())
+	// 双向流先发 header，客户端不用等第一条响应就能读到
+	stream.SendHeader(header)
+	stream.SetTrailer(trailer)
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(&testpb.StreamingOutputCallResponse{Payload: req.Payload}); err != nil {
+			return err
+		}
+	}
+}
+
+// startProxy 启动一个后端和一个把所有调用都转发给它的代理，返回连接代理的客户端
+func startProxy(t *testing.T) (testpb.TestServiceClient, *backend) {
+	b := &backend{incoming: make(chan metadata.MD, 1)}
+	backendConn := grpctest.Start(t, func(s *grpc.Server) { testpb.RegisterTestServiceServer(s, b) })
+	director := func(context.Context, string) (*grpc.ClientConn, error) { return backendConn, nil }
+	conn := grpctest.Start(t, func(*grpc.Server) {}, grpctest.WithServerOptions(ServerOptions(director)...))
+	return testpb.NewTestServiceClient(conn), b
+}
+
+func TestProxyStreamKinds(t *testing.T) {
+	body := &testpb.Payload{Body: []byte("0123456789")}
+	tests := []struct {
+		name string
+		// call 发起一次调用，返回收到的消息数
+		call     func(ctx context.Context, c testpb.TestServiceClient, header, trailer *metadata.MD) (int, error)
+		messages int
+	}{
+		{"unary", func(ctx context.Context, c testpb.TestServiceClient, header, trailer *metadata.MD) (int, error) {
+			_, err := c.UnaryCall(ctx, &testpb.SimpleRequest{Payload: body}, grpc.Header(header), grpc.Trailer(trailer))
+			return 1, err
+		}, 1},
+		{"server streaming", func(ctx context.Context, c testpb.TestServiceClient, header, trailer *metadata.MD) (int, error) {
+			params := []*testpb.ResponseParameters{{Size: 1}, {Size: 2}, {Size: 3}}
+			stream, err := c.StreamingOutputCall(ctx, &testpb.StreamingOutputCallRequest{ResponseParameters: params})
+			if err != nil {
+				return 0, err
+			}
+			n := 0
+			for {
+				resp, err := stream.Recv()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					return n, err
+				}
+				if len(resp.Payload.Body) != n+1 {
+					t.Errorf("server streaming: message %d is %d bytes, expect %d", n, len(resp.Payload.Body), n+1)
+				}
+				n++
+			}
+			*header, _ = stream.Header()
+			*trailer = stream.Trailer()
+			return n, nil
+		}, 3},
+		{"client streaming", func(ctx context.Context, c testpb.TestServiceClient, header, trailer *metadata.MD) (int, error) {
+			stream, err := c.StreamingInputCall(ctx)
+			if err != nil {
+				return 0, err
+			}
+			for i := 0; i < 4; i++ {
+				if err := stream.Send(&testpb.StreamingInputCallRequest{Payload: body}); err != nil {
+					return 0, err
+				}
+			}
+			resp, err := stream.CloseAndRecv()
+			if err != nil {
+				return 0, err
+			}
+			if resp.AggregatedPayloadSize != 40 {
+				t.Errorf("client streaming: aggregated size = %d, expect 40", resp.AggregatedPayloadSize)
+			}
+			*header, _ = stream.Header()
+			*trailer = stream.Trailer()
+			return 1, nil
+		}, 1},
+		{"bidi streaming", func(ctx context.Context, c testpb.TestServiceClient, header, trailer *metadata.MD) (int, error) {
+			stream, err := c.FullDuplexCall(ctx)
+			if err != nil {
+				return 0, err
+			}
+			// 一问一答，确认代理不会等客户端发完才转发
+			n := 0
+			for i := 0; i < 5; i++ {
+				if err := stream.Send(&testpb.StreamingOutputCallRequest{Payload: body}); err != nil {
+					return n, err
+				}
+				if _, err := stream.Recv(); err != nil {
+					return n, err
+				}
+				n++
+			}
+			stream.CloseSend()
+			if _, err := stream.Recv(); err != io.EOF {
+				return n, err
+			}
+			*header, _ = stream.Header()
+			*trailer = stream.Trailer()
+			return n, nil
+		}, 5},
+	}
+	for _, tt := range tests {
+		c, _ := startProxy(t)
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "client-id", "client-1")
+		var header, trailer metadata.MD
+		n, err := tt.call(ctx, c, &header, &trailer)
+		if err != nil {
+			t.Errorf("%s: error = %v", tt.name, err)
+			continue
+		}
+		if n != tt.messages {
+			t.Errorf("%s: received %d messages, expect %d", tt.name, n, tt.messages)
+		}
+		if got := header.Get("server-id"); len(got) != 1 || got[0] != "client-1" {
+			t.Errorf("%s: header server-id = %v, expect [client-1]", tt.name, got)
+		}
+		if got := trailer.Get("server-trailer"); len(got) != 1 || got[0] != "done" {
+			t.Errorf("%s: trailer server-trailer = %v, expect [done]", tt.name, got)
+		}
+	}
+}
+
+func TestProxyForwardsRequestMetadata(t *testing.T) {
+	c, b := startProxy(t)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "client-id", "client-1", "client-id2", "client-2")
+	if _, err := c.UnaryCall(ctx, &testpb.SimpleRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	md := <-b.incoming
+	for _, key := range []string{"client-id", "client-id2", ForwardedForKey} {
+		if len(md.Get(key)) != 1 {
+			t.Errorf("backend metadata %s = %v, expect exactly one value", key, md.Get(key))
+		}
+	}
+}
+
+func TestProxyStatus(t *testing.T) {
+	c, _ := startProxy(t)
+	var trailer metadata.MD
+	req := &testpb.SimpleRequest{ResponseStatus: &testpb.EchoStatus{Code: int32(codes.FailedPrecondition), Message: "stock locked"}}
+	_, err := c.UnaryCall(context.Background(), req, grpc.Trailer(&trailer))
+	st := status.Convert(err)
+	if st.Code() != codes.FailedPrecondition || st.Message() != "stock locked" {
+		t.Errorf("UnaryCall() error = %v, expect FailedPrecondition: stock locked", err)
+	}
+	if details := st.Details(); len(details) != 1 || details[0].(*errdetails.ErrorInfo).Reason != "BACKEND" {
+		t.Errorf("status details = %v, expect the backend ErrorInfo", details)
+	}
+	if got := trailer.Get("server-trailer"); len(got) != 1 {
+		t.Errorf("trailer server-trailer = %v, expect it to survive an error", got)
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{"valid", `{"routes": [{"service": "hello.HelloService", "backend": "127.0.0.1:50051"}, {"service": "*", "backend": "127.0.0.1:50052"}]}`, false},
+		{"method instead of service", `{"routes": [{"service": "/Greeter/StreamNumbers", "backend": "127.0.0.1:50051"}]}`, true},
+		{"empty service", `{"routes": [{"backend": "127.0.0.1:50051"}]}`, true},
+		{"missing backend", `{"routes": [{"service": "Greeter"}]}`, true},
+		{"duplicate service", `{"routes": [{"service": "Greeter", "backend": "a:1"}, {"service": "Greeter", "backend": "b:1"}]}`, true},
+		{"bad json", `{"routes": [`, true},
+	}
+	for _, tt := range tests {
+		if _, err := ParseConfig([]byte(tt.config)); (err != nil) != tt.wantErr {
+			t.Errorf("%s: ParseConfig() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestRouterDirect(t *testing.T) {
+	cfg, err := ParseConfig([]byte(`{"routes": [
+		{"service": "hello.HelloService", "backend": "127.0.0.1:50051"},
+		{"service": "HelloService", "backend": "127.0.0.1:50052"},
+		{"service": "Greeter", "backend": "127.0.0.1:50051"}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRouter(cfg, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	defer r.Close()
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		code   codes.Code
+	}{
+		{"service with package", "/hello.HelloService/SayHello", "127.0.0.1:50051", codes.OK},
+		{"same name without package", "/HelloService/SayHello", "127.0.0.1:50052", codes.OK},
+		{"streaming method", "/Greeter/StreamNumbers", "127.0.0.1:50051", codes.OK},
+		{"no route", "/SumService/StreamSum", "", codes.Unimplemented},
+	}
+	for _, tt := range tests {
+		conn, err := r.Direct(context.Background(), tt.method)
+		if status.Code(err) != tt.code {
+			t.Errorf("%s: Direct(%s) error = %v, expect %v", tt.name, tt.method, err, tt.code)
+			continue
+		}
+		if err == nil && conn.Target() != tt.target {
+			t.Errorf("%s: Direct(%s) target = %s, expect %s", tt.name, tt.method, conn.Target(), tt.target)
+		}
+	}
+
+	// 同一个后端只有一个连接，更新配置后不再使用的连接被关闭
+	hello, _ := r.Direct(context.Background(), "/hello.HelloService/SayHello")
+	greeter, _ := r.Direct(context.Background(), "/Greeter/StreamNumbers")
+	if hello != greeter {
+		t.Errorf("services on the same backend got different connections")
+	}
+	r.Update(&Config{Routes: []Route{{Service: "*", Backend: "127.0.0.1:50052"}}})
+	if s := hello.GetState(); s != connectivity.Shutdown {
+		t.Errorf("connection to a removed backend is %v, expect SHUTDOWN", s)
+	}
+	if conn, err := r.Direct(context.Background(), "/SumService/StreamSum"); err != nil || conn.Target() != "127.0.0.1:50052" {
+		t.Errorf("Direct() after Update = %v, %v; expect the fallback backend 127.0.0.1:50052", conn, err)
+	}
+}
+
+func TestRouterRouted(t *testing.T) {
+	cfg, err := ParseConfig([]byte(`{"routes": [
+		{"service": "hello.HelloService", "backend": "127.0.0.1:50051"},
+		{"service": "*", "backend": "127.0.0.1:50052"}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRouter(cfg, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	defer r.Close()
+
+	tests := []struct {
+		name   string
+		method string
+		routed bool
+	}{
+		{"exact route", "/hello.HelloService/SayHello", true},
+		{"any method of a routed service", "/hello.HelloService/Anything", true},
+		{"only the fallback route", "/Greeter/SayHello", false},
+	}
+	for _, tt := range tests {
+		if got := r.Routed(tt.method); got != tt.routed {
+			t.Errorf("%s: Routed(%s) = %v, expect %v", tt.name, tt.method, got, tt.routed)
+		}
+	}
+}
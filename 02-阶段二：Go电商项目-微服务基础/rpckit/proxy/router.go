@@ -0,0 +1,140 @@
+/**
+ * @File : router.go
+ * @Description : 按路由配置把调用交给对应后端的连接，配置文件修改后自动重新加载
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package proxy
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Router 并发安全，实现了 Director。每个后端地址只建立一个连接，配置更新后仍在使用的连接会被复用
+type Router struct {
+	opts []grpc.DialOption
+
+	mu    sync.Mutex
+	cfg   *Config
+	conns map[string]*grpc.ClientConn
+}
+
+// NewRouter 用 opts 连接后端，opts 里至少要有传输凭证
+func NewRouter(cfg *Config, opts ...grpc.DialOption) *Router {
+	return &Router{opts: opts, cfg: cfg, conns: make(map[string]*grpc.ClientConn)}
+}
+
+// Update 替换路由配置，不再出现在配置里的后端连接会被关闭，正在这些连接上进行的调用会失败
+func (r *Router) Update(cfg *Config) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cfg = cfg
+	used := make(map[string]bool)
+	for _, route := range cfg.Routes {
+		used[route.Backend] = true
+	}
+	for addr, conn := range r.conns {
+		if !used[addr] {
+			conn.Close()
+			delete(r.conns, addr)
+		}
+	}
+}
+
+// Direct 根据方法所属的服务选择后端，没有匹配的路由时返回 Unimplemented，和直接调用不存在的服务一样
+func (r *Router) Direct(_ context.Context, fullMethod string) (*grpc.ClientConn, error) {
+	service := serviceName(fullMethod)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	addr, ok := r.cfg.backend(service)
+	if !ok {
+		return nil, status.Errorf(codes.Unimplemented, "proxy: no route for service %q", service)
+	}
+	if conn, ok := r.conns[addr]; ok {
+		return conn, nil
+	}
+	// NewClient 不会建立连接，只有地址格式错误时才会失败
+	conn, err := grpc.NewClient(addr, r.opts...)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "proxy: backend %s: %v", addr, err)
+	}
+	r.conns[addr] = conn
+	return conn, nil
+}
+
+// Routed 判断方法所属的服务是否有精确匹配的路由，"*" 兜底的调用不算。
+// 用作 metrics.ServerHandler 的方法过滤，避免任意方法名都成为一个指标标签
+func (r *Router) Routed(fullMethod string) bool {
+	service := serviceName(fullMethod)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, route := range r.cfg.Routes {
+		if route.Service == service {
+			return true
+		}
+	}
+	return false
+}
+
+// Close 关闭所有后端连接
+func (r *Router) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for addr, conn := range r.conns {
+		conn.Close()
+		delete(r.conns, addr)
+	}
+}
+
+// WatchFile 从文件加载路由配置，并每隔 interval 检查一次文件的修改时间，变化后重新加载。
+// 新配置有错误时继续使用旧配置。返回的 stop 函数停止检查
+func WatchFile(path string, interval time.Duration, opts ...grpc.DialOption) (*Router, func(), error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	r := NewRouter(cfg, opts...)
+	modTime := info.ModTime()
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				log.Printf("proxy: stat %s: %v", path, err)
+				continue
+			}
+			if info.ModTime().Equal(modTime) {
+				continue
+			}
+			cfg, err := LoadConfig(path)
+			if err != nil {
+				log.Printf("proxy: reload %s: %v", path, err)
+				continue
+			}
+			modTime = info.ModTime()
+			r.Update(cfg)
+			log.Printf("proxy: reloaded %s, %d routes", path, len(cfg.Routes))
+		}
+	}()
+	var once sync.Once
+	return r, func() { once.Do(func() { close(done) }) }, nil
+}
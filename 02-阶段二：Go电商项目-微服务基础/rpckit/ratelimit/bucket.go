@@ -0,0 +1,61 @@
+/**
+ * @File : bucket.go
+ * @Description : 令牌桶：按固定速率补充令牌，桶满时最多攒 burst 个
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package ratelimit
+
+import (
+	"math"
+	"time"
+)
+
+// bucket 不加锁，由 Limiter 的锁保护
+type bucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate float64, burst int, now time.Time) *bucket {
+	return &bucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: now}
+}
+
+func (b *bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed*b.rate)
+	}
+	b.last = now
+}
+
+// setLimit 改变速率和容量，之前攒下的令牌按旧速率结算后保留，超过新容量的部分丢掉
+func (b *bucket) setLimit(rate float64, burst int, now time.Time) {
+	b.refill(now)
+	b.rate = rate
+	b.burst = float64(burst)
+	b.tokens = math.Min(b.burst, b.tokens)
+}
+
+// take 取一个令牌；取不到时返回还要等多久才会有下一个令牌
+func (b *bucket) take(now time.Time) (time.Duration, bool) {
+	b.refill(now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+	wait := (1 - b.tokens) / b.rate
+	return time.Duration(math.Ceil(wait * float64(time.Second))), false
+}
+
+// giveBack 归还 take 取走的令牌，用于后面的检查没有通过时
+func (b *bucket) giveBack() {
+	b.tokens = math.Min(b.burst, b.tokens+1)
+}
+
+// full 报告桶是否已经攒满，满的桶和新建的桶没有区别，可以丢掉
+func (b *bucket) full(now time.Time) bool {
+	b.refill(now)
+	return b.tokens >= b.burst
+}
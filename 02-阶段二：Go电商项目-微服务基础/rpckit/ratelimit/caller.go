@@ -0,0 +1,38 @@
+/**
+ * @File : caller.go
+ * @Description : 识别调用方：优先使用 mTLS 证书中的身份，其次是 client-id 元数据，最后是对端 IP
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package ratelimit
+
+import (
+	"context"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"net"
+	"rpckit/tlsutil"
+)
+
+// CallerKey 是客户端自报身份的元数据键，与 protobuf_grpc_advance 中延迟统计使用的键一致
+const CallerKey = "client-id"
+
+// Caller 返回用于限流的调用方标识。
+// 证书身份无法伪造，所以排在元数据前面；对端地址只取 IP，同一台机器的多个连接算同一个调用方
+func Caller(ctx context.Context) string {
+	if id, ok := tlsutil.PeerIdentity(ctx); ok && id.Name != "" {
+		return id.Name
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(CallerKey); len(v) > 0 && v[0] != "" {
+			return v[0]
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			return host
+		}
+		return p.Addr.String()
+	}
+	return "unknown"
+}
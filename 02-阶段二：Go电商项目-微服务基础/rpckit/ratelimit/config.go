@@ -0,0 +1,93 @@
+/**
+ * @File : config.go
+ * @Description : 限流规则：按方法匹配，分别限制整个方法的速率、单个调用方的速率和单个调用方的并发流数
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+)
+
+// Rule 是一条限流规则，各项为 0 表示不限制。Method 可以写成：
+//
+//	"/hello.v1.HelloService/SayHello"  精确匹配一个方法
+//	"/greeter.v1.Greeter/*"            匹配一个服务的所有方法
+//	"*"                                兜底规则
+type Rule struct {
+	Method string `json:"method"`
+	// Rate/Burst 限制这个方法的总请求速率（每秒），所有调用方共享
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst"`
+	// CallerRate/CallerBurst 限制单个调用方对这个方法的请求速率
+	CallerRate  float64 `json:"callerRate"`
+	CallerBurst int     `json:"callerBurst"`
+	// MaxStreamsPerCaller 限制单个调用方同时打开的流数，只对流式方法生效
+	MaxStreamsPerCaller int `json:"maxStreamsPerCaller"`
+}
+
+// Config 对应限流配置文件：
+//
+//	{"rules": [{"method": "/greeter.v1.Greeter/*", "callerRate": 2, "maxStreamsPerCaller": 2}]}
+type Config struct {
+	Rules []Rule `json:"rules"`
+}
+
+// LoadConfig 从文件读取配置
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(data)
+}
+
+// ParseConfig 解析并检查配置，Burst 没有填写时取 ceil(Rate)
+func ParseConfig(data []byte) (*Config, error) {
+	var c Config
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("ratelimit: parse config: %w", err)
+	}
+	for i := range c.Rules {
+		r := &c.Rules[i]
+		if r.Method != "*" && !strings.HasPrefix(r.Method, "/") {
+			return nil, fmt.Errorf("ratelimit: method %q must be \"*\" or start with /", r.Method)
+		}
+		if r.Rate < 0 || r.CallerRate < 0 || r.Burst < 0 || r.CallerBurst < 0 || r.MaxStreamsPerCaller < 0 {
+			return nil, fmt.Errorf("ratelimit: negative limit for %q", r.Method)
+		}
+		if r.Burst == 0 {
+			r.Burst = int(math.Ceil(r.Rate))
+		}
+		if r.CallerBurst == 0 {
+			r.CallerBurst = int(math.Ceil(r.CallerRate))
+		}
+	}
+	return &c, nil
+}
+
+// match 的优先级：精确匹配 > 服务匹配 > "*"
+func (c *Config) match(fullMethod string) *Rule {
+	service := fullMethod[:strings.LastIndex(fullMethod, "/")+1] + "*"
+	var byService, fallback *Rule
+	for i := range c.Rules {
+		r := &c.Rules[i]
+		switch r.Method {
+		case fullMethod:
+			return r
+		case service:
+			byService = r
+		case "*":
+			fallback = r
+		}
+	}
+	if byService != nil {
+		return byService
+	}
+	return fallback
+}
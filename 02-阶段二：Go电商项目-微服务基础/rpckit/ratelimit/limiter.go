@@ -0,0 +1,251 @@
+/**
+ * @File : limiter.go
+ * @Description : 服务端限流拦截器，超限时返回 ResourceExhausted 并通过 RetryInfo 告诉客户端多久后再试
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// PushbackKey 是 gRPC 内置重试识别的 trailer，客户端会按这个值等待后再重试
+const PushbackKey = "grpc-retry-pushback-ms"
+
+// streamRetryDelay 是并发流超限时建议的重试间隔，流什么时候结束无法预知，只能给一个经验值
+const streamRetryDelay = time.Second
+
+// maxIdleBuckets 超过这个数量时清理已经攒满的调用方令牌桶，避免调用方很多时内存一直增长
+const maxIdleBuckets = 10000
+
+type callerKey struct {
+	method string
+	caller string
+}
+
+// Limiter 并发安全，配置可以在运行时通过 Update 或 WatchFile 替换
+type Limiter struct {
+	mu      sync.Mutex
+	cfg     *Config
+	methods map[string]*bucket
+	callers map[callerKey]*bucket
+	streams map[callerKey]int
+	now     func() time.Time
+}
+
+func New(cfg *Config) *Limiter {
+	return &Limiter{
+		cfg:     cfg,
+		methods: make(map[string]*bucket),
+		callers: make(map[callerKey]*bucket),
+		streams: make(map[callerKey]int),
+		now:     time.Now,
+	}
+}
+
+// Update 替换配置。已有的令牌桶保留剩余令牌，只按新规则改变速率和容量，
+// 否则每次重新加载都会让所有桶重新计满；不再受限的桶直接丢掉。正在进行的流仍然计入并发数
+func (l *Limiter) Update(cfg *Config) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cfg = cfg
+	now := l.now()
+	for method, b := range l.methods {
+		if rule := cfg.match(method); rule != nil && rule.Rate > 0 {
+			b.setLimit(rule.Rate, rule.Burst, now)
+		} else {
+			delete(l.methods, method)
+		}
+	}
+	for key, b := range l.callers {
+		if rule := cfg.match(key.method); rule != nil && rule.CallerRate > 0 {
+			b.setLimit(rule.CallerRate, rule.CallerBurst, now)
+		} else {
+			delete(l.callers, key)
+		}
+	}
+}
+
+// WatchFile 读取配置文件并定期检查修改时间，文件变化后重新加载；
+// 第一次读取失败时返回错误，之后的失败只记日志并保留旧配置
+func WatchFile(path string, interval time.Duration) (*Limiter, func(), error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	l := New(cfg)
+	modTime := info.ModTime()
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				log.Printf("ratelimit: stat %s: %v", path, err)
+				continue
+			}
+			if info.ModTime().Equal(modTime) {
+				continue
+			}
+			cfg, err := LoadConfig(path)
+			if err != nil {
+				log.Printf("ratelimit: reload %s: %v", path, err)
+				continue
+			}
+			modTime = info.ModTime()
+			l.Update(cfg)
+			log.Printf("ratelimit: reloaded %s, %d rules", path, len(cfg.Rules))
+		}
+	}()
+	var once sync.Once
+	return l, func() { once.Do(func() { close(done) }) }, nil
+}
+
+// allow 先检查调用方自己的速率，再检查方法的总速率，两者都通过才消耗令牌
+func (l *Limiter) allow(rule *Rule, method, caller string) error {
+	now := l.now()
+	var cb *bucket
+	if rule.CallerRate > 0 {
+		key := callerKey{method, caller}
+		cb = l.callers[key]
+		if cb == nil {
+			l.sweep(now)
+			cb = newBucket(rule.CallerRate, rule.CallerBurst, now)
+			l.callers[key] = cb
+		}
+		if wait, ok := cb.take(now); !ok {
+			return exhausted(wait, "caller %s exceeded %g requests/s on %s", caller, rule.CallerRate, method)
+		}
+	}
+	if rule.Rate > 0 {
+		mb := l.methods[method]
+		if mb == nil {
+			mb = newBucket(rule.Rate, rule.Burst, now)
+			l.methods[method] = mb
+		}
+		if wait, ok := mb.take(now); !ok {
+			if cb != nil {
+				cb.giveBack()
+			}
+			return exhausted(wait, "%s exceeded %g requests/s", method, rule.Rate)
+		}
+	}
+	return nil
+}
+
+// sweep 需要持有 mu
+func (l *Limiter) sweep(now time.Time) {
+	if len(l.callers) < maxIdleBuckets {
+		return
+	}
+	for key, b := range l.callers {
+		if b.full(now) {
+			delete(l.callers, key)
+		}
+	}
+}
+
+func (l *Limiter) checkUnary(ctx context.Context, method string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	rule := l.cfg.match(method)
+	if rule == nil {
+		return nil
+	}
+	return l.allow(rule, method, Caller(ctx))
+}
+
+// acquireStream 检查并发流数和速率，成功时返回的 release 必须在流结束时调用
+func (l *Limiter) acquireStream(ctx context.Context, method string) (func(), error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	rule := l.cfg.match(method)
+	if rule == nil {
+		return func() {}, nil
+	}
+	caller := Caller(ctx)
+	key := callerKey{method, caller}
+	if limit := rule.MaxStreamsPerCaller; limit > 0 && l.streams[key] >= limit {
+		return nil, exhausted(streamRetryDelay, "caller %s already has %d open streams on %s", caller, limit, method)
+	}
+	if err := l.allow(rule, method, caller); err != nil {
+		return nil, err
+	}
+	l.streams[key]++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			if l.streams[key]--; l.streams[key] <= 0 {
+				delete(l.streams, key)
+			}
+		})
+	}, nil
+}
+
+func (l *Limiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := l.checkUnary(ctx, info.FullMethod); err != nil {
+			grpc.SetTrailer(ctx, pushback(err))
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func (l *Limiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		release, err := l.acquireStream(ss.Context(), info.FullMethod)
+		if err != nil {
+			ss.SetTrailer(pushback(err))
+			return err
+		}
+		defer release()
+		return handler(srv, ss)
+	}
+}
+
+func exhausted(wait time.Duration, format string, args ...any) error {
+	st, err := status.New(codes.ResourceExhausted, fmt.Sprintf(format, args...)).
+		WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(wait)})
+	if err != nil {
+		return status.Errorf(codes.ResourceExhausted, format, args...)
+	}
+	return st.Err()
+}
+
+// pushback 把 RetryInfo 中的等待时间同时放进 grpc-retry-pushback-ms，
+// 这样只配置了 service config 重试的 gRPC 客户端也会按服务端的建议等待
+func pushback(err error) metadata.MD {
+	for _, d := range status.Convert(err).Details() {
+		if ri, ok := d.(*errdetails.RetryInfo); ok {
+			return metadata.Pairs(PushbackKey, strconv.FormatInt(ri.RetryDelay.AsDuration().Milliseconds(), 10))
+		}
+	}
+	return nil
+}
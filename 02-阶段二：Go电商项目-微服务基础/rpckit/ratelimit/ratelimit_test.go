@@ -0,0 +1,313 @@
+/**
+ * @File : ratelimit_test.go
+ * @Description : 测试令牌桶的补充和容量、规则匹配、令牌归还、并发流的释放、RetryInfo 和配置热加载
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package ratelimit
+
+import (
+	"context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	testpb "google.golang.org/grpc/interop/grpc_testing"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"os"
+	"path/filepath"
+	"rpckit/grpctest"
+	"testing"
+	"time"
+)
+
+const unaryMethod = "/grpc.testing.TestService/UnaryCall"
+
+// clock 是可以手动拨动的时间，测试不用真的等待令牌补充
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time { return c.now }
+
+func (c *clock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newLimiter(t *testing.T, config string) (*Limiter, *clock) {
+	t.Helper()
+	cfg, err := ParseConfig([]byte(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := New(cfg)
+	c := &clock{now: time.Unix(1700000000, 0)}
+	l.now = c.Now
+	return l, c
+}
+
+func callerCtx(caller string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(CallerKey, caller))
+}
+
+func TestBucket(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	b := newBucket(2, 3, now)
+	for i := 0; i < 3; i++ {
+		if _, ok := b.take(now); !ok {
+			t.Fatalf("take %d within burst failed", i)
+		}
+	}
+	wait, ok := b.take(now)
+	if ok || wait != 500*time.Millisecond {
+		t.Errorf("take on an empty bucket = (%v, %v), expect (500ms, false)", wait, ok)
+	}
+	// 0.5s 补充 1 个令牌
+	if _, ok := b.take(now.Add(500 * time.Millisecond)); !ok {
+		t.Errorf("take after refilling one token failed")
+	}
+	// 空闲再久也只攒到 burst
+	now = now.Add(time.Hour)
+	if !b.full(now) || b.tokens != 3 {
+		t.Errorf("tokens after an hour = %g, expect burst 3", b.tokens)
+	}
+	// 缩小容量时多余的令牌丢掉，放大容量时不会凭空多出令牌
+	b.setLimit(2, 1, now)
+	b.setLimit(2, 5, now)
+	if b.tokens != 1 {
+		t.Errorf("tokens after shrinking and growing burst = %g, expect 1", b.tokens)
+	}
+}
+
+func TestRules(t *testing.T) {
+	l, _ := newLimiter(t, `{"rules": [
+		{"method": "*", "rate": 100},
+		{"method": "/grpc.testing.TestService/*", "rate": 3},
+		{"method": "/grpc.testing.TestService/EmptyCall", "callerRate": 1}
+	]}`)
+	tests := []struct {
+		method string
+		expect string
+	}{
+		{"/grpc.testing.TestService/EmptyCall", "/grpc.testing.TestService/EmptyCall"},
+		{unaryMethod, "/grpc.testing.TestService/*"},
+		{"/other.Service/Call", "*"},
+	}
+	for _, tt := range tests {
+		if got := l.cfg.match(tt.method); got == nil || got.Method != tt.expect {
+			t.Errorf("match(%s) = %+v, expect rule %s", tt.method, got, tt.expect)
+		}
+	}
+
+	// UnaryCall 的限制是整个方法共享的，换一个调用方也没有用
+	for i, caller := range []string{"a", "b", "c", "d"} {
+		err := l.checkUnary(callerCtx(caller), unaryMethod)
+		if allowed := err == nil; allowed != (i < 3) {
+			t.Errorf("%s call %d by %s: err = %v", unaryMethod, i, caller, err)
+		}
+	}
+	// EmptyCall 按调用方分别限制，一个调用方用完不影响另一个
+	method := "/grpc.testing.TestService/EmptyCall"
+	steps := []struct {
+		caller  string
+		allowed bool
+	}{{"a", true}, {"a", false}, {"b", true}, {"b", false}}
+	for _, st := range steps {
+		if err := l.checkUnary(callerCtx(st.caller), method); (err == nil) != st.allowed {
+			t.Errorf("%s by %s: err = %v, expect allowed %v", method, st.caller, err, st.allowed)
+		}
+	}
+}
+
+func TestGiveBack(t *testing.T) {
+	// 调用方令牌补充得慢，方法令牌补充得快：被方法限制拒绝的请求要把调用方的令牌还回去
+	l, c := newLimiter(t, `{"rules": [{"method": "*", "rate": 1, "callerRate": 0.5, "callerBurst": 2}]}`)
+	ctx := callerCtx("a")
+	if err := l.checkUnary(ctx, unaryMethod); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	if err := l.checkUnary(ctx, unaryMethod); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("second call err = %v, expect ResourceExhausted from the method limit", err)
+	}
+	// 1s 后方法补充 1 个令牌，调用方只补充 0.5 个，没有归还的话这次会被调用方限制拒绝
+	c.Advance(time.Second)
+	if err := l.checkUnary(ctx, unaryMethod); err != nil {
+		t.Errorf("call after the method refilled: %v, the caller token was not given back", err)
+	}
+}
+
+// streamServer 的 StreamingOutputCall 一直阻塞到 finish 关闭
+type streamServer struct {
+	testpb.UnimplementedTestServiceServer
+	started chan struct{}
+	finish  chan struct{}
+}
+
+func (s *streamServer) UnaryCall(context.Context, *testpb.SimpleRequest) (*testpb.SimpleResponse, error) {
+	return &testpb.SimpleResponse{}, nil
+}
+
+func (s *streamServer) StreamingOutputCall(_ *testpb.StreamingOutputCallRequest, stream testpb.TestService_StreamingOutputCallServer) error {
+	s.started <- struct{}{}
+	select {
+	case <-s.finish:
+		return nil
+	case <-stream.Context().Done():
+		return stream.Context().Err()
+	}
+}
+
+func start(t *testing.T, l *Limiter) (testpb.TestServiceClient, *streamServer) {
+	t.Helper()
+	srv := &streamServer{started: make(chan struct{}, 1), finish: make(chan struct{})}
+	conn := grpctest.Start(t, func(s *grpc.Server) { testpb.RegisterTestServiceServer(s, srv) },
+		grpctest.WithServerOptions(
+			grpc.ChainUnaryInterceptor(l.UnaryServerInterceptor()),
+			grpc.ChainStreamInterceptor(l.StreamServerInterceptor()),
+		))
+	return testpb.NewTestServiceClient(conn), srv
+}
+
+// openStream 打开一个流，被拒绝时返回错误，成功时等服务端开始处理后返回
+func openStream(t *testing.T, ctx context.Context, client testpb.TestServiceClient, srv *streamServer) (testpb.TestService_StreamingOutputCallClient, error) {
+	t.Helper()
+	stream, err := client.StreamingOutputCall(ctx, &testpb.StreamingOutputCallRequest{})
+	if err != nil {
+		return nil, err
+	}
+	errs := make(chan error, 1)
+	go func() {
+		_, err := stream.Recv()
+		errs <- err
+	}()
+	select {
+	case <-srv.started:
+		return stream, nil
+	case err := <-errs:
+		return nil, err
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream neither started nor failed")
+		return nil, nil
+	}
+}
+
+func TestStreamRelease(t *testing.T) {
+	l, _ := newLimiter(t, `{"rules": [{"method": "*", "maxStreamsPerCaller": 1}]}`)
+	client, srv := start(t, l)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), CallerKey, "a")
+
+	if _, err := openStream(t, ctx, client, srv); err != nil {
+		t.Fatalf("first stream: %v", err)
+	}
+	_, err := openStream(t, ctx, client, srv)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("second concurrent stream err = %v, expect ResourceExhausted", err)
+	}
+	// 其他调用方不受影响
+	other := metadata.AppendToOutgoingContext(context.Background(), CallerKey, "b")
+	otherCtx, cancelOther := context.WithCancel(other)
+	if _, err := openStream(t, otherCtx, client, srv); err != nil {
+		t.Fatalf("stream of another caller: %v", err)
+	}
+	cancelOther()
+
+	// 流结束后名额释放，释放发生在服务端的 goroutine 中，等到计数清零再打开新的流
+	close(srv.finish)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		l.mu.Lock()
+		open := len(l.streams)
+		l.mu.Unlock()
+		if open == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d stream slots still held after the streams ended", open)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if _, err := openStream(t, cctx, client, srv); err != nil {
+		t.Errorf("stream after the first one ended: %v", err)
+	}
+}
+
+func TestRetryInfo(t *testing.T) {
+	l, _ := newLimiter(t, `{"rules": [{"method": "*", "callerRate": 2, "callerBurst": 1}]}`)
+	client, _ := start(t, l)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), CallerKey, "a")
+	if _, err := client.UnaryCall(ctx, &testpb.SimpleRequest{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var trailer metadata.MD
+	_, err := client.UnaryCall(ctx, &testpb.SimpleRequest{}, grpc.Trailer(&trailer))
+	st := status.Convert(err)
+	if st.Code() != codes.ResourceExhausted {
+		t.Fatalf("err = %v, expect ResourceExhausted", err)
+	}
+	var retry *errdetails.RetryInfo
+	for _, d := range st.Details() {
+		if ri, ok := d.(*errdetails.RetryInfo); ok {
+			retry = ri
+		}
+	}
+	// 时间是假的，没有流逝，下一个令牌正好在 1/2s 后补充
+	if retry == nil || retry.RetryDelay.AsDuration() != 500*time.Millisecond {
+		t.Errorf("RetryInfo = %v, expect a 500ms delay", retry)
+	}
+	if got := trailer.Get(PushbackKey); len(got) != 1 || got[0] != "500" {
+		t.Errorf("%s = %v, expect [500]", PushbackKey, got)
+	}
+}
+
+func TestWatchFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "limits.json")
+	write := func(config string, mtime time.Time) {
+		if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		// 显式设置修改时间，避免两次写入落在文件系统时间精度的同一个刻度上
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := WatchFile(path, time.Millisecond); err == nil {
+		t.Errorf("WatchFile on a missing file should fail")
+	}
+
+	mtime := time.Now().Add(-time.Minute)
+	write(`{"rules": [{"method": "*", "rate": 0.001, "burst": 2}]}`, mtime)
+	l, stop, err := WatchFile(path, 5*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stop()
+	ctx := callerCtx("a")
+	for i := 0; i < 2; i++ {
+		if err := l.checkUnary(ctx, unaryMethod); err != nil {
+			t.Fatalf("call %d within burst: %v", i, err)
+		}
+	}
+
+	write(`{"rules": [{"method": "*", "rate": 0.001, "burst": 5}]}`, mtime.Add(time.Second))
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		l.mu.Lock()
+		burst := l.cfg.Rules[0].Burst
+		l.mu.Unlock()
+		if burst == 5 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("config was not reloaded")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	// 重新加载只改变了容量，用完的令牌不会因此重新计满
+	if err := l.checkUnary(ctx, unaryMethod); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("call after reload err = %v, expect ResourceExhausted", err)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if b := l.methods[unaryMethod]; b == nil || b.burst != 5 {
+		t.Errorf("method bucket after reload = %+v, expect burst 5", b)
+	}
+}
@@ -0,0 +1,150 @@
+/**
+ * @File : registry.go
+ * @Description : 服务注册表：进程内的 Memory 和基于 JSON 文件的 File 两种实现
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package registry
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Endpoint 是服务的一个实例
+type Endpoint struct {
+	Addr   string `json:"addr"`
+	Weight int    `json:"weight,omitempty"`
+}
+
+// Source 提供服务实例列表，列表变化时调用 update；返回的 stop 用于取消订阅
+// Watch 返回之前至少要回调一次当前的列表（可能为空）
+type Source interface {
+	Watch(service string, update func([]Endpoint)) (stop func())
+}
+
+// Memory 是进程内注册表，适合测试和单进程演示
+type Memory struct {
+	mu       sync.Mutex
+	services map[string][]Endpoint
+	watchers map[string]map[int]func([]Endpoint)
+	nextID   int
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		services: make(map[string][]Endpoint),
+		watchers: make(map[string]map[int]func([]Endpoint)),
+	}
+}
+
+// Set 覆盖某个服务的实例列表并通知所有订阅者
+func (m *Memory) Set(service string, eps []Endpoint) {
+	m.mu.Lock()
+	m.services[service] = append([]Endpoint(nil), eps...)
+	var fns []func([]Endpoint)
+	for _, fn := range m.watchers[service] {
+		fns = append(fns, fn)
+	}
+	m.mu.Unlock()
+	for _, fn := range fns {
+		fn(append([]Endpoint(nil), eps...))
+	}
+}
+
+func (m *Memory) Watch(service string, update func([]Endpoint)) func() {
+	m.mu.Lock()
+	id := m.nextID
+	m.nextID++
+	if m.watchers[service] == nil {
+		m.watchers[service] = make(map[int]func([]Endpoint))
+	}
+	m.watchers[service][id] = update
+	eps := append([]Endpoint(nil), m.services[service]...)
+	m.mu.Unlock()
+
+	update(eps)
+	return func() {
+		m.mu.Lock()
+		delete(m.watchers[service], id)
+		m.mu.Unlock()
+	}
+}
+
+// File 从 JSON 文件读取注册信息，并定期检查文件的修改时间，格式：
+//
+//	{"HelloService": [{"addr": "127.0.0.1:50051", "weight": 1}]}
+type File struct {
+	path     string
+	interval time.Duration
+}
+
+func NewFile(path string, interval time.Duration) *File {
+	return &File{path: path, interval: interval}
+}
+
+func (f *File) Watch(service string, update func([]Endpoint)) func() {
+	var modTime time.Time
+	var last []Endpoint
+	// reload 在回调了 update 时返回 true
+	reload := func(force bool) bool {
+		info, err := os.Stat(f.path)
+		if err != nil {
+			log.Printf("registry: stat %s: %v", f.path, err)
+			return false
+		}
+		if !force && info.ModTime().Equal(modTime) {
+			return false
+		}
+		all, err := f.read()
+		if err != nil {
+			// 文件写到一半时可能解析失败，保留上一次的列表，下次轮询再试
+			log.Printf("registry: read %s: %v", f.path, err)
+			return false
+		}
+		modTime = info.ModTime()
+		eps := all[service]
+		if !force && reflect.DeepEqual(eps, last) {
+			return false
+		}
+		last = eps
+		update(append([]Endpoint(nil), eps...))
+		return true
+	}
+
+	if !reload(true) {
+		// 第一次读取失败也要回调一次，让 resolver 知道当前没有可用实例
+		update(nil)
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(f.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				reload(false)
+			}
+		}
+	}()
+	var once sync.Once
+	return func() { once.Do(func() { close(done) }) }
+}
+
+func (f *File) read() (map[string][]Endpoint, error) {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return nil, err
+	}
+	var all map[string][]Endpoint
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	return all, nil
+}
@@ -0,0 +1,181 @@
+/**
+ * @File : registry_test.go
+ * @Description : 测试 Memory 和 File 注册表的订阅推送，以及 resolver 交给 gRPC 的地址和权重
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package registry
+
+import (
+	"fmt"
+	"google.golang.org/grpc/resolver"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"rpckit/loadbalance"
+	"sync"
+	"testing"
+	"time"
+)
+
+// updates 记录 Watch 回调收到的列表
+type updates struct {
+	mu   sync.Mutex
+	got  [][]Endpoint
+	next chan struct{}
+}
+
+func newUpdates() *updates {
+	return &updates{next: make(chan struct{}, 16)}
+}
+
+func (u *updates) update(eps []Endpoint) {
+	u.mu.Lock()
+	u.got = append(u.got, eps)
+	u.mu.Unlock()
+	u.next <- struct{}{}
+}
+
+func (u *updates) list() [][]Endpoint {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return append([][]Endpoint(nil), u.got...)
+}
+
+// wait 等到一共收到 n 次回调
+func (u *updates) wait(t *testing.T, n int) [][]Endpoint {
+	t.Helper()
+	deadline := time.After(5 * time.Second)
+	for len(u.list()) < n {
+		select {
+		case <-u.next:
+		case <-deadline:
+			t.Fatalf("got %d updates, expect %d", len(u.list()), n)
+		}
+	}
+	return u.list()
+}
+
+func TestMemoryWatch(t *testing.T) {
+	m := NewMemory()
+	m.Set("HelloService", []Endpoint{{Addr: "a:1"}})
+	u := newUpdates()
+	stop := m.Watch("HelloService", u.update)
+
+	m.Set("HelloService", []Endpoint{{Addr: "a:1"}, {Addr: "b:1", Weight: 3}})
+	m.Set("OtherService", []Endpoint{{Addr: "c:1"}})
+	m.Set("HelloService", nil)
+	stop()
+	m.Set("HelloService", []Endpoint{{Addr: "d:1"}})
+
+	expect := [][]Endpoint{
+		{{Addr: "a:1"}},
+		{{Addr: "a:1"}, {Addr: "b:1", Weight: 3}},
+		{},
+	}
+	if got := u.list(); fmt.Sprint(got) != fmt.Sprint(expect) {
+		t.Errorf("updates = %v, expect %v", got, expect)
+	}
+}
+
+func TestFileWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "registry.json")
+	mtime := time.Now().Add(-time.Minute)
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		// 每次写入都推后修改时间，避免落在文件系统时间精度的同一个刻度上
+		mtime = mtime.Add(time.Second)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 文件还不存在时也要回调一次空列表
+	u := newUpdates()
+	stop := NewFile(path, 5*time.Millisecond).Watch("HelloService", u.update)
+	defer stop()
+	if got := u.wait(t, 1); got[0] != nil {
+		t.Errorf("first update without a file = %v, expect nil", got[0])
+	}
+
+	write(`{"HelloService": [{"addr": "a:1", "weight": 2}]}`)
+	u.wait(t, 2)
+	// 只改了其他服务时不回调；写到一半的文件解析失败，保留上一次的列表
+	write(`{"HelloService": [{"addr": "a:1", "weight": 2}], "OtherService": [{"addr": "c:1"}]}`)
+	write(`{"HelloService": [`)
+	time.Sleep(50 * time.Millisecond)
+	write(`{"HelloService": [{"addr": "a:1", "weight": 2}, {"addr": "b:1"}]}`)
+	got := u.wait(t, 3)
+
+	expect := [][]Endpoint{
+		nil,
+		{{Addr: "a:1", Weight: 2}},
+		{{Addr: "a:1", Weight: 2}, {Addr: "b:1"}},
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("updates = %v, expect %v", got, expect)
+	}
+}
+
+// fakeClientConn 记录 resolver 推给 gRPC 的状态和错误
+type fakeClientConn struct {
+	resolver.ClientConn
+	states []resolver.State
+	errs   []error
+}
+
+func (c *fakeClientConn) UpdateState(s resolver.State) error {
+	c.states = append(c.states, s)
+	return nil
+}
+
+func (c *fakeClientConn) ReportError(err error) {
+	c.errs = append(c.errs, err)
+}
+
+func TestResolver(t *testing.T) {
+	m := NewMemory()
+	m.Set("HelloService", []Endpoint{{Addr: "a:1"}, {Addr: "b:1", Weight: 3}})
+	u, err := url.Parse("registry:///HelloService")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cc := &fakeClientConn{}
+	r, err := NewBuilder(m).Build(resolver.Target{URL: *u}, cc, resolver.BuildOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if len(cc.states) != 1 || len(cc.errs) != 0 {
+		t.Fatalf("after Build: %d states, errors %v, expect one state", len(cc.states), cc.errs)
+	}
+	// 权重放在地址的 BalancerAttributes 中，由 weighted_load 的 picker 读取
+	addrs := cc.states[0].Addresses
+	var got []string
+	for _, a := range addrs {
+		got = append(got, fmt.Sprintf("%s/%d", a.Addr, loadbalance.AddrWeight(a)))
+	}
+	if expect := []string{"a:1/1", "b:1/3"}; !reflect.DeepEqual(got, expect) {
+		t.Errorf("addresses = %v, expect %v", got, expect)
+	}
+
+	// 实例全部下线时报告错误，而不是推一个空列表
+	m.Set("HelloService", nil)
+	if len(cc.states) != 1 || len(cc.errs) != 1 {
+		t.Errorf("after all endpoints left: %d states, errors %v, expect one error", len(cc.states), cc.errs)
+	}
+
+	r.Close()
+	m.Set("HelloService", []Endpoint{{Addr: "a:1"}})
+	if len(cc.states) != 1 {
+		t.Errorf("resolver still updated after Close")
+	}
+
+	if _, err := NewBuilder(m).Build(resolver.Target{URL: url.URL{Scheme: Scheme}}, cc, resolver.BuildOptions{}); err == nil {
+		t.Errorf("Build without a service name should fail")
+	}
+}
@@ -0,0 +1,62 @@
+/**
+ * @File : resolver.go
+ * @Description : registry:///<service> 形式的 gRPC resolver，把注册表中的实例交给负载均衡器
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package registry
+
+import (
+	"errors"
+	"google.golang.org/grpc/resolver"
+	"rpckit/loadbalance"
+	"strings"
+)
+
+// Scheme 是 resolver 的 scheme，目标地址写成 registry:///HelloService
+const Scheme = "registry"
+
+// NewBuilder 返回基于 src 的 resolver.Builder，通过 grpc.WithResolvers 传给客户端
+func NewBuilder(src Source) resolver.Builder {
+	return &builder{src: src}
+}
+
+type builder struct {
+	src Source
+}
+
+func (b *builder) Scheme() string { return Scheme }
+
+func (b *builder) Build(target resolver.Target, cc resolver.ClientConn, _ resolver.BuildOptions) (resolver.Resolver, error) {
+	service := strings.TrimPrefix(target.Endpoint(), "/")
+	if service == "" {
+		return nil, errors.New("registry: target must look like registry:///<service>")
+	}
+	r := &registryResolver{cc: cc}
+	r.stop = b.src.Watch(service, r.update)
+	return r, nil
+}
+
+type registryResolver struct {
+	cc   resolver.ClientConn
+	stop func()
+}
+
+func (r *registryResolver) update(eps []Endpoint) {
+	if len(eps) == 0 {
+		r.cc.ReportError(errors.New("registry: no endpoints registered"))
+		return
+	}
+	addrs := make([]resolver.Address, 0, len(eps))
+	for _, ep := range eps {
+		addrs = append(addrs, loadbalance.SetAddrWeight(resolver.Address{Addr: ep.Addr}, ep.Weight))
+	}
+	if err := r.cc.UpdateState(resolver.State{Addresses: addrs}); err != nil {
+		r.cc.ReportError(err)
+	}
+}
+
+// ResolveNow 不需要做什么，注册表变化时会主动推送
+func (r *registryResolver) ResolveNow(resolver.ResolveNowOptions) {}
+
+func (r *registryResolver) Close() { r.stop() }
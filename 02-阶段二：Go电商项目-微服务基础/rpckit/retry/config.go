@@ -0,0 +1,162 @@
+/**
+ * @File : config.go
+ * @Description : 读取 gRPC service config 格式的 JSON，解析出每个方法的重试、对冲和超时策略
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package retry
+
+import (
+	"encoding/json"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duration 对应 service config 中 "0.1s" 这种写法
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil || !strings.HasSuffix(s, "s") {
+		return fmt.Errorf("retry: invalid duration %s", b)
+	}
+	secs, err := strconv.ParseFloat(strings.TrimSuffix(s, "s"), 64)
+	if err != nil {
+		return fmt.Errorf("retry: invalid duration %s", b)
+	}
+	*d = Duration(secs * float64(time.Second))
+	return nil
+}
+
+// Name 为空的 Method 表示整个服务，Service 也为空表示所有方法的默认配置
+type Name struct {
+	Service string `json:"service"`
+	Method  string `json:"method"`
+}
+
+type RetryPolicy struct {
+	MaxAttempts          int          `json:"maxAttempts"`
+	InitialBackoff       Duration     `json:"initialBackoff"`
+	MaxBackoff           Duration     `json:"maxBackoff"`
+	BackoffMultiplier    float64      `json:"backoffMultiplier"`
+	RetryableStatusCodes []codes.Code `json:"retryableStatusCodes"`
+}
+
+// HedgingPolicy 是 gRPC 规范中的对冲策略，grpc-go 本身没有实现，由本包的一元拦截器执行
+type HedgingPolicy struct {
+	MaxAttempts         int          `json:"maxAttempts"`
+	HedgingDelay        Duration     `json:"hedgingDelay"`
+	NonFatalStatusCodes []codes.Code `json:"nonFatalStatusCodes"`
+}
+
+type MethodConfig struct {
+	Name          []Name         `json:"name"`
+	Timeout       *Duration      `json:"timeout"`
+	RetryPolicy   *RetryPolicy   `json:"retryPolicy"`
+	HedgingPolicy *HedgingPolicy `json:"hedgingPolicy"`
+}
+
+// Policy 同时持有原始 JSON（交给 gRPC）和解析后的配置（交给拦截器）
+type Policy struct {
+	raw     string
+	methods map[Name]*MethodConfig
+	metrics *Metrics
+}
+
+// Load 从文件中读取 service config
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse 解析 service config，并做 gRPC 不会替我们做的检查（对冲策略）
+func Parse(data []byte) (*Policy, error) {
+	var sc struct {
+		MethodConfig []*MethodConfig `json:"methodConfig"`
+	}
+	if err := json.Unmarshal(data, &sc); err != nil {
+		return nil, fmt.Errorf("retry: parse service config: %w", err)
+	}
+	p := &Policy{raw: string(data), methods: make(map[Name]*MethodConfig), metrics: NewMetrics()}
+	for _, mc := range sc.MethodConfig {
+		if mc.RetryPolicy != nil && mc.HedgingPolicy != nil {
+			return nil, fmt.Errorf("retry: %v has both retryPolicy and hedgingPolicy", mc.Name)
+		}
+		if hp := mc.HedgingPolicy; hp != nil && hp.MaxAttempts < 2 {
+			return nil, fmt.Errorf("retry: hedgingPolicy.maxAttempts must be at least 2 for %v", mc.Name)
+		}
+		for _, n := range mc.Name {
+			if n.Service == "" && n.Method != "" {
+				return nil, fmt.Errorf("retry: method %q without service", n.Method)
+			}
+			p.methods[n] = mc
+		}
+	}
+	return p, nil
+}
+
+// lookup 按 gRPC 的规则查找：先精确匹配方法，再匹配服务，最后是默认配置
+func (p *Policy) lookup(fullMethod string) *MethodConfig {
+	service, method, _ := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	for _, n := range []Name{{service, method}, {service, ""}, {}} {
+		if mc, ok := p.methods[n]; ok {
+			return mc
+		}
+	}
+	return nil
+}
+
+// WithLoadBalancing 在 service config 中加入 loadBalancingConfig。
+// 客户端只能有一份默认 service config，需要同时指定负载均衡策略时用它合并
+func (p *Policy) WithLoadBalancing(name string) (*Policy, error) {
+	var sc map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(p.raw), &sc); err != nil {
+		return nil, err
+	}
+	if sc == nil {
+		sc = make(map[string]json.RawMessage)
+	}
+	lb, err := json.Marshal([]map[string]struct{}{{name: {}}})
+	if err != nil {
+		return nil, err
+	}
+	sc["loadBalancingConfig"] = lb
+	raw, err := json.Marshal(sc)
+	if err != nil {
+		return nil, err
+	}
+	return &Policy{raw: string(raw), methods: p.methods, metrics: p.metrics}, nil
+}
+
+// Metrics 返回按方法统计的尝试次数
+func (p *Policy) Metrics() *Metrics {
+	return p.metrics
+}
+
+// DialOptions 返回客户端需要的全部选项：
+// service config 交给 gRPC 执行内置的一元重试和超时，拦截器负责对冲、流式调用的重试和统计尝试次数
+func (p *Policy) DialOptions() []grpc.DialOption {
+	return []grpc.DialOption{
+		grpc.WithDefaultServiceConfig(p.raw),
+		grpc.WithChainUnaryInterceptor(p.UnaryClientInterceptor()),
+		grpc.WithChainStreamInterceptor(p.StreamClientInterceptor()),
+		grpc.WithStatsHandler(attemptCounter{}),
+	}
+}
+
+func contains(list []codes.Code, c codes.Code) bool {
+	for _, x := range list {
+		if x == c {
+			return true
+		}
+	}
+	return false
+}
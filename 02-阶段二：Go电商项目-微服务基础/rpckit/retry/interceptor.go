@@ -0,0 +1,134 @@
+/**
+ * @File : interceptor.go
+ * @Description : 一元调用的客户端拦截器：统一超时、执行对冲策略并记录尝试次数，普通重试交给 gRPC 内置实现
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package retry
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"time"
+)
+
+// UnaryClientInterceptor 返回一元调用的拦截器。
+// 超时在这里再套一层，是为了让对冲发出的多个尝试共用同一个截止时间
+func (p *Policy) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		mc := p.lookup(method)
+		ctx, attempts := withAttemptCounter(ctx)
+		if mc != nil && mc.Timeout != nil {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, time.Duration(*mc.Timeout))
+			defer cancel()
+		}
+
+		var err error
+		if m, ok := reply.(proto.Message); ok && mc != nil && mc.HedgingPolicy != nil {
+			err = hedge(ctx, mc.HedgingPolicy, method, req, m, cc, invoker, opts...)
+		} else {
+			err = invoker(ctx, method, req, reply, cc, opts...)
+		}
+		p.metrics.observe(method, int(attempts.Load()), err != nil)
+		return err
+	}
+}
+
+// hedge 先发出一个尝试，每过 hedgingDelay 还没有结果就再发一个，直到 maxAttempts；
+// 某个尝试以非致命状态码失败时立即补发下一个。第一个成功的结果胜出，其余尝试被取消。
+// 对冲会让服务端收到重复请求，只应该配置在幂等的方法上
+func hedge(ctx context.Context, hp *HedgingPolicy, method string, req any, reply proto.Message, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		reply  proto.Message
+		commit func()
+		err    error
+	}
+	// 缓冲足够大，落败的尝试返回时不会阻塞
+	results := make(chan result, hp.MaxAttempts)
+	launched, pending := 0, 0
+	launch := func() {
+		launched++
+		pending++
+		r := reply.ProtoReflect().New().Interface()
+		attemptOpts, commit := isolate(opts)
+		go func() {
+			results <- result{reply: r, commit: commit, err: invoker(ctx, method, req, r, cc, attemptOpts...)}
+		}()
+	}
+
+	launch()
+	timer := time.NewTimer(time.Duration(hp.HedgingDelay))
+	defer timer.Stop()
+	var lastErr error
+	for pending > 0 {
+		select {
+		case <-timer.C:
+			if launched < hp.MaxAttempts {
+				launch()
+				timer.Reset(time.Duration(hp.HedgingDelay))
+			}
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				r.commit()
+				proto.Reset(reply)
+				proto.Merge(reply, r.reply)
+				return nil
+			}
+			lastErr = r.err
+			r.commit()
+			if !contains(hp.NonFatalStatusCodes, status.Code(r.err)) {
+				return r.err
+			}
+			if launched < hp.MaxAttempts {
+				launch()
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(time.Duration(hp.HedgingDelay))
+			}
+		}
+	}
+	return lastErr
+}
+
+// isolate 让每个尝试把 Header、Trailer 和 Peer 写到自己的副本里，避免并发写调用方的变量；
+// commit 把某个尝试的结果拷回调用方
+func isolate(opts []grpc.CallOption) ([]grpc.CallOption, func()) {
+	out := make([]grpc.CallOption, 0, len(opts))
+	var commits []func()
+	for _, o := range opts {
+		switch o := o.(type) {
+		case grpc.HeaderCallOption:
+			md := new(metadata.MD)
+			out = append(out, grpc.Header(md))
+			commits = append(commits, func() { *o.HeaderAddr = *md })
+		case grpc.TrailerCallOption:
+			md := new(metadata.MD)
+			out = append(out, grpc.Trailer(md))
+			commits = append(commits, func() { *o.TrailerAddr = *md })
+		case grpc.PeerCallOption:
+			p := new(peer.Peer)
+			out = append(out, grpc.Peer(p))
+			commits = append(commits, func() { *o.PeerAddr = *p })
+		default:
+			out = append(out, o)
+		}
+	}
+	return out, func() {
+		for _, c := range commits {
+			c()
+		}
+	}
+}
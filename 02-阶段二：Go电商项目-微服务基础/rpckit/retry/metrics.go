@@ -0,0 +1,124 @@
+/**
+ * @File : metrics.go
+ * @Description : 统计每次调用实际发出了几次尝试（包括 gRPC 内置重试和拦截器发起的重试、对冲）
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package retry
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/grpc/stats"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// maxTracked 以上的尝试次数都记在最后一个桶里
+const maxTracked = 10
+
+type methodStats struct {
+	calls    uint64
+	failures uint64
+	attempts uint64
+	// perCall[i] 是恰好尝试了 i+1 次的调用数
+	perCall [maxTracked]uint64
+}
+
+// Metrics 并发安全
+type Metrics struct {
+	mu      sync.Mutex
+	methods map[string]*methodStats
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{methods: make(map[string]*methodStats)}
+}
+
+func (m *Metrics) observe(method string, attempts int, failed bool) {
+	if attempts < 1 {
+		attempts = 1
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.methods[method]
+	if !ok {
+		s = &methodStats{}
+		m.methods[method] = s
+	}
+	s.calls++
+	s.attempts += uint64(attempts)
+	if failed {
+		s.failures++
+	}
+	s.perCall[min(attempts, maxTracked)-1]++
+}
+
+// MethodSnapshot 是单个方法的统计
+type MethodSnapshot struct {
+	Calls    uint64
+	Failures uint64
+	Attempts uint64
+	// PerCall[i] 是恰好尝试了 i+1 次的调用数，最后一个元素包含更多次的情况
+	PerCall []uint64
+}
+
+// Snapshot 返回所有方法的统计
+func (m *Metrics) Snapshot() map[string]MethodSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make(map[string]MethodSnapshot, len(m.methods))
+	for name, s := range m.methods {
+		out[name] = MethodSnapshot{
+			Calls:    s.calls,
+			Failures: s.failures,
+			Attempts: s.attempts,
+			PerCall:  append([]uint64(nil), s.perCall[:]...),
+		}
+	}
+	return out
+}
+
+// String 按方法名排序输出，便于在命令行示例中直接打印
+func (m *Metrics) String() string {
+	snap := m.Snapshot()
+	names := make([]string, 0, len(snap))
+	for name := range snap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		s := snap[name]
+		fmt.Fprintf(&b, "%s calls=%d failures=%d attempts=%d per_call=%v\n", name, s.Calls, s.Failures, s.Attempts, s.PerCall)
+	}
+	return b.String()
+}
+
+type attemptsKey struct{}
+
+// withAttemptCounter 在拦截器中调用，之后每一次尝试都会由 attemptCounter 加一
+func withAttemptCounter(ctx context.Context) (context.Context, *atomic.Int32) {
+	n := new(atomic.Int32)
+	return context.WithValue(ctx, attemptsKey{}, n), n
+}
+
+// attemptCounter 是 stats.Handler，gRPC 每发起一次尝试（包括内置重试）都会调用一次 TagRPC
+type attemptCounter struct{}
+
+func (attemptCounter) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	if n, ok := ctx.Value(attemptsKey{}).(*atomic.Int32); ok {
+		n.Add(1)
+	}
+	return ctx
+}
+
+func (attemptCounter) HandleRPC(context.Context, stats.RPCStats) {}
+
+func (attemptCounter) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (attemptCounter) HandleConn(context.Context, stats.ConnStats) {}
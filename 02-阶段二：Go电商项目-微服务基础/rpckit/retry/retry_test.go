@@ -0,0 +1,223 @@
+/**
+ * @File : retry_test.go
+ * @Description : 通过 bufconn 测试对冲（胜出后取消其余尝试、致命和非致命状态码）、流式重放的缓存上限和尝试次数统计
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package retry
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	testpb "google.golang.org/grpc/interop/grpc_testing"
+	"google.golang.org/grpc/status"
+	"io"
+	"rpckit/grpctest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const (
+	unaryMethod  = "/grpc.testing.TestService/UnaryCall"
+	streamMethod = "/grpc.testing.TestService/StreamingInputCall"
+)
+
+// block 表示这次尝试一直等到被客户端取消
+const block = codes.Code(1000)
+
+// testServer 按到达顺序给每次尝试分配 codes 中的结果，超出部分都成功
+type testServer struct {
+	testpb.UnimplementedTestServiceServer
+	codes     []codes.Code
+	calls     atomic.Int32
+	cancelled atomic.Int32
+}
+
+func (s *testServer) next() codes.Code {
+	if i := int(s.calls.Add(1)) - 1; i < len(s.codes) {
+		return s.codes[i]
+	}
+	return codes.OK
+}
+
+func (s *testServer) UnaryCall(ctx context.Context, _ *testpb.SimpleRequest) (*testpb.SimpleResponse, error) {
+	switch code := s.next(); code {
+	case codes.OK:
+		return &testpb.SimpleResponse{}, nil
+	case block:
+		<-ctx.Done()
+		s.cancelled.Add(1)
+		return nil, ctx.Err()
+	default:
+		return nil, status.Error(code, "injected")
+	}
+}
+
+// StreamingInputCall 先读完所有请求再决定结果，这样客户端发送时不会提前收到错误
+func (s *testServer) StreamingInputCall(stream testpb.TestService_StreamingInputCallServer) error {
+	var size int32
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		size += int32(len(req.GetPayload().GetBody()))
+	}
+	if code := s.next(); code != codes.OK {
+		return status.Error(code, "injected")
+	}
+	return stream.SendAndClose(&testpb.StreamingInputCallResponse{AggregatedPayloadSize: size})
+}
+
+func start(t *testing.T, srv *testServer, config string) (testpb.TestServiceClient, *Policy) {
+	t.Helper()
+	p, err := Parse([]byte(config))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := grpctest.Start(t, func(s *grpc.Server) { testpb.RegisterTestServiceServer(s, srv) },
+		grpctest.WithDialOptions(p.DialOptions()...))
+	return testpb.NewTestServiceClient(conn), p
+}
+
+// eventually 轮询 cond 直到成立或超时，服务端感知取消是异步的
+func eventually(cond func() bool) bool {
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return true
+}
+
+func TestHedge(t *testing.T) {
+	tests := []struct {
+		name      string
+		delay     string
+		codes     []codes.Code
+		code      codes.Code
+		attempts  int32
+		cancelled int32
+		// 非致命状态码应该立即补发，不用等 hedgingDelay
+		maxElapsed time.Duration
+	}{
+		{"first attempt succeeds", "0.05s", nil, codes.OK, 1, 0, time.Second},
+		{"hedged attempt wins and cancels the slow one", "0.05s", []codes.Code{block}, codes.OK, 2, 1, time.Second},
+		{"winner cancels every other attempt", "0.05s", []codes.Code{block, block}, codes.OK, 3, 2, time.Second},
+		{"non-fatal code launches the next attempt at once", "10s", []codes.Code{codes.Unavailable}, codes.OK, 2, 0, time.Second},
+		{"fatal code stops hedging", "10s", []codes.Code{codes.InvalidArgument}, codes.InvalidArgument, 1, 0, time.Second},
+		{"fatal code cancels the slow attempt", "0.05s", []codes.Code{block, codes.PermissionDenied}, codes.PermissionDenied, 2, 1, time.Second},
+		{"non-fatal codes exhaust maxAttempts", "10s", []codes.Code{codes.Unavailable, codes.Unavailable, codes.Unavailable}, codes.Unavailable, 3, 0, time.Second},
+	}
+	for _, tt := range tests {
+		srv := &testServer{codes: tt.codes}
+		client, p := start(t, srv, `{"methodConfig": [{
+			"name": [{"service": "grpc.testing.TestService", "method": "UnaryCall"}],
+			"timeout": "5s",
+			"hedgingPolicy": {"maxAttempts": 3, "hedgingDelay": "`+tt.delay+`", "nonFatalStatusCodes": ["UNAVAILABLE"]}
+		}]}`)
+
+		begin := time.Now()
+		_, err := client.UnaryCall(context.Background(), &testpb.SimpleRequest{})
+		if code := status.Code(err); code != tt.code {
+			t.Errorf("%s: UnaryCall() error = %v, expect code %v", tt.name, err, tt.code)
+		}
+		if elapsed := time.Since(begin); elapsed > tt.maxElapsed {
+			t.Errorf("%s: UnaryCall() took %v, expect at most %v", tt.name, elapsed, tt.maxElapsed)
+		}
+		if n := srv.calls.Load(); n != tt.attempts {
+			t.Errorf("%s: server saw %d attempts, expect %d", tt.name, n, tt.attempts)
+		}
+		if !eventually(func() bool { return srv.cancelled.Load() == tt.cancelled }) {
+			t.Errorf("%s: %d attempts were cancelled, expect %d", tt.name, srv.cancelled.Load(), tt.cancelled)
+		}
+
+		s := p.Metrics().Snapshot()[unaryMethod]
+		failures := uint64(0)
+		if tt.code != codes.OK {
+			failures = 1
+		}
+		if s.Calls != 1 || s.Failures != failures || s.Attempts != uint64(tt.attempts) || s.PerCall[tt.attempts-1] != 1 {
+			t.Errorf("%s: metrics = %+v, expect 1 call, %d failures, %d attempts", tt.name, s, failures, tt.attempts)
+		}
+	}
+}
+
+func TestStreamReplay(t *testing.T) {
+	const chunk = 256 << 10
+	tests := []struct {
+		name     string
+		codes    []codes.Code
+		chunks   int
+		code     codes.Code
+		attempts int32
+	}{
+		// 超过 256KB 后 gRPC 内置重试已经提交，重试由拦截器重放完成
+		{"replayed after gRPC commits", []codes.Code{codes.Unavailable}, 4, codes.OK, 2},
+		{"replayed until maxAttempts", []codes.Code{codes.Unavailable, codes.Unavailable, codes.Unavailable}, 4, codes.Unavailable, 3},
+		{"over the replay limit is not retried", []codes.Code{codes.Unavailable}, maxReplayBytes/chunk + 1, codes.Unavailable, 1},
+		{"non-retryable code", []codes.Code{codes.InvalidArgument}, 4, codes.InvalidArgument, 1},
+	}
+	for _, tt := range tests {
+		srv := &testServer{codes: tt.codes}
+		client, p := start(t, srv, `{"methodConfig": [{
+			"name": [{"service": "grpc.testing.TestService"}],
+			"timeout": "10s",
+			"retryPolicy": {"maxAttempts": 3, "initialBackoff": "0.01s", "maxBackoff": "0.05s", "backoffMultiplier": 2, "retryableStatusCodes": ["UNAVAILABLE"]}
+		}]}`)
+
+		stream, err := client.StreamingInputCall(context.Background())
+		if err != nil {
+			t.Fatalf("%s: StreamingInputCall() error = %v", tt.name, err)
+		}
+		for i := 0; i < tt.chunks; i++ {
+			if err := stream.Send(&testpb.StreamingInputCallRequest{Payload: &testpb.Payload{Body: make([]byte, chunk)}}); err != nil {
+				t.Fatalf("%s: Send() error = %v", tt.name, err)
+			}
+		}
+		resp, err := stream.CloseAndRecv()
+		if code := status.Code(err); code != tt.code {
+			t.Errorf("%s: CloseAndRecv() error = %v, expect code %v", tt.name, err, tt.code)
+		}
+		// 重放的流要把全部消息重新送到服务端
+		if err == nil && resp.GetAggregatedPayloadSize() != int32(tt.chunks*chunk) {
+			t.Errorf("%s: server received %d bytes, expect %d", tt.name, resp.GetAggregatedPayloadSize(), tt.chunks*chunk)
+		}
+		if n := srv.calls.Load(); n != tt.attempts {
+			t.Errorf("%s: server saw %d attempts, expect %d", tt.name, n, tt.attempts)
+		}
+		s := p.Metrics().Snapshot()[streamMethod]
+		if s.Calls != 1 || s.Attempts != uint64(tt.attempts) || (s.Failures == 1) != (tt.code != codes.OK) {
+			t.Errorf("%s: metrics = %+v, expect 1 call and %d attempts", tt.name, s, tt.attempts)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	m := NewMetrics()
+	m.observe("/a/A", 1, false)
+	m.observe("/a/A", 3, true)
+	m.observe("/a/A", 0, false) // 没有统计到尝试时按 1 次算
+	m.observe("/b/B", maxTracked+5, true)
+
+	a := m.Snapshot()["/a/A"]
+	if a.Calls != 3 || a.Failures != 1 || a.Attempts != 5 || a.PerCall[0] != 2 || a.PerCall[2] != 1 {
+		t.Errorf("/a/A snapshot = %+v", a)
+	}
+	b := m.Snapshot()["/b/B"]
+	if b.Calls != 1 || b.Attempts != maxTracked+5 || b.PerCall[maxTracked-1] != 1 {
+		t.Errorf("/b/B snapshot = %+v, expect the last bucket to hold the call", b)
+	}
+	expect := "/a/A calls=3 failures=1 attempts=5 per_call=[2 0 1 0 0 0 0 0 0 0]\n" +
+		"/b/B calls=1 failures=1 attempts=15 per_call=[0 0 0 0 0 0 0 0 0 1]\n"
+	if got := m.String(); got != expect {
+		t.Errorf("String() = %q, expect %q", got, expect)
+	}
+}
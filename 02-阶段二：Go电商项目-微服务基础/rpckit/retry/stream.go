@@ -0,0 +1,234 @@
+/**
+ * @File : stream.go
+ * @Description : 流式调用的客户端拦截器，在 gRPC 内置重试已经放弃的情况下按 retryPolicy 重新建立流
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package retry
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxReplayBytes 是为重放缓存的请求消息上限，超过后这个流就不再重试
+const maxReplayBytes = 4 << 20
+
+// StreamClientInterceptor 返回流式调用的拦截器。
+// gRPC 内置的重试一旦"提交"就不再重试：调用过 Header/Context、缓存超过 256KB 或收到第一条响应。
+// 这里自己缓存已发送的消息，只要还没有响应交给调用方，就可以重新建流并重放。
+// 双向流的请求往往依赖已经收到的响应，重放没有意义，只统计不重试
+func (p *Policy) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		mc := p.lookup(method)
+		ctx, attempts := withAttemptCounter(ctx)
+		cancel := context.CancelFunc(func() {})
+		if mc != nil && mc.Timeout != nil {
+			ctx, cancel = context.WithTimeout(ctx, time.Duration(*mc.Timeout))
+		}
+
+		s := &retryStream{
+			ctx:      ctx,
+			desc:     desc,
+			cc:       cc,
+			method:   method,
+			streamer: streamer,
+			opts:     opts,
+			attempts: attempts,
+			done: func(err error) {
+				p.metrics.observe(method, int(attempts.Load()), err != nil)
+				cancel()
+			},
+		}
+		if mc != nil && mc.RetryPolicy != nil && !(desc.ClientStreams && desc.ServerStreams) {
+			s.policy = mc.RetryPolicy
+			s.backoff = time.Duration(mc.RetryPolicy.InitialBackoff)
+		} else {
+			s.overflow = true
+		}
+
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		for err != nil && s.canRetry(err) {
+			if err = s.sleep(); err == nil {
+				cs, err = streamer(ctx, desc, cc, method, opts...)
+			}
+		}
+		if err != nil {
+			s.finish(err)
+			return nil, err
+		}
+		s.cur = cs
+		return s, nil
+	}
+}
+
+type retryStream struct {
+	ctx      context.Context
+	desc     *grpc.StreamDesc
+	cc       *grpc.ClientConn
+	method   string
+	streamer grpc.Streamer
+	opts     []grpc.CallOption
+	policy   *RetryPolicy
+	attempts *atomic.Int32
+	backoff  time.Duration
+	retries  int
+	done     func(err error)
+	once     sync.Once
+
+	mu         sync.Mutex
+	cur        grpc.ClientStream
+	sent       []proto.Message
+	size       int
+	overflow   bool // 不再缓存，也不再重试
+	received   bool // 已经有响应交给调用方
+	closedSend bool
+}
+
+func (s *retryStream) current() grpc.ClientStream {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cur
+}
+
+func (s *retryStream) Header() (metadata.MD, error) { return s.current().Header() }
+
+func (s *retryStream) Trailer() metadata.MD { return s.current().Trailer() }
+
+func (s *retryStream) Context() context.Context { return s.current().Context() }
+
+func (s *retryStream) CloseSend() error {
+	s.mu.Lock()
+	s.closedSend = true
+	cs := s.cur
+	s.mu.Unlock()
+	return cs.CloseSend()
+}
+
+// SendMsg 在发送前先把消息放进重放缓存。
+// 当前流已经失败时 gRPC 返回 io.EOF，这里吞掉它，让调用方继续发完，真正的状态在 RecvMsg 中处理
+func (s *retryStream) SendMsg(m any) error {
+	s.mu.Lock()
+	buffered := s.buffer(m)
+	cs := s.cur
+	s.mu.Unlock()
+
+	err := cs.SendMsg(m)
+	if err == io.EOF && buffered {
+		return nil
+	}
+	return err
+}
+
+// buffer 需要持有 mu
+func (s *retryStream) buffer(m any) bool {
+	if s.overflow || s.received {
+		return false
+	}
+	pm, ok := m.(proto.Message)
+	if ok {
+		s.size += proto.Size(pm)
+	}
+	if !ok || s.size > maxReplayBytes {
+		s.overflow = true
+		s.sent = nil
+		return false
+	}
+	s.sent = append(s.sent, proto.Clone(pm))
+	return true
+}
+
+func (s *retryStream) RecvMsg(m any) error {
+	err := s.current().RecvMsg(m)
+	for err != nil && err != io.EOF && s.canRetry(err) {
+		if err = s.sleep(); err == nil {
+			if err = s.reopen(); err == nil {
+				err = s.current().RecvMsg(m)
+			}
+		}
+	}
+
+	switch {
+	case err == nil:
+		s.mu.Lock()
+		s.received = true
+		s.sent = nil
+		s.mu.Unlock()
+		// 客户端流只有一条响应，收到就结束了
+		if !s.desc.ServerStreams {
+			s.finish(nil)
+		}
+	case err == io.EOF:
+		s.finish(nil)
+	default:
+		s.finish(err)
+	}
+	return err
+}
+
+// canRetry 判断是否还能再试：状态码可重试、还没有响应交给调用方，且总尝试次数（含 gRPC 内置重试）没有用完
+func (s *retryStream) canRetry(err error) bool {
+	if s.policy == nil || s.ctx.Err() != nil {
+		return false
+	}
+	s.mu.Lock()
+	committed := s.overflow || s.received
+	s.mu.Unlock()
+	// 没有安装 attemptCounter 时 attempts 一直是 0，用自己的重试次数兜底
+	return !committed &&
+		s.retries+1 < s.policy.MaxAttempts &&
+		int(s.attempts.Load()) < s.policy.MaxAttempts &&
+		contains(s.policy.RetryableStatusCodes, status.Code(err))
+}
+
+// sleep 按 gRPC 的规则退避：在 [0, backoff] 中随机等待，然后 backoff 乘以倍数，不超过 maxBackoff
+func (s *retryStream) sleep() error {
+	s.retries++
+	d := time.Duration(rand.Int63n(int64(s.backoff) + 1))
+	s.backoff = time.Duration(float64(s.backoff) * s.policy.BackoffMultiplier)
+	if maxBackoff := time.Duration(s.policy.MaxBackoff); s.backoff > maxBackoff {
+		s.backoff = maxBackoff
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-s.ctx.Done():
+		return status.FromContextError(s.ctx.Err()).Err()
+	}
+}
+
+// reopen 重新建流并重放缓存的消息，重放时的发送错误会在下一次 RecvMsg 中体现
+func (s *retryStream) reopen() error {
+	cs, err := s.streamer(s.ctx, s.desc, s.cc, s.method, s.opts...)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	sent, closedSend := s.sent, s.closedSend
+	s.cur = cs
+	s.mu.Unlock()
+
+	for _, m := range sent {
+		if cs.SendMsg(m) != nil {
+			return nil
+		}
+	}
+	if closedSend {
+		cs.CloseSend()
+	}
+	return nil
+}
+
+func (s *retryStream) finish(err error) {
+	s.once.Do(func() { s.done(err) })
+}
@@ -0,0 +1,49 @@
+/**
+ * @File : identity.go
+ * @Description : 从 mTLS 对端证书中提取已认证的客户端身份
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package tlsutil
+
+import (
+	"context"
+	"crypto/x509"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// Identity 是经过证书链校验的对端身份
+type Identity struct {
+	// Name 按 URI SAN、DNS SAN、Email SAN、CommonName 的优先级取第一个非空值
+	Name string
+	// Cert 是对端的叶子证书，需要更多字段时可以直接读取
+	Cert *x509.Certificate
+}
+
+// PeerIdentity 在 handler 中调用；只有在 mTLS 且客户端证书校验通过时才返回 ok
+func PeerIdentity(ctx context.Context) (Identity, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return Identity{}, false
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return Identity{}, false
+	}
+	leaf := info.State.VerifiedChains[0][0]
+	return Identity{Name: nameFromCert(leaf), Cert: leaf}, true
+}
+
+func nameFromCert(c *x509.Certificate) string {
+	switch {
+	case len(c.URIs) > 0:
+		return c.URIs[0].String()
+	case len(c.DNSNames) > 0:
+		return c.DNSNames[0]
+	case len(c.EmailAddresses) > 0:
+		return c.EmailAddresses[0]
+	default:
+		return c.Subject.CommonName
+	}
+}
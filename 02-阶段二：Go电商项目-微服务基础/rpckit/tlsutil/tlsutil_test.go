@@ -0,0 +1,250 @@
+/**
+ * @File : tlsutil_test.go
+ * @Description : 用临时签发的证书测试 mTLS 身份映射、拒绝不受信任的客户端证书，以及不重启服务的证书热更新
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package tlsutil
+
+import (
+	"context"
+	"crypto/x509"
+	"flag"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	testpb "google.golang.org/grpc/interop/grpc_testing"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// identityServer 把 handler 看到的对端身份放在 Username 里返回，没有身份时为空
+type identityServer struct {
+	testpb.UnimplementedTestServiceServer
+}
+
+func (identityServer) UnaryCall(ctx context.Context, _ *testpb.SimpleRequest) (*testpb.SimpleResponse, error) {
+	id, _ := PeerIdentity(ctx)
+	return &testpb.SimpleResponse{Username: id.Name}, nil
+}
+
+// pki 是测试用的一套证书文件
+type pki struct {
+	dir string
+	ca  *Cert
+}
+
+func newPKI(t *testing.T) *pki {
+	t.Helper()
+	p := &pki{dir: t.TempDir()}
+	p.ca = p.newCA(t, "ca")
+	return p
+}
+
+func (p *pki) newCA(t *testing.T, name string) *Cert {
+	t.Helper()
+	ca, err := NewCA("rpckit test "+name, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.write(t, ca, name)
+	return ca
+}
+
+// issue 用 ca 签发证书并写到 <name>.pem / <name>.key
+func (p *pki) issue(t *testing.T, ca *Cert, name string, opts LeafOptions) *Cert {
+	t.Helper()
+	opts.Validity = time.Hour
+	c, err := ca.Issue(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.write(t, c, name)
+	return c
+}
+
+func (p *pki) write(t *testing.T, c *Cert, name string) {
+	t.Helper()
+	if err := c.WriteFiles(p.path(name+".pem"), p.path(name+".key")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (p *pki) path(name string) string { return filepath.Join(p.dir, name) }
+
+// serve 用 flags 启动服务，返回监听地址
+func serve(t *testing.T, f *Flags) string {
+	t.Helper()
+	creds, err := f.ServerOption()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer(creds)
+	testpb.RegisterTestServiceServer(s, identityServer{})
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+	return lis.Addr().String()
+}
+
+// call 建立一个新连接发起调用，返回 handler 看到的身份和服务端证书
+func call(t *testing.T, addr string, f *Flags) (string, *x509.Certificate, error) {
+	t.Helper()
+	creds, err := f.DialOption()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := grpc.NewClient(addr, creds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var p peer.Peer
+	resp, err := testpb.NewTestServiceClient(conn).UnaryCall(ctx, &testpb.SimpleRequest{}, grpc.Peer(&p))
+	if err != nil {
+		return "", nil, err
+	}
+	info := p.AuthInfo.(credentials.TLSInfo)
+	return resp.GetUsername(), info.State.PeerCertificates[0], nil
+}
+
+func TestPeerIdentity(t *testing.T) {
+	p := newPKI(t)
+	p.issue(t, p.ca, "server", LeafOptions{CommonName: "server", Hosts: []string{"localhost"}, Server: true})
+	p.issue(t, p.ca, "alice", LeafOptions{CommonName: "alice", URIs: []string{"spiffe://rpckit/alice"}, Hosts: []string{"alice.internal"}, Client: true})
+	p.issue(t, p.ca, "bob", LeafOptions{CommonName: "bob", Hosts: []string{"bob.internal"}, Client: true})
+	p.issue(t, p.ca, "carol", LeafOptions{CommonName: "carol", Client: true})
+	// 由另一个 CA 签发，服务端不信任
+	p.issue(t, p.newCA(t, "other-ca"), "mallory", LeafOptions{CommonName: "mallory", URIs: []string{"spiffe://rpckit/alice"}, Client: true})
+	// 受信任的 CA 签发，但只能用于服务端
+	p.issue(t, p.ca, "server-only", LeafOptions{CommonName: "server-only", Server: true})
+
+	mtls := serve(t, &Flags{CertFile: p.path("server.pem"), KeyFile: p.path("server.key"), CAFile: p.path("ca.pem")})
+	tlsOnly := serve(t, &Flags{CertFile: p.path("server.pem"), KeyFile: p.path("server.key")})
+
+	tests := []struct {
+		name     string
+		addr     string
+		client   string
+		identity string
+		code     codes.Code
+	}{
+		{"URI SAN comes first", mtls, "alice", "spiffe://rpckit/alice", codes.OK},
+		{"DNS SAN without URI SAN", mtls, "bob", "bob.internal", codes.OK},
+		{"common name without SANs", mtls, "carol", "carol", codes.OK},
+		{"untrusted client certificate", mtls, "mallory", "", codes.Unavailable},
+		{"certificate without client auth usage", mtls, "server-only", "", codes.Unavailable},
+		{"no client certificate", mtls, "", "", codes.Unavailable},
+		{"TLS without client auth has no identity", tlsOnly, "alice", "", codes.OK},
+	}
+	for _, tt := range tests {
+		f := &Flags{CAFile: p.path("ca.pem"), ServerName: "localhost"}
+		if tt.client != "" {
+			f.CertFile, f.KeyFile = p.path(tt.client+".pem"), p.path(tt.client+".key")
+		}
+		identity, _, err := call(t, tt.addr, f)
+		if code := status.Code(err); code != tt.code {
+			t.Errorf("%s: UnaryCall() error = %v, expect code %v", tt.name, err, tt.code)
+			continue
+		}
+		if identity != tt.identity {
+			t.Errorf("%s: handler saw identity %q, expect %q", tt.name, identity, tt.identity)
+		}
+	}
+}
+
+func TestServerCertificateReload(t *testing.T) {
+	p := newPKI(t)
+	first := p.issue(t, p.ca, "server", LeafOptions{CommonName: "first", Hosts: []string{"localhost"}, Server: true})
+	addr := serve(t, &Flags{CertFile: p.path("server.pem"), KeyFile: p.path("server.key"), Reload: 10 * time.Millisecond})
+	client := &Flags{CAFile: p.path("ca.pem"), ServerName: "localhost"}
+
+	_, cert, err := call(t, addr, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.SerialNumber.Cmp(first.Cert.SerialNumber) != 0 {
+		t.Fatalf("server presented %q, expect %q", cert.Subject.CommonName, "first")
+	}
+
+	// 写了一半的文件加载失败，继续使用旧证书
+	if err := os.WriteFile(p.path("server.pem"), []byte("-----BEGIN CERTIFICATE-----\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	touch(t, p.path("server.pem"), time.Second)
+	time.Sleep(50 * time.Millisecond)
+	if _, cert, err = call(t, addr, client); err != nil || cert.SerialNumber.Cmp(first.Cert.SerialNumber) != 0 {
+		t.Fatalf("after a broken write: error = %v, expect the first certificate to stay in use", err)
+	}
+
+	// 换上新证书，之后的握手拿到新证书，服务没有重启
+	second := p.issue(t, p.ca, "server", LeafOptions{CommonName: "second", Hosts: []string{"localhost"}, Server: true})
+	touch(t, p.path("server.pem"), 2*time.Second)
+	touch(t, p.path("server.key"), 2*time.Second)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, cert, err = call(t, addr, client)
+		if err == nil && cert.SerialNumber.Cmp(second.Cert.SerialNumber) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server still presents %v (error %v), expect the second certificate", cert.Subject.CommonName, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// touch 把修改时间往后挪，避免文件系统的时间精度让两次写入的修改时间相同
+func touch(t *testing.T, path string, d time.Duration) {
+	t.Helper()
+	mod := time.Now().Add(d)
+	if err := os.Chtimes(path, mod, mod); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPrefixedFlags(t *testing.T) {
+	p := newPKI(t)
+	p.issue(t, p.ca, "server", LeafOptions{CommonName: "server", Hosts: []string{"localhost"}, Server: true})
+	p.issue(t, p.ca, "proxy", LeafOptions{CommonName: "proxy", Hosts: []string{"proxy.internal"}, Client: true})
+	backend := serve(t, &Flags{CertFile: p.path("server.pem"), KeyFile: p.path("server.key"), CAFile: p.path("ca.pem")})
+
+	// 代理的两组参数注册在同一个 FlagSet 上，互不影响
+	fs := flag.NewFlagSet("proxy", flag.ContinueOnError)
+	front := RegisterFlags(fs)
+	back := RegisterPrefixedFlags(fs, "backend-")
+	err := fs.Parse([]string{
+		"-tls-cert", p.path("server.pem"), "-tls-key", p.path("server.key"),
+		"-backend-tls-ca", p.path("ca.pem"), "-backend-tls-cert", p.path("proxy.pem"), "-backend-tls-key", p.path("proxy.key"),
+		"-backend-tls-server-name", "localhost", "-backend-tls-reload", "0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := (Flags{CertFile: p.path("server.pem"), KeyFile: p.path("server.key"), Reload: 10 * time.Second}); *front != expect {
+		t.Errorf("front flags = %+v, expect %+v", *front, expect)
+	}
+	if expect := (Flags{CertFile: p.path("proxy.pem"), KeyFile: p.path("proxy.key"), CAFile: p.path("ca.pem"), ServerName: "localhost"}); *back != expect {
+		t.Errorf("backend flags = %+v, expect %+v", *back, expect)
+	}
+
+	// 用后端参数连接要求 mTLS 的后端
+	identity, _, err := call(t, backend, back)
+	if err != nil || identity != "proxy.internal" {
+		t.Errorf("call with backend flags = %q, %v, expect identity proxy.internal", identity, err)
+	}
+	// 不带后端参数时是明文连接，被 TLS 后端拒绝
+	if _, _, err := call(t, backend, &Flags{}); status.Code(err) != codes.Unavailable {
+		t.Errorf("plaintext call to a TLS backend error = %v, expect Unavailable", err)
+	}
+}
@@ -0,0 +1,115 @@
+/**
+ * @File : exporter.go
+ * @Description : span 的去处：进程内的 Memory 收集器和按行追加 JSON 的 File，多个进程可以写同一个文件
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package trace
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// Memory 把 span 保存在内存中，适合测试和单进程查看
+type Memory struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+func (m *Memory) Export(s SpanData) {
+	m.mu.Lock()
+	m.spans = append(m.spans, s)
+	m.mu.Unlock()
+}
+
+// Spans 返回目前收集到的全部 span
+func (m *Memory) Spans() []SpanData {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]SpanData(nil), m.spans...)
+}
+
+// File 每个 span 写一行 JSON。文件以 O_APPEND 打开，一行一次 write，多个进程追加时不会交错
+type File struct {
+	mu sync.Mutex
+	f  *os.File
+}
+
+func NewFile(path string) (*File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &File{f: f}, nil
+}
+
+func (f *File) Export(s SpanData) {
+	line, err := json.Marshal(s)
+	if err != nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.f.Write(append(line, '\n'))
+}
+
+func (f *File) Close() error {
+	return f.f.Close()
+}
+
+// ReadFile 读取 File 写出的全部 span，跳过无法解析的行
+func ReadFile(path string) ([]SpanData, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var spans []SpanData
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	for sc.Scan() {
+		var s SpanData
+		if json.Unmarshal(sc.Bytes(), &s) == nil {
+			spans = append(spans, s)
+		}
+	}
+	return spans, sc.Err()
+}
+
+// Tee 把 span 同时交给多个 exporter，nil 会被跳过
+func Tee(exporters ...Exporter) Exporter {
+	var list tee
+	for _, e := range exporters {
+		if e != nil {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
+type tee []Exporter
+
+func (t tee) Export(s SpanData) {
+	for _, e := range t {
+		e.Export(s)
+	}
+}
+
+// OpenTracer 是各个 main 中的常用写法：path 为空时只传播上下文，不记录 span
+func OpenTracer(service, path string) (*Tracer, error) {
+	if path == "" {
+		return NewTracer(service, nil), nil
+	}
+	f, err := NewFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewTracer(service, f), nil
+}
@@ -0,0 +1,151 @@
+/**
+ * @File : grpc.go
+ * @Description : gRPC 拦截器：通过 traceparent 元数据传播上下文，每次调用和每条流消息各记一个 span
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package trace
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// extract 从入站元数据中取出远端父节点，格式不对时忽略，开始新的 trace
+func extract(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	if v := md.Get(HeaderKey); len(v) > 0 {
+		if sc, err := ParseTraceparent(v[0]); err == nil {
+			return ContextWithSpanContext(ctx, sc)
+		}
+	}
+	return ctx
+}
+
+// inject 把当前 span 写进出站元数据，覆盖调用方可能已经带上的旧值
+func inject(ctx context.Context, sc SpanContext) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	md.Set(HeaderKey, sc.Traceparent())
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+func (t *Tracer) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, span := t.Start(extract(ctx), info.FullMethod, KindServer)
+		resp, err := handler(ctx, req)
+		span.End(err)
+		return resp, err
+	}
+}
+
+func (t *Tracer) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, span := t.Start(extract(ss.Context()), info.FullMethod, KindServer)
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx, msgs: messageSpans{tracer: t, ctx: ctx}})
+		span.End(err)
+		return err
+	}
+}
+
+func (t *Tracer) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, span := t.Start(ctx, method, KindClient)
+		err := invoker(inject(ctx, span.Context()), method, req, reply, cc, opts...)
+		span.End(err)
+		return err
+	}
+}
+
+// StreamClientInterceptor 在流结束时（RecvMsg 返回错误或 io.EOF，客户端流收到唯一的响应）结束调用的 span
+func (t *Tracer) StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx, span := t.Start(ctx, method, KindClient)
+		cs, err := streamer(inject(ctx, span.Context()), desc, cc, method, opts...)
+		if err != nil {
+			span.End(err)
+			return nil, err
+		}
+		return &clientStream{ClientStream: cs, desc: desc, span: span, msgs: messageSpans{tracer: t, ctx: ctx}}, nil
+	}
+}
+
+// messageSpans 为流上的每条消息创建一个子 span，发送和接收分别编号
+type messageSpans struct {
+	tracer   *Tracer
+	ctx      context.Context
+	sent     atomic.Int64
+	received atomic.Int64
+}
+
+func (m *messageSpans) send(fn func() error) error {
+	_, span := m.tracer.Start(m.ctx, fmt.Sprintf("send #%d", m.sent.Add(1)), KindInternal)
+	err := fn()
+	span.End(err)
+	return err
+}
+
+// recv 的 span 包含等待对端发送的时间，io.EOF 表示正常结束，不单独记 span
+func (m *messageSpans) recv(fn func() error) error {
+	_, span := m.tracer.Start(m.ctx, fmt.Sprintf("recv #%d", m.received.Load()+1), KindInternal)
+	err := fn()
+	if err == io.EOF {
+		return err
+	}
+	m.received.Add(1)
+	span.End(err)
+	return err
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	msgs messageSpans
+}
+
+func (s *serverStream) Context() context.Context { return s.ctx }
+
+func (s *serverStream) SendMsg(m any) error {
+	return s.msgs.send(func() error { return s.ServerStream.SendMsg(m) })
+}
+
+func (s *serverStream) RecvMsg(m any) error {
+	return s.msgs.recv(func() error { return s.ServerStream.RecvMsg(m) })
+}
+
+type clientStream struct {
+	grpc.ClientStream
+	desc *grpc.StreamDesc
+	span *Span
+	msgs messageSpans
+	once sync.Once
+}
+
+func (s *clientStream) SendMsg(m any) error {
+	return s.msgs.send(func() error { return s.ClientStream.SendMsg(m) })
+}
+
+func (s *clientStream) RecvMsg(m any) error {
+	err := s.msgs.recv(func() error { return s.ClientStream.RecvMsg(m) })
+	switch {
+	case err == io.EOF:
+		s.finish(nil)
+	case err != nil:
+		s.finish(err)
+	case !s.desc.ServerStreams:
+		s.finish(nil)
+	}
+	return err
+}
+
+func (s *clientStream) finish(err error) {
+	s.once.Do(func() { s.span.End(err) })
+}
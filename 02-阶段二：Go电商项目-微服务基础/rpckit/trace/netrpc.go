@@ -0,0 +1,221 @@
+/**
+ * @File : netrpc.go
+ * @Description : net/rpc 的 gob codec 加一层信封：每个请求头前面先发送一个带 traceparent 和 metadata 的 envelope
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package trace
+
+import (
+	"bufio"
+	"context"
+	"encoding/gob"
+	"io"
+	"net/rpc"
+	"sync"
+)
+
+// envelope 在线路上位于 rpc.Request 之前，客户端和服务端都必须使用本包的 codec
+type envelope struct {
+	Traceparent string
+	// Metadata 相当于 gRPC 的 metadata，net/rpc 的方法签名里没有 ctx，服务端只能在 codec 层读取
+	Metadata map[string]string
+}
+
+type metadataKey struct{}
+
+// WithMetadata 返回带有 key=value 的 ctx，用 Tracer.Call 调用时放进 envelope 发给服务端。
+// 不修改 ctx 中已有的 map，同一个 key 再次设置时覆盖旧值
+func WithMetadata(ctx context.Context, key, value string) context.Context {
+	old := Metadata(ctx)
+	md := make(map[string]string, len(old)+1)
+	for k, v := range old {
+		md[k] = v
+	}
+	md[key] = value
+	return context.WithValue(ctx, metadataKey{}, md)
+}
+
+// Metadata 返回 WithMetadata 设置的全部 metadata，调用方不能修改返回的 map
+func Metadata(ctx context.Context) map[string]string {
+	md, _ := ctx.Value(metadataKey{}).(map[string]string)
+	return md
+}
+
+// MetadataCodec 由 NewServerCodec 返回的 codec 实现，包在它外面的 codec 可以按 Seq 读取请求的 metadata
+type MetadataCodec interface {
+	rpc.ServerCodec
+	// RequestMetadata 在 ReadRequestHeader 之后、WriteResponse 之前有效
+	RequestMetadata(seq uint64) map[string]string
+}
+
+// Client 是请求前面带 envelope 的 net/rpc 客户端，用 Tracer.Call 调用时会带上 traceparent；
+// 直接调用 Call/Go 也可以，这时 envelope 为空，服务端开始新的 trace
+type Client struct {
+	*rpc.Client
+}
+
+// NewClient 用法：client := trace.NewClient(conn)，服务端要用 NewServerCodec
+func NewClient(conn io.ReadWriteCloser) *Client {
+	return &Client{rpc.NewClientWithCodec(newClientCodec(conn))}
+}
+
+// tracedArgs 把 traceparent、metadata 和参数一起交给 codec。
+// rpc.Client.Call 没有 ctx 参数，请求体是 codec 唯一能看到的调用方数据；codec 会拆开它，线路上的请求体仍是原来的参数
+type tracedArgs struct {
+	args        any
+	traceparent string
+	metadata    map[string]string
+}
+
+// Call 在 ctx 的 trace 中调用 net/rpc 方法，ctx 中的 metadata 一起发给服务端
+func (t *Tracer) Call(ctx context.Context, client *Client, serviceMethod string, args, reply any) error {
+	_, span := t.Start(ctx, serviceMethod, KindClient)
+	err := client.Call(serviceMethod, &tracedArgs{args: args, traceparent: span.Context().Traceparent(), metadata: Metadata(ctx)}, reply)
+	span.End(err)
+	return err
+}
+
+type clientCodec struct {
+	rwc    io.ReadWriteCloser
+	dec    *gob.Decoder
+	enc    *gob.Encoder
+	encBuf *bufio.Writer
+}
+
+func newClientCodec(conn io.ReadWriteCloser) rpc.ClientCodec {
+	buf := bufio.NewWriter(conn)
+	return &clientCodec{rwc: conn, dec: gob.NewDecoder(conn), enc: gob.NewEncoder(buf), encBuf: buf}
+}
+
+func (c *clientCodec) WriteRequest(r *rpc.Request, body any) error {
+	var env envelope
+	if t, ok := body.(*tracedArgs); ok {
+		env.Traceparent, env.Metadata, body = t.traceparent, t.metadata, t.args
+	}
+	if err := c.enc.Encode(&env); err != nil {
+		return err
+	}
+	if err := c.enc.Encode(r); err != nil {
+		return err
+	}
+	if err := c.enc.Encode(body); err != nil {
+		return err
+	}
+	return c.encBuf.Flush()
+}
+
+func (c *clientCodec) ReadResponseHeader(r *rpc.Response) error {
+	return c.dec.Decode(r)
+}
+
+func (c *clientCodec) ReadResponseBody(body any) error {
+	return c.dec.Decode(body)
+}
+
+func (c *clientCodec) Close() error {
+	return c.rwc.Close()
+}
+
+type serverCodec struct {
+	tracer *Tracer
+	rwc    io.ReadWriteCloser
+	dec    *gob.Decoder
+	enc    *gob.Encoder
+	encBuf *bufio.Writer
+	closed bool
+
+	// net/rpc 并发执行请求，响应可能乱序写回，按 Seq 找到对应的 span 和 metadata
+	mu       sync.Mutex
+	spans    map[uint64]*Span
+	metadata map[uint64]map[string]string
+}
+
+// NewServerCodec 用法：rpc.ServeCodec(trace.NewServerCodec(conn, tracer))。
+// 每个请求记一个 server span，traceparent 无效时开始新的 trace
+func NewServerCodec(conn io.ReadWriteCloser, t *Tracer) MetadataCodec {
+	buf := bufio.NewWriter(conn)
+	return &serverCodec{
+		tracer:   t,
+		rwc:      conn,
+		dec:      gob.NewDecoder(conn),
+		enc:      gob.NewEncoder(buf),
+		encBuf:   buf,
+		spans:    make(map[uint64]*Span),
+		metadata: make(map[uint64]map[string]string),
+	}
+}
+
+func (c *serverCodec) ReadRequestHeader(r *rpc.Request) error {
+	var env envelope
+	if err := c.dec.Decode(&env); err != nil {
+		return err
+	}
+	if err := c.dec.Decode(r); err != nil {
+		return err
+	}
+	ctx := context.Background()
+	if sc, err := ParseTraceparent(env.Traceparent); err == nil {
+		ctx = ContextWithSpanContext(ctx, sc)
+	}
+	_, span := c.tracer.Start(ctx, r.ServiceMethod, KindServer)
+	c.mu.Lock()
+	c.spans[r.Seq] = span
+	if len(env.Metadata) > 0 {
+		c.metadata[r.Seq] = env.Metadata
+	}
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *serverCodec) RequestMetadata(seq uint64) map[string]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.metadata[seq]
+}
+
+func (c *serverCodec) ReadRequestBody(body any) error {
+	return c.dec.Decode(body)
+}
+
+func (c *serverCodec) WriteResponse(r *rpc.Response, body any) error {
+	c.mu.Lock()
+	span := c.spans[r.Seq]
+	delete(c.spans, r.Seq)
+	delete(c.metadata, r.Seq)
+	c.mu.Unlock()
+
+	err := c.write(r, body)
+	if span != nil {
+		if r.Error != "" {
+			span.End(rpc.ServerError(r.Error))
+		} else {
+			span.End(err)
+		}
+	}
+	return err
+}
+
+func (c *serverCodec) write(r *rpc.Response, body any) error {
+	if err := c.enc.Encode(r); err != nil {
+		if c.encBuf.Flush() == nil {
+			c.Close()
+		}
+		return err
+	}
+	if err := c.enc.Encode(body); err != nil {
+		if c.encBuf.Flush() == nil {
+			c.Close()
+		}
+		return err
+	}
+	return c.encBuf.Flush()
+}
+
+func (c *serverCodec) Close() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	return c.rwc.Close()
+}
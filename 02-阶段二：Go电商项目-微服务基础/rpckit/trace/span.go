@@ -0,0 +1,125 @@
+/**
+ * @File : span.go
+ * @Description : Tracer 和 Span：记录一次操作的起止时间、父子关系和属性，结束时交给 Exporter
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package trace
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type Kind string
+
+const (
+	KindServer   Kind = "server"
+	KindClient   Kind = "client"
+	KindInternal Kind = "internal"
+)
+
+// SpanData 是导出的 span，也是文件中每一行 JSON 的格式
+type SpanData struct {
+	TraceID    string            `json:"trace_id"`
+	SpanID     string            `json:"span_id"`
+	ParentID   string            `json:"parent_id,omitempty"`
+	Service    string            `json:"service"`
+	Name       string            `json:"name"`
+	Kind       Kind              `json:"kind"`
+	Start      time.Time         `json:"start"`
+	End        time.Time         `json:"end"`
+	Error      string            `json:"error,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// Exporter 接收结束的 span，需要并发安全
+type Exporter interface {
+	Export(SpanData)
+}
+
+// Tracer 代表一个服务，exporter 为 nil 时只传播上下文，不记录 span
+type Tracer struct {
+	service  string
+	exporter Exporter
+}
+
+func NewTracer(service string, exporter Exporter) *Tracer {
+	return &Tracer{service: service, exporter: exporter}
+}
+
+// Span 的方法都是并发安全的，End 之后的修改会被忽略
+type Span struct {
+	tracer *Tracer
+	sc     SpanContext
+
+	mu    sync.Mutex
+	data  SpanData
+	ended bool
+}
+
+// Start 以 ctx 中的 span 为父节点创建新的 span，没有父节点时开始一条新的 trace；
+// 返回的 ctx 携带新 span，在它上面发起的下游调用会成为它的子节点
+func (t *Tracer) Start(ctx context.Context, name string, kind Kind) (context.Context, *Span) {
+	sc := SpanContext{SpanID: newSpanID(), Sampled: true}
+	var parentID string
+	if parent, ok := FromContext(ctx); ok {
+		sc.TraceID = parent.TraceID
+		sc.Sampled = parent.Sampled
+		parentID = parent.SpanID.String()
+	} else {
+		sc.TraceID = newTraceID()
+	}
+	s := &Span{
+		tracer: t,
+		sc:     sc,
+		data: SpanData{
+			TraceID:  sc.TraceID.String(),
+			SpanID:   sc.SpanID.String(),
+			ParentID: parentID,
+			Service:  t.service,
+			Name:     name,
+			Kind:     kind,
+			Start:    time.Now(),
+		},
+	}
+	return ContextWithSpanContext(ctx, sc), s
+}
+
+// Context 返回用于传播的 SpanContext
+func (s *Span) Context() SpanContext {
+	return s.sc
+}
+
+func (s *Span) SetAttribute(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return
+	}
+	if s.data.Attributes == nil {
+		s.data.Attributes = make(map[string]string)
+	}
+	s.data.Attributes[key] = value
+}
+
+// End 结束 span，err 不为 nil 时记录为失败；重复调用只有第一次生效
+func (s *Span) End(err error) {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	if err != nil {
+		s.data.Error = err.Error()
+	}
+	data := s.data
+	s.mu.Unlock()
+
+	if s.tracer.exporter != nil && s.sc.Sampled {
+		s.tracer.exporter.Export(data)
+	}
+}
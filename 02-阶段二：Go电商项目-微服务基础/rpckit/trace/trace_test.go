@@ -0,0 +1,140 @@
+/**
+ * @File : trace_test.go
+ * @Description : 测试 traceparent 的解析规则，以及 net/rpc 信封把 traceparent 带到服务端而不改动方法名
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package trace
+
+import (
+	"context"
+	"net"
+	"net/rpc"
+	"testing"
+	"time"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+	tests := []struct {
+		name    string
+		in      string
+		sampled bool
+		wantErr bool
+	}{
+		{"sampled", "00-" + traceID + "-" + spanID + "-01", true, false},
+		{"not sampled", "00-" + traceID + "-" + spanID + "-00", false, false},
+		{"other flag bits are ignored", "00-" + traceID + "-" + spanID + "-fe", false, false},
+		{"surrounding spaces", " 00-" + traceID + "-" + spanID + "-01 ", true, false},
+		{"future version with extra fields", "cc-" + traceID + "-" + spanID + "-01-what-the-future-holds", true, false},
+		{"future version without extra fields", "01-" + traceID + "-" + spanID + "-01", true, false},
+		{"version ff is forbidden", "ff-" + traceID + "-" + spanID + "-01", false, true},
+		{"version 00 with extra fields", "00-" + traceID + "-" + spanID + "-01-extra", false, true},
+		{"uppercase trace-id", "00-4BF92F3577B34DA6A3CE929D0E0E4736-" + spanID + "-01", false, true},
+		{"uppercase parent-id", "00-" + traceID + "-00F067AA0BA902B7-01", false, true},
+		{"uppercase version", "0A-" + traceID + "-" + spanID + "-01", false, true},
+		{"uppercase flags", "00-" + traceID + "-" + spanID + "-0A", false, true},
+		{"all-zero trace-id", "00-00000000000000000000000000000000-" + spanID + "-01", false, true},
+		{"all-zero parent-id", "00-" + traceID + "-0000000000000000-01", false, true},
+		{"short trace-id", "00-" + traceID[:30] + "-" + spanID + "-01", false, true},
+		{"long trace-id", "00-" + traceID + "00-" + spanID + "-01", false, true},
+		{"short parent-id", "00-" + traceID + "-" + spanID[:14] + "-01", false, true},
+		{"long parent-id", "00-" + traceID + "-" + spanID + "00-01", false, true},
+		{"short version", "0-" + traceID + "-" + spanID + "-01", false, true},
+		{"short flags", "00-" + traceID + "-" + spanID + "-1", false, true},
+		{"long flags", "00-" + traceID + "-" + spanID + "-011", false, true},
+		{"non-hex character", "00-" + traceID[:31] + "g-" + spanID + "-01", false, true},
+		{"missing field", "00-" + traceID + "-" + spanID, false, true},
+		{"empty", "", false, true},
+	}
+	for _, tt := range tests {
+		sc, err := ParseTraceparent(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: ParseTraceparent(%q) error = %v, wantErr %v", tt.name, tt.in, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			if sc != (SpanContext{}) {
+				t.Errorf("%s: ParseTraceparent(%q) = %+v on error, expect the zero value", tt.name, tt.in, sc)
+			}
+			continue
+		}
+		if sc.TraceID.String() != traceID || sc.SpanID.String() != spanID || sc.Sampled != tt.sampled {
+			t.Errorf("%s: ParseTraceparent(%q) = %s, expect %s-%s sampled=%v", tt.name, tt.in, sc.Traceparent(), traceID, spanID, tt.sampled)
+		}
+	}
+}
+
+func TestTraceparentRoundTrip(t *testing.T) {
+	sc := SpanContext{TraceID: newTraceID(), SpanID: newSpanID(), Sampled: true}
+	got, err := ParseTraceparent(sc.Traceparent())
+	if err != nil || got != sc {
+		t.Errorf("ParseTraceparent(%s) = %+v, %v, expect %+v", sc.Traceparent(), got, err, sc)
+	}
+}
+
+type Echo struct{}
+
+func (Echo) Say(in string, out *string) error {
+	*out = in
+	return nil
+}
+
+func TestNetRPC(t *testing.T) {
+	server := NewMemory()
+	srv := rpc.NewServer()
+	if err := srv.Register(Echo{}); err != nil {
+		t.Fatal(err)
+	}
+	serverConn, clientConn := net.Pipe()
+	go srv.ServeCodec(NewServerCodec(serverConn, NewTracer("server", server)))
+	client := NewClient(clientConn)
+	defer client.Close()
+
+	exported := NewMemory()
+	tracer := NewTracer("client", exported)
+	ctx, parent := tracer.Start(context.Background(), "parent", KindInternal)
+	tests := []struct {
+		name string
+		call func(*string) error
+		// 为 true 时服务端 span 是客户端 span 的子节点，否则服务端开始新的 trace
+		traced bool
+	}{
+		{"Tracer.Call carries traceparent", func(out *string) error { return tracer.Call(ctx, client, "Echo.Say", "hi", out) }, true},
+		{"plain Call starts a new trace", func(out *string) error { return client.Call("Echo.Say", "hi", out) }, false},
+	}
+	for _, tt := range tests {
+		before := len(server.Spans())
+		var out string
+		if err := tt.call(&out); err != nil || out != "hi" {
+			t.Errorf("%s: Echo.Say = %q, %v", tt.name, out, err)
+			continue
+		}
+		// 服务端 span 在写完响应后才结束，可能比客户端收到响应稍晚
+		var spans []SpanData
+		for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+			if spans = server.Spans(); len(spans) > before {
+				break
+			}
+		}
+		if len(spans) != before+1 {
+			t.Fatalf("%s: server exported %d spans, expect 1", tt.name, len(spans)-before)
+		}
+		got := spans[before]
+		// 方法名原样到达服务端
+		if got.Name != "Echo.Say" {
+			t.Errorf("%s: server span name = %q, expect Echo.Say", tt.name, got.Name)
+		}
+		if tt.traced {
+			call := exported.Spans()[0]
+			if got.TraceID != parent.Context().TraceID.String() || got.ParentID != call.SpanID {
+				t.Errorf("%s: server span %s/%s, expect trace %s and parent %s", tt.name, got.TraceID, got.ParentID, parent.Context().TraceID, call.SpanID)
+			}
+		} else if got.ParentID != "" || got.TraceID == parent.Context().TraceID.String() {
+			t.Errorf("%s: server span %s/%s, expect a new trace", tt.name, got.TraceID, got.ParentID)
+		}
+	}
+}
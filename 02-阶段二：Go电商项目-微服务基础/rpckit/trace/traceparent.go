@@ -0,0 +1,123 @@
+/**
+ * @File : traceparent.go
+ * @Description : W3C Trace Context 的 traceparent 头：00-<trace-id>-<parent-id>-<flags>
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// HeaderKey 是 traceparent 在 HTTP 头和 gRPC 元数据中的键（gRPC 要求小写）
+const HeaderKey = "traceparent"
+
+type TraceID [16]byte
+
+type SpanID [8]byte
+
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+
+func (s SpanID) String() string { return hex.EncodeToString(s[:]) }
+
+// SpanContext 是跨进程传递的那部分信息
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid 报告 trace-id 和 span-id 是否都不全为 0，规范规定全 0 无效
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// Traceparent 按版本 00 格式化
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceID, sc.SpanID, flags)
+}
+
+// ParseTraceparent 解析 traceparent。
+// 版本 00 必须正好 55 个字符；更高的版本只要前缀格式正确就接受，后面的字段忽略
+func ParseTraceparent(s string) (SpanContext, error) {
+	var sc SpanContext
+	parts := strings.SplitN(strings.TrimSpace(s), "-", 5)
+	if len(parts) < 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) < 2 {
+		return sc, fmt.Errorf("trace: malformed traceparent %q", s)
+	}
+	version, err := decodeHex(parts[0], 1)
+	if err != nil || version[0] == 0xff {
+		return sc, fmt.Errorf("trace: invalid traceparent version %q", parts[0])
+	}
+	if version[0] == 0 && (len(parts) != 4 || len(parts[3]) != 2) {
+		return sc, fmt.Errorf("trace: malformed traceparent %q", s)
+	}
+	traceID, err := decodeHex(parts[1], 16)
+	if err != nil {
+		return sc, err
+	}
+	spanID, err := decodeHex(parts[2], 8)
+	if err != nil {
+		return sc, err
+	}
+	flags, err := decodeHex(parts[3][:2], 1)
+	if err != nil {
+		return sc, err
+	}
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Sampled = flags[0]&1 == 1
+	if !sc.IsValid() {
+		return SpanContext{}, errors.New("trace: all-zero trace-id or parent-id")
+	}
+	return sc, nil
+}
+
+// decodeHex 只接受小写十六进制，与规范一致
+func decodeHex(s string, n int) ([]byte, error) {
+	if strings.ToLower(s) != s {
+		return nil, fmt.Errorf("trace: %q must be lowercase hex", s)
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != n {
+		return nil, fmt.Errorf("trace: invalid hex field %q", s)
+	}
+	return b, nil
+}
+
+func newTraceID() (t TraceID) {
+	for t == (TraceID{}) {
+		rand.Read(t[:])
+	}
+	return t
+}
+
+func newSpanID() (s SpanID) {
+	for s == (SpanID{}) {
+		rand.Read(s[:])
+	}
+	return s
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpanContext 把 sc 作为之后新建 span 的父节点；从网络上提取出的远端父节点也通过它放进 ctx
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// FromContext 返回 ctx 中当前的 span
+func FromContext(ctx context.Context) (SpanContext, bool) {
+	sc, ok := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc, ok && sc.IsValid()
+}
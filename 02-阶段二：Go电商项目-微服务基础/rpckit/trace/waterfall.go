@@ -0,0 +1,96 @@
+/**
+ * @File : waterfall.go
+ * @Description : 把 span 按 trace 分组，以缩进表示父子关系，用字符条画出各自在整条 trace 中的时间位置
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package trace
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// barWidth 是时间条的字符宽度
+const barWidth = 40
+
+// Waterfall 输出 spans 中的所有 trace，trace 按开始时间排序
+func Waterfall(w io.Writer, spans []SpanData) {
+	byTrace := make(map[string][]SpanData)
+	for _, s := range spans {
+		byTrace[s.TraceID] = append(byTrace[s.TraceID], s)
+	}
+	traces := make([][]SpanData, 0, len(byTrace))
+	for _, t := range byTrace {
+		sort.Slice(t, func(i, j int) bool { return t[i].Start.Before(t[j].Start) })
+		traces = append(traces, t)
+	}
+	sort.Slice(traces, func(i, j int) bool { return traces[i][0].Start.Before(traces[j][0].Start) })
+	for _, t := range traces {
+		writeTrace(w, t)
+	}
+}
+
+func writeTrace(w io.Writer, spans []SpanData) {
+	start, end := spans[0].Start, spans[0].End
+	ids := make(map[string]bool, len(spans))
+	for _, s := range spans {
+		ids[s.SpanID] = true
+		if s.End.After(end) {
+			end = s.End
+		}
+	}
+	total := end.Sub(start)
+	if total <= 0 {
+		total = time.Nanosecond
+	}
+
+	// 父节点不在这批 span 里（还没写完或者在别的文件）的也当作根节点
+	children := make(map[string][]SpanData)
+	var roots []SpanData
+	for _, s := range spans {
+		if s.ParentID == "" || !ids[s.ParentID] {
+			roots = append(roots, s)
+		} else {
+			children[s.ParentID] = append(children[s.ParentID], s)
+		}
+	}
+
+	fmt.Fprintf(w, "trace %s  %v, %d spans\n", spans[0].TraceID, total.Round(time.Microsecond), len(spans))
+	var walk func(s SpanData, depth int)
+	walk = func(s SpanData, depth int) {
+		label := strings.Repeat("  ", depth) + s.Name
+		status := ""
+		if s.Error != "" {
+			status = "  ERROR: " + s.Error
+		}
+		fmt.Fprintf(w, "  %-12s %-48s |%s| %v%s\n",
+			truncate(s.Service, 12), truncate(label, 48), bar(s, start, total), s.End.Sub(s.Start).Round(time.Microsecond), status)
+		for _, c := range children[s.SpanID] {
+			walk(c, depth+1)
+		}
+	}
+	for _, r := range roots {
+		walk(r, 0)
+	}
+	fmt.Fprintln(w)
+}
+
+func bar(s SpanData, start time.Time, total time.Duration) string {
+	from := int(float64(s.Start.Sub(start)) / float64(total) * barWidth)
+	to := int(float64(s.End.Sub(start)) / float64(total) * barWidth)
+	from = min(max(from, 0), barWidth-1)
+	// 很短的 span 也至少画一格
+	to = min(max(to, from+1), barWidth)
+	return strings.Repeat(" ", from) + strings.Repeat("█", to-from) + strings.Repeat(" ", barWidth-to)
+}
+
+func truncate(s string, n int) string {
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-1]) + "…"
+	}
+	return s
+}
@@ -0,0 +1,136 @@
+/**
+ * @File : flags.go
+ * @Description : 通过命令行参数配置 keepalive、消息大小上限、流控窗口和连接最大存活时间
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package transport
+
+import (
+	"flag"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+	"time"
+)
+
+// DefaultMaxMsgSize 和 gRPC 默认的接收上限一致，发送方向也用同样的值，避免发出对方收不了的消息
+const DefaultMaxMsgSize = 4 << 20
+
+// ServerFlags 保存服务端的传输参数，时间类参数为 0 表示不限制
+type ServerFlags struct {
+	// 客户端 ping 的最小间隔，更频繁的 ping 会被当作滥用，连接收到 GOAWAY(too_many_pings)
+	MinPingInterval time.Duration
+	// 是否允许客户端在没有活跃流时发 ping
+	PermitWithoutStream bool
+	// 连接空闲这么久后服务端主动 ping，超过 PingTimeout 没有回应就关闭连接
+	PingInterval time.Duration
+	PingTimeout  time.Duration
+	// 没有活跃流的连接在 MaxConnectionIdle 后关闭；
+	// 任何连接存活 MaxConnectionAge 后收到 GOAWAY，进行中的调用还有 MaxConnectionAgeGrace 可以结束
+	MaxConnectionIdle     time.Duration
+	MaxConnectionAge      time.Duration
+	MaxConnectionAgeGrace time.Duration
+	MaxRecvMsgSize        int
+	MaxSendMsgSize        int
+	// 接收窗口决定对端在等待确认前最多能发多少字节，为 0 时由 gRPC 按带宽时延积动态调整
+	InitialWindowSize     int
+	InitialConnWindowSize int
+	MaxConcurrentStreams  uint
+	// 收到退出信号后等待进行中的调用结束的时间，超时后强制关闭
+	ShutdownGrace time.Duration
+}
+
+// RegisterServerFlags 注册服务端参数，需要在 flag.Parse 之前调用
+func RegisterServerFlags(fs *flag.FlagSet) *ServerFlags {
+	f := &ServerFlags{}
+	fs.DurationVar(&f.MinPingInterval, "keepalive-min-time", 10*time.Second, "minimum interval between client pings, faster clients are disconnected")
+	fs.BoolVar(&f.PermitWithoutStream, "keepalive-permit-without-stream", true, "allow client pings on connections without active streams")
+	fs.DurationVar(&f.PingInterval, "keepalive-time", time.Minute, "ping clients after the connection has been idle this long")
+	fs.DurationVar(&f.PingTimeout, "keepalive-timeout", 20*time.Second, "close the connection when a ping is not answered within this time")
+	fs.DurationVar(&f.MaxConnectionIdle, "max-conn-idle", 0, "close connections without streams after this long, 0 disables it")
+	fs.DurationVar(&f.MaxConnectionAge, "max-conn-age", 0, "send GOAWAY to connections older than this so clients reconnect and rebalance, 0 disables it")
+	fs.DurationVar(&f.MaxConnectionAgeGrace, "max-conn-age-grace", 30*time.Second, "time in-flight calls get to finish after -max-conn-age")
+	fs.IntVar(&f.MaxRecvMsgSize, "max-recv-msg-size", DefaultMaxMsgSize, "largest message the server accepts, in bytes")
+	fs.IntVar(&f.MaxSendMsgSize, "max-send-msg-size", DefaultMaxMsgSize, "largest message the server sends, in bytes")
+	fs.IntVar(&f.InitialWindowSize, "initial-window-size", 0, "per-stream receive window in bytes (at least 64KB), 0 lets gRPC size it dynamically")
+	fs.IntVar(&f.InitialConnWindowSize, "initial-conn-window-size", 0, "per-connection receive window in bytes (at least 64KB), 0 lets gRPC size it dynamically")
+	fs.UintVar(&f.MaxConcurrentStreams, "max-concurrent-streams", 0, "streams allowed per connection, 0 means no limit")
+	fs.DurationVar(&f.ShutdownGrace, "shutdown-grace", 10*time.Second, "time in-flight calls get to finish on SIGINT/SIGTERM")
+	return f
+}
+
+// ServerOptions 返回按参数配置的服务端选项
+func (f *ServerFlags) ServerOptions() []grpc.ServerOption {
+	opts := []grpc.ServerOption{
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             f.MinPingInterval,
+			PermitWithoutStream: f.PermitWithoutStream,
+		}),
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			MaxConnectionIdle:     f.MaxConnectionIdle,
+			MaxConnectionAge:      f.MaxConnectionAge,
+			MaxConnectionAgeGrace: f.MaxConnectionAgeGrace,
+			Time:                  f.PingInterval,
+			Timeout:               f.PingTimeout,
+		}),
+		grpc.MaxRecvMsgSize(f.MaxRecvMsgSize),
+		grpc.MaxSendMsgSize(f.MaxSendMsgSize),
+	}
+	// 设置了窗口大小就会关闭动态调整，所以只在明确指定时才传
+	if f.InitialWindowSize > 0 {
+		opts = append(opts, grpc.InitialWindowSize(int32(f.InitialWindowSize)))
+	}
+	if f.InitialConnWindowSize > 0 {
+		opts = append(opts, grpc.InitialConnWindowSize(int32(f.InitialConnWindowSize)))
+	}
+	if f.MaxConcurrentStreams > 0 {
+		opts = append(opts, grpc.MaxConcurrentStreams(uint32(f.MaxConcurrentStreams)))
+	}
+	return opts
+}
+
+// ClientFlags 保存客户端的传输参数
+type ClientFlags struct {
+	// 连接空闲这么久后客户端 ping 服务端，为 0 表示不发；不能小于服务端的 -keepalive-min-time
+	PingInterval          time.Duration
+	PingTimeout           time.Duration
+	PermitWithoutStream   bool
+	MaxRecvMsgSize        int
+	MaxSendMsgSize        int
+	InitialWindowSize     int
+	InitialConnWindowSize int
+}
+
+// RegisterClientFlags 注册客户端参数，需要在 flag.Parse 之前调用
+func RegisterClientFlags(fs *flag.FlagSet) *ClientFlags {
+	f := &ClientFlags{}
+	fs.DurationVar(&f.PingInterval, "keepalive-time", 30*time.Second, "ping the server after the connection has been idle this long, 0 disables it")
+	fs.DurationVar(&f.PingTimeout, "keepalive-timeout", 10*time.Second, "treat the connection as broken when a ping is not answered within this time")
+	fs.BoolVar(&f.PermitWithoutStream, "keepalive-permit-without-stream", false, "also ping when there are no active streams")
+	fs.IntVar(&f.MaxRecvMsgSize, "max-recv-msg-size", DefaultMaxMsgSize, "largest message the client accepts, in bytes")
+	fs.IntVar(&f.MaxSendMsgSize, "max-send-msg-size", DefaultMaxMsgSize, "largest message the client sends, in bytes")
+	fs.IntVar(&f.InitialWindowSize, "initial-window-size", 0, "per-stream receive window in bytes (at least 64KB), 0 lets gRPC size it dynamically")
+	fs.IntVar(&f.InitialConnWindowSize, "initial-conn-window-size", 0, "per-connection receive window in bytes (at least 64KB), 0 lets gRPC size it dynamically")
+	return f
+}
+
+// DialOptions 返回按参数配置的客户端选项
+func (f *ClientFlags) DialOptions() []grpc.DialOption {
+	opts := []grpc.DialOption{
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(f.MaxRecvMsgSize), grpc.MaxCallSendMsgSize(f.MaxSendMsgSize)),
+	}
+	if f.PingInterval > 0 {
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                f.PingInterval,
+			Timeout:             f.PingTimeout,
+			PermitWithoutStream: f.PermitWithoutStream,
+		}))
+	}
+	if f.InitialWindowSize > 0 {
+		opts = append(opts, grpc.WithInitialWindowSize(int32(f.InitialWindowSize)))
+	}
+	if f.InitialConnWindowSize > 0 {
+		opts = append(opts, grpc.WithInitialConnWindowSize(int32(f.InitialConnWindowSize)))
+	}
+	return opts
+}
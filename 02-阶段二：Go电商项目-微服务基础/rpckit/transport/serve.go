@@ -0,0 +1,58 @@
+/**
+ * @File : serve.go
+ * @Description : 运行 gRPC 服务直到收到 SIGINT/SIGTERM，然后在限定时间内排空进行中的调用
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package transport
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Shutdown 先 GracefulStop：不再接受新连接和新调用，并向已有连接发送 GOAWAY，等进行中的调用结束。
+// 超过 grace 仍未结束就强制 Stop，这时剩下的调用会收到 Unavailable。返回是否在期限内排空
+func Shutdown(s *grpc.Server, grace time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		s.Stop()
+		<-done
+		return false
+	}
+}
+
+// Serve 在 lis 上运行 s，收到 SIGINT 或 SIGTERM 后按 -shutdown-grace 排空再返回
+func (f *ServerFlags) Serve(s *grpc.Server, lis net.Listener) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errc := make(chan error, 1)
+	go func() { errc <- s.Serve(lis) }()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	log.Printf("shutting down, waiting up to %v for in-flight calls", f.ShutdownGrace)
+	if !Shutdown(s, f.ShutdownGrace) {
+		log.Printf("in-flight calls did not finish within %v, closed them", f.ShutdownGrace)
+	}
+	// GracefulStop 或 Stop 之后 Serve 返回 nil
+	return <-errc
+}
@@ -0,0 +1,202 @@
+/**
+ * @File : transport_test.go
+ * @Description : 测试消息大小上限、连接到达最大存活时间后的平滑迁移，以及 Shutdown 的排空和强制关闭
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package transport
+
+import (
+	"context"
+	"flag"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	testpb "google.golang.org/grpc/interop/grpc_testing"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"io"
+	"net"
+	"rpckit/grpctest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func serverFlags(t *testing.T, args ...string) *ServerFlags {
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	f := RegisterServerFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func clientFlags(t *testing.T, args ...string) *ClientFlags {
+	fs := flag.NewFlagSet("client", flag.ContinueOnError)
+	f := RegisterClientFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+type testServer struct {
+	testpb.UnimplementedTestServiceServer
+}
+
+func (testServer) UnaryCall(_ context.Context, req *testpb.SimpleRequest) (*testpb.SimpleResponse, error) {
+	return &testpb.SimpleResponse{Payload: &testpb.Payload{Body: make([]byte, req.ResponseSize)}}, nil
+}
+
+// StreamingOutputCall 每条消息之前等待 IntervalUs，用来制造一个持续一段时间的调用
+func (testServer) StreamingOutputCall(req *testpb.StreamingOutputCallRequest, stream testpb.TestService_StreamingOutputCallServer) error {
+	for _, p := range req.ResponseParameters {
+		select {
+		case <-time.After(time.Duration(p.IntervalUs) * time.Microsecond):
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		}
+		if err := stream.Send(&testpb.StreamingOutputCallResponse{Payload: &testpb.Payload{Body: make([]byte, p.Size)}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func register(s *grpc.Server) { testpb.RegisterTestServiceServer(s, testServer{}) }
+
+// slowCall 发起一个大约持续 n*interval 的服务端流
+func slowCall(client testpb.TestServiceClient, n int, interval time.Duration) error {
+	req := &testpb.StreamingOutputCallRequest{}
+	for i := 0; i < n; i++ {
+		req.ResponseParameters = append(req.ResponseParameters, &testpb.ResponseParameters{Size: 10, IntervalUs: int32(interval / time.Microsecond)})
+	}
+	stream, err := client.StreamingOutputCall(context.Background(), req)
+	if err != nil {
+		return err
+	}
+	for {
+		if _, err := stream.Recv(); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+func TestMaxMsgSize(t *testing.T) {
+	tests := []struct {
+		name         string
+		serverArgs   []string
+		clientArgs   []string
+		requestSize  int
+		responseSize int32
+		code         codes.Code
+	}{
+		{"within the defaults", nil, nil, 1 << 20, 1 << 20, codes.OK},
+		{"request over the server limit", []string{"-max-recv-msg-size=1024"}, nil, 2048, 10, codes.ResourceExhausted},
+		{"response over the server limit", []string{"-max-send-msg-size=1024"}, nil, 10, 2048, codes.ResourceExhausted},
+		{"request over the client limit", nil, []string{"-max-send-msg-size=1024"}, 2048, 10, codes.ResourceExhausted},
+		{"response over the client limit", nil, []string{"-max-recv-msg-size=1024"}, 10, 2048, codes.ResourceExhausted},
+		{"default limit", nil, nil, DefaultMaxMsgSize + 1, 10, codes.ResourceExhausted},
+	}
+	for _, tt := range tests {
+		conn := grpctest.Start(t, register,
+			grpctest.WithServerOptions(serverFlags(t, tt.serverArgs...).ServerOptions()...),
+			grpctest.WithDialOptions(clientFlags(t, tt.clientArgs...).DialOptions()...))
+		req := &testpb.SimpleRequest{ResponseSize: tt.responseSize, Payload: &testpb.Payload{Body: make([]byte, tt.requestSize)}}
+		_, err := testpb.NewTestServiceClient(conn).UnaryCall(context.Background(), req)
+		if code := status.Code(err); code != tt.code {
+			t.Errorf("%s: UnaryCall() error = %v, expect code %v", tt.name, err, tt.code)
+		}
+	}
+}
+
+// connCounter 统计服务端建立过的连接数
+type connCounter struct {
+	conns atomic.Int32
+}
+
+func (c *connCounter) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context   { return ctx }
+func (c *connCounter) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context { return ctx }
+func (c *connCounter) HandleRPC(context.Context, stats.RPCStats)                         {}
+func (c *connCounter) HandleConn(_ context.Context, s stats.ConnStats) {
+	if _, ok := s.(*stats.ConnBegin); ok {
+		c.conns.Add(1)
+	}
+}
+
+func TestMaxConnectionAge(t *testing.T) {
+	tests := []struct {
+		name  string
+		grace string
+		code  codes.Code
+	}{
+		// 连接在 100ms 时收到 GOAWAY，持续 400ms 的调用在宽限期内正常结束
+		{"call finishes within the grace period", "2s", codes.OK},
+		// 宽限期太短，进行中的调用被关闭
+		{"call outlives the grace period", "50ms", codes.Unavailable},
+	}
+	for _, tt := range tests {
+		counter := &connCounter{}
+		flags := serverFlags(t, "-max-conn-age=100ms", "-max-conn-age-grace="+tt.grace)
+		conn := grpctest.Start(t, register,
+			grpctest.WithServerOptions(append(flags.ServerOptions(), grpc.StatsHandler(counter))...))
+		client := testpb.NewTestServiceClient(conn)
+
+		err := slowCall(client, 8, 50*time.Millisecond)
+		if code := status.Code(err); code != tt.code {
+			t.Errorf("%s: slow call error = %v, expect code %v", tt.name, err, tt.code)
+		}
+		// 客户端收到 GOAWAY 后重新建立连接，之后的调用不受影响
+		if _, err := client.UnaryCall(context.Background(), &testpb.SimpleRequest{}); err != nil {
+			t.Errorf("%s: UnaryCall() after GOAWAY error = %v", tt.name, err)
+		}
+		if n := counter.conns.Load(); n < 2 {
+			t.Errorf("%s: server saw %d connections, expect the client to reconnect", tt.name, n)
+		}
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	tests := []struct {
+		name    string
+		grace   time.Duration
+		drained bool
+		code    codes.Code
+	}{
+		{"in-flight call is drained", 2 * time.Second, true, codes.OK},
+		{"in-flight call is cut off", 50 * time.Millisecond, false, codes.Unavailable},
+	}
+	for _, tt := range tests {
+		lis := bufconn.Listen(1 << 20)
+		s := grpc.NewServer()
+		register(s)
+		go s.Serve(lis)
+		conn, err := grpc.NewClient("passthrough:///bufconn",
+			grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := testpb.NewTestServiceClient(conn)
+
+		errc := make(chan error, 1)
+		go func() { errc <- slowCall(client, 6, 50*time.Millisecond) }()
+		time.Sleep(100 * time.Millisecond) // 等调用开始
+		if drained := Shutdown(s, tt.grace); drained != tt.drained {
+			t.Errorf("%s: Shutdown() = %v, expect %v", tt.name, drained, tt.drained)
+		}
+		if code := status.Code(<-errc); code != tt.code {
+			t.Errorf("%s: in-flight call ended with %v, expect %v", tt.name, code, tt.code)
+		}
+		// 关闭之后不再接受新调用
+		if _, err := client.UnaryCall(context.Background(), &testpb.SimpleRequest{}); err == nil {
+			t.Errorf("%s: UnaryCall() after Shutdown error = nil, expect a failure", tt.name)
+		}
+		conn.Close()
+	}
+}
@@ -7,17 +7,24 @@
 package main
 
 import (
+	"context"                         // 导入 context 包，携带幂等键
+	"flag"                            // 导入命令行参数包
 	"fmt"                             // 导入 fmt 包，用于输出
 	"grpc_test/full_rpc/client_proxy" // 引入客户端代理包
+	"rpckit/idempotency"              // 引入幂等包，重试时带上同一个幂等键
 )
 
 func main() {
+	compressor := flag.String("compressor", "", "compress requests with gzip or zstd, empty sends them uncompressed")
+	flag.Parse()
+
 	// 创建与服务端的连接代理，使用 TCP 协议连接 127.0.0.1:1234 地址
-	conn := client_proxy.NewHelloServiceStub("tcp", "127.0.0.1:1234")
+	conn := client_proxy.NewHelloServiceStub("tcp", "127.0.0.1:1234", *compressor)
 
 	var reply string // 用于接收服务端返回的数据
-	// 调用远程 Hello 方法，传入请求 "cc"
-	err := conn.Hello("cc", &reply)
+	// 调用远程 Hello 方法，传入请求 "cc"；带上幂等键，失败后用同一个 ctx 重试时服务端不会重复执行
+	ctx := idempotency.WithKey(context.Background(), idempotency.NewKey())
+	err := conn.HelloContext(ctx, "cc", &reply)
 	if err != nil {
 		// 记录调用失败的错误日志
 		fmt.Printf("远程调用失败: %v\n", err)
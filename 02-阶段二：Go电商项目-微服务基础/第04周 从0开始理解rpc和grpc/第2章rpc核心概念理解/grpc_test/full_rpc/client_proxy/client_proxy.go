@@ -7,35 +7,51 @@
 package client_proxy
 
 import (
+	"context"                    // 导入 context 包，携带链路追踪信息
 	"grpc_test/full_rpc/handler" // 引入处理业务逻辑的包
 	"log"                        // 导入日志包，便于记录日志
-	"net/rpc"                    // 导入 RPC 包，处理远程过程调用
+	"net"                        // 导入网络包，建立连接
+	"rpckit/compress"            // 引入压缩包，请求和响应按帧压缩
+	"rpckit/trace"               // 引入链路追踪包，在请求信封中携带 traceparent
 )
 
 // HelloServiceStub 是客户端调用远程服务的代理
 // 通过此结构体封装与服务端的连接和调用逻辑
 type HelloServiceStub struct {
-	*rpc.Client // 嵌入 rpc.Client，实现 RPC 调用功能
+	*trace.Client // 嵌入 trace.Client，实现 RPC 调用功能，请求信封中可以携带 traceparent
+	tracer        *trace.Tracer
 }
 
 // NewHelloServiceStub 函数：用于创建一个新的服务代理实例
 // 参数 protcol 是连接协议，如 "tcp"，addr 是服务端地址
-func NewHelloServiceStub(protocol, addr string) HelloServiceStub {
+// compressor 是压缩算法（gzip、zstd），为空时不压缩；服务端的响应会使用同样的算法
+func NewHelloServiceStub(protocol, addr, compressor string) HelloServiceStub {
 	// 建立与服务端的连接
-	conn, err := rpc.Dial(protocol, addr)
+	conn, err := net.Dial(protocol, addr)
 	if err != nil {
 		// 日志记录连接错误，避免 panic
 		log.Fatalf("连接失败: %v", err)
 	}
-	// 返回封装好的 HelloServiceStub 实例
-	return HelloServiceStub{conn}
+	// 连接上先是压缩分帧，不小于阈值的请求才压缩
+	cc, err := compress.NewConn(conn, compressor, compress.DefaultThreshold)
+	if err != nil {
+		log.Fatalf("压缩配置错误: %v", err)
+	}
+	// 服务端在 gob 编码外加了一层信封，客户端要用配套的 trace.Client
+	// 返回封装好的 HelloServiceStub 实例，客户端只传播 traceparent，不记录 span
+	return HelloServiceStub{Client: trace.NewClient(cc), tracer: trace.NewTracer("hello-client", nil)}
 }
 
 // Hello 方法：调用服务端的 Hello 方法，发送请求并接收响应
 // 参数 request 是发送给服务端的请求数据，reply 是服务端返回的响应
 func (s *HelloServiceStub) Hello(request string, reply *string) error {
+	return s.HelloContext(context.Background(), request, reply)
+}
+
+// HelloContext 方法：与 Hello 相同，ctx 中有 span 时服务端的 span 会成为它的子节点
+func (s *HelloServiceStub) HelloContext(ctx context.Context, request string, reply *string) error {
 	// 调用服务端的 Hello 方法
-	err := s.Call(handler.HelloServiceName+".Hello", request, reply)
+	err := s.tracer.Call(ctx, s.Client, handler.HelloServiceName+".Hello", request, reply)
 	if err != nil {
 		// 记录错误日志，返回错误
 		log.Printf("RPC 调用失败: %v", err)
@@ -7,14 +7,35 @@
 package main
 
 import (
+	"flag"                            // 导入命令行参数包
 	"grpc_test/full_rpc/handler"      // 引入处理业务逻辑的包
 	"grpc_test/full_rpc/server_proxy" // 引入服务代理注册的包
 	"log"                             // 导入日志包，便于记录日志
 	"net"                             // 导入网络包，用于监听 TCP 连接
 	"net/rpc"                         // 导入 RPC 包，用于处理远程过程调用
+	"rpckit/compress"                 // 引入压缩包，请求和响应按帧压缩
+	"rpckit/idempotency"              // 引入幂等包，带幂等键的重复请求直接重放第一次的结果
+	"rpckit/metrics"                  // 引入指标包，在 codec 层记录每次调用
+	"rpckit/trace"                    // 引入链路追踪包，从请求信封中取出 traceparent
+	"time"                            // 导入时间包，设置打开数据库的超时
 )
 
 func main() {
+	metricsAddr := flag.String("metrics-addr", "127.0.0.1:9090", "address of the Prometheus /metrics endpoint, empty disables it")
+	traceFile := flag.String("trace-file", "", "append spans to this file as JSON lines, empty only propagates traceparent")
+	threshold := flag.Int("compress-threshold", compress.DefaultThreshold, "responses smaller than this many bytes are sent uncompressed")
+	idempotencyDB := flag.String("idempotency-db", "", "BoltDB file that keeps idempotency records across restarts, empty keeps them in memory")
+	flag.Parse()
+
+	// 所有连接共用一份指标，通过 /metrics 以 Prometheus 文本格式导出
+	reg := metrics.NewRegistry()
+	rpcMetrics := metrics.NewRPCServer(reg)
+	metrics.Serve(*metricsAddr, reg)
+	tracer, err := trace.OpenTracer("hello", *traceFile)
+	if err != nil {
+		log.Fatalf("打开 span 文件失败: %v", err)
+	}
+
 	// 创建监听器，监听 TCP 端口 1234
 	listener, err := net.Listen("tcp", ":1234")
 	if err != nil {
@@ -23,12 +44,27 @@ func main() {
 	}
 
 	// 注册 Hello 服务
-	err = server_proxy.RegisterHelloService(&handler.HelloServer{})
+	hello := &handler.HelloServer{}
+	err = server_proxy.RegisterHelloService(hello)
 	if err != nil {
 		// 错误处理，确保服务注册成功
 		log.Fatalf("服务注册失败: %v", err)
 	}
 
+	// 幂等记录默认保存在内存中，指定文件后重启也不会丢
+	var store idempotency.Store = idempotency.NewMemory()
+	if *idempotencyDB != "" {
+		if store, err = idempotency.OpenBolt(*idempotencyDB, time.Second); err != nil {
+			log.Fatalf("打开幂等数据库失败: %v", err)
+		}
+	}
+	defer store.Close()
+	guard := idempotency.New(store, idempotency.Options{})
+	// 重放时需要知道返回值的类型，net/rpc 不公开这些信息，要在这里再登记一次
+	if err := guard.RegisterName(handler.HelloServiceName, hello); err != nil {
+		log.Fatalf("登记幂等方法失败: %v", err)
+	}
+
 	// 循环处理客户端连接
 	for {
 		conn, err := listener.Accept() // 接收客户端连接
@@ -38,6 +74,11 @@ func main() {
 			continue
 		}
 		// 使用 goroutine 并发处理客户端请求，避免阻塞
-		go rpc.ServeConn(conn)
+		// gob 编码外面加了一层带 traceparent 的信封，客户端需要使用 trace.NewClient
+		// 指标包在最外层，请求大小包含信封；幂等 codec 紧贴着信封，从中读取幂等键
+		// 压缩分帧紧贴着连接，字节计数记录的是压缩后真正上线的字节数；响应使用客户端选择的算法
+		counter := metrics.NewByteCounter(conn)
+		cc := compress.NewServerConn(counter, *threshold)
+		go rpc.ServeCodec(rpcMetrics.WrapServerCodec(guard.WrapServerCodec(trace.NewServerCodec(cc, tracer)), counter))
 	}
 }
@@ -2,8 +2,11 @@ module grpc_test
 
 go 1.22.5
 
+require rpckit v0.0.0
+
 require (
-	github.com/golang/protobuf v1.5.4 // indirect
+	github.com/klauspost/compress v1.18.0 // indirect
+	go.etcd.io/bbolt v1.3.11 // indirect
 	golang.org/x/net v0.30.0 // indirect
 	golang.org/x/sys v0.26.0 // indirect
 	golang.org/x/text v0.19.0 // indirect
@@ -11,3 +14,5 @@ require (
 	google.golang.org/grpc v1.67.1 // indirect
 	google.golang.org/protobuf v1.34.2 // indirect
 )
+
+replace rpckit => ../../../rpckit
@@ -3,13 +3,23 @@ module grpc_protoc
 go 1.22.5
 
 require (
+	api v0.0.0
 	google.golang.org/grpc v1.67.1
-	google.golang.org/protobuf v1.34.2
+	rpckit v0.0.0
 )
 
 require (
+	github.com/klauspost/compress v1.18.0 // indirect
+	go.etcd.io/bbolt v1.3.11 // indirect
 	golang.org/x/net v0.28.0 // indirect
+	golang.org/x/sync v0.8.0 // indirect
 	golang.org/x/sys v0.24.0 // indirect
 	golang.org/x/text v0.17.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20240814211410-ddb44dafa142 // indirect
+	google.golang.org/protobuf v1.34.2 // indirect
+)
+
+replace (
+	api => ../../../api
+	rpckit => ../../../rpckit
 )
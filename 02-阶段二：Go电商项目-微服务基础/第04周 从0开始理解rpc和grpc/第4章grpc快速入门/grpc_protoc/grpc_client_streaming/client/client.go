@@ -7,28 +7,48 @@
 package main
 
 import (
+	"api/sum/v1"
 	"context"
+	"flag"
 	"fmt"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
-	"grpc_protoc/grpc_client_streaming/proto"
+	"rpckit/compress"
+	"rpckit/retry"
+	"rpckit/tlsutil"
+	"rpckit/transport"
 	"time"
 )
 
 func main() {
-	conn, err := grpc.NewClient("127.0.0.1:50051", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	serviceConfig := flag.String("service-config", "service_config.json", "gRPC service config with per-method retry, hedging and timeout policies")
+	compressFlags := compress.RegisterClientFlags(flag.CommandLine)
+	transportFlags := transport.RegisterClientFlags(flag.CommandLine)
+	tlsFlags := tlsutil.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	creds, err := tlsFlags.DialOption()
+	if err != nil {
+		panic(err)
+	}
+	policy, err := retry.Load(*serviceConfig)
+	if err != nil {
+		panic(err)
+	}
+
+	opts := append(policy.DialOptions(), compressFlags.DialOptions()...)
+	opts = append(opts, transportFlags.DialOptions()...)
+	conn, err := grpc.NewClient("127.0.0.1:50051", append(opts, creds)...)
 	if err != nil {
 		panic(err)
 	}
 	defer conn.Close()
-	c := proto.NewSumServiceClient(conn)
+	c := sumv1.NewSumServiceClient(conn)
 
 	r, err := c.StreamSum(context.Background())
 	if err != nil {
 		panic(err)
 	}
 	for i := 1; i <= 10; i++ {
-		err := r.Send(&proto.SumRequest{Number: int32(i)})
+		err := r.Send(&sumv1.SumRequest{Number: int32(i)})
 		if err != nil {
 			panic(err)
 		}
@@ -40,4 +60,5 @@ func main() {
 		panic(err)
 	}
 	fmt.Println("Received sum: " + fmt.Sprint(a.Sum))
+	fmt.Print(policy.Metrics())
 }
@@ -7,18 +7,24 @@
 package main
 
 import (
+	"api/sum/v1"
+	"flag"
 	"fmt"
 	"google.golang.org/grpc"
-	"grpc_protoc/grpc_client_streaming/proto"
 	"io"
 	"net"
+	"rpckit/compress"
+	"rpckit/metrics"
+	"rpckit/tlsutil"
+	"rpckit/trace"
+	"rpckit/transport"
 )
 
 type server struct {
-	proto.UnimplementedSumServiceServer
+	sumv1.UnimplementedSumServiceServer
 }
 
-func (s *server) StreamSum(stream proto.SumService_StreamSumServer) error {
+func (s *server) StreamSum(stream sumv1.SumService_StreamSumServer) error {
 	var sum int32
 	for {
 		req, err := stream.Recv()
@@ -32,15 +38,40 @@ func (s *server) StreamSum(stream proto.SumService_StreamSumServer) error {
 		fmt.Println("Received number: " + fmt.Sprint(req.Number))
 	}
 	fmt.Println("Returning sum: " + fmt.Sprint(sum))
-	return stream.SendAndClose(&proto.SumResponse{Sum: sum})
+	return stream.SendAndClose(&sumv1.SumResponse{Sum: sum})
 }
 
 func main() {
-	listen, err := net.Listen("tcp", ":50051")
+	addr := flag.String("addr", ":50051", "listen address")
+	traceFile := flag.String("trace-file", "", "append spans to this file as JSON lines, empty only propagates traceparent")
+	metricsAddr := flag.String("metrics-addr", "127.0.0.1:9090", "address of the Prometheus /metrics endpoint, empty disables it")
+	compressFlags := compress.RegisterServerFlags(flag.CommandLine)
+	transportFlags := transport.RegisterServerFlags(flag.CommandLine)
+	tlsFlags := tlsutil.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	creds, err := tlsFlags.ServerOption()
 	if err != nil {
 		panic(err)
 	}
-	s := grpc.NewServer()
-	proto.RegisterSumServiceServer(s, &server{})
-	s.Serve(listen)
+	// 请求数、耗时、消息数和大小都由 stats handler 记录，访问 /metrics 查看
+	reg := metrics.NewRegistry()
+	metrics.Serve(*metricsAddr, reg)
+	tracer, err := trace.OpenTracer("sum", *traceFile)
+	if err != nil {
+		panic(err)
+	}
+
+	listen, err := net.Listen("tcp", *addr)
+	if err != nil {
+		panic(err)
+	}
+	// keepalive、消息大小、流控窗口和连接存活时间由 transport 参数统一配置
+	opts := append(transportFlags.ServerOptions(), creds,
+		grpc.StatsHandler(metrics.NewServerHandler(reg)),
+		grpc.ChainUnaryInterceptor(tracer.UnaryServerInterceptor(), compressFlags.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(tracer.StreamServerInterceptor(), compressFlags.StreamServerInterceptor()),
+	)
+	s := grpc.NewServer(opts...)
+	sumv1.RegisterSumServiceServer(s, &server{})
+	transportFlags.Serve(s, listen)
 }
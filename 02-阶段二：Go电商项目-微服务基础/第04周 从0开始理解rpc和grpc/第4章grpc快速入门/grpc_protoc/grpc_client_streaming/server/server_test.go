@@ -0,0 +1,49 @@
+/**
+ * @File : server_test.go
+ * @Description : 通过 bufconn 测试 SumService 的客户端流
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package main
+
+import (
+	"api/sum/v1"
+	"context"
+	"google.golang.org/grpc"
+	"rpckit/grpctest"
+	"testing"
+)
+
+func TestStreamSum(t *testing.T) {
+	conn := grpctest.Start(t, func(s *grpc.Server) { sumv1.RegisterSumServiceServer(s, &server{}) })
+	client := sumv1.NewSumServiceClient(conn)
+
+	tests := []struct {
+		name    string
+		numbers []int32
+		exp     int32
+	}{
+		{"one to ten", []int32{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}, 55},
+		{"no numbers", nil, 0},
+		{"negative", []int32{-3, 5, -7}, -5},
+		{"single", []int32{42}, 42},
+	}
+	for _, tt := range tests {
+		stream, err := client.StreamSum(context.Background())
+		if err != nil {
+			t.Fatalf("%s: StreamSum() error = %v", tt.name, err)
+		}
+		for _, n := range tt.numbers {
+			if err := stream.Send(&sumv1.SumRequest{Number: n}); err != nil {
+				t.Fatalf("%s: Send(%d) error = %v", tt.name, n, err)
+			}
+		}
+		resp, err := stream.CloseAndRecv()
+		if err != nil {
+			t.Fatalf("%s: CloseAndRecv() error = %v", tt.name, err)
+		}
+		if resp.Sum != tt.exp {
+			t.Errorf("%s: sum = %d, expect %d", tt.name, resp.Sum, tt.exp)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+/**
+ * @File : client.go
+ * @Description : FileService 客户端：上传时先查询服务端已收到的字节数再续传，下载支持区间并校验 SHA-256
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package main
+
+// 用法示例：
+//
+//	go run ./grpc_file_streaming/client upload shoe.jpg
+//	go run ./grpc_file_streaming/client stat shoe.jpg
+//	go run ./grpc_file_streaming/client download shoe.jpg out.jpg
+//	go run ./grpc_file_streaming/client -offset 1024 -length 4096 download shoe.jpg part.bin
+
+import (
+	"api/file/v1"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"rpckit/compress"
+	"rpckit/tlsutil"
+	"rpckit/transport"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:50051", "file server address")
+	chunkSize := flag.Int("chunk-size", 64<<10, "bytes per message when uploading")
+	offset := flag.Int64("offset", 0, "download: first byte to fetch")
+	length := flag.Int64("length", 0, "download: number of bytes to fetch, 0 means to the end")
+	compressFlags := compress.RegisterClientFlags(flag.CommandLine)
+	transportFlags := transport.RegisterClientFlags(flag.CommandLine)
+	tlsFlags := tlsutil.RegisterFlags(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: client [flags] upload <path> [name] | download <name> <out> | stat <name>\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	args := flag.Args()
+	if len(args) < 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	creds, err := tlsFlags.DialOption()
+	if err != nil {
+		panic(err)
+	}
+	opts := append(compressFlags.DialOptions(), transportFlags.DialOptions()...)
+	conn, err := grpc.NewClient(*addr, append(opts, creds)...)
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+	c := filev1.NewFileServiceClient(conn)
+	ctx := context.Background()
+
+	switch args[0] {
+	case "upload":
+		name := filepath.Base(args[1])
+		if len(args) > 2 {
+			name = args[2]
+		}
+		err = upload(ctx, c, args[1], name, *chunkSize)
+	case "download":
+		if len(args) < 3 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		err = download(ctx, c, args[1], args[2], *offset, *length)
+	case "stat":
+		var fi *filev1.FileInfo
+		if fi, err = c.Stat(ctx, &filev1.StatRequest{Name: args[1]}); err == nil {
+			fmt.Printf("%s: %d bytes, %s, sha256 %s, complete %v, received %d\n", fi.Name, fi.Size, fi.ContentType, fi.Sha256, fi.Complete, fi.Received)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func hashFile(f *os.File) (string, int64, error) {
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// resumeOffset 问服务端这个文件已经收到多少字节：同一个文件（大小和 SHA-256 都相同）的未完成上传从断点继续，否则从头开始。
+// 服务端已经有完全相同的文件时返回 -1
+func resumeOffset(ctx context.Context, c filev1.FileServiceClient, name, sum string, size int64) (int64, error) {
+	fi, err := c.Stat(ctx, &filev1.StatRequest{Name: name})
+	if status.Code(err) == codes.NotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if fi.Sha256 != sum || fi.Size != size {
+		return 0, nil
+	}
+	if fi.Complete {
+		return -1, nil
+	}
+	return fi.Received, nil
+}
+
+func upload(ctx context.Context, c filev1.FileServiceClient, path, name string, chunkSize int) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	sum, size, err := hashFile(f)
+	if err != nil {
+		return err
+	}
+	offset, err := resumeOffset(ctx, c, name, sum, size)
+	if err != nil {
+		return err
+	}
+	if offset < 0 {
+		fmt.Printf("%s is already on the server (sha256 %s)\n", name, sum)
+		return nil
+	}
+	if offset > 0 {
+		fmt.Printf("resuming %s at byte %d of %d\n", name, offset, size)
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	stream, err := c.Upload(ctx)
+	if err != nil {
+		return err
+	}
+	meta := &filev1.FileMeta{Name: name, Size: size, Sha256: sum, Offset: offset}
+	if err := stream.Send(&filev1.UploadRequest{Data: &filev1.UploadRequest_Meta{Meta: meta}}); err != nil {
+		return closeErr(stream, err)
+	}
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := f.Read(buf)
+		if n > 0 {
+			if err := stream.Send(&filev1.UploadRequest{Data: &filev1.UploadRequest_Chunk{Chunk: buf[:n]}}); err != nil {
+				return closeErr(stream, err)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	fi, err := stream.CloseAndRecv()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("uploaded %s: %d bytes, %s, sha256 %s\n", fi.Name, fi.Size, fi.ContentType, fi.Sha256)
+	return nil
+}
+
+// closeErr 在 Send 失败时取回服务端给出的真正错误，Send 本身只会返回 io.EOF
+func closeErr(stream filev1.FileService_UploadClient, err error) error {
+	if err == io.EOF {
+		_, err = stream.CloseAndRecv()
+	}
+	return err
+}
+
+func download(ctx context.Context, c filev1.FileServiceClient, name, out string, offset, length int64) error {
+	stream, err := c.Download(ctx, &filev1.DownloadRequest{Name: name, Offset: offset, Length: length})
+	if err != nil {
+		return err
+	}
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	fi := first.GetInfo()
+	if fi == nil {
+		return fmt.Errorf("the first message of a download must be info")
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	w := io.MultiWriter(f, h)
+	var n int64
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		m, err := w.Write(resp.GetChunk())
+		if err != nil {
+			return err
+		}
+		n += int64(m)
+	}
+	// 只有下载完整文件时才能和服务端的 SHA-256 对比
+	if offset == 0 && n == fi.Size {
+		if sum := hex.EncodeToString(h.Sum(nil)); sum != fi.Sha256 {
+			return fmt.Errorf("sha256 mismatch: got %s, expect %s", sum, fi.Sha256)
+		}
+		fmt.Printf("downloaded %s to %s: %d bytes, %s, sha256 verified\n", name, out, n, fi.ContentType)
+		return nil
+	}
+	fmt.Printf("downloaded bytes [%d, %d) of %s (%d bytes) to %s\n", offset, offset+n, name, fi.Size, out)
+	return nil
+}
@@ -0,0 +1,205 @@
+/**
+ * @File : server.go
+ * @Description : FileService 服务端：客户端流分块上传（带 SHA-256 校验和断点续传），服务端流按区间下载
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package main
+
+import (
+	"api/file/v1"
+	"context"
+	"errors"
+	"flag"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"log"
+	"net"
+	"rpckit/compress"
+	"rpckit/metrics"
+	"rpckit/tlsutil"
+	"rpckit/trace"
+	"rpckit/transport"
+)
+
+type server struct {
+	filev1.UnimplementedFileServiceServer
+	store     *storage
+	uploads   chan struct{} // 容量就是同时进行的上传数上限
+	chunkSize int
+}
+
+func newServer(store *storage, maxUploads, chunkSize int) *server {
+	return &server{store: store, uploads: make(chan struct{}, maxUploads), chunkSize: chunkSize}
+}
+
+// toStatus 把存储层的错误转换成 gRPC 状态码，客户端据此决定是续传、重传还是放弃
+func toStatus(err error) error {
+	code := codes.Internal
+	switch {
+	case errors.Is(err, errNotFound):
+		code = codes.NotFound
+	case errors.Is(err, errBadName), errors.Is(err, errOverflow):
+		code = codes.InvalidArgument
+	case errors.Is(err, errOffset):
+		code = codes.FailedPrecondition
+	case errors.Is(err, errBusy), errors.Is(err, errIncomplete):
+		code = codes.Aborted
+	case errors.Is(err, errTooLarge):
+		code = codes.ResourceExhausted
+	case errors.Is(err, errChecksum):
+		code = codes.DataLoss
+	case errors.Is(err, errRange):
+		code = codes.OutOfRange
+	case errors.Is(err, errReplacing):
+		code = codes.Unavailable
+	}
+	return status.Error(code, err.Error())
+}
+
+func info(name string, st fileStat) *filev1.FileInfo {
+	return &filev1.FileInfo{
+		Name:        name,
+		Size:        st.Size,
+		Sha256:      st.SHA256,
+		ContentType: st.ContentType,
+		Complete:    st.complete,
+		Received:    st.received,
+	}
+}
+
+func (s *server) Upload(stream filev1.FileService_UploadServer) error {
+	select {
+	case s.uploads <- struct{}{}:
+		defer func() { <-s.uploads }()
+	default:
+		return status.Errorf(codes.ResourceExhausted, "too many concurrent uploads (limit %d)", cap(s.uploads))
+	}
+
+	req, err := stream.Recv()
+	if err == io.EOF {
+		return status.Error(codes.InvalidArgument, "empty upload, the first message must be meta")
+	}
+	if err != nil {
+		return err
+	}
+	meta := req.GetMeta()
+	if meta == nil {
+		return status.Error(codes.InvalidArgument, "the first message must be meta")
+	}
+	u, err := s.store.begin(meta.Name, fileMeta{Size: meta.Size, SHA256: meta.Sha256, ContentType: meta.ContentType}, meta.Offset)
+	if err != nil {
+		return toStatus(err)
+	}
+
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// 连接断开或客户端取消：保留已收到的部分，客户端用 Stat 查到 received 后续传
+			u.close()
+			log.Printf("upload %s interrupted at %d bytes: %v", meta.Name, u.received, err)
+			return err
+		}
+		if req.GetMeta() != nil {
+			u.close()
+			return status.Error(codes.InvalidArgument, "meta may only be sent as the first message")
+		}
+		if err := u.write(req.GetChunk()); err != nil {
+			u.close()
+			return toStatus(err)
+		}
+	}
+
+	m, err := u.commit()
+	if err != nil {
+		return toStatus(err)
+	}
+	log.Printf("uploaded %s: %d bytes, %s, sha256 %s", meta.Name, m.Size, m.ContentType, m.SHA256)
+	return stream.SendAndClose(info(meta.Name, fileStat{fileMeta: m, complete: true}))
+}
+
+func (s *server) Download(req *filev1.DownloadRequest, stream filev1.FileService_DownloadServer) error {
+	m, r, err := s.store.openRange(req.Name, req.Offset, req.Length)
+	if err != nil {
+		return toStatus(err)
+	}
+	defer r.Close()
+
+	// 第一条消息告诉客户端整个文件的信息，客户端下载完整文件时可以用 sha256 校验
+	first := &filev1.DownloadResponse{Data: &filev1.DownloadResponse_Info{Info: info(req.Name, fileStat{fileMeta: m, complete: true})}}
+	if err := stream.Send(first); err != nil {
+		return err
+	}
+	buf := make([]byte, s.chunkSize)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			// Send 返回前消息已经序列化，buf 可以复用
+			if err := stream.Send(&filev1.DownloadResponse{Data: &filev1.DownloadResponse_Chunk{Chunk: buf[:n]}}); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+	}
+}
+
+func (s *server) Stat(_ context.Context, req *filev1.StatRequest) (*filev1.FileInfo, error) {
+	st, err := s.store.stat(req.Name)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return info(req.Name, st), nil
+}
+
+func main() {
+	addr := flag.String("addr", ":50051", "listen address")
+	dir := flag.String("dir", "files", "storage directory for uploaded files")
+	maxSize := flag.Int64("max-size", 10<<20, "largest file accepted, in bytes")
+	maxUploads := flag.Int("max-uploads", 16, "uploads allowed to run at the same time")
+	chunkSize := flag.Int("chunk-size", 64<<10, "bytes per message when downloading")
+	traceFile := flag.String("trace-file", "", "append spans to this file as JSON lines, empty only propagates traceparent")
+	metricsAddr := flag.String("metrics-addr", "127.0.0.1:9090", "address of the Prometheus /metrics endpoint, empty disables it")
+	compressFlags := compress.RegisterServerFlags(flag.CommandLine)
+	transportFlags := transport.RegisterServerFlags(flag.CommandLine)
+	tlsFlags := tlsutil.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	creds, err := tlsFlags.ServerOption()
+	if err != nil {
+		panic(err)
+	}
+	store, err := newStorage(*dir, *maxSize)
+	if err != nil {
+		panic(err)
+	}
+	reg := metrics.NewRegistry()
+	metrics.Serve(*metricsAddr, reg)
+	tracer, err := trace.OpenTracer("file", *traceFile)
+	if err != nil {
+		panic(err)
+	}
+
+	listen, err := net.Listen("tcp", *addr)
+	if err != nil {
+		panic(err)
+	}
+	// keepalive、消息大小、流控窗口和连接存活时间由 transport 参数统一配置
+	opts := append(transportFlags.ServerOptions(), creds,
+		grpc.StatsHandler(metrics.NewServerHandler(reg)),
+		grpc.ChainUnaryInterceptor(tracer.UnaryServerInterceptor(), compressFlags.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(tracer.StreamServerInterceptor(), compressFlags.StreamServerInterceptor()),
+	)
+	s := grpc.NewServer(opts...)
+	filev1.RegisterFileServiceServer(s, newServer(store, *maxUploads, *chunkSize))
+	log.Printf("file server listening on %s, storing files in %s", *addr, *dir)
+	transportFlags.Serve(s, listen)
+}
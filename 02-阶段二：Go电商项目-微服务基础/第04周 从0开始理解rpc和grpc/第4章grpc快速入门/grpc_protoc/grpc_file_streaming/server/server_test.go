@@ -0,0 +1,347 @@
+/**
+ * @File : server_test.go
+ * @Description : 通过 bufconn 测试 FileService：上传下载、校验失败、续传、区间下载和并发上传
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package main
+
+import (
+	"api/file/v1"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"io"
+	"os"
+	"rpckit/grpctest"
+	"sync"
+	"testing"
+)
+
+const chunkSize = 1024
+
+func startFileService(t *testing.T, maxSize int64) filev1.FileServiceClient {
+	store, err := newStorage(t.TempDir(), maxSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn := grpctest.Start(t, func(s *grpc.Server) { filev1.RegisterFileServiceServer(s, newServer(store, 16, chunkSize)) })
+	return filev1.NewFileServiceClient(conn)
+}
+
+// pngData 生成以 PNG 文件头开头的数据，服务端应当识别为 image/png
+func pngData(n int) []byte {
+	b := append([]byte("\x89PNG\x0D\x0A\x1A\x0A"), bytes.Repeat([]byte("pixel"), n/5+1)...)
+	return b[:n]
+}
+
+func sum(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
+
+// send 发送 meta 和 data 中的全部分块，返回 CloseAndRecv 的结果
+func send(ctx context.Context, c filev1.FileServiceClient, meta *filev1.FileMeta, data []byte) (*filev1.FileInfo, error) {
+	stream, err := c.Upload(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.Send(&filev1.UploadRequest{Data: &filev1.UploadRequest_Meta{Meta: meta}}); err != nil {
+		return stream.CloseAndRecv()
+	}
+	for len(data) > 0 {
+		n := min(chunkSize, len(data))
+		if err := stream.Send(&filev1.UploadRequest{Data: &filev1.UploadRequest_Chunk{Chunk: data[:n]}}); err != nil {
+			return stream.CloseAndRecv()
+		}
+		data = data[n:]
+	}
+	return stream.CloseAndRecv()
+}
+
+func fetch(ctx context.Context, c filev1.FileServiceClient, req *filev1.DownloadRequest) (*filev1.FileInfo, []byte, error) {
+	stream, err := c.Download(ctx, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	first, err := stream.Recv()
+	if err != nil {
+		return nil, nil, err
+	}
+	var data []byte
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return first.GetInfo(), data, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		data = append(data, resp.GetChunk()...)
+	}
+}
+
+func TestUploadDownload(t *testing.T) {
+	c := startFileService(t, 1<<20)
+	ctx := context.Background()
+	data := pngData(10*chunkSize + 123)
+
+	fi, err := send(ctx, c, &filev1.FileMeta{Name: "shoe.png", Size: int64(len(data)), Sha256: sum(data)}, data)
+	if err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+	if fi.Sha256 != sum(data) || fi.ContentType != "image/png" || !fi.Complete {
+		t.Errorf("Upload() = %v, expect sha256 %s, image/png, complete", fi, sum(data))
+	}
+
+	info, got, err := fetch(ctx, c, &filev1.DownloadRequest{Name: "shoe.png"})
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("Download() returned %d bytes that differ from the %d uploaded", len(got), len(data))
+	}
+	if info.Size != int64(len(data)) || info.Sha256 != sum(data) {
+		t.Errorf("Download() info = %v, expect size %d and sha256 %s", info, len(data), sum(data))
+	}
+}
+
+func TestUploadErrors(t *testing.T) {
+	data := pngData(4 * chunkSize)
+	tests := []struct {
+		name string
+		meta *filev1.FileMeta
+		data []byte
+		code codes.Code
+	}{
+		{"checksum mismatch", &filev1.FileMeta{Name: "a.png", Size: int64(len(data)), Sha256: sum([]byte("other"))}, data, codes.DataLoss},
+		{"more bytes than declared", &filev1.FileMeta{Name: "a.png", Size: 10}, data, codes.InvalidArgument},
+		{"exceeds size limit", &filev1.FileMeta{Name: "a.png", Size: 1 << 30}, nil, codes.ResourceExhausted},
+		{"path in name", &filev1.FileMeta{Name: "../a.png", Size: int64(len(data))}, data, codes.InvalidArgument},
+		{"hidden name", &filev1.FileMeta{Name: ".meta", Size: int64(len(data))}, data, codes.InvalidArgument},
+		{"resume without partial upload", &filev1.FileMeta{Name: "a.png", Size: int64(len(data)), Offset: 100}, data[100:], codes.FailedPrecondition},
+		{"stream ends early", &filev1.FileMeta{Name: "a.png", Size: int64(len(data))}, data[:100], codes.Aborted},
+	}
+	for _, tt := range tests {
+		c := startFileService(t, 1<<20)
+		_, err := send(context.Background(), c, tt.meta, tt.data)
+		if status.Code(err) != tt.code {
+			t.Errorf("%s: Upload() error = %v, expect %v", tt.name, err, tt.code)
+		}
+	}
+}
+
+func TestUploadWithoutMeta(t *testing.T) {
+	c := startFileService(t, 1<<20)
+	stream, err := c.Upload(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.Send(&filev1.UploadRequest{Data: &filev1.UploadRequest_Chunk{Chunk: []byte("x")}})
+	if _, err := stream.CloseAndRecv(); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Upload() without meta error = %v, expect InvalidArgument", err)
+	}
+}
+
+func TestUploadResume(t *testing.T) {
+	c := startFileService(t, 1<<20)
+	ctx := context.Background()
+	data := pngData(8*chunkSize + 7)
+	meta := &filev1.FileMeta{Name: "bag.png", Size: int64(len(data)), Sha256: sum(data)}
+
+	// 第一次只发一半就结束流，服务端保留已收到的部分
+	half := 3*chunkSize + 11
+	if _, err := send(ctx, c, meta, data[:half]); status.Code(err) != codes.Aborted {
+		t.Fatalf("first Upload() error = %v, expect Aborted", err)
+	}
+	st, err := c.Stat(ctx, &filev1.StatRequest{Name: "bag.png"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Complete || st.Received != int64(half) {
+		t.Fatalf("Stat() after the interrupted upload = %v, expect received %d", st, half)
+	}
+
+	// 从错误的位置续传会被拒绝，从 received 续传则成功，最终校验的是整个文件
+	meta.Offset = st.Received + 1
+	if _, err := send(ctx, c, meta, data[meta.Offset:]); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Upload() from a wrong offset error = %v, expect FailedPrecondition", err)
+	}
+	meta.Offset = st.Received
+	fi, err := send(ctx, c, meta, data[st.Received:])
+	if err != nil {
+		t.Fatalf("resumed Upload() error = %v", err)
+	}
+	if fi.Sha256 != sum(data) {
+		t.Errorf("resumed Upload() sha256 = %s, expect %s", fi.Sha256, sum(data))
+	}
+	if _, got, err := fetch(ctx, c, &filev1.DownloadRequest{Name: "bag.png"}); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("Download() after resume = %d bytes, %v; expect the original %d bytes", len(got), err, len(data))
+	}
+}
+
+func TestDownloadRange(t *testing.T) {
+	c := startFileService(t, 1<<20)
+	ctx := context.Background()
+	data := pngData(5*chunkSize + 99)
+	if _, err := send(ctx, c, &filev1.FileMeta{Name: "hat.png", Size: int64(len(data))}, data); err != nil {
+		t.Fatal(err)
+	}
+
+	size := int64(len(data))
+	tests := []struct {
+		name           string
+		offset, length int64
+		want           []byte
+		code           codes.Code
+	}{
+		{"whole file", 0, 0, data, codes.OK},
+		{"head", 0, 100, data[:100], codes.OK},
+		{"middle across chunks", 1000, 2500, data[1000:3500], codes.OK},
+		{"tail", size - 10, 0, data[size-10:], codes.OK},
+		{"length past the end", size - 10, 1000, data[size-10:], codes.OK},
+		{"empty at the end", size, 0, nil, codes.OK},
+		{"offset past the end", size + 1, 0, nil, codes.OutOfRange},
+		{"negative offset", -1, 0, nil, codes.OutOfRange},
+	}
+	for _, tt := range tests {
+		_, got, err := fetch(ctx, c, &filev1.DownloadRequest{Name: "hat.png", Offset: tt.offset, Length: tt.length})
+		if status.Code(err) != tt.code {
+			t.Errorf("%s: Download() error = %v, expect %v", tt.name, err, tt.code)
+			continue
+		}
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("%s: Download() returned %d bytes, expect %d", tt.name, len(got), len(tt.want))
+		}
+	}
+
+	if _, _, err := fetch(ctx, c, &filev1.DownloadRequest{Name: "missing.png"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Download(missing.png) error = %v, expect NotFound", err)
+	}
+}
+
+func TestConcurrentUploads(t *testing.T) {
+	c := startFileService(t, 1<<20)
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			data := pngData((i + 1) * chunkSize)
+			name := fmt.Sprintf("item-%d.png", i)
+			if _, err := send(ctx, c, &filev1.FileMeta{Name: name, Size: int64(len(data)), Sha256: sum(data)}, data); err != nil {
+				errs <- fmt.Errorf("%s: %v", name, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("concurrent Upload() error = %v", err)
+	}
+}
+
+func TestUploadBusy(t *testing.T) {
+	store, err := newStorage(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := store.begin("a.png", fileMeta{Size: 10}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.begin("a.png", fileMeta{Size: 10}, 0); err != errBusy {
+		t.Errorf("second begin() of the same file = %v, expect %v", err, errBusy)
+	}
+	u.close()
+	if _, err := store.begin("a.png", fileMeta{Size: 10}, 0); err != nil {
+		t.Errorf("begin() after the first upload closed = %v, expect nil", err)
+	}
+}
+
+// put 通过 storage 直接完成一次上传
+func put(store *storage, name string, data []byte) error {
+	u, err := store.begin(name, fileMeta{Size: int64(len(data))}, 0)
+	if err != nil {
+		return err
+	}
+	if err := u.write(data); err != nil {
+		u.abort()
+		return err
+	}
+	_, err = u.commit()
+	return err
+}
+
+func TestReplaceConsistency(t *testing.T) {
+	store, err := newStorage(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, replacement := pngData(100), pngData(300)
+	if err := put(store, "a.png", old); err != nil {
+		t.Fatal(err)
+	}
+
+	// 模拟覆盖上传停在两次改名之间：新数据已经就位，元数据还是旧的
+	if err := os.WriteFile(store.path("a.png"), replacement, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := store.openRange("a.png", 0, 0); !errors.Is(err, errReplacing) {
+		t.Errorf("openRange() with stale metadata = %v, expect %v", err, errReplacing)
+	}
+
+	// 只有数据没有元数据的文件还不算完成
+	if err := os.WriteFile(store.path("b.png"), old, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := store.openRange("b.png", 0, 0); err != errNotFound {
+		t.Errorf("openRange() without metadata = %v, expect %v", err, errNotFound)
+	}
+
+	// 下载和覆盖上传并发进行，每次下载拿到的数据都和它的元数据一致
+	if err := put(store, "a.png", old); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		var err error
+		for i := 0; i < 50 && err == nil; i++ {
+			err = put(store, "a.png", [][]byte{old, replacement}[i%2])
+		}
+		done <- err
+	}()
+	for running := true; running; {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+			running = false
+		default:
+		}
+		m, r, err := store.openRange("a.png", 0, 0)
+		if errors.Is(err, errReplacing) {
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int64(len(got)) != m.Size || sum(got) != m.SHA256 {
+			t.Fatalf("downloaded %d bytes with sha256 %s, metadata says %d bytes with %s", len(got), sum(got), m.Size, m.SHA256)
+		}
+	}
+}
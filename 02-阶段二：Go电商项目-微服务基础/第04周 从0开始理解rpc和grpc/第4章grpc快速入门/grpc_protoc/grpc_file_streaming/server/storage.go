@@ -0,0 +1,345 @@
+/**
+ * @File : storage.go
+ * @Description : 本地文件存储：未完成的上传放在 .partial 目录，校验通过后移到根目录，元数据保存在 .meta 目录
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 目录结构：
+//
+//	<root>/<name>                       已完成的文件
+//	<root>/.meta/<name>.json            已完成文件的元数据（大小、SHA-256、Content-Type）
+//	<root>/.partial/<name>              上传中的文件，中断后保留，用于续传
+//	<root>/.partial/.meta/<name>.json   上传中文件的元数据，覆盖已有文件时不影响正在进行的下载
+//
+// 文件名不能以点开头，所以这些目录不会和用户文件冲突。
+// 完成上传时先把数据文件改名到根目录，再发布元数据；元数据本身也是先写临时文件再改名，
+// 读到的元数据总是完整的。两次改名之间数据和元数据可能对不上，下载时用实际大小校验
+const (
+	partialDir = ".partial"
+	metaDir    = ".meta"
+)
+
+var (
+	errNotFound   = errors.New("file not found")
+	errBusy       = errors.New("file is being uploaded by another client")
+	errBadName    = errors.New("file name must be a plain name without path separators and must not start with a dot")
+	errOffset     = errors.New("upload offset does not match the bytes already received")
+	errTooLarge   = errors.New("file exceeds the size limit")
+	errOverflow   = errors.New("received more bytes than the declared size")
+	errChecksum   = errors.New("SHA-256 of the received bytes does not match")
+	errIncomplete = errors.New("stream ended before all bytes were received")
+	errRange      = errors.New("requested range is outside the file")
+	errReplacing  = errors.New("file is being replaced, retry the download")
+)
+
+// fileMeta 是 .meta 目录里保存的内容
+type fileMeta struct {
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+	ContentType string `json:"content_type"`
+}
+
+type storage struct {
+	root    string
+	maxSize int64
+
+	mu        sync.Mutex
+	uploading map[string]bool // 同一个文件同时只允许一个上传
+}
+
+func newStorage(root string, maxSize int64) (*storage, error) {
+	for _, dir := range []string{root, filepath.Join(root, metaDir), filepath.Join(root, partialDir, metaDir)} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return &storage{root: root, maxSize: maxSize, uploading: make(map[string]bool)}, nil
+}
+
+func validName(name string) bool {
+	return name != "" && !strings.HasPrefix(name, ".") && filepath.Base(name) == name && !strings.ContainsAny(name, `/\`)
+}
+
+func (s *storage) path(name string) string    { return filepath.Join(s.root, name) }
+func (s *storage) meta(name string) string    { return filepath.Join(s.root, metaDir, name+".json") }
+func (s *storage) partial(name string) string { return filepath.Join(s.root, partialDir, name) }
+func (s *storage) partialMeta(name string) string {
+	return filepath.Join(s.root, partialDir, metaDir, name+".json")
+}
+
+func readMeta(path string) (fileMeta, error) {
+	var m fileMeta
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return m, err
+	}
+	return m, json.Unmarshal(b, &m)
+}
+
+// writeMeta 先写同目录下的临时文件再改名，并发的 readMeta 不会读到写了一半的内容
+func writeMeta(path string, m fileMeta) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0o644)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// fileStat 描述一个文件的状态，received 只对未完成的上传有意义
+type fileStat struct {
+	fileMeta
+	complete bool
+	received int64
+}
+
+// stat 优先返回未完成的上传，这样覆盖已有文件的上传中断后也能续传
+func (s *storage) stat(name string) (fileStat, error) {
+	if !validName(name) {
+		return fileStat{}, errBadName
+	}
+	if m, err := readMeta(s.partialMeta(name)); err == nil {
+		if fi, err := os.Stat(s.partial(name)); err == nil {
+			return fileStat{fileMeta: m, received: fi.Size()}, nil
+		}
+	}
+	return s.statComplete(name)
+}
+
+func (s *storage) statComplete(name string) (fileStat, error) {
+	m, err := readMeta(s.meta(name))
+	if err != nil {
+		return fileStat{}, errNotFound
+	}
+	if _, err := os.Stat(s.path(name)); err != nil {
+		return fileStat{}, errNotFound
+	}
+	return fileStat{fileMeta: m, complete: true}, nil
+}
+
+// upload 是一次进行中的上传，由 begin 创建，commit 或 abort 结束
+type upload struct {
+	s        *storage
+	name     string
+	meta     fileMeta
+	f        *os.File
+	hash     hash.Hash
+	received int64
+}
+
+// begin 开始或续传一个文件。offset 为 0 时丢弃之前未完成的部分；
+// 大于 0 时必须和已收到的字节数相同，并且文件的大小和 SHA-256 都没有变
+func (s *storage) begin(name string, m fileMeta, offset int64) (*upload, error) {
+	if !validName(name) {
+		return nil, errBadName
+	}
+	if m.Size < 0 || m.Size > s.maxSize {
+		return nil, fmt.Errorf("%w: %d > %d bytes", errTooLarge, m.Size, s.maxSize)
+	}
+	s.mu.Lock()
+	if s.uploading[name] {
+		s.mu.Unlock()
+		return nil, errBusy
+	}
+	s.uploading[name] = true
+	s.mu.Unlock()
+
+	u, err := s.open(name, m, offset)
+	if err != nil {
+		s.release(name)
+		return nil, err
+	}
+	return u, nil
+}
+
+func (s *storage) open(name string, m fileMeta, offset int64) (*upload, error) {
+	u := &upload{s: s, name: name, meta: m, hash: sha256.New()}
+	if offset == 0 {
+		f, err := os.Create(s.partial(name))
+		if err != nil {
+			return nil, err
+		}
+		u.f = f
+		if err := writeMeta(s.partialMeta(name), m); err != nil {
+			f.Close()
+			return nil, err
+		}
+		return u, nil
+	}
+
+	old, err := readMeta(s.partialMeta(name))
+	if err != nil || old.Size != m.Size || old.SHA256 != m.SHA256 {
+		return nil, fmt.Errorf("%w: no partial upload of the same file", errOffset)
+	}
+	f, err := os.OpenFile(s.partial(name), os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("%w: no partial upload of the same file", errOffset)
+	}
+	// 续传时先把已经收到的部分算进哈希，最后校验的是整个文件
+	n, err := io.Copy(u.hash, f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if n != offset {
+		f.Close()
+		return nil, fmt.Errorf("%w: received %d bytes, upload starts at %d", errOffset, n, offset)
+	}
+	u.f, u.received = f, n
+	return u, nil
+}
+
+func (s *storage) release(name string) {
+	s.mu.Lock()
+	delete(s.uploading, name)
+	s.mu.Unlock()
+}
+
+func (u *upload) write(p []byte) error {
+	if u.received+int64(len(p)) > u.meta.Size {
+		return fmt.Errorf("%w: %d > %d bytes", errOverflow, u.received+int64(len(p)), u.meta.Size)
+	}
+	if _, err := u.f.Write(p); err != nil {
+		return err
+	}
+	u.hash.Write(p)
+	u.received += int64(len(p))
+	return nil
+}
+
+// close 在流中断时调用：保留已写入的部分，等待客户端续传
+func (u *upload) close() {
+	u.f.Close()
+	u.s.release(u.name)
+}
+
+// abort 丢弃这次上传，校验失败时使用，避免在错误的数据上续传
+func (u *upload) abort() {
+	u.f.Close()
+	os.Remove(u.s.partial(u.name))
+	os.Remove(u.s.partialMeta(u.name))
+	u.s.release(u.name)
+}
+
+// commit 校验大小和 SHA-256，通过后把文件移到根目录
+func (u *upload) commit() (fileMeta, error) {
+	if u.received != u.meta.Size {
+		u.close()
+		return fileMeta{}, fmt.Errorf("%w: %d of %d bytes", errIncomplete, u.received, u.meta.Size)
+	}
+	sum := hex.EncodeToString(u.hash.Sum(nil))
+	if u.meta.SHA256 != "" && !strings.EqualFold(sum, u.meta.SHA256) {
+		u.abort()
+		return fileMeta{}, fmt.Errorf("%w: got %s, expect %s", errChecksum, sum, u.meta.SHA256)
+	}
+	u.meta.SHA256 = sum
+	if u.meta.ContentType == "" {
+		u.meta.ContentType = sniff(u.f)
+	}
+	defer u.s.release(u.name)
+	if err := u.f.Close(); err != nil {
+		return fileMeta{}, err
+	}
+	// 先移动数据再发布元数据：首次上传时，元数据出现之前文件不会被当作已完成
+	if err := os.Rename(u.s.partial(u.name), u.s.path(u.name)); err != nil {
+		return fileMeta{}, err
+	}
+	if err := writeMeta(u.s.meta(u.name), u.meta); err != nil {
+		return fileMeta{}, err
+	}
+	os.Remove(u.s.partialMeta(u.name))
+	return u.meta, nil
+}
+
+// sniff 根据文件开头的 512 字节识别类型，商品图片会得到 image/jpeg、image/png 等
+func sniff(f *os.File) string {
+	head := make([]byte, 512)
+	n, _ := f.ReadAt(head, 0)
+	return http.DetectContentType(head[:n])
+}
+
+// openRange 打开一个已完成的文件，返回 [offset, offset+length) 区间的读取器，length 为 0 表示读到末尾
+func (s *storage) openRange(name string, offset, length int64) (fileMeta, io.ReadCloser, error) {
+	if !validName(name) {
+		return fileMeta{}, nil, errBadName
+	}
+	// 只下载已完成的文件，同名文件正在重新上传时下载的仍是旧版本
+	f, m, err := s.openComplete(name)
+	if err != nil {
+		return fileMeta{}, nil, err
+	}
+	if offset < 0 || offset > m.Size || length < 0 {
+		f.Close()
+		return fileMeta{}, nil, fmt.Errorf("%w: offset %d, length %d, file size %d", errRange, offset, length, m.Size)
+	}
+	if length == 0 || offset+length > m.Size {
+		length = m.Size - offset
+	}
+	return m, struct {
+		io.Reader
+		io.Closer
+	}{io.NewSectionReader(f, offset, length), f}, nil
+}
+
+// openComplete 先打开数据文件再读元数据，用打开的文件的实际大小校验元数据。
+// 大小不一致说明另一个上传正在两次改名之间，稍等重试，仍不一致就让客户端重新下载
+func (s *storage) openComplete(name string) (*os.File, fileMeta, error) {
+	for attempt := 0; ; attempt++ {
+		f, err := os.Open(s.path(name))
+		if err != nil {
+			return nil, fileMeta{}, errNotFound
+		}
+		m, err := readMeta(s.meta(name))
+		if err != nil {
+			f.Close()
+			return nil, fileMeta{}, errNotFound
+		}
+		fi, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, fileMeta{}, err
+		}
+		if fi.Size() == m.Size {
+			return f, m, nil
+		}
+		f.Close()
+		if attempt == 2 {
+			return nil, fileMeta{}, fmt.Errorf("%w: %d bytes on disk, metadata says %d", errReplacing, fi.Size(), m.Size)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -7,17 +7,35 @@
 package main
 
 import (
+	pb "api/hello/v1" // 导入生成的 protobuf 包
 	"context"
+	"flag"
 	"fmt"
 	"google.golang.org/grpc"
-	pb "grpc_protoc/grpc_protoc/hello" // 导入生成的 protobuf 包
 	"log"
-	"time"
+	"rpckit/compress"
+	"rpckit/retry"
+	"rpckit/tlsutil"
 )
 
 func main() {
+	serviceConfig := flag.String("service-config", "service_config.json", "gRPC service config with per-method retry, hedging and timeout policies")
+	compressFlags := compress.RegisterClientFlags(flag.CommandLine)
+	tlsFlags := tlsutil.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	creds, err := tlsFlags.DialOption()
+	if err != nil {
+		log.Fatalf("invalid tls flags: %v", err)
+	}
+	// 超时、重试不再写死在代码里，由 service config 按方法配置
+	policy, err := retry.Load(*serviceConfig)
+	if err != nil {
+		log.Fatalf("invalid service config: %v", err)
+	}
+
 	// 连接到 gRPC 服务器
-	conn, err := grpc.Dial("localhost:50051", grpc.WithInsecure())
+	opts := append(policy.DialOptions(), compressFlags.DialOptions()...)
+	conn, err := grpc.NewClient("localhost:50051", append(opts, creds)...)
 	if err != nil {
 		log.Fatalf("did not connect: %v", err)
 	}
@@ -25,15 +43,12 @@ func main() {
 
 	client := pb.NewHelloServiceClient(conn)
 
-	// 设置一个 5 秒的超时上下文
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
-	defer cancel()
-
 	// 调用服务的 SayHello 方法
-	response, err := client.SayHello(ctx, &pb.HelloRequest{Name: "Alice"})
+	response, err := client.SayHello(context.Background(), &pb.HelloRequest{Name: "Alice"})
 	if err != nil {
 		log.Fatalf("could not greet: %v", err)
 	}
 
 	fmt.Println("Greeting:", response.Message)
+	fmt.Print(policy.Metrics())
 }
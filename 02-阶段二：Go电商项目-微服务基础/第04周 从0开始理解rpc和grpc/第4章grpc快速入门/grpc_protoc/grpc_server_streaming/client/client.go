@@ -7,32 +7,58 @@
 package main
 
 import (
+	"api/greeter/v1"
 	"context"
+	"flag"
 	"fmt"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
-	"grpc_protoc/grpc_server_streaming/proto"
 	"io"
+	"rpckit/compress"
+	"rpckit/retry"
+	"rpckit/tlsutil"
+	"rpckit/transport"
+	"time"
 )
 
 func main() {
+	serviceConfig := flag.String("service-config", "service_config.json", "gRPC service config with per-method retry, hedging and timeout policies")
+	count := flag.Int("count", 0, "numbers to request, 0 means the server default of 10")
+	payloadSize := flag.Int("payload-size", 0, "padding bytes the server adds to every message")
+	recvDelay := flag.Duration("recv-delay", 0, "pause before reading each message to act as a slow consumer")
+	compressFlags := compress.RegisterClientFlags(flag.CommandLine)
+	transportFlags := transport.RegisterClientFlags(flag.CommandLine)
+	tlsFlags := tlsutil.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	creds, err := tlsFlags.DialOption()
+	if err != nil {
+		panic(err)
+	}
+	policy, err := retry.Load(*serviceConfig)
+	if err != nil {
+		panic(err)
+	}
+
 	// 连接到 gRPC 服务器，使用不安全凭证（没有 TLS 加密）
-	conn, err := grpc.NewClient("127.0.0.1:50051", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	opts := append(policy.DialOptions(), compressFlags.DialOptions()...)
+	opts = append(opts, transportFlags.DialOptions()...)
+	conn, err := grpc.NewClient("127.0.0.1:50051", append(opts, creds)...)
 	if err != nil {
 		panic(err)
 	}
 	defer conn.Close()
 
 	// 创建 Greeter 客户端
-	c := proto.NewGreeterClient(conn)
+	c := greeterv1.NewGreeterClient(conn)
 
 	// 调用 StreamNumbers 以开始服务器流
-	r, err := c.StreamNumbers(context.Background(), &proto.StreamRequest{})
+	r, err := c.StreamNumbers(context.Background(), &greeterv1.StreamRequest{Count: int32(*count), PayloadSize: int32(*payloadSize)})
 	if err != nil {
 		panic(err)
 	}
 	// 接收来自服务器的消息流，直到流结束
+	// 读得慢时服务端的 Send 会被流控窗口挡住，而不是把消息无限堆在服务端内存里
 	for {
+		time.Sleep(*recvDelay)
 		a, err := r.Recv()
 		if err == io.EOF {
 			fmt.Println("All numbers received.")
@@ -43,5 +69,5 @@ func main() {
 		}
 		fmt.Println("Received number: " + a.Data)
 	}
-
+	fmt.Print(policy.Metrics())
 }
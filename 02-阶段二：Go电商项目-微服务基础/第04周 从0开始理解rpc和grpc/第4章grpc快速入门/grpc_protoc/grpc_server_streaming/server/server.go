@@ -7,59 +7,188 @@
 package main
 
 import (
+	"api/greeter/v1"
+	sumpb "api/sum/v1"
+	"crypto/rand"
+	"flag"
 	"fmt"
 	"google.golang.org/grpc"
-	"grpc_protoc/grpc_server_streaming/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"net"
+	"rpckit/compress"
+	"rpckit/grpcweb"
+	"rpckit/metrics"
+	"rpckit/ratelimit"
+	"rpckit/tlsutil"
+	"rpckit/trace"
+	"rpckit/transport"
 	"strconv"
 	"time"
 )
 
 // server 结构体实现了 proto 定义的 Greeter 服务
 type server struct {
-	proto.UnimplementedGreeterServer
+	greeterv1.UnimplementedGreeterServer
+	interval time.Duration
+	// sum 不为 nil 时，发出的数字同时交给 SumService 求和，最后一条消息是总和
+	sum sumpb.SumServiceClient
 }
 
+// 一次流最多发送的消息数和每条消息的填充上限，防止一个请求占满服务端
+const (
+	defaultCount   = 10
+	maxCount       = 1000000
+	maxPayloadSize = 1 << 20
+)
+
 // StreamNumbers 是服务器流式传输的核心逻辑
-// 它会向客户端发送 1 到 10 的连续数字，逐次发送后等待 1 秒
-func (s *server) StreamNumbers(req *proto.StreamRequest, res proto.Greeter_StreamNumbersServer) error {
-	i := 1
-	for {
-		// 如果 i 大于 10，结束流式传输
-		if i > 10 {
-			break
+// 它会向客户端发送 1 到 count（默认 10）的连续数字，逐次发送后等待 interval（默认 1 秒）。
+// res.Send 在流控窗口用完时阻塞，客户端读得慢服务端就跟着慢下来，未确认的数据不会超过窗口大小
+func (s *server) StreamNumbers(req *greeterv1.StreamRequest, res greeterv1.Greeter_StreamNumbersServer) error {
+	count := int(req.GetCount())
+	if count == 0 {
+		count = defaultCount
+	}
+	if count < 0 || count > maxCount {
+		return status.Errorf(codes.InvalidArgument, "count must be between 0 and %d, got %d", maxCount, count)
+	}
+	if req.GetPayloadSize() < 0 || req.GetPayloadSize() > maxPayloadSize {
+		return status.Errorf(codes.InvalidArgument, "payload_size must be between 0 and %d, got %d", maxPayloadSize, req.GetPayloadSize())
+	}
+	// 填充用随机字节，否则开启压缩后几乎不占带宽，也就看不到流控的效果；
+	// Send 返回前已经完成序列化，同一块填充可以反复使用
+	payload := make([]byte, req.GetPayloadSize())
+	rand.Read(payload)
+
+	// 用 res.Context() 发起下游调用，SumService 的 span 会挂在这次调用下面
+	var upstream sumpb.SumService_StreamSumClient
+	if s.sum != nil {
+		var err error
+		upstream, err = s.sum.StreamSum(res.Context())
+		if err != nil {
+			return err
+		}
+	}
+
+	var blocked time.Duration
+	for i := 1; i <= count; i++ {
+		// 大流量时不逐条打印
+		if count <= defaultCount {
+			fmt.Println("Sending number: " + strconv.Itoa(i))
 		}
-		// 打印服务器正在发送的数字
-		fmt.Println("Sending number: " + strconv.Itoa(i))
 
 		// 通过 res.Send 方法向客户端发送 StreamResponse 消息
-		err := res.Send(&proto.StreamResponse{
-			Data: fmt.Sprintf("%d", i), // 将数字 i 转换为字符串并放入消息中
+		start := time.Now()
+		err := res.Send(&greeterv1.StreamResponse{
+			Data:    fmt.Sprintf("%d", i), // 将数字 i 转换为字符串并放入消息中
+			Payload: payload,
 		})
 		if err != nil {
 			return err // 传输错误处理
 		}
+		blocked += time.Since(start)
+		if upstream != nil {
+			if err := upstream.Send(&sumpb.SumRequest{Number: int32(i)}); err != nil {
+				return err
+			}
+		}
 
-		i++
-		time.Sleep(time.Second) // 每次发送后等待 1 秒
+		if s.interval > 0 {
+			time.Sleep(s.interval) // 每次发送后等待一段时间
+		}
+	}
+	if count > defaultCount {
+		fmt.Printf("sent %d numbers with %d bytes of payload each, %v spent waiting for the client to read\n",
+			count, len(payload), blocked.Round(time.Millisecond))
 	}
-	return nil
+	if upstream == nil {
+		return nil
+	}
+	sum, err := upstream.CloseAndRecv()
+	if err != nil {
+		return err
+	}
+	return res.Send(&greeterv1.StreamResponse{Data: fmt.Sprintf("sum=%d", sum.Sum)})
 }
 
 func main() {
-	// 监听 50051 端口，准备接受 gRPC 请求
-	listen, err := net.Listen("tcp", ":50051")
+	addr := flag.String("addr", ":50051", "listen address")
+	interval := flag.Duration("interval", time.Second, "pause between two numbers")
+	sumAddr := flag.String("sum-addr", "", "address of SumService, when set the numbers are also summed there")
+	traceFile := flag.String("trace-file", "", "append spans to this file as JSON lines, empty only propagates traceparent")
+	limits := flag.String("limits", "", "rate and concurrency limits such as limits.json, reloaded when the file changes, empty disables limiting")
+	metricsAddr := flag.String("metrics-addr", "127.0.0.1:9090", "address of the Prometheus /metrics endpoint, empty disables it")
+	webAddr := flag.String("web-addr", "", "address of the gRPC-Web endpoint for browsers, empty disables it")
+	compressFlags := compress.RegisterServerFlags(flag.CommandLine)
+	transportFlags := transport.RegisterServerFlags(flag.CommandLine)
+	tlsFlags := tlsutil.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	creds, err := tlsFlags.ServerOption()
+	if err != nil {
+		panic(err)
+	}
+	// 请求数、耗时、消息数和大小都由 stats handler 记录，访问 /metrics 查看
+	reg := metrics.NewRegistry()
+	metrics.Serve(*metricsAddr, reg)
+	tracer, err := trace.OpenTracer("greeter", *traceFile)
+	if err != nil {
+		panic(err)
+	}
+
+	srv := &server{interval: *interval}
+	if *sumAddr != "" {
+		dialCreds, err := tlsFlags.DialOption()
+		if err != nil {
+			panic(err)
+		}
+		conn, err := grpc.NewClient(*sumAddr, dialCreds,
+			grpc.WithChainUnaryInterceptor(tracer.UnaryClientInterceptor()),
+			grpc.WithChainStreamInterceptor(tracer.StreamClientInterceptor()),
+		)
+		if err != nil {
+			panic(err)
+		}
+		defer conn.Close()
+		srv.sum = sumpb.NewSumServiceClient(conn)
+	}
+
+	// trace 放在最前面，被限流拒绝的请求也能在 trace 中看到
+	unary := []grpc.UnaryServerInterceptor{tracer.UnaryServerInterceptor()}
+	stream := []grpc.StreamServerInterceptor{tracer.StreamServerInterceptor()}
+	// 限流规则可以在运行时修改，文件变化后自动生效；没有指定文件时不限流
+	if *limits != "" {
+		limiter, stop, err := ratelimit.WatchFile(*limits, time.Second)
+		if err != nil {
+			panic(err)
+		}
+		defer stop()
+		unary = append(unary, limiter.UnaryServerInterceptor())
+		stream = append(stream, limiter.StreamServerInterceptor())
+	}
+	unary = append(unary, compressFlags.UnaryServerInterceptor())
+	stream = append(stream, compressFlags.StreamServerInterceptor())
+
+	// 监听端口，准备接受 gRPC 请求
+	listen, err := net.Listen("tcp", *addr)
 	if err != nil {
 		panic(err)
 	}
-	// 创建 gRPC 服务器
-	s := grpc.NewServer()
+	// 创建 gRPC 服务器，keepalive、消息大小、流控窗口和连接存活时间由 transport 参数统一配置
+	opts := append(transportFlags.ServerOptions(), creds,
+		grpc.StatsHandler(metrics.NewServerHandler(reg)),
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	)
+	s := grpc.NewServer(opts...)
 
 	// 注册 Greeter 服务到服务器
-	proto.RegisterGreeterServer(s, &server{})
+	greeterv1.RegisterGreeterServer(s, srv)
+	// 浏览器通过 gRPC-Web 调用同一个 Server，拦截器和限流同样生效
+	grpcweb.Serve(*webAddr, s)
 
-	// 启动服务器，监听传入的 gRPC 请求
-	err = s.Serve(listen)
+	// 启动服务器，监听传入的 gRPC 请求，收到 SIGINT/SIGTERM 后等进行中的流结束再退出
+	err = transportFlags.Serve(s, listen)
 	if err != nil {
 		panic(err)
 	}
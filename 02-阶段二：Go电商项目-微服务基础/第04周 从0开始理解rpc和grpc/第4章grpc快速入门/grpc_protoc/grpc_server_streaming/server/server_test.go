@@ -0,0 +1,330 @@
+/**
+ * @File : server_test.go
+ * @Description : 通过 bufconn 测试 Greeter 的服务端流：完整接收、下游求和、客户端取消、限流时的 trailer、通过 gRPC-Web 调用以及慢客户端的背压
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package main
+
+import (
+	"api/greeter/v1"
+	sumpb "api/sum/v1"
+	"context"
+	"flag"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"rpckit/grpctest"
+	"rpckit/grpcweb"
+	"rpckit/ratelimit"
+	"rpckit/transport"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// sumServer 是测试用的 SumService，真正的实现在另一个 main 包里
+type sumServer struct {
+	sumpb.UnimplementedSumServiceServer
+}
+
+func (sumServer) StreamSum(stream sumpb.SumService_StreamSumServer) error {
+	var sum int32
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&sumpb.SumResponse{Sum: sum})
+		}
+		if err != nil {
+			return err
+		}
+		sum += req.Number
+	}
+}
+
+func startGreeter(t *testing.T, srv *server, opts ...grpctest.Option) greeterv1.GreeterClient {
+	conn := grpctest.Start(t, func(s *grpc.Server) { greeterv1.RegisterGreeterServer(s, srv) }, opts...)
+	return greeterv1.NewGreeterClient(conn)
+}
+
+func numbers(n int, extra ...string) []string {
+	var out []string
+	for i := 1; i <= n; i++ {
+		out = append(out, fmt.Sprint(i))
+	}
+	return append(out, extra...)
+}
+
+func TestStreamNumbers(t *testing.T) {
+	sumConn := grpctest.Start(t, func(s *grpc.Server) { sumpb.RegisterSumServiceServer(s, sumServer{}) })
+	tests := []struct {
+		name string
+		srv  *server
+		want []string
+	}{
+		{"numbers only", &server{}, numbers(10)},
+		{"with interval", &server{interval: time.Millisecond}, numbers(10)},
+		{"summed downstream", &server{sum: sumpb.NewSumServiceClient(sumConn)}, numbers(10, "sum=55")},
+	}
+	for _, tt := range tests {
+		client := startGreeter(t, tt.srv)
+		stream, err := client.StreamNumbers(context.Background(), &greeterv1.StreamRequest{})
+		if err != nil {
+			t.Fatalf("%s: StreamNumbers() error = %v", tt.name, err)
+		}
+		var got []string
+		for {
+			msg, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s: Recv() error = %v", tt.name, err)
+			}
+			got = append(got, msg.Data)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: received %v, expect %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStreamNumbersCancel(t *testing.T) {
+	// 用拦截器拿到 handler 的返回值，确认客户端取消后服务端也停止发送
+	handlerErr := make(chan error, 1)
+	record := grpc.StreamInterceptor(func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		err := handler(srv, ss)
+		handlerErr <- err
+		return err
+	})
+	client := startGreeter(t, &server{interval: 20 * time.Millisecond}, grpctest.WithServerOptions(record))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := client.StreamNumbers(ctx, &greeterv1.StreamRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := stream.Recv(); err != nil {
+			t.Fatalf("Recv() #%d error = %v", i+1, err)
+		}
+	}
+	cancel()
+	if _, err := stream.Recv(); status.Code(err) != codes.Canceled {
+		t.Errorf("Recv() after cancel = %v, expect Canceled", err)
+	}
+	select {
+	case err := <-handlerErr:
+		if err == nil {
+			t.Errorf("handler returned nil after the client canceled, expect an error")
+		}
+	case <-time.After(time.Second):
+		t.Errorf("handler still running 1s after the client canceled")
+	}
+}
+
+func TestStreamNumbersLimitedMetadata(t *testing.T) {
+	cfg, err := ratelimit.ParseConfig([]byte(`{"rules": [{"method": "/greeter.v1.Greeter/StreamNumbers", "maxStreamsPerCaller": 1}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	limiter := ratelimit.New(cfg)
+	client := startGreeter(t, &server{interval: 20 * time.Millisecond},
+		grpctest.WithServerOptions(grpc.StreamInterceptor(limiter.StreamServerInterceptor())))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	first, err := client.StreamNumbers(ctx, &greeterv1.StreamRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 收到第一条消息说明第一个流已经占住了名额
+	if _, err := first.Recv(); err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := client.StreamNumbers(ctx, &greeterv1.StreamRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := second.Recv(); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("second stream Recv() = %v, expect ResourceExhausted", err)
+	}
+	grpctest.Golden(t, "limited_trailer", grpctest.FormatMetadata(second.Trailer(), "grpc-status-details-bin"))
+}
+
+func TestStreamNumbersGRPCWeb(t *testing.T) {
+	s := grpc.NewServer()
+	greeterv1.RegisterGreeterServer(s, &server{interval: time.Millisecond})
+	ts := httptest.NewServer(grpcweb.Wrap(s))
+	defer s.Stop()
+	defer ts.Close()
+
+	for _, text := range []bool{false, true} {
+		stream, err := grpcweb.NewClient(ts.URL, text).NewStream(context.Background(), "/greeter.v1.Greeter/StreamNumbers", &greeterv1.StreamRequest{})
+		if err != nil {
+			t.Fatalf("text=%v: NewStream() error = %v", text, err)
+		}
+		var got []string
+		for {
+			msg := &greeterv1.StreamResponse{}
+			err := stream.Recv(msg)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("text=%v: Recv() error = %v", text, err)
+			}
+			got = append(got, msg.Data)
+		}
+		stream.Close()
+		if !reflect.DeepEqual(got, numbers(10)) {
+			t.Errorf("text=%v: received %v over gRPC-Web, expect %v", text, got, numbers(10))
+		}
+	}
+}
+
+func TestStreamNumbersRequest(t *testing.T) {
+	client := startGreeter(t, &server{})
+	tests := []struct {
+		name        string
+		req         *greeterv1.StreamRequest
+		count       int
+		payloadSize int
+		code        codes.Code
+	}{
+		{"defaults", &greeterv1.StreamRequest{}, 10, 0, codes.OK},
+		{"count", &greeterv1.StreamRequest{Count: 3}, 3, 0, codes.OK},
+		{"payload", &greeterv1.StreamRequest{Count: 2, PayloadSize: 1000}, 2, 1000, codes.OK},
+		{"negative count", &greeterv1.StreamRequest{Count: -1}, 0, 0, codes.InvalidArgument},
+		{"too many numbers", &greeterv1.StreamRequest{Count: maxCount + 1}, 0, 0, codes.InvalidArgument},
+		{"payload too large", &greeterv1.StreamRequest{PayloadSize: maxPayloadSize + 1}, 0, 0, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		stream, err := client.StreamNumbers(context.Background(), tt.req)
+		if err != nil {
+			t.Fatalf("%s: StreamNumbers() error = %v", tt.name, err)
+		}
+		n := 0
+		for {
+			msg, err := stream.Recv()
+			if err != nil {
+				if err == io.EOF {
+					err = nil
+				}
+				if code := status.Code(err); code != tt.code {
+					t.Errorf("%s: Recv() error = %v, expect code %v", tt.name, err, tt.code)
+				}
+				break
+			}
+			if len(msg.Payload) != tt.payloadSize {
+				t.Errorf("%s: message %s has %d bytes of payload, expect %d", tt.name, msg.Data, len(msg.Payload), tt.payloadSize)
+			}
+			n++
+		}
+		if n != tt.count {
+			t.Errorf("%s: received %d numbers, expect %d", tt.name, n, tt.count)
+		}
+	}
+}
+
+// sentBytes 统计服务端已经交给传输层的字节数，Send 被流控挡住时它就不再增长
+type sentBytes struct {
+	n atomic.Int64
+}
+
+func (h *sentBytes) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context   { return ctx }
+func (h *sentBytes) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context { return ctx }
+func (h *sentBytes) HandleConn(context.Context, stats.ConnStats)                       {}
+func (h *sentBytes) HandleRPC(_ context.Context, s stats.RPCStats) {
+	if p, ok := s.(*stats.OutPayload); ok {
+		h.n.Add(int64(p.WireLength))
+	}
+}
+
+// plateau 轮询 n，直到它不小于 min 并且在 stable 时间内不再变化，返回这时的值。
+// 不用固定的 sleep，机器负载高时服务端填满窗口慢一些也不会误判；timeout 内没有稳定下来返回 false
+func plateau(n *atomic.Int64, min int64, stable, timeout time.Duration) (int64, bool) {
+	const poll = 5 * time.Millisecond
+	deadline := time.Now().Add(timeout)
+	last, since := n.Load(), time.Now()
+	for time.Now().Before(deadline) {
+		time.Sleep(poll)
+		cur := n.Load()
+		if cur != last {
+			last, since = cur, time.Now()
+			continue
+		}
+		if cur >= min && time.Since(since) >= stable {
+			return cur, true
+		}
+	}
+	return last, false
+}
+
+// TestSlowConsumer 让客户端停止读取：服务端发出的数据停在流控窗口附近不再增长，
+// 也就是说慢客户端最多让服务端积压一个窗口的数据，而不是整个流
+func TestSlowConsumer(t *testing.T) {
+	const (
+		count       = 5000
+		payloadSize = 1 << 10
+		// gRPC 服务端每个流在窗口之外还有 64KB 的写配额，再留两条消息（含帧头）的余量
+		writeQuota = 64 << 10
+		slack      = 2 * (payloadSize + 64)
+	)
+	tests := []struct {
+		name   string
+		window int
+	}{
+		{"64KB window", 64 << 10},
+		{"1MB window", 1 << 20},
+	}
+	for _, tt := range tests {
+		fs := flag.NewFlagSet("client", flag.ContinueOnError)
+		clientFlags := transport.RegisterClientFlags(fs)
+		if err := fs.Parse([]string{fmt.Sprintf("-initial-window-size=%d", tt.window), fmt.Sprintf("-initial-conn-window-size=%d", tt.window)}); err != nil {
+			t.Fatal(err)
+		}
+		sent := &sentBytes{}
+		client := startGreeter(t, &server{},
+			grpctest.WithServerOptions(grpc.StatsHandler(sent)),
+			grpctest.WithDialOptions(clientFlags.DialOptions()...))
+
+		stream, err := client.StreamNumbers(context.Background(), &greeterv1.StreamRequest{Count: count, PayloadSize: payloadSize})
+		if err != nil {
+			t.Fatalf("%s: StreamNumbers() error = %v", tt.name, err)
+		}
+		if _, err := stream.Recv(); err != nil {
+			t.Fatalf("%s: first Recv() error = %v", tt.name, err)
+		}
+
+		// 客户端暂停读取，等服务端把窗口填满后停下来
+		stalled, ok := plateau(&sent.n, int64(tt.window), 100*time.Millisecond, 10*time.Second)
+		if !ok {
+			t.Fatalf("%s: sent bytes did not settle at or above the %d byte window, last seen %d", tt.name, tt.window, stalled)
+		}
+		if max := int64(tt.window + writeQuota + slack); stalled > max {
+			t.Errorf("%s: %d bytes sent before blocking, expect at most %d", tt.name, stalled, max)
+		}
+
+		// 客户端恢复读取后剩下的消息一条不少
+		n := 1
+		for {
+			if _, err := stream.Recv(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s: Recv() error = %v", tt.name, err)
+			}
+			n++
+		}
+		if n != count {
+			t.Errorf("%s: received %d numbers, expect %d", tt.name, n, count)
+		}
+	}
+}
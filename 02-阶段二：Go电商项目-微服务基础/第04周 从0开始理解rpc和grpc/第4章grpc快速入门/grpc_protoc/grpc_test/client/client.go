@@ -7,30 +7,48 @@
 package main
 
 import (
+	"api/hello/v1"
 	"context"
+	"flag"
 	"fmt"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
-	"grpc_protoc/grpc_test/proto"
+	"rpckit/compress"
+	"rpckit/retry"
+	"rpckit/tlsutil"
 )
 
 func main() {
+	serviceConfig := flag.String("service-config", "grpc_test/service_config.json", "gRPC service config, HelloService is hedged")
+	compressFlags := compress.RegisterClientFlags(flag.CommandLine)
+	tlsFlags := tlsutil.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	creds, err := tlsFlags.DialOption()
+	if err != nil {
+		panic(err)
+	}
+	policy, err := retry.Load(*serviceConfig)
+	if err != nil {
+		panic(err)
+	}
+
 	/*	conn, err := grpc.Dial("127.0.0.1:50051", grpc.WithInsecure())
 		if err != nil {
 			panic(err)
 		}
 		defer conn.Close()*/
 
-	conn, err := grpc.NewClient("127.0.0.1:50051", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	opts := append(policy.DialOptions(), compressFlags.DialOptions()...)
+	conn, err := grpc.NewClient("127.0.0.1:50051", append(opts, creds)...)
 	if err != nil {
 		panic(err)
 	}
 	defer conn.Close()
 
-	c := proto.NewHelloServiceClient(conn)
-	r, err := c.SayHello(context.Background(), &proto.HelloRequest{Name: "Alice"})
+	c := hellov1.NewHelloServiceClient(conn)
+	r, err := c.SayHello(context.Background(), &hellov1.HelloRequest{Name: "Alice"})
 	if err != nil {
 		panic(err)
 	}
 	fmt.Println(r.Message)
+	fmt.Print(policy.Metrics())
 }
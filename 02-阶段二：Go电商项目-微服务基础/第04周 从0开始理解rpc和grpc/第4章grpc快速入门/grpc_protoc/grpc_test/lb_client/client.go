@@ -0,0 +1,104 @@
+/**
+ * @File : client.go
+ * @Description : 通过 registry:///HelloService 解析多个副本，并统计请求在各副本之间的分布
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package main
+
+import (
+	"api/hello/v1"
+	"context"
+	"flag"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"log"
+	"rpckit/compress"
+	"rpckit/loadbalance"
+	"rpckit/registry"
+	"rpckit/retry"
+	"rpckit/tlsutil"
+	"sort"
+	"sync"
+	"time"
+)
+
+func main() {
+	file := flag.String("registry", "grpc_test/registry.json", "registry file, reloaded when it changes")
+	lbPolicy := flag.String("policy", "round_robin", "load balancing policy: round_robin or "+loadbalance.Name)
+	total := flag.Int("n", 60, "number of requests")
+	concurrency := flag.Int("c", 4, "number of concurrent callers")
+	serviceConfig := flag.String("service-config", "", "gRPC service config with retry or hedging policies, empty only sets the load balancing policy")
+	compressFlags := compress.RegisterClientFlags(flag.CommandLine)
+	tlsFlags := tlsutil.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	creds, err := tlsFlags.DialOption()
+	if err != nil {
+		panic(err)
+	}
+	// 默认不重试也不对冲，否则对冲请求会落到别的副本上，统计出来的分布就不只是负载均衡的结果
+	policy, err := retry.Parse([]byte(`{}`))
+	if *serviceConfig != "" {
+		policy, err = retry.Load(*serviceConfig)
+	}
+	if err != nil {
+		panic(err)
+	}
+	// 负载均衡策略和重试、对冲策略要放在同一份 service config 中
+	policy, err = policy.WithLoadBalancing(*lbPolicy)
+	if err != nil {
+		panic(err)
+	}
+
+	opts := append(policy.DialOptions(), compressFlags.DialOptions()...)
+	conn, err := grpc.NewClient("registry:///HelloService", append(opts,
+		creds,
+		grpc.WithResolvers(registry.NewBuilder(registry.NewFile(*file, time.Second))),
+	)...)
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+	c := hellov1.NewHelloServiceClient(conn)
+
+	var (
+		mu     sync.Mutex
+		counts = make(map[string]int)
+		wg     sync.WaitGroup
+		jobs   = make(chan int)
+	)
+	for i := 0; i < *concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				var p peer.Peer
+				_, err := c.SayHello(context.Background(), &hellov1.HelloRequest{Name: fmt.Sprint(i)}, grpc.Peer(&p))
+				if err != nil {
+					log.Printf("request %d failed: %v", i, err)
+					continue
+				}
+				mu.Lock()
+				counts[p.Addr.String()]++
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := 0; i < *total; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	addrs := make([]string, 0, len(counts))
+	for addr := range counts {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	fmt.Printf("policy %s, %d requests:\n", *lbPolicy, *total)
+	for _, addr := range addrs {
+		fmt.Printf("  %-20s %d\n", addr, counts[addr])
+	}
+	fmt.Print(policy.Metrics())
+}
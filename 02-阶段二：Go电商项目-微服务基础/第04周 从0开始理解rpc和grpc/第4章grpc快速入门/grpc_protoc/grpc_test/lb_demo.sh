@@ -0,0 +1,20 @@
+#!/usr/bin/env bash
+# 启动三个 grpc_test/server 副本，分别用 round_robin 和 weighted_load 发送请求并打印分布
+# 在 grpc_protoc 目录下执行：bash grpc_test/lb_demo.sh
+# lb_client 默认不带重试和对冲策略，打印的分布只反映负载均衡
+set -euo pipefail
+
+bin=$(mktemp -d)
+trap 'kill $(jobs -p) 2>/dev/null; rm -rf "$bin"' EXIT
+
+go build -o "$bin/server" ./grpc_test/server
+go build -o "$bin/client" ./grpc_test/lb_client
+
+# 第三个副本权重为 2，但处理得比较慢，weighted_load 会根据上报的负载少给它分一些请求
+"$bin/server" -addr 127.0.0.1:50051 -metrics-addr 127.0.0.1:9101 &
+"$bin/server" -addr 127.0.0.1:50052 -metrics-addr 127.0.0.1:9102 &
+"$bin/server" -addr 127.0.0.1:50053 -metrics-addr 127.0.0.1:9103 -delay 50ms &
+sleep 1
+
+"$bin/client" -registry grpc_test/registry.json -policy round_robin -n 90 -c 6
+"$bin/client" -registry grpc_test/registry.json -policy weighted_load -n 90 -c 6
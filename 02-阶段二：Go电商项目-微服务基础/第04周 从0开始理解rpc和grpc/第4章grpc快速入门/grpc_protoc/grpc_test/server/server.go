@@ -8,25 +8,54 @@ package main
 
 import (
 	"context"
+	"flag"
 	"google.golang.org/grpc"
 	"net"
+	"time"
 
-	"grpc_protoc/grpc_test/proto"
+	"api/hello/v1"
+	"rpckit/compress"
+	"rpckit/loadbalance"
+	"rpckit/metrics"
+	"rpckit/tlsutil"
 )
 
 type Server struct {
-	proto.UnimplementedHelloServiceServer
+	hellov1.UnimplementedHelloServiceServer
+	delay time.Duration
 }
 
-func (s *Server) SayHello(ctx context.Context, request *proto.HelloRequest) (*proto.HelloResponse, error) {
-	return &proto.HelloResponse{Message: "hello" + request.Name}, nil
+func (s *Server) SayHello(ctx context.Context, request *hellov1.HelloRequest) (*hellov1.HelloResponse, error) {
+	// 人为增加处理时间，用来模拟一台比较慢的副本
+	time.Sleep(s.delay)
+	return &hellov1.HelloResponse{Message: "hello" + request.Name}, nil
 }
 
 func main() {
-	g := grpc.NewServer()
-	proto.RegisterHelloServiceServer(g, &Server{})
+	addr := flag.String("addr", ":50051", "listen address, start several replicas with different ports")
+	delay := flag.Duration("delay", 0, "artificial handling time per request")
+	metricsAddr := flag.String("metrics-addr", "127.0.0.1:9090", "address of the Prometheus /metrics endpoint, empty disables it")
+	compressFlags := compress.RegisterServerFlags(flag.CommandLine)
+	tlsFlags := tlsutil.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	creds, err := tlsFlags.ServerOption()
+	if err != nil {
+		panic(err)
+	}
+	// 请求数、耗时、消息数和大小都由 stats handler 记录，访问 /metrics 查看
+	reg := metrics.NewRegistry()
+	metrics.Serve(*metricsAddr, reg)
+
+	// 每次调用结束时在 trailer 中上报负载，供客户端的 weighted_load 均衡器使用
+	reporter := loadbalance.NewReporter()
+	g := grpc.NewServer(creds,
+		grpc.StatsHandler(metrics.NewServerHandler(reg)),
+		grpc.ChainUnaryInterceptor(reporter.UnaryServerInterceptor(), compressFlags.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(reporter.StreamServerInterceptor(), compressFlags.StreamServerInterceptor()),
+	)
+	hellov1.RegisterHelloServiceServer(g, &Server{delay: *delay})
 
-	listener, err := net.Listen("tcp", ":50051")
+	listener, err := net.Listen("tcp", *addr)
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,57 @@
+/**
+ * @File : server_test.go
+ * @Description : 通过 bufconn 测试 HelloService 的一元调用，以及负载上报写在 trailer 中的元数据
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package main
+
+import (
+	"api/hello/v1"
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"rpckit/grpctest"
+	"rpckit/loadbalance"
+	"testing"
+	"time"
+)
+
+func TestSayHello(t *testing.T) {
+	tests := []struct {
+		name    string
+		srv     *Server
+		request string
+		exp     string
+	}{
+		{"plain", &Server{}, "Alice", "helloAlice"},
+		{"empty name", &Server{}, "", "hello"},
+		{"unicode", &Server{}, "尤俊溪", "hello尤俊溪"},
+		{"slow replica", &Server{delay: 10 * time.Millisecond}, "Bob", "helloBob"},
+	}
+	for _, tt := range tests {
+		conn := grpctest.Start(t, func(s *grpc.Server) { hellov1.RegisterHelloServiceServer(s, tt.srv) })
+		resp, err := hellov1.NewHelloServiceClient(conn).SayHello(context.Background(), &hellov1.HelloRequest{Name: tt.request})
+		if err != nil {
+			t.Fatalf("%s: SayHello() error = %v", tt.name, err)
+		}
+		if resp.Message != tt.exp {
+			t.Errorf("%s: SayHello() = %q, expect %q", tt.name, resp.Message, tt.exp)
+		}
+	}
+}
+
+func TestSayHelloMetadata(t *testing.T) {
+	reporter := loadbalance.NewReporter()
+	conn := grpctest.Start(t, func(s *grpc.Server) { hellov1.RegisterHelloServiceServer(s, &Server{}) },
+		grpctest.WithServerOptions(grpc.UnaryInterceptor(reporter.UnaryServerInterceptor())))
+
+	var header, trailer metadata.MD
+	_, err := hellov1.NewHelloServiceClient(conn).SayHello(context.Background(), &hellov1.HelloRequest{Name: "Alice"},
+		grpc.Header(&header), grpc.Trailer(&trailer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpctest.Golden(t, "sayhello_header", grpctest.FormatMetadata(header))
+	grpctest.Golden(t, "sayhello_trailer", grpctest.FormatMetadata(trailer))
+}
@@ -0,0 +1,180 @@
+/**
+ * @File : main.go
+ * @Description : 压测工具：以固定并发或目标 QPS 调用本目录下的 SayHello、StreamNumbers、StreamSum，输出延迟分位数、状态码分布和吞吐量
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package main
+
+// 用法示例：
+//
+//	go run ./loadgen -call hello.v1.HelloService/SayHello -data '{"name": "user-{{.RequestNumber}}"}' -c 20 -n 2000
+//	go run ./loadgen -call greeter.v1.Greeter/StreamNumbers -qps 50 -z 30s -o run.html
+//	go run ./loadgen -call sum.v1.SumService/StreamSum -data '{"number": {{.MessageNumber}}}' -stream-count 100 -o run.csv
+
+import (
+	"api/greeter/v1"
+	"api/hello/v1"
+	"api/sum/v1"
+	"context"
+	"flag"
+	"fmt"
+	"google.golang.org/grpc"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"rpckit/compress"
+	"rpckit/loadgen"
+	"rpckit/tlsutil"
+	"sort"
+	"strings"
+)
+
+// callers 把方法名映射为一次调用的实现，conn 由调用方按请求序号轮流选择
+var callers = map[string]func(tpl *loadgen.Template, streamCount int) func(ctx context.Context, conn *grpc.ClientConn, n int) error{
+	"hello.v1.HelloService/SayHello": func(tpl *loadgen.Template, _ int) func(context.Context, *grpc.ClientConn, int) error {
+		return func(ctx context.Context, conn *grpc.ClientConn, n int) error {
+			req := &hellov1.HelloRequest{}
+			if err := tpl.Render(loadgen.NewTemplateData(n, 0), req); err != nil {
+				return err
+			}
+			_, err := hellov1.NewHelloServiceClient(conn).SayHello(ctx, req)
+			return err
+		}
+	},
+	// 服务端流：一次调用包含从建立流到收完最后一条消息
+	"greeter.v1.Greeter/StreamNumbers": func(tpl *loadgen.Template, _ int) func(context.Context, *grpc.ClientConn, int) error {
+		return func(ctx context.Context, conn *grpc.ClientConn, n int) error {
+			req := &greeterv1.StreamRequest{}
+			if err := tpl.Render(loadgen.NewTemplateData(n, 0), req); err != nil {
+				return err
+			}
+			stream, err := greeterv1.NewGreeterClient(conn).StreamNumbers(ctx, req)
+			if err != nil {
+				return err
+			}
+			for {
+				if _, err := stream.Recv(); err == io.EOF {
+					return nil
+				} else if err != nil {
+					return err
+				}
+			}
+		}
+	},
+	// 客户端流：每次调用发送 streamCount 条消息，模板中可以用 {{.MessageNumber}}
+	"sum.v1.SumService/StreamSum": func(tpl *loadgen.Template, streamCount int) func(context.Context, *grpc.ClientConn, int) error {
+		return func(ctx context.Context, conn *grpc.ClientConn, n int) error {
+			stream, err := sumv1.NewSumServiceClient(conn).StreamSum(ctx)
+			if err != nil {
+				return err
+			}
+			for i := 0; i < streamCount; i++ {
+				req := &sumv1.SumRequest{}
+				if err := tpl.Render(loadgen.NewTemplateData(n, i), req); err != nil {
+					return err
+				}
+				if err := stream.Send(req); err != nil {
+					break // 真正的错误由 CloseAndRecv 返回
+				}
+			}
+			_, err = stream.CloseAndRecv()
+			return err
+		}
+	},
+}
+
+func methods() string {
+	var names []string
+	for name := range callers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:50051", "target server address")
+	call := flag.String("call", "hello.v1.HelloService/SayHello", "method to call: "+methods())
+	data := flag.String("data", "", `request template in JSON, e.g. {"name": "user-{{.RequestNumber}}"}`)
+	dataFile := flag.String("data-file", "", "read the request template from a file instead of -data")
+	concurrency := flag.Int("c", 10, "number of concurrent workers")
+	qps := flag.Float64("qps", 0, "target total requests per second, 0 means as fast as the workers can go")
+	total := flag.Int("n", 0, "total number of requests; with neither -n nor -z set, 200 requests are sent")
+	duration := flag.Duration("z", 0, "run for this long instead of a fixed -n")
+	timeout := flag.Duration("timeout", 0, "per-call timeout, 0 means none")
+	connections := flag.Int("connections", 1, "number of client connections the workers share")
+	streamCount := flag.Int("stream-count", 10, "messages sent per call for client streams")
+	name := flag.String("name", "", "run name shown in the report, e.g. the git commit")
+	format := flag.String("format", "", "report format: text, json, csv or html; defaults to the -o extension, else text")
+	output := flag.String("o", "", "write the report to this file instead of stdout")
+	compressFlags := compress.RegisterClientFlags(flag.CommandLine)
+	tlsFlags := tlsutil.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	newCaller, ok := callers[*call]
+	if !ok {
+		log.Fatalf("unknown method %q, expect one of: %s", *call, methods())
+	}
+	text := *data
+	if *dataFile != "" {
+		b, err := os.ReadFile(*dataFile)
+		if err != nil {
+			log.Fatalf("read request template: %v", err)
+		}
+		text = string(b)
+	}
+	tpl, err := loadgen.ParseTemplate(text)
+	if err != nil {
+		log.Fatalf("parse request template: %v", err)
+	}
+	opts := loadgen.Options{
+		Concurrency: *concurrency,
+		QPS:         *qps,
+		Total:       *total,
+		Duration:    *duration,
+		Timeout:     *timeout,
+	}
+	if err := opts.Validate(); err != nil {
+		log.Fatal(err)
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(filepath.Ext(*output), ".")
+	}
+
+	creds, err := tlsFlags.DialOption()
+	if err != nil {
+		log.Fatal(err)
+	}
+	conns := make([]*grpc.ClientConn, max(*connections, 1))
+	for i := range conns {
+		// 压测要看到真实的失败率，所以这里不挂重试策略
+		conns[i], err = grpc.NewClient(*addr, append(compressFlags.DialOptions(), creds)...)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer conns[i].Close()
+	}
+
+	do := newCaller(tpl, *streamCount)
+	if *name == "" {
+		*name = fmt.Sprintf("%s @ %s", *call, *addr)
+	}
+	report := loadgen.Run(context.Background(), *name, opts, func(ctx context.Context, n int) error {
+		return do(ctx, conns[n%len(conns)], n)
+	})
+
+	w := io.Writer(os.Stdout)
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := report.Write(w, *format); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,63 @@
+/**
+ * @File : main.go
+ * @Description : gRPC 统一入口：不依赖生成代码，按 routes.json 中的服务名把调用转发到各个后端
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package main
+
+import (
+	"flag"
+	"google.golang.org/grpc"
+	"log"
+	"net"
+	"rpckit/metrics"
+	"rpckit/proxy"
+	"rpckit/tlsutil"
+	"time"
+)
+
+// 用法：在 grpc_protoc 目录下执行
+//
+//	go run ./proxy -addr :50050 -routes routes.json
+//
+// 客户端连接 50050 即可调用 Greeter、HelloService、SumService 等所有配置了路由的服务。
+// -tls-* 参数作用于客户端到代理这一段，-backend-tls-* 参数作用于代理到后端这一段，例如
+//
+//	go run ./proxy -tls-cert proxy.pem -tls-key proxy.key -backend-tls-ca ca.pem -backend-tls-cert proxy-client.pem -backend-tls-key proxy-client.key
+func main() {
+	addr := flag.String("addr", ":50050", "listen address")
+	routes := flag.String("routes", "routes.json", "service to backend routes, reloaded when the file changes")
+	metricsAddr := flag.String("metrics-addr", "127.0.0.1:9090", "address of the Prometheus /metrics endpoint, empty disables it")
+	tlsFlags := tlsutil.RegisterFlags(flag.CommandLine)
+	backendTLS := tlsutil.RegisterPrefixedFlags(flag.CommandLine, "backend-")
+	flag.Parse()
+	creds, err := tlsFlags.ServerOption()
+	if err != nil {
+		panic(err)
+	}
+	// 没有配置 -backend-tls-* 时和后端之间仍是明文连接
+	backendCreds, err := backendTLS.DialOption()
+	if err != nil {
+		panic(err)
+	}
+	router, stop, err := proxy.WatchFile(*routes, time.Second, backendCreds)
+	if err != nil {
+		panic(err)
+	}
+	defer stop()
+	defer router.Close()
+	reg := metrics.NewRegistry()
+	metrics.Serve(*metricsAddr, reg)
+
+	listen, err := net.Listen("tcp", *addr)
+	if err != nil {
+		panic(err)
+	}
+	opts := append(proxy.ServerOptions(router.Direct), creds, grpc.StatsHandler(metrics.NewServerHandler(reg).WithMethodFilter(router.Routed)))
+	s := grpc.NewServer(opts...)
+	log.Printf("grpc proxy listening on %s, routes from %s", *addr, *routes)
+	if err := s.Serve(listen); err != nil {
+		log.Fatalf("serve: %v", err)
+	}
+}
@@ -0,0 +1,31 @@
+#!/usr/bin/env bash
+# 启动 HelloService、Greeter、SumService 和 FileService，通过同一个代理入口调用它们
+# 在 grpc_protoc 目录下执行：bash proxy/proxy_demo.sh
+set -euo pipefail
+
+bin=$(mktemp -d)
+trap 'kill $(jobs -p) 2>/dev/null; rm -rf "$bin"' EXIT
+
+go build -o "$bin/hello" .
+go build -o "$bin/greeter" ./grpc_server_streaming/server
+go build -o "$bin/sum" ./grpc_client_streaming/server
+go build -o "$bin/file" ./grpc_file_streaming/server
+go build -o "$bin/proxy" ./proxy
+go build -o "$bin/loadgen" ./loadgen
+go build -o "$bin/fileclient" ./grpc_file_streaming/client
+
+# 后端端口和 routes.json 一致，HelloService 固定监听 50051
+"$bin/hello" -metrics-addr "" &
+"$bin/greeter" -addr 127.0.0.1:50052 -interval 10ms -metrics-addr "" &
+"$bin/sum" -addr 127.0.0.1:50053 -metrics-addr "" &
+"$bin/file" -addr 127.0.0.1:50055 -dir "$bin/files" -metrics-addr "" &
+"$bin/proxy" -addr 127.0.0.1:50050 -routes routes.json -metrics-addr 127.0.0.1:9100 &
+sleep 1
+
+for call in hello.v1.HelloService/SayHello greeter.v1.Greeter/StreamNumbers sum.v1.SumService/StreamSum; do
+	"$bin/loadgen" -addr 127.0.0.1:50050 -call "$call" -n 5 -c 1
+done
+
+"$bin/fileclient" -addr 127.0.0.1:50050 upload routes.json
+"$bin/fileclient" -addr 127.0.0.1:50050 download routes.json "$bin/routes.out"
+curl -s 127.0.0.1:9100/metrics | grep '^grpc_server_handled_total' || true
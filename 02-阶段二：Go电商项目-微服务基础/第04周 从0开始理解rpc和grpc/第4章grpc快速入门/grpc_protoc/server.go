@@ -7,11 +7,19 @@
 package main
 
 import (
+	pb "api/hello/v1" // 导入生成的 protobuf 包
 	"context"
+	"flag"
 	"fmt"
 	"google.golang.org/grpc"
-	pb "grpc_protoc/grpc_protoc/hello" // 导入生成的 protobuf 包
 	"net"
+	"rpckit/cache"
+	"rpckit/compress"
+	"rpckit/idempotency"
+	"rpckit/metrics"
+	"rpckit/ratelimit"
+	"rpckit/tlsutil"
+	"time"
 )
 
 // 定义一个服务器结构体，实现 HelloServiceServer 接口
@@ -22,17 +30,66 @@ type HelloServer struct {
 // 实现 SayHello 方法
 func (s *HelloServer) SayHello(ctx context.Context, req *pb.HelloRequest) (*pb.HelloResponse, error) {
 	message := fmt.Sprintf("Hello, %s!", req.Name)
+	// 开启 mTLS 时，客户端证书中的 SAN 就是经过认证的调用方身份
+	if id, ok := tlsutil.PeerIdentity(ctx); ok {
+		message = fmt.Sprintf("Hello, %s! (authenticated as %s)", req.Name, id.Name)
+	}
 	return &pb.HelloResponse{Message: message}, nil
 }
 
 func main() {
+	limits := flag.String("limits", "", "rate and concurrency limits such as limits.json, reloaded when the file changes, empty disables limiting")
+	metricsAddr := flag.String("metrics-addr", "127.0.0.1:9090", "address of the Prometheus /metrics endpoint, empty disables it")
+	idempotencyDB := flag.String("idempotency-db", "", "BoltDB file that keeps idempotency records across restarts, empty keeps them in memory")
+	compressFlags := compress.RegisterServerFlags(flag.CommandLine)
+	tlsFlags := tlsutil.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	creds, err := tlsFlags.ServerOption()
+	if err != nil {
+		panic(err)
+	}
+	// 请求数、耗时、消息数和大小都由 stats handler 记录，访问 /metrics 查看
+	reg := metrics.NewRegistry()
+	metrics.Serve(*metricsAddr, reg)
+	// SayHello 在 proto 中用 (cache.v1.cache) 开启了缓存，相同的请求在 TTL 内直接返回上次的结果；
+	// 客户端可以带 cache-control: no-cache 强制刷新
+	responses := cache.New(cache.Options{Registry: reg})
+	// 带 idempotency-key 的请求只执行一次，客户端重试时重放第一次的结果
+	var store idempotency.Store = idempotency.NewMemory()
+	if *idempotencyDB != "" {
+		if store, err = idempotency.OpenBolt(*idempotencyDB, time.Second); err != nil {
+			panic(err)
+		}
+	}
+	defer store.Close()
+	guard := idempotency.New(store, idempotency.Options{})
+
+	// 限流规则可以在运行时修改，文件变化后自动生效；没有指定文件时不限流
+	var unary []grpc.UnaryServerInterceptor
+	var stream []grpc.StreamServerInterceptor
+	if *limits != "" {
+		limiter, stop, err := ratelimit.WatchFile(*limits, time.Second)
+		if err != nil {
+			panic(err)
+		}
+		defer stop()
+		unary = append(unary, limiter.UnaryServerInterceptor())
+		stream = append(stream, limiter.StreamServerInterceptor())
+	}
+	unary = append(unary, compressFlags.UnaryServerInterceptor(), guard.UnaryServerInterceptor(), responses.UnaryServerInterceptor())
+	stream = append(stream, compressFlags.StreamServerInterceptor())
+
 	// 启动 gRPC 服务器
 	listener, err := net.Listen("tcp", ":50051")
 	if err != nil {
 		panic(err)
 	}
 
-	server := grpc.NewServer()
+	server := grpc.NewServer(creds,
+		grpc.StatsHandler(metrics.NewServerHandler(reg)),
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	)
 	pb.RegisterHelloServiceServer(server, &HelloServer{})
 
 	fmt.Println("gRPC server listening on port 50051...")
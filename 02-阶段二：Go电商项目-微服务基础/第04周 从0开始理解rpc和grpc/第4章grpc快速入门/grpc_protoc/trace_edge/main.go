@@ -0,0 +1,125 @@
+/**
+ * @File : main.go
+ * @Description : REST 入口：GET /numbers 依次调用 Greeter（gRPC）和 full_rpc 的 HelloService（net/rpc），整条链路共享一个 trace
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package main
+
+import (
+	"api/greeter/v1"
+	"flag"
+	"fmt"
+	"google.golang.org/grpc"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"rpckit/compress"
+	"rpckit/tlsutil"
+	"rpckit/trace"
+)
+
+// helloMethod 是 full_rpc 中 handler.HelloServiceName + ".Hello"，那个包在另一个 module 里，这里直接写出来
+const helloMethod = "handler/HelloService.Hello"
+
+func main() {
+	listen := flag.String("listen", "127.0.0.1:8088", "HTTP listen address")
+	greeterAddr := flag.String("greeter", "127.0.0.1:50051", "Greeter gRPC address")
+	helloAddr := flag.String("hello", "", "full_rpc HelloService address (net/rpc), empty skips that hop")
+	traceFile := flag.String("trace-file", "", "also append spans to this file as JSON lines")
+	compressFlags := compress.RegisterClientFlags(flag.CommandLine)
+	tlsFlags := tlsutil.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	creds, err := tlsFlags.DialOption()
+	if err != nil {
+		panic(err)
+	}
+
+	// 本进程的 span 总是留在内存里供 /debug/traces 查看，指定文件时同时写文件
+	mem := trace.NewMemory()
+	var exporter trace.Exporter = mem
+	if *traceFile != "" {
+		f, err := trace.NewFile(*traceFile)
+		if err != nil {
+			panic(err)
+		}
+		defer f.Close()
+		exporter = trace.Tee(mem, f)
+	}
+	tracer := trace.NewTracer("edge", exporter)
+
+	conn, err := grpc.NewClient(*greeterAddr, append(compressFlags.DialOptions(), creds,
+		grpc.WithChainUnaryInterceptor(tracer.UnaryClientInterceptor()),
+		grpc.WithChainStreamInterceptor(tracer.StreamClientInterceptor()),
+	)...)
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+	greeter := greeterv1.NewGreeterClient(conn)
+
+	var hello *trace.Client
+	if *helloAddr != "" {
+		c, err := net.Dial("tcp", *helloAddr)
+		if err != nil {
+			panic(err)
+		}
+		// full_rpc 服务端在 gob 外面还有一层压缩分帧，和 gRPC 使用相同的 -compressor 参数
+		cc, err := compress.NewConn(c, compressFlags.Compressor, compressFlags.Threshold)
+		if err != nil {
+			panic(err)
+		}
+		hello = trace.NewClient(cc)
+		defer hello.Close()
+	}
+
+	http.HandleFunc("/numbers", func(w http.ResponseWriter, r *http.Request) {
+		// 上游带了 traceparent 就接着它的 trace，否则从这里开始
+		ctx := r.Context()
+		if sc, err := trace.ParseTraceparent(r.Header.Get(trace.HeaderKey)); err == nil {
+			ctx = trace.ContextWithSpanContext(ctx, sc)
+		}
+		ctx, span := tracer.Start(ctx, r.Method+" "+r.URL.Path, trace.KindServer)
+		w.Header().Set(trace.HeaderKey, span.Context().Traceparent())
+
+		var err error
+		defer func() { span.End(err) }()
+
+		stream, err := greeter.StreamNumbers(ctx, &greeterv1.StreamRequest{})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		for {
+			var msg *greeterv1.StreamResponse
+			msg, err = stream.Recv()
+			if err == io.EOF {
+				err = nil
+				break
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			fmt.Fprintln(w, msg.Data)
+		}
+
+		if hello != nil {
+			var reply string
+			if err = tracer.Call(ctx, hello, helloMethod, "edge", &reply); err != nil {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			fmt.Fprintln(w, reply)
+		}
+	})
+	// 只能看到本进程记录的 span，完整链路用 traceview 读取共享的 span 文件
+	http.HandleFunc("/debug/traces", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		trace.Waterfall(w, mem.Spans())
+	})
+
+	log.Printf("edge listening on http://%s/numbers", *listen)
+	log.Fatal(http.ListenAndServe(*listen, nil))
+}
@@ -0,0 +1,27 @@
+#!/usr/bin/env bash
+# 启动 SumService、Greeter、full_rpc HelloService 和 REST 入口，发一个请求后用 traceview 画出整条链路
+# 在 grpc_protoc 目录下执行：bash trace_edge/trace_demo.sh
+set -euo pipefail
+
+bin=$(mktemp -d)
+spans="$bin/spans.jsonl"
+trap 'kill $(jobs -p) 2>/dev/null; rm -rf "$bin"' EXIT
+
+go build -o "$bin/sum" ./grpc_client_streaming/server
+go build -o "$bin/greeter" ./grpc_server_streaming/server
+go build -o "$bin/edge" ./trace_edge
+go build -o "$bin/traceview" rpckit/cmd/traceview
+(cd "../../第2章rpc核心概念理解/grpc_test" && go build -o "$bin/hello" ./full_rpc/server)
+
+"$bin/sum" -addr 127.0.0.1:50052 -metrics-addr "" -trace-file "$spans" &
+"$bin/greeter" -addr 127.0.0.1:50051 -sum-addr 127.0.0.1:50052 -interval 20ms -metrics-addr "" -trace-file "$spans" &
+"$bin/hello" -metrics-addr "" -trace-file "$spans" &
+# edge 启动时就会连接 HelloService，等后端都起来
+sleep 1
+"$bin/edge" -greeter 127.0.0.1:50051 -hello 127.0.0.1:1234 -trace-file "$spans" &
+sleep 1
+
+# 带上自己的 traceparent，整条链路都会沿用这个 trace-id
+curl -s -H "traceparent: 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" http://127.0.0.1:8088/numbers
+sleep 0.5
+"$bin/traceview" -file "$spans"
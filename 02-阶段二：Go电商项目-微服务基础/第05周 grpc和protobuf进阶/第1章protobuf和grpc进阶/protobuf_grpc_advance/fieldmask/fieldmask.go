@@ -0,0 +1,79 @@
+/**
+ * @File : fieldmask.go
+ * @Description : 按 FieldMask 裁剪响应，只保留 mask 中列出的字段及其子字段
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package fieldmask
+
+import (
+	"fmt"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"strings"
+)
+
+// tree 是 mask 路径按 "." 拆开后的前缀树，空 tree 表示保留整个字段
+type tree map[protoreflect.Name]tree
+
+// Check 检查 mask 中的每条路径都能在 msg 中找到，nil 或空 mask 总是合法
+func Check(mask *fieldmaskpb.FieldMask, msg proto.Message) error {
+	for _, path := range mask.GetPaths() {
+		// 逐条检查，错误里才能指出是哪一条路径
+		if !(&fieldmaskpb.FieldMask{Paths: []string{path}}).IsValid(msg) {
+			return fmt.Errorf("invalid field mask path %q for %s", path, msg.ProtoReflect().Descriptor().FullName())
+		}
+	}
+	return nil
+}
+
+// Prune 清除 msg 中不在 mask 里的字段，nil 或空 mask 表示保留全部字段。
+// 路径不能穿过 repeated 和 map 字段，这和 fieldmaskpb 的规定一致
+func Prune(mask *fieldmaskpb.FieldMask, msg proto.Message) error {
+	if len(mask.GetPaths()) == 0 {
+		return nil
+	}
+	if err := Check(mask, msg); err != nil {
+		return err
+	}
+	// Normalize 会排序并去掉被更短路径覆盖的路径，比如同时有 "a" 和 "a.b" 时只剩 "a"
+	normalized := proto.Clone(mask).(*fieldmaskpb.FieldMask)
+	normalized.Normalize()
+	prune(msg.ProtoReflect(), build(normalized.GetPaths()))
+	return nil
+}
+
+func build(paths []string) tree {
+	root := tree{}
+	for _, path := range paths {
+		node := root
+		for _, name := range strings.Split(path, ".") {
+			child, ok := node[protoreflect.Name(name)]
+			if !ok {
+				child = tree{}
+				node[protoreflect.Name(name)] = child
+			}
+			node = child
+		}
+	}
+	return root
+}
+
+func prune(m protoreflect.Message, t tree) {
+	// Range 过程中不能修改 message，先记下要清除的字段
+	var drop []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		child, ok := t[fd.Name()]
+		switch {
+		case !ok:
+			drop = append(drop, fd)
+		case len(child) > 0:
+			prune(v.Message(), child)
+		}
+		return true
+	})
+	for _, fd := range drop {
+		m.Clear(fd)
+	}
+}
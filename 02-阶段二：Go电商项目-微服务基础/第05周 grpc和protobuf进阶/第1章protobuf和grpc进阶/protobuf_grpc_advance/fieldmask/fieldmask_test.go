@@ -0,0 +1,103 @@
+/**
+ * @File : fieldmask_test.go
+ * @Description : 测试按 FieldMask 裁剪消息，以及拦截器在 handler 前拒绝非法路径
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package fieldmask
+
+import (
+	"api/greeter/v1"
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"testing"
+	"time"
+)
+
+func greeting() *greeterv1.Greeting {
+	return &greeterv1.Greeting{
+		Message:     "Hello gophers",
+		Language:    greeterv1.Language_LANGUAGE_EN,
+		Recipient:   &greeterv1.Greeting_Group{Group: &greeterv1.Group{Name: "gophers", Members: []string{"a", "b"}}},
+		Labels:      map[string]string{"source": "test"},
+		Ttl:         durationpb.New(time.Hour),
+		MemberCount: wrapperspb.UInt32(2),
+	}
+}
+
+func TestPrune(t *testing.T) {
+	tests := []struct {
+		name    string
+		paths   []string
+		want    *greeterv1.Greeting
+		wantErr bool
+	}{
+		{"nil mask keeps everything", nil, greeting(), false},
+		{"top-level fields", []string{"message", "labels"},
+			&greeterv1.Greeting{Message: "Hello gophers", Labels: map[string]string{"source": "test"}}, false},
+		{"nested field", []string{"group.name"},
+			&greeterv1.Greeting{Recipient: &greeterv1.Greeting_Group{Group: &greeterv1.Group{Name: "gophers"}}}, false},
+		{"whole field wins over its sub-path", []string{"group.name", "group"},
+			&greeterv1.Greeting{Recipient: &greeterv1.Greeting_Group{Group: &greeterv1.Group{Name: "gophers", Members: []string{"a", "b"}}}}, false},
+		{"unset oneof member is ignored", []string{"user", "ttl"}, &greeterv1.Greeting{Ttl: durationpb.New(time.Hour)}, false},
+		{"wrapper field", []string{"member_count"}, &greeterv1.Greeting{MemberCount: wrapperspb.UInt32(2)}, false},
+		{"unknown field", []string{"message", "color"}, nil, true},
+		{"json name is not a path", []string{"memberCount"}, nil, true},
+		{"path through a repeated field", []string{"translations.text"}, nil, true},
+		{"path through a scalar", []string{"message.length"}, nil, true},
+	}
+	for _, tt := range tests {
+		got := greeting()
+		err := Prune(&fieldmaskpb.FieldMask{Paths: tt.paths}, got)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Prune() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil && !proto.Equal(got, tt.want) {
+			t.Errorf("%s: Prune() = %v, expect %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/greeter.v1.Greeter/GetGreeting"}
+	tests := []struct {
+		name    string
+		req     any
+		want    *greeterv1.Greeting
+		code    codes.Code
+		handled bool
+	}{
+		{"no mask", &greeterv1.GetGreetingRequest{}, greeting(), codes.OK, true},
+		{"mask prunes the response",
+			&greeterv1.GetGreetingRequest{ReadMask: &fieldmaskpb.FieldMask{Paths: []string{"message"}}},
+			&greeterv1.Greeting{Message: "Hello gophers"}, codes.OK, true},
+		{"invalid path is rejected before the handler",
+			&greeterv1.GetGreetingRequest{ReadMask: &fieldmaskpb.FieldMask{Paths: []string{"colour"}}},
+			nil, codes.InvalidArgument, false},
+		{"request without read_mask", &greeterv1.HelloRequest{Name: "gopher"}, greeting(), codes.OK, true},
+	}
+	for _, tt := range tests {
+		handled := false
+		handler := func(ctx context.Context, req any) (any, error) {
+			handled = true
+			return greeting(), nil
+		}
+		resp, err := UnaryServerInterceptor()(context.Background(), tt.req, info, handler)
+		if code := status.Code(err); code != tt.code {
+			t.Errorf("%s: error = %v, expect code %v", tt.name, err, tt.code)
+		}
+		if handled != tt.handled {
+			t.Errorf("%s: handler called = %v, expect %v", tt.name, handled, tt.handled)
+		}
+		if tt.want != nil && !proto.Equal(resp.(proto.Message), tt.want) {
+			t.Errorf("%s: response = %v, expect %v", tt.name, resp, tt.want)
+		}
+	}
+}
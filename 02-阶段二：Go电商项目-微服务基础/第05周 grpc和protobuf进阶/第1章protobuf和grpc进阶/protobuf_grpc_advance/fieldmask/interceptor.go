@@ -0,0 +1,108 @@
+/**
+ * @File : interceptor.go
+ * @Description : 服务端拦截器，按请求中的 read_mask 字段裁剪一元调用的响应
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package fieldmask
+
+import (
+	"context"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"strings"
+)
+
+// FieldName 是请求中携带 mask 的字段名，类型必须是 google.protobuf.FieldMask
+const FieldName = "read_mask"
+
+// UnaryServerInterceptor 在 handler 之前检查 read_mask 的路径，之后按它裁剪响应。
+// 请求没有 read_mask 字段或者没有设置时，响应保持原样
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		mask := readMask(req)
+		if len(mask.GetPaths()) == 0 {
+			return handler(ctx, req)
+		}
+		// 能查到响应类型时提前检查，避免 handler 白白执行一次
+		if out := outputType(info.FullMethod); out != nil {
+			if err := Check(mask, out.New().Interface()); err != nil {
+				return nil, invalidMask(err)
+			}
+		}
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, err
+		}
+		msg, ok := resp.(proto.Message)
+		if !ok {
+			return resp, nil
+		}
+		if err := Prune(mask, msg); err != nil {
+			return nil, invalidMask(err)
+		}
+		return msg, nil
+	}
+}
+
+// readMask 取出请求中的 read_mask，没有这个字段时返回 nil
+func readMask(req any) *fieldmaskpb.FieldMask {
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return nil
+	}
+	m := msg.ProtoReflect()
+	fd := m.Descriptor().Fields().ByName(FieldName)
+	if fd == nil || fd.Message() == nil || fd.Message().FullName() != "google.protobuf.FieldMask" || !m.Has(fd) {
+		return nil
+	}
+	// 动态消息不能直接断言成 *fieldmaskpb.FieldMask，统一复制一份
+	v := m.Get(fd).Message().Interface()
+	mask, ok := v.(*fieldmaskpb.FieldMask)
+	if !ok {
+		mask = &fieldmaskpb.FieldMask{}
+		proto.Merge(mask, v)
+	}
+	return mask
+}
+
+// outputType 从全局注册表中找到 "/pkg.Service/Method" 的响应类型
+func outputType(fullMethod string) protoreflect.MessageType {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return nil
+	}
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil
+	}
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil
+	}
+	md := sd.Methods().ByName(protoreflect.Name(method))
+	if md == nil {
+		return nil
+	}
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(md.Output().FullName())
+	if err != nil {
+		return nil
+	}
+	return mt
+}
+
+func invalidMask(err error) error {
+	st, detailErr := status.New(codes.InvalidArgument, err.Error()).WithDetails(&errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: FieldName, Description: err.Error()}},
+	})
+	if detailErr != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return st.Err()
+}
@@ -3,13 +3,20 @@ module protobuf_grpc_advance
 go 1.22.5
 
 require (
+	api v0.0.0
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20240814211410-ddb44dafa142
 	google.golang.org/grpc v1.67.1
 	google.golang.org/protobuf v1.35.1
+	rpckit v0.0.0
 )
 
 require (
 	golang.org/x/net v0.28.0 // indirect
 	golang.org/x/sys v0.24.0 // indirect
 	golang.org/x/text v0.17.0 // indirect
-	google.golang.org/genproto/googleapis/rpc v0.0.0-20240814211410-ddb44dafa142 // indirect
+)
+
+replace (
+	api => ../../../api
+	rpckit => ../../../rpckit
 )
@@ -7,21 +7,31 @@
 package main
 
 import (
+	"api/greeter/v1"
 	"context"
+	"flag"
 	"fmt"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
-	"protobuf_grpc_advance/protobuf_test/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"rpckit/tlsutil"
+	"time"
 )
 
 func main() {
-	conn, err := grpc.NewClient("127.0.0.1:8080", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	tlsFlags := tlsutil.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	creds, err := tlsFlags.DialOption()
+	if err != nil {
+		panic(err)
+	}
+
+	conn, err := grpc.NewClient("127.0.0.1:8080", creds)
 	if err != nil {
 		panic(err)
 	}
 	defer conn.Close()
-	c := proto.NewGreeterClient(conn)
+	c := greeterv1.NewGreeterClient(conn)
 	md := metadata.New(map[string]string{
 		"Client-ID1": "111",
 		"Client-ID2": "222",
@@ -30,7 +40,9 @@ func main() {
 	ctx := metadata.NewOutgoingContext(context.Background(), md)
 
 	serMD := metadata.MD{}
-	r, err := c.SayHello(ctx, &proto.HelloRequest{Name: "gRPC!"}, grpc.Header(&serMD))
+	r, err := c.SayHello(ctx, &greeterv1.HelloRequest{Name: "gRPC",
+		RequestTime: timestamppb.New(time.Now()),
+	}, grpc.Header(&serMD))
 	for k, v := range md {
 		fmt.Println("Client sending metadata: ", k, "=", v)
 	}
@@ -7,22 +7,27 @@
 package main
 
 import (
+	"api/greeter/v1"
 	"context"
+	"flag"
 	"fmt"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 	"net"
-	"protobuf_grpc_advance/protobuf_test/proto"
+	"protobuf_grpc_advance/validate"
+	"rpckit/grpcweb"
+	"rpckit/metrics"
+	"rpckit/tlsutil"
 )
 
 type server struct {
-	proto.UnimplementedGreeterServer
+	greeterv1.UnimplementedGreeterServer
 }
 
-func (s *server) SayHello(ctx context.Context, in *proto.HelloRequest) (*proto.HelloReply, error) {
+func (s *server) SayHello(ctx context.Context, in *greeterv1.HelloRequest) (*greeterv1.HelloReply, error) {
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
-		return &proto.HelloReply{Message: "Hello " + in.GetName()}, nil
+		return &greeterv1.HelloReply{Message: "Hello " + in.GetName()}, nil
 	}
 	for k, v := range md {
 		fmt.Println("Server received metadata: ", k, "=", v)
@@ -35,16 +40,36 @@ func (s *server) SayHello(ctx context.Context, in *proto.HelloRequest) (*proto.H
 	})
 	newctx := metadata.NewOutgoingContext(ctx, repMD)
 	grpc.SetHeader(newctx, repMD)
-	return &proto.HelloReply{Message: "Hello " + in.GetName()}, nil
+	return &greeterv1.HelloReply{Message: "Hello " + in.GetName()}, nil
 }
 
 func main() {
+	metricsAddr := flag.String("metrics-addr", "127.0.0.1:9090", "address of the Prometheus /metrics endpoint, empty disables it")
+	webAddr := flag.String("web-addr", "127.0.0.1:8081", "address of the gRPC-Web endpoint for browsers, empty disables it")
+	tlsFlags := tlsutil.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	creds, err := tlsFlags.ServerOption()
+	if err != nil {
+		panic(err)
+	}
+	// 请求数、耗时、消息数和大小都由 stats handler 记录，访问 /metrics 查看
+	reg := metrics.NewRegistry()
+	metrics.Serve(*metricsAddr, reg)
+
 	listen, err := net.Listen("tcp", "127.0.0.1:8080")
 	if err != nil {
 		panic(err)
 	}
-	s := grpc.NewServer()
-	proto.RegisterGreeterServer(s, &server{})
+	// 在 handler 之前统一校验请求字段
+	s := grpc.NewServer(
+		creds,
+		grpc.StatsHandler(metrics.NewServerHandler(reg)),
+		grpc.ChainUnaryInterceptor(validate.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(validate.StreamServerInterceptor()),
+	)
+	greeterv1.RegisterGreeterServer(s, &server{})
+	// 浏览器不能直接发 HTTP/2 gRPC 请求，通过 gRPC-Web 端点调用同一个 Greeter
+	grpcweb.Serve(*webAddr, s)
 	err = s.Serve(listen)
 	if err != nil {
 		panic(err)
@@ -0,0 +1,61 @@
+/**
+ * @File : server_test.go
+ * @Description : 用 gRPC-Web 客户端调用 Greeter.SayHello，确认浏览器能拿到回复和 Server-ID 响应头
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package main
+
+import (
+	"api/greeter/v1"
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"net/http/httptest"
+	"protobuf_grpc_advance/validate"
+	"rpckit/grpcweb"
+	"testing"
+)
+
+func TestSayHelloGRPCWeb(t *testing.T) {
+	s := grpc.NewServer(grpc.ChainUnaryInterceptor(validate.UnaryServerInterceptor()))
+	greeterv1.RegisterGreeterServer(s, &server{})
+	ts := httptest.NewServer(grpcweb.Wrap(s))
+	defer s.Stop()
+	defer ts.Close()
+
+	tests := []struct {
+		name string
+		text bool
+		req  *greeterv1.HelloRequest
+		code codes.Code
+	}{
+		{"binary", false, &greeterv1.HelloRequest{Name: "gopher", RequestTime: timestamppb.Now()}, codes.OK},
+		{"text", true, &greeterv1.HelloRequest{Name: "gopher", RequestTime: timestamppb.Now()}, codes.OK},
+		{"validation error", false, &greeterv1.HelloRequest{RequestTime: timestamppb.Now()}, codes.InvalidArgument},
+	}
+	for _, tt := range tests {
+		c := grpcweb.NewClient(ts.URL, tt.text)
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "Client-ID1", "browser")
+		resp := &greeterv1.HelloReply{}
+		header, _, err := c.Invoke(ctx, "/greeter.v1.Greeter/SayHello", tt.req, resp)
+		if status.Code(err) != tt.code {
+			t.Errorf("%s: SayHello() error = %v, expect %v", tt.name, err, tt.code)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if resp.Message != "Hello gopher" {
+			t.Errorf("%s: message = %q, expect %q", tt.name, resp.Message, "Hello gopher")
+		}
+		for k, want := range map[string]string{"server-id1": "111", "server-id2": "222", "server-id3": "333"} {
+			if got := header.Get(k); len(got) != 1 || got[0] != want {
+				t.Errorf("%s: header %s = %v, expect [%s]", tt.name, k, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+/**
+ * @File : histogram.go
+ * @Description : 固定桶边界的直方图，桶的含义和 Prometheus 一致（累计到 le 为止）
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package latency
+
+import (
+	"sort"
+	"sync"
+)
+
+// Histogram 并发安全，边界按升序排列，允许出现负数（时钟偏差可能为负）
+type Histogram struct {
+	mu     sync.Mutex
+	bounds []float64
+	counts []uint64 // 最后一个元素是 +Inf 桶
+	sum    float64
+	count  uint64
+}
+
+// HistogramSnapshot 是某一时刻的直方图数据，Counts[i] 为落在 (Bounds[i-1], Bounds[i]] 的样本数
+type HistogramSnapshot struct {
+	Bounds []float64 `json:"bounds"`
+	Counts []uint64  `json:"counts"`
+	Sum    float64   `json:"sum"`
+	Count  uint64    `json:"count"`
+}
+
+// NewHistogram 使用给定的桶上界创建直方图
+func NewHistogram(bounds []float64) *Histogram {
+	b := append([]float64(nil), bounds...)
+	sort.Float64s(b)
+	return &Histogram{bounds: b, counts: make([]uint64, len(b)+1)}
+}
+
+// Observe 记录一个样本
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.bounds, v)
+	h.mu.Lock()
+	h.counts[i]++
+	h.sum += v
+	h.count++
+	h.mu.Unlock()
+}
+
+// Snapshot 复制当前数据，调用方可以随意修改返回值
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return HistogramSnapshot{
+		Bounds: append([]float64(nil), h.bounds...),
+		Counts: append([]uint64(nil), h.counts...),
+		Sum:    h.sum,
+		Count:  h.count,
+	}
+}
+
+// ExponentialBuckets 生成 start, start*factor, ... 共 n 个边界
+func ExponentialBuckets(start, factor float64, n int) []float64 {
+	b := make([]float64, n)
+	for i := range b {
+		b[i] = start
+		start *= factor
+	}
+	return b
+}
+
+// SymmetricBuckets 在 ExponentialBuckets 的基础上补上对应的负数边界和 0
+func SymmetricBuckets(start, factor float64, n int) []float64 {
+	pos := ExponentialBuckets(start, factor, n)
+	b := make([]float64, 0, 2*n+1)
+	for i := n - 1; i >= 0; i-- {
+		b = append(b, -pos[i])
+	}
+	b = append(b, 0)
+	return append(b, pos...)
+}
@@ -0,0 +1,178 @@
+/**
+ * @File : latency_test.go
+ * @Description : 测试 NTP 偏差计算、直方图分桶、按客户端统计以及客户端数量上限
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package latency
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSample(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+	at := func(ms int) time.Time { return base.Add(time.Duration(ms) * time.Millisecond) }
+	tests := []struct {
+		name      string
+		sample    Sample
+		offset    time.Duration
+		roundTrip time.Duration
+	}{
+		// 单程 10ms，服务端处理 5ms，两边时钟一致
+		{"synchronized clocks", Sample{at(0), at(10), at(15), at(25)}, 0, 20 * time.Millisecond},
+		// 同样的调用，服务端时钟快 100ms
+		{"server ahead", Sample{at(0), at(110), at(115), at(25)}, 100 * time.Millisecond, 20 * time.Millisecond},
+		{"server behind", Sample{at(0), at(-40), at(-35), at(25)}, -50 * time.Millisecond, 20 * time.Millisecond},
+		// 去程 30ms、回程 10ms 时偏差会被估成 10ms，这是 NTP 方法固有的误差
+		{"asymmetric path", Sample{at(0), at(30), at(35), at(45)}, 10 * time.Millisecond, 40 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := tt.sample.Offset(); got != tt.offset {
+			t.Errorf("%s: Offset() = %v, expect %v", tt.name, got, tt.offset)
+		}
+		if got := tt.sample.RoundTrip(); got != tt.roundTrip {
+			t.Errorf("%s: RoundTrip() = %v, expect %v", tt.name, got, tt.roundTrip)
+		}
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram([]float64{1, -1, 0}) // 乱序的边界会被排序
+	for _, v := range []float64{-2, -1, -0.5, 0, 0.5, 1, 3} {
+		h.Observe(v)
+	}
+	got := h.Snapshot()
+	expect := HistogramSnapshot{
+		Bounds: []float64{-1, 0, 1},
+		// 边界值落在以它为上界的桶里
+		Counts: []uint64{2, 2, 2, 1},
+		Sum:    1,
+		Count:  7,
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("Snapshot() = %+v, expect %+v", got, expect)
+	}
+	// 快照是副本
+	got.Counts[0] = 100
+	if h.Snapshot().Counts[0] != 2 {
+		t.Errorf("modifying a snapshot changed the histogram")
+	}
+}
+
+func TestBuckets(t *testing.T) {
+	if got, expect := ExponentialBuckets(0.001, 2, 4), []float64{0.001, 0.002, 0.004, 0.008}; !reflect.DeepEqual(got, expect) {
+		t.Errorf("ExponentialBuckets() = %v, expect %v", got, expect)
+	}
+	if got, expect := SymmetricBuckets(1, 10, 3), []float64{-100, -10, -1, 0, 1, 10, 100}; !reflect.DeepEqual(got, expect) {
+		t.Errorf("SymmetricBuckets() = %v, expect %v", got, expect)
+	}
+}
+
+func incoming(id string, offset *time.Duration) context.Context {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 50000}})
+	md := metadata.MD{}
+	if id != "" {
+		md.Set(ClientIDKey, id)
+	}
+	if offset != nil {
+		md.Set(OffsetKey, fmt.Sprint(offset.Nanoseconds()))
+	}
+	return metadata.NewIncomingContext(ctx, md)
+}
+
+func TestClientID(t *testing.T) {
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		expect string
+	}{
+		{"client-id metadata", incoming("Junxi", nil), "Junxi"},
+		{"peer address without the port", incoming("", nil), "10.0.0.1"},
+		{"nothing known", context.Background(), "unknown"},
+	}
+	for _, tt := range tests {
+		if got := ClientID(tt.ctx); got != tt.expect {
+			t.Errorf("%s: ClientID() = %q, expect %q", tt.name, got, tt.expect)
+		}
+	}
+}
+
+func TestTrackerObserve(t *testing.T) {
+	tracker := NewTracker()
+	sent := time.Unix(1700000000, 0)
+	received := sent.Add(110 * time.Millisecond)
+	offset := 100 * time.Millisecond
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		oneWay time.Duration
+		skew   time.Duration
+	}{
+		{"first call assumes synchronized clocks", incoming("a", nil), 110 * time.Millisecond, 0},
+		{"reported offset is subtracted", incoming("a", &offset), 10 * time.Millisecond, offset},
+		{"last offset is reused", incoming("a", nil), 10 * time.Millisecond, offset},
+		{"offsets are per client", incoming("b", nil), 110 * time.Millisecond, 0},
+	}
+	for _, tt := range tests {
+		oneWay, skew := tracker.Observe(tt.ctx, sent, received)
+		if oneWay != tt.oneWay || skew != tt.skew {
+			t.Errorf("%s: Observe() = %v, %v, expect %v, %v", tt.name, oneWay, skew, tt.oneWay, tt.skew)
+		}
+	}
+
+	snap := tracker.Snapshot()
+	if a := snap["a"]; a.OneWayLatency.Count != 3 || a.ClockSkew.Count != 1 || a.LastSkew != offset.String() {
+		t.Errorf("client a = %+v, expect 3 latency samples and 1 skew sample", a)
+	}
+	if b := snap["b"]; b.OneWayLatency.Count != 1 || b.ClockSkew.Count != 0 {
+		t.Errorf("client b = %+v, expect 1 latency sample and no skew sample", b)
+	}
+}
+
+func TestTrackerMaxClients(t *testing.T) {
+	tests := []struct {
+		name   string
+		max    int
+		calls  []string
+		expect []string
+	}{
+		{"under the limit", 3, []string{"a", "b", "a"}, []string{"a", "b"}},
+		{"oldest client is evicted", 3, []string{"a", "b", "c", "d"}, []string{"b", "c", "d"}},
+		{"recent calls keep a client", 3, []string{"a", "b", "c", "a", "d"}, []string{"a", "c", "d"}},
+		{"rotating ids stay bounded", 2, []string{"x1", "x2", "x3", "x4", "x5", "x6"}, []string{"x5", "x6"}},
+	}
+	for _, tt := range tests {
+		tracker := NewTrackerSize(tt.max)
+		now := time.Now()
+		for _, id := range tt.calls {
+			tracker.Observe(incoming(id, nil), now, now)
+		}
+		snap := tracker.Snapshot()
+		if len(snap) != len(tt.expect) {
+			t.Errorf("%s: tracking %d clients, expect %v", tt.name, len(snap), tt.expect)
+		}
+		for _, id := range tt.expect {
+			if _, ok := snap[id]; !ok {
+				t.Errorf("%s: client %q was evicted, expect %v", tt.name, id, tt.expect)
+			}
+		}
+	}
+
+	// 被淘汰的客户端再次出现时从头开始统计
+	tracker := NewTrackerSize(1)
+	now := time.Now()
+	tracker.Observe(incoming("a", nil), now, now)
+	tracker.Observe(incoming("b", nil), now, now)
+	tracker.Observe(incoming("a", nil), now, now)
+	if n := tracker.Snapshot()["a"].OneWayLatency.Count; n != 1 {
+		t.Errorf("evicted client came back with %d samples, expect 1", n)
+	}
+}
@@ -0,0 +1,27 @@
+/**
+ * @File : ntp.go
+ * @Description : NTP 风格的时钟偏差和往返时延计算
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package latency
+
+import "time"
+
+// Sample 是一次调用中的四个时间点
+// T0 客户端发送，T1 服务端接收，T2 服务端发送，T3 客户端接收
+// T0、T3 使用客户端时钟，T1、T2 使用服务端时钟
+type Sample struct {
+	T0, T1, T2, T3 time.Time
+}
+
+// Offset 估算服务端时钟减去客户端时钟的差值：((T1-T0) + (T2-T3)) / 2
+// 前提是去程和回程的网络时延大致相等
+func (s Sample) Offset() time.Duration {
+	return (s.T1.Sub(s.T0) + s.T2.Sub(s.T3)) / 2
+}
+
+// RoundTrip 是扣除服务端处理时间之后的网络往返时延：(T3-T0) - (T2-T1)
+func (s Sample) RoundTrip() time.Duration {
+	return s.T3.Sub(s.T0) - s.T2.Sub(s.T1)
+}
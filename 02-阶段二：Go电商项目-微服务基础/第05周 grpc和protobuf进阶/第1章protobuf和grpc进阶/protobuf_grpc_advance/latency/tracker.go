@@ -0,0 +1,180 @@
+/**
+ * @File : tracker.go
+ * @Description : 服务端按客户端统计单向时延和时钟偏差
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package latency
+
+import (
+	"container/list"
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// ClientIDKey 客户端可以通过这个 metadata 声明自己的身份，缺省时使用对端 IP
+	ClientIDKey = "client-id"
+	// OffsetKey 客户端把上一次按 NTP 方式算出的偏差（服务端 - 客户端）带给服务端，单位纳秒
+	OffsetKey = "x-clock-offset-ns"
+	// DefaultMaxClients 是 NewTracker 最多跟踪的客户端数
+	DefaultMaxClients = 1000
+)
+
+var (
+	// 单向时延 1ms ~ 约 16s
+	latencyBuckets = ExponentialBuckets(0.001, 2, 15)
+	// 时钟偏差 ±1ms ~ ±约 16s
+	skewBuckets = SymmetricBuckets(0.001, 2, 15)
+)
+
+type clientStats struct {
+	id       string
+	oneWay   *Histogram
+	skew     *Histogram
+	lastSkew time.Duration
+}
+
+// Tracker 并发安全，零值不可用，请使用 NewTracker。
+// client-id 由客户端随意填写，所以只保留最近有请求的 maxClients 个客户端，更早的按 LRU 淘汰
+type Tracker struct {
+	mu         sync.Mutex
+	maxClients int
+	clients    map[string]*list.Element
+	// lru 的元素是 *clientStats，最近有请求的在前面
+	lru *list.List
+}
+
+func NewTracker() *Tracker {
+	return NewTrackerSize(DefaultMaxClients)
+}
+
+// NewTrackerSize 最多跟踪 maxClients 个客户端
+func NewTrackerSize(maxClients int) *Tracker {
+	if maxClients < 1 {
+		maxClients = 1
+	}
+	return &Tracker{maxClients: maxClients, clients: make(map[string]*list.Element), lru: list.New()}
+}
+
+// client 返回 id 的统计并把它移到最前面，需要持有 mu
+func (t *Tracker) client(id string) *clientStats {
+	if e, ok := t.clients[id]; ok {
+		t.lru.MoveToFront(e)
+		return e.Value.(*clientStats)
+	}
+	if t.lru.Len() >= t.maxClients {
+		oldest := t.lru.Back()
+		t.lru.Remove(oldest)
+		delete(t.clients, oldest.Value.(*clientStats).id)
+	}
+	cs := &clientStats{id: id, oneWay: NewHistogram(latencyBuckets), skew: NewHistogram(skewBuckets)}
+	t.clients[id] = t.lru.PushFront(cs)
+	return cs
+}
+
+// Observe 记录一次请求：sent 是客户端填写的 request_time，received 是服务端收到请求的时间
+// 返回扣除时钟偏差后的单向时延以及本次采用的偏差估计
+func (t *Tracker) Observe(ctx context.Context, sent, received time.Time) (oneWay, skew time.Duration) {
+	id := ClientID(ctx)
+
+	t.mu.Lock()
+	cs := t.client(id)
+	// 客户端本次没有上报时沿用上一次的估计，第一次调用时只能假设两边时钟一致
+	reported, hasReport := reportedOffset(ctx)
+	if hasReport {
+		cs.lastSkew = reported
+	}
+	skew = cs.lastSkew
+	t.mu.Unlock()
+
+	oneWay = received.Sub(sent) - skew
+	cs.oneWay.Observe(oneWay.Seconds())
+	if hasReport {
+		cs.skew.Observe(skew.Seconds())
+	}
+	return oneWay, skew
+}
+
+// ClientStats 是单个客户端的统计快照
+type ClientStats struct {
+	OneWayLatency HistogramSnapshot `json:"one_way_latency_seconds"`
+	ClockSkew     HistogramSnapshot `json:"clock_skew_seconds"`
+	LastSkew      string            `json:"last_skew"`
+}
+
+// Snapshot 返回所有客户端的统计，可以直接交给 expvar.Func 导出
+func (t *Tracker) Snapshot() map[string]ClientStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make(map[string]ClientStats, len(t.clients))
+	for e := t.lru.Front(); e != nil; e = e.Next() {
+		cs := e.Value.(*clientStats)
+		out[cs.id] = ClientStats{
+			OneWayLatency: cs.oneWay.Snapshot(),
+			ClockSkew:     cs.skew.Snapshot(),
+			LastSkew:      cs.lastSkew.String(),
+		}
+	}
+	return out
+}
+
+// ClientID 优先取 metadata 中的 client-id，其次取对端 IP
+func ClientID(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(ClientIDKey); len(v) > 0 && v[0] != "" {
+			return v[0]
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			return host
+		}
+		return p.Addr.String()
+	}
+	return "unknown"
+}
+
+func reportedOffset(ctx context.Context) (time.Duration, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return 0, false
+	}
+	v := md.Get(OffsetKey)
+	if len(v) == 0 {
+		return 0, false
+	}
+	ns, err := strconv.ParseInt(v[0], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(ns), true
+}
+
+// WithOffset 供客户端使用，把估算出的偏差附加到 outgoing metadata 中
+func WithOffset(ctx context.Context, offset time.Duration) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, OffsetKey, strconv.FormatInt(offset.Nanoseconds(), 10))
+}
+
+type receiveTimeKey struct{}
+
+// UnaryServerInterceptor 需要放在拦截器链的最前面，尽早记下收到请求的时间
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(context.WithValue(ctx, receiveTimeKey{}, time.Now()), req)
+	}
+}
+
+// ReceiveTime 取出拦截器记录的接收时间，没有经过拦截器时返回当前时间
+func ReceiveTime(ctx context.Context) time.Time {
+	if t, ok := ctx.Value(receiveTimeKey{}).(time.Time); ok {
+		return t
+	}
+	return time.Now()
+}
@@ -7,27 +7,69 @@
 package main
 
 import (
+	"api/greeter/v1"
 	"context"
+	"flag"
 	"fmt"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
-	"protobuf_grpc_advance/protobuf_test/proto"
+	"protobuf_grpc_advance/latency"
+	"rpckit/tlsutil"
 	"time"
 )
 
 func main() {
-	conn, err := grpc.NewClient("127.0.0.1:8080", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	tlsFlags := tlsutil.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	creds, err := tlsFlags.DialOption()
+	if err != nil {
+		panic(err)
+	}
+
+	conn, err := grpc.NewClient("127.0.0.1:8080", creds)
 	if err != nil {
 		panic(err)
 	}
 	defer conn.Close()
-	c := proto.NewGreeterClient(conn)
-	r, err := c.SayHello(context.Background(), &proto.HelloRequest{Name: "Junxi",
-		RequestTime: timestamppb.New(time.Now()),
+	c := greeterv1.NewGreeterClient(conn)
+
+	// 连续调用几次，每次把上一次算出的偏差告诉服务端，服务端据此修正单向时延
+	var offset time.Duration
+	for i := 0; i < 3; i++ {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), latency.ClientIDKey, "Junxi")
+		if i > 0 {
+			ctx = latency.WithOffset(ctx, offset)
+		}
+		sent := time.Now()
+		r, err := c.SayHello(ctx, &greeterv1.HelloRequest{Name: "Junxi",
+			RequestTime: timestamppb.New(sent),
+		})
+		if err != nil {
+			panic(err)
+		}
+		sample := latency.Sample{
+			T0: sent,
+			T1: r.ServerReceiveTime.AsTime(),
+			T2: r.ServerSendTime.AsTime(),
+			T3: time.Now(),
+		}
+		offset = sample.Offset()
+		fmt.Printf("%s (offset %v, round trip %v)\n", r.Message, offset, sample.RoundTrip())
+		time.Sleep(time.Second)
+	}
+
+	// 问候一个群组，只要问候语、群组名和成员数，其余字段由服务端按 read_mask 裁掉
+	g, err := c.GetGreeting(context.Background(), &greeterv1.GetGreetingRequest{
+		Recipient: &greeterv1.GetGreetingRequest_Group{Group: &greeterv1.Group{Name: "gophers", Members: []string{"Junxi", "Rob"}}},
+		Language:  greeterv1.Language_LANGUAGE_ZH,
+		Labels:    map[string]string{"source": "client"},
+		ReadMask:  &fieldmaskpb.FieldMask{Paths: []string{"message", "group.name", "member_count"}},
 	})
 	if err != nil {
 		panic(err)
 	}
-	fmt.Println(r.Message)
+	fmt.Println(protojson.Format(g))
 }
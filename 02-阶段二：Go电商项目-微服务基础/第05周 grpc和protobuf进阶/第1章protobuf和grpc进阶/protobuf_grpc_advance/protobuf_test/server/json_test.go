@@ -0,0 +1,219 @@
+/**
+ * @File : json_test.go
+ * @Description : protojson 与 Greeter 消息的互相转换：默认值、包装类型、枚举名与数字、未知字段和常用的 well-known 类型
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package main
+
+import (
+	"api/greeter/v1"
+	"bytes"
+	"encoding/json"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"rpckit/grpctest"
+	"testing"
+	"time"
+)
+
+// compact 去掉 protojson 故意随机加入的空白，输出才能直接比较
+func compact(t *testing.T, b []byte) string {
+	t.Helper()
+	var out bytes.Buffer
+	if err := json.Compact(&out, b); err != nil {
+		t.Fatalf("invalid JSON %s: %v", b, err)
+	}
+	return out.String()
+}
+
+var createTime = time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+
+// fullGreeting 把每个字段都填上，用来检查完整的 JSON 形态
+func fullGreeting(t *testing.T) *greeterv1.Greeting {
+	info, err := anypb.New(&greeterv1.ServerInfo{Hostname: "greeter-1", StartTime: timestamppb.New(createTime.Add(-time.Hour))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &greeterv1.Greeting{
+		Message:   "你好，gophers",
+		Language:  greeterv1.Language_LANGUAGE_ZH,
+		Recipient: &greeterv1.Greeting_Group{Group: &greeterv1.Group{Name: "gophers", Members: []string{"Junxi", "Rob"}}},
+		Translations: []*greeterv1.Translation{
+			{Language: greeterv1.Language_LANGUAGE_EN, Text: "Hello gophers"},
+			{Language: greeterv1.Language_LANGUAGE_ZH, Text: "你好，gophers"},
+		},
+		Labels:      map[string]string{"team": "go", "env": "dev"},
+		Details:     []*anypb.Any{info},
+		Ttl:         durationpb.New(24*time.Hour + 500*time.Millisecond),
+		CreateTime:  timestamppb.New(createTime),
+		Nickname:    wrapperspb.String(""),
+		MemberCount: wrapperspb.UInt32(2),
+	}
+}
+
+func TestProtoJSONGolden(t *testing.T) {
+	want := fullGreeting(t)
+	b, err := protojson.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, []byte(compact(t, b)), "", "  "); err != nil {
+		t.Fatal(err)
+	}
+	indented.WriteByte('\n')
+	grpctest.Golden(t, "greeting_json", indented.Bytes())
+
+	// 从黄金文件解析回来必须和原消息完全一致，包括 Any 里的 ServerInfo
+	got := &greeterv1.Greeting{}
+	if err := protojson.Unmarshal(indented.Bytes(), got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !proto.Equal(got, want) {
+		t.Errorf("round trip = %v, expect %v", got, want)
+	}
+}
+
+func TestProtoJSONMarshal(t *testing.T) {
+	tests := []struct {
+		name string
+		opts protojson.MarshalOptions
+		msg  proto.Message
+		want string
+	}{
+		{"default values are omitted", protojson.MarshalOptions{}, &greeterv1.Greeting{Language: greeterv1.Language_LANGUAGE_UNSPECIFIED}, `{}`},
+		{"EmitUnpopulated writes defaults", protojson.MarshalOptions{EmitUnpopulated: true},
+			&greeterv1.Translation{}, `{"language":"LANGUAGE_UNSPECIFIED","text":""}`},
+		{"EmitUnpopulated writes null for unset messages", protojson.MarshalOptions{EmitUnpopulated: true},
+			&greeterv1.ServerInfo{}, `{"hostname":"","startTime":null}`},
+		{"wrappers keep explicit zero values", protojson.MarshalOptions{},
+			&greeterv1.Greeting{Nickname: wrapperspb.String(""), MemberCount: wrapperspb.UInt32(0)}, `{"nickname":"","memberCount":0}`},
+		{"oneof keeps an empty string", protojson.MarshalOptions{},
+			&greeterv1.Greeting{Recipient: &greeterv1.Greeting_User{User: ""}}, `{"user":""}`},
+		{"enum as name", protojson.MarshalOptions{}, &greeterv1.Translation{Language: greeterv1.Language_LANGUAGE_ZH}, `{"language":"LANGUAGE_ZH"}`},
+		{"enum as number", protojson.MarshalOptions{UseEnumNumbers: true},
+			&greeterv1.Translation{Language: greeterv1.Language_LANGUAGE_ZH}, `{"language":2}`},
+		{"unknown enum value is written as a number", protojson.MarshalOptions{}, &greeterv1.Translation{Language: 99}, `{"language":99}`},
+		{"proto field names", protojson.MarshalOptions{UseProtoNames: true},
+			&greeterv1.Greeting{MemberCount: wrapperspb.UInt32(3)}, `{"member_count":3}`},
+		{"map keys are sorted", protojson.MarshalOptions{},
+			&greeterv1.Greeting{Labels: map[string]string{"b": "2", "a": "1"}}, `{"labels":{"a":"1","b":"2"}}`},
+		{"duration and timestamp", protojson.MarshalOptions{},
+			&greeterv1.Greeting{Ttl: durationpb.New(1500 * time.Millisecond), CreateTime: timestamppb.New(createTime)},
+			`{"ttl":"1.500s","createTime":"2026-10-19T08:00:00Z"}`},
+		{"field mask uses lowerCamel paths", protojson.MarshalOptions{},
+			&greeterv1.GetGreetingRequest{ReadMask: &fieldmaskpb.FieldMask{Paths: []string{"message", "group.name", "member_count"}}},
+			`{"readMask":"message,group.name,memberCount"}`},
+		{"Any carries its type URL", protojson.MarshalOptions{},
+			&greeterv1.Greeting{Details: []*anypb.Any{mustAny(t, &greeterv1.ServerInfo{Hostname: "h"})}},
+			`{"details":[{"@type":"type.googleapis.com/greeter.v1.ServerInfo","hostname":"h"}]}`},
+		{"Any of a well-known type uses value", protojson.MarshalOptions{},
+			&greeterv1.Greeting{Details: []*anypb.Any{mustAny(t, durationpb.New(time.Second))}},
+			`{"details":[{"@type":"type.googleapis.com/google.protobuf.Duration","value":"1s"}]}`},
+	}
+	for _, tt := range tests {
+		b, err := tt.opts.Marshal(tt.msg)
+		if err != nil {
+			t.Errorf("%s: Marshal() error = %v", tt.name, err)
+			continue
+		}
+		if got := compact(t, b); got != tt.want {
+			t.Errorf("%s: Marshal() = %s, expect %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func mustAny(t *testing.T, m proto.Message) *anypb.Any {
+	a, err := anypb.New(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestProtoJSONUnmarshal(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    protojson.UnmarshalOptions
+		in      string
+		want    *greeterv1.Greeting
+		wantErr bool
+	}{
+		{"enum by name", protojson.UnmarshalOptions{}, `{"language":"LANGUAGE_ZH"}`,
+			&greeterv1.Greeting{Language: greeterv1.Language_LANGUAGE_ZH}, false},
+		{"enum by number", protojson.UnmarshalOptions{}, `{"language":2}`,
+			&greeterv1.Greeting{Language: greeterv1.Language_LANGUAGE_ZH}, false},
+		{"unknown enum number is kept", protojson.UnmarshalOptions{}, `{"language":99}`, &greeterv1.Greeting{Language: 99}, false},
+		{"unknown enum name", protojson.UnmarshalOptions{}, `{"language":"LANGUAGE_FR"}`, nil, true},
+		{"unknown enum name with DiscardUnknown", protojson.UnmarshalOptions{DiscardUnknown: true}, `{"language":"LANGUAGE_FR"}`,
+			&greeterv1.Greeting{}, false},
+		{"unknown field", protojson.UnmarshalOptions{}, `{"message":"hi","colour":"red"}`, nil, true},
+		{"unknown field with DiscardUnknown", protojson.UnmarshalOptions{DiscardUnknown: true}, `{"message":"hi","colour":"red"}`,
+			&greeterv1.Greeting{Message: "hi"}, false},
+		{"proto names are accepted too", protojson.UnmarshalOptions{}, `{"member_count":3,"createTime":"2026-10-19T08:00:00Z"}`,
+			&greeterv1.Greeting{MemberCount: wrapperspb.UInt32(3), CreateTime: timestamppb.New(createTime)}, false},
+		{"null leaves fields unset", protojson.UnmarshalOptions{}, `{"nickname":null,"language":null,"ttl":null}`, &greeterv1.Greeting{}, false},
+		{"explicit default is not the same as null for wrappers", protojson.UnmarshalOptions{}, `{"nickname":"","language":"LANGUAGE_UNSPECIFIED"}`,
+			&greeterv1.Greeting{Nickname: wrapperspb.String("")}, false},
+		{"64-bit style quoted number", protojson.UnmarshalOptions{}, `{"memberCount":"3"}`, &greeterv1.Greeting{MemberCount: wrapperspb.UInt32(3)}, false},
+		{"negative number for uint32", protojson.UnmarshalOptions{}, `{"memberCount":-1}`, nil, true},
+		{"timestamp with an offset", protojson.UnmarshalOptions{}, `{"createTime":"2026-10-19T16:00:00+08:00"}`,
+			&greeterv1.Greeting{CreateTime: timestamppb.New(createTime)}, false},
+		{"duration without the s suffix", protojson.UnmarshalOptions{}, `{"ttl":"1.5"}`, nil, true},
+		{"two members of a oneof", protojson.UnmarshalOptions{}, `{"user":"a","group":{"name":"g"}}`, nil, true},
+		{"duplicate field", protojson.UnmarshalOptions{}, `{"message":"a","message":"b"}`, nil, true},
+		{"Any with an unregistered type", protojson.UnmarshalOptions{DiscardUnknown: true},
+			`{"details":[{"@type":"type.googleapis.com/shop.v1.Order"}]}`, nil, true},
+	}
+	for _, tt := range tests {
+		got := &greeterv1.Greeting{}
+		err := tt.opts.Unmarshal([]byte(tt.in), got)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Unmarshal(%s) error = %v, wantErr %v", tt.name, tt.in, err, tt.wantErr)
+			continue
+		}
+		if err == nil && !proto.Equal(got, tt.want) {
+			t.Errorf("%s: Unmarshal(%s) = %v, expect %v", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestUnknownBinaryFields 模拟新版本的服务端多发了一个字段：二进制转发时保留，转成 JSON 时丢弃
+func TestUnknownBinaryFields(t *testing.T) {
+	b, err := proto.Marshal(&greeterv1.Translation{Language: greeterv1.Language_LANGUAGE_EN, Text: "Hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b = protowire.AppendTag(b, 99, protowire.BytesType)
+	b = protowire.AppendString(b, "added in v2")
+
+	msg := &greeterv1.Translation{}
+	if err := proto.Unmarshal(b, msg); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if len(msg.ProtoReflect().GetUnknown()) == 0 {
+		t.Fatalf("unknown field 99 was dropped by proto.Unmarshal")
+	}
+	again, err := proto.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again, b) {
+		t.Errorf("binary round trip = %x, expect %x", again, b)
+	}
+
+	j, err := protojson.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := compact(t, j), `{"language":"LANGUAGE_EN","text":"Hello"}`; got != want {
+		t.Errorf("protojson.Marshal() = %s, expect %s", got, want)
+	}
+}
@@ -7,29 +7,141 @@
 package main
 
 import (
+	"api/greeter/v1"
 	"context"
+	"expvar"
+	"flag"
 	"fmt"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"log"
 	"net"
-	"protobuf_grpc_advance/protobuf_test/proto"
+	"net/http"
+	"os"
+	"protobuf_grpc_advance/fieldmask"
+	"protobuf_grpc_advance/latency"
+	"protobuf_grpc_advance/validate"
+	"rpckit/metrics"
+	"rpckit/tlsutil"
+	"time"
 )
 
 type server struct {
-	proto.UnimplementedGreeterServer
+	greeterv1.UnimplementedGreeterServer
+	tracker  *latency.Tracker
+	hostname string
+	start    time.Time
 }
 
-func (s *server) SayHello(ctx context.Context, in *proto.HelloRequest) (*proto.HelloReply, error) {
-	fmt.Println(in.RequestTime.AsTime())
-	return &proto.HelloReply{Message: "Hello " + in.GetName()}, nil
+func (s *server) SayHello(ctx context.Context, in *greeterv1.HelloRequest) (*greeterv1.HelloReply, error) {
+	received := latency.ReceiveTime(ctx)
+	oneWay, skew := s.tracker.Observe(ctx, in.RequestTime.AsTime(), received)
+	fmt.Printf("client %s: sent at %v, one-way latency %v, clock skew %v\n",
+		latency.ClientID(ctx), in.RequestTime.AsTime(), oneWay, skew)
+	return &greeterv1.HelloReply{
+		Message:           "Hello " + in.GetName(),
+		ServerReceiveTime: timestamppb.New(received),
+		ServerSendTime:    timestamppb.Now(),
+	}, nil
+}
+
+// GetGreeting 展示 enum、oneof、map、wrapper、Any 和 Duration，响应由 fieldmask 拦截器按 read_mask 裁剪
+func (s *server) GetGreeting(ctx context.Context, in *greeterv1.GetGreetingRequest) (*greeterv1.Greeting, error) {
+	lang := in.GetLanguage()
+	if lang == greeterv1.Language_LANGUAGE_UNSPECIFIED {
+		lang = greeterv1.Language_LANGUAGE_EN
+	}
+	out := &greeterv1.Greeting{
+		Language:   lang,
+		Labels:     in.GetLabels(),
+		Ttl:        durationpb.New(24 * time.Hour),
+		CreateTime: timestamppb.Now(),
+		Nickname:   in.GetNickname(),
+	}
+
+	var who string
+	switch r := in.GetRecipient().(type) {
+	case *greeterv1.GetGreetingRequest_User:
+		if r.User == "" {
+			return nil, status.Error(codes.InvalidArgument, "recipient: user must not be empty")
+		}
+		who = r.User
+		out.Recipient = &greeterv1.Greeting_User{User: r.User}
+	case *greeterv1.GetGreetingRequest_Group:
+		who = r.Group.GetName()
+		out.Recipient = &greeterv1.Greeting_Group{Group: r.Group}
+		out.MemberCount = wrapperspb.UInt32(uint32(len(r.Group.GetMembers())))
+	default:
+		return nil, status.Error(codes.InvalidArgument, "recipient: one of user or group is required")
+	}
+	// nickname 设置了就用它，哪怕是空串
+	if in.GetNickname() != nil {
+		who = in.GetNickname().GetValue()
+	}
+
+	out.Message = greet(lang, who)
+	for _, l := range []greeterv1.Language{greeterv1.Language_LANGUAGE_EN, greeterv1.Language_LANGUAGE_ZH} {
+		out.Translations = append(out.Translations, &greeterv1.Translation{Language: l, Text: greet(l, who)})
+	}
+	info, err := anypb.New(&greeterv1.ServerInfo{Hostname: s.hostname, StartTime: timestamppb.New(s.start)})
+	if err != nil {
+		return nil, err
+	}
+	out.Details = []*anypb.Any{info}
+	return out, nil
+}
+
+// greet 按语言拼出问候语，who 为空时不带称呼
+func greet(lang greeterv1.Language, who string) string {
+	switch {
+	case lang == greeterv1.Language_LANGUAGE_ZH && who == "":
+		return "你好"
+	case lang == greeterv1.Language_LANGUAGE_ZH:
+		return "你好，" + who
+	case who == "":
+		return "Hello"
+	default:
+		return "Hello " + who
+	}
 }
 
 func main() {
+	tlsFlags := tlsutil.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	creds, err := tlsFlags.ServerOption()
+	if err != nil {
+		panic(err)
+	}
+
+	tracker := latency.NewTracker()
+	// 直方图通过 expvar 导出，访问 http://127.0.0.1:8081/debug/vars 查看
+	expvar.Publish("greeter_latency", expvar.Func(func() any { return tracker.Snapshot() }))
+	// 服务端指标挂在同一个 HTTP 服务上：http://127.0.0.1:8081/metrics
+	reg := metrics.NewRegistry()
+	http.Handle("/metrics", reg)
+	go func() {
+		log.Println(http.ListenAndServe("127.0.0.1:8081", nil))
+	}()
+
 	listen, err := net.Listen("tcp", "127.0.0.1:8080")
 	if err != nil {
 		panic(err)
 	}
-	s := grpc.NewServer()
-	proto.RegisterGreeterServer(s, &server{})
+	// 在 handler 之前统一校验请求字段，latency 拦截器放在最前面以便尽早记录接收时间，
+	// fieldmask 放在最后，裁剪的是 handler 直接返回的响应
+	s := grpc.NewServer(
+		creds,
+		grpc.StatsHandler(metrics.NewServerHandler(reg)),
+		grpc.ChainUnaryInterceptor(latency.UnaryServerInterceptor(), validate.UnaryServerInterceptor(), fieldmask.UnaryServerInterceptor()),
+		grpc.ChainStreamInterceptor(validate.StreamServerInterceptor()),
+	)
+	hostname, _ := os.Hostname()
+	greeterv1.RegisterGreeterServer(s, &server{tracker: tracker, hostname: hostname, start: time.Now()})
 	err = s.Serve(listen)
 	if err != nil {
 		panic(err)
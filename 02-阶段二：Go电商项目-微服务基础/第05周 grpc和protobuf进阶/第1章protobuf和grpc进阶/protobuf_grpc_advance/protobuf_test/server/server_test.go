@@ -0,0 +1,103 @@
+/**
+ * @File : server_test.go
+ * @Description : 通过 bufconn 测试 Greeter.GetGreeting：oneof 收件人、nickname 包装类型、Any 附加信息和 read_mask 裁剪
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package main
+
+import (
+	"api/greeter/v1"
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+	"protobuf_grpc_advance/fieldmask"
+	"protobuf_grpc_advance/validate"
+	"rpckit/grpctest"
+	"testing"
+	"time"
+)
+
+func startGreeter(t *testing.T) greeterv1.GreeterClient {
+	conn := grpctest.Start(t, func(s *grpc.Server) {
+		greeterv1.RegisterGreeterServer(s, &server{hostname: "test-host", start: time.Unix(1700000000, 0)})
+	}, grpctest.WithServerOptions(grpc.ChainUnaryInterceptor(validate.UnaryServerInterceptor(), fieldmask.UnaryServerInterceptor())))
+	return greeterv1.NewGreeterClient(conn)
+}
+
+func TestGetGreeting(t *testing.T) {
+	client := startGreeter(t)
+	gophers := &greeterv1.Group{Name: "gophers", Members: []string{"Junxi", "Rob", "Ken"}}
+	tests := []struct {
+		name         string
+		req          *greeterv1.GetGreetingRequest
+		code         codes.Code
+		message      string
+		memberCount  *wrapperspb.UInt32Value
+		translations int
+	}{
+		{"user", &greeterv1.GetGreetingRequest{Recipient: &greeterv1.GetGreetingRequest_User{User: "Junxi"}},
+			codes.OK, "Hello Junxi", nil, 2},
+		{"group in Chinese", &greeterv1.GetGreetingRequest{Recipient: &greeterv1.GetGreetingRequest_Group{Group: gophers}, Language: greeterv1.Language_LANGUAGE_ZH},
+			codes.OK, "你好，gophers", wrapperspb.UInt32(3), 2},
+		{"nickname replaces the name", &greeterv1.GetGreetingRequest{Recipient: &greeterv1.GetGreetingRequest_User{User: "Junxi"}, Nickname: wrapperspb.String("JX")},
+			codes.OK, "Hello JX", nil, 2},
+		{"empty nickname drops the name", &greeterv1.GetGreetingRequest{Recipient: &greeterv1.GetGreetingRequest_User{User: "Junxi"}, Nickname: wrapperspb.String("")},
+			codes.OK, "Hello", nil, 2},
+		{"mask keeps only the listed fields", &greeterv1.GetGreetingRequest{
+			Recipient: &greeterv1.GetGreetingRequest_Group{Group: gophers},
+			ReadMask:  &fieldmaskpb.FieldMask{Paths: []string{"message", "member_count"}},
+		}, codes.OK, "Hello gophers", wrapperspb.UInt32(3), 0},
+		{"no recipient", &greeterv1.GetGreetingRequest{}, codes.InvalidArgument, "", nil, 0},
+		{"empty user", &greeterv1.GetGreetingRequest{Recipient: &greeterv1.GetGreetingRequest_User{}}, codes.InvalidArgument, "", nil, 0},
+		{"group without a name fails validation", &greeterv1.GetGreetingRequest{Recipient: &greeterv1.GetGreetingRequest_Group{Group: &greeterv1.Group{}}},
+			codes.InvalidArgument, "", nil, 0},
+		{"invalid mask", &greeterv1.GetGreetingRequest{
+			Recipient: &greeterv1.GetGreetingRequest_User{User: "Junxi"},
+			ReadMask:  &fieldmaskpb.FieldMask{Paths: []string{"createTime"}},
+		}, codes.InvalidArgument, "", nil, 0},
+	}
+	for _, tt := range tests {
+		got, err := client.GetGreeting(context.Background(), tt.req)
+		if code := status.Code(err); code != tt.code {
+			t.Errorf("%s: GetGreeting() error = %v, expect code %v", tt.name, err, tt.code)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if got.GetMessage() != tt.message {
+			t.Errorf("%s: message = %q, expect %q", tt.name, got.GetMessage(), tt.message)
+		}
+		if !proto.Equal(got.GetMemberCount(), tt.memberCount) {
+			t.Errorf("%s: member_count = %v, expect %v", tt.name, got.GetMemberCount(), tt.memberCount)
+		}
+		if len(got.GetTranslations()) != tt.translations {
+			t.Errorf("%s: %d translations, expect %d", tt.name, len(got.GetTranslations()), tt.translations)
+		}
+		// 没有 mask 时附加信息里应该能解出 ServerInfo；有 mask 时只剩列出的字段
+		if tt.req.GetReadMask() != nil {
+			if got.GetCreateTime() != nil || got.GetRecipient() != nil || len(got.GetDetails()) > 0 {
+				t.Errorf("%s: fields outside the mask were returned: %v", tt.name, got)
+			}
+			continue
+		}
+		if len(got.GetDetails()) != 1 {
+			t.Fatalf("%s: %d details, expect 1", tt.name, len(got.GetDetails()))
+		}
+		info := &greeterv1.ServerInfo{}
+		if err := got.GetDetails()[0].UnmarshalTo(info); err != nil {
+			t.Fatalf("%s: details[0] is not a ServerInfo: %v", tt.name, err)
+		}
+		if info.GetHostname() != "test-host" {
+			t.Errorf("%s: hostname = %q, expect %q", tt.name, info.GetHostname(), "test-host")
+		}
+		if !proto.Equal(got.GetNickname(), tt.req.GetNickname()) {
+			t.Errorf("%s: nickname = %v, expect %v", tt.name, got.GetNickname(), tt.req.GetNickname())
+		}
+	}
+}
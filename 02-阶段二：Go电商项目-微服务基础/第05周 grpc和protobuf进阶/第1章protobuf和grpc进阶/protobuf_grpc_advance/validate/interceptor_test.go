@@ -0,0 +1,150 @@
+/**
+ * @File : interceptor_test.go
+ * @Description : 测试拦截器拒绝不合法的请求：返回 InvalidArgument 和 BadRequest 字段详情，handler 不会处理这条请求
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package validate_test
+
+import (
+	"api/greeter/v1"
+	"context"
+	"errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"io"
+	"protobuf_grpc_advance/validate"
+	"rpckit/grpctest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type greeterServer struct {
+	greeterv1.UnimplementedGreeterServer
+	calls atomic.Int32
+}
+
+func (s *greeterServer) SayHello(_ context.Context, req *greeterv1.HelloRequest) (*greeterv1.HelloReply, error) {
+	s.calls.Add(1)
+	return &greeterv1.HelloReply{Message: "Hello, " + req.Name}, nil
+}
+
+// violations 检查 err 是 InvalidArgument，并返回 BadRequest 中违反规则的字段
+func violations(t *testing.T, err error) []string {
+	t.Helper()
+	st := status.Convert(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("err = %v, expect InvalidArgument", err)
+	}
+	var fields []string
+	for _, d := range st.Details() {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			for _, v := range br.GetFieldViolations() {
+				fields = append(fields, v.GetField())
+			}
+		}
+	}
+	return fields
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	srv := &greeterServer{}
+	conn := grpctest.Start(t, func(s *grpc.Server) { greeterv1.RegisterGreeterServer(s, srv) },
+		grpctest.WithServerOptions(grpc.UnaryInterceptor(validate.UnaryServerInterceptor())))
+	client := greeterv1.NewGreeterClient(conn)
+
+	_, err := client.SayHello(context.Background(), &greeterv1.HelloRequest{Name: "<script>"})
+	if got := violations(t, err); strings.Join(got, ",") != "name,request_time" {
+		t.Errorf("violations on %v, expect [name request_time]", got)
+	}
+	if n := srv.calls.Load(); n != 0 {
+		t.Errorf("handler called %d times for an invalid request, expect 0", n)
+	}
+
+	if _, err := client.SayHello(context.Background(), &greeterv1.HelloRequest{Name: "Junxi", RequestTime: timestamppb.Now()}); err != nil {
+		t.Fatalf("valid request: %v", err)
+	}
+	if n := srv.calls.Load(); n != 1 {
+		t.Errorf("handler called %d times after a valid request, expect 1", n)
+	}
+}
+
+// collectDesc 是一个客户端流式方法：服务端逐条接收 HelloRequest，结束时回复收到的名字
+var collectDesc = grpc.ServiceDesc{
+	ServiceName: "validate.test.Collector",
+	HandlerType: (*any)(nil),
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "Collect",
+		ClientStreams: true,
+		Handler: func(srv any, ss grpc.ServerStream) error {
+			c := srv.(*collector)
+			var names []string
+			for {
+				req := &greeterv1.HelloRequest{}
+				err := ss.RecvMsg(req)
+				if errors.Is(err, io.EOF) {
+					return ss.SendMsg(&greeterv1.HelloReply{Message: strings.Join(names, ",")})
+				}
+				if err != nil {
+					return err
+				}
+				c.handled.Add(1)
+				names = append(names, req.Name)
+			}
+		},
+	}},
+}
+
+type collector struct {
+	handled atomic.Int32
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	srv := &collector{}
+	conn := grpctest.Start(t, func(s *grpc.Server) { s.RegisterService(&collectDesc, srv) },
+		grpctest.WithServerOptions(grpc.StreamInterceptor(validate.StreamServerInterceptor())))
+
+	collect := func(reqs ...*greeterv1.HelloRequest) (string, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		stream, err := conn.NewStream(ctx, &collectDesc.Streams[0], "/validate.test.Collector/Collect")
+		if err != nil {
+			return "", err
+		}
+		for _, req := range reqs {
+			// 服务端拒绝后流已经结束，后面的 Send 会返回 io.EOF，真正的错误由 RecvMsg 返回
+			if err := stream.SendMsg(req); err != nil {
+				break
+			}
+		}
+		stream.CloseSend()
+		reply := &greeterv1.HelloReply{}
+		if err := stream.RecvMsg(reply); err != nil {
+			return "", err
+		}
+		return reply.Message, nil
+	}
+
+	valid := func(name string) *greeterv1.HelloRequest {
+		return &greeterv1.HelloRequest{Name: name, RequestTime: timestamppb.Now()}
+	}
+	got, err := collect(valid("a"), valid("b"))
+	if err != nil || got != "a,b" {
+		t.Fatalf("valid stream = (%q, %v), expect \"a,b\"", got, err)
+	}
+
+	// 第二条消息不合法：handler 的 RecvMsg 直接拿到错误，只处理了前面那条合法的消息
+	srv.handled.Store(0)
+	_, err = collect(valid("a"), &greeterv1.HelloRequest{Name: strings.Repeat("x", 65), RequestTime: timestamppb.Now()}, valid("c"))
+	if got := violations(t, err); strings.Join(got, ",") != "name" {
+		t.Errorf("violations on %v, expect [name]", got)
+	}
+	if n := srv.handled.Load(); n != 1 {
+		t.Errorf("handler processed %d messages, expect only the valid one before the invalid message", n)
+	}
+}
@@ -0,0 +1,167 @@
+/**
+ * @File : validator.go
+ * @Description : 基于 protoreflect 的校验器，读取字段上的 (validate.rules) 并逐条检查
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package validate
+
+import (
+	"api/validate/v1"
+	"fmt"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Error 汇总一次校验中发现的所有字段错误
+type Error struct {
+	Violations []*errdetails.BadRequest_FieldViolation
+}
+
+func (e *Error) Error() string {
+	msgs := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		msgs = append(msgs, v.GetField()+": "+v.GetDescription())
+	}
+	return "invalid request: " + strings.Join(msgs, "; ")
+}
+
+// patterns 缓存已编译的正则，key 是字段的全名
+var patterns sync.Map
+
+// now 方便测试时替换当前时间
+var now = time.Now
+
+// Validate 递归校验 msg 的所有字段，全部通过时返回 nil，否则返回 *Error
+func Validate(msg proto.Message) error {
+	var violations []*errdetails.BadRequest_FieldViolation
+	validateMessage(msg.ProtoReflect(), "", &violations)
+	if len(violations) == 0 {
+		return nil
+	}
+	return &Error{Violations: violations}
+}
+
+func validateMessage(m protoreflect.Message, prefix string, out *[]*errdetails.BadRequest_FieldViolation) {
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		path := prefix + string(fd.Name())
+
+		rules, _ := proto.GetExtension(fd.Options(), validatev1.E_Rules).(*validatev1.FieldRules)
+		if rules != nil {
+			for _, desc := range checkField(m, fd, rules) {
+				*out = append(*out, &errdetails.BadRequest_FieldViolation{Field: path, Description: desc})
+			}
+		}
+
+		// 没有规则的嵌套 message 也要继续向下检查
+		if fd.Kind() != protoreflect.MessageKind && fd.Kind() != protoreflect.GroupKind {
+			continue
+		}
+		if !m.Has(fd) {
+			continue
+		}
+		switch {
+		case fd.IsList():
+			list := m.Get(fd).List()
+			for j := 0; j < list.Len(); j++ {
+				validateMessage(list.Get(j).Message(), fmt.Sprintf("%s[%d].", path, j), out)
+			}
+		case fd.IsMap():
+			if fd.MapValue().Kind() != protoreflect.MessageKind {
+				continue
+			}
+			m.Get(fd).Map().Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+				validateMessage(v.Message(), fmt.Sprintf("%s[%v].", path, k.Interface()), out)
+				return true
+			})
+		default:
+			validateMessage(m.Get(fd).Message(), path+".", out)
+		}
+	}
+}
+
+// checkField 返回该字段违反的规则描述，repeated 和 map 字段只检查 required
+func checkField(m protoreflect.Message, fd protoreflect.FieldDescriptor, rules *validatev1.FieldRules) []string {
+	var errs []string
+	if !m.Has(fd) && rules.GetRequired() {
+		return append(errs, "is required")
+	}
+	// 未设置的 message 没有内容可查；proto3 的空字符串仍按默认值参与长度检查
+	if fd.IsList() || fd.IsMap() || (fd.Kind() == protoreflect.MessageKind && !m.Has(fd)) {
+		return errs
+	}
+
+	v := m.Get(fd)
+	if sr := rules.GetString_(); sr != nil && fd.Kind() == protoreflect.StringKind {
+		errs = append(errs, checkString(fd, v.String(), sr)...)
+	}
+	if tr := rules.GetTimestamp(); tr != nil && fd.Kind() == protoreflect.MessageKind &&
+		fd.Message().FullName() == "google.protobuf.Timestamp" {
+		errs = append(errs, checkTimestamp(v.Message(), tr)...)
+	}
+	return errs
+}
+
+func checkString(fd protoreflect.FieldDescriptor, s string, r *validatev1.StringRules) []string {
+	var errs []string
+	n := uint64(utf8.RuneCountInString(s))
+	if r.MinLen != nil && n < r.GetMinLen() {
+		errs = append(errs, fmt.Sprintf("length must be at least %d characters, got %d", r.GetMinLen(), n))
+	}
+	if r.MaxLen != nil && n > r.GetMaxLen() {
+		errs = append(errs, fmt.Sprintf("length must be at most %d characters, got %d", r.GetMaxLen(), n))
+	}
+	if r.GetPattern() != "" {
+		re, err := compilePattern(fd, r.GetPattern())
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("invalid pattern %q in rules: %v", r.GetPattern(), err))
+		} else if !re.MatchString(s) {
+			errs = append(errs, fmt.Sprintf("must match pattern %q", r.GetPattern()))
+		}
+	}
+	return errs
+}
+
+func checkTimestamp(m protoreflect.Message, r *validatev1.TimestampRules) []string {
+	// 动态消息不能直接断言成 *timestamppb.Timestamp，也不能和它 Merge，按字段名读出 seconds 和 nanos
+	fields := m.Descriptor().Fields()
+	seconds, nanos := fields.ByName("seconds"), fields.ByName("nanos")
+	if seconds == nil || nanos == nil {
+		return []string{"is not a google.protobuf.Timestamp"}
+	}
+	ts := &timestamppb.Timestamp{Seconds: m.Get(seconds).Int(), Nanos: int32(m.Get(nanos).Int())}
+	if err := ts.CheckValid(); err != nil {
+		return []string{err.Error()}
+	}
+
+	var errs []string
+	t, current := ts.AsTime(), now()
+	if r.MaxPast != nil && t.Before(current.Add(-r.GetMaxPast().AsDuration())) {
+		errs = append(errs, fmt.Sprintf("must not be more than %v in the past", r.GetMaxPast().AsDuration()))
+	}
+	if r.MaxFuture != nil && t.After(current.Add(r.GetMaxFuture().AsDuration())) {
+		errs = append(errs, fmt.Sprintf("must not be more than %v in the future", r.GetMaxFuture().AsDuration()))
+	}
+	return errs
+}
+
+func compilePattern(fd protoreflect.FieldDescriptor, pattern string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(fd.FullName()); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patterns.Store(fd.FullName(), re)
+	return re, nil
+}
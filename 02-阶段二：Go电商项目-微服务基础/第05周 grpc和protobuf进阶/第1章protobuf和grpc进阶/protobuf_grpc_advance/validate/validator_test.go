@@ -0,0 +1,116 @@
+/**
+ * @File : validator_test.go
+ * @Description : 用 protobuf_test 的 HelloRequest 验证各条校验规则
+ * @Author : Junxi You
+ * @Date : 2026-10-19
+ */
+package validate_test
+
+import (
+	"api/greeter/v1"
+	"errors"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"protobuf_grpc_advance/validate"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateHelloRequest(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name   string
+		req    *greeterv1.HelloRequest
+		fields []string
+	}{
+		{"valid", &greeterv1.HelloRequest{Name: "Junxi", RequestTime: timestamppb.New(now)}, nil},
+		{"chinese name", &greeterv1.HelloRequest{Name: "尤俊溪", RequestTime: timestamppb.New(now)}, nil},
+		{"empty", &greeterv1.HelloRequest{}, []string{"name", "request_time"}},
+		{"too long", &greeterv1.HelloRequest{Name: strings.Repeat("a", 65), RequestTime: timestamppb.New(now)}, []string{"name"}},
+		{"bad chars", &greeterv1.HelloRequest{Name: "<script>", RequestTime: timestamppb.New(now)}, []string{"name"}},
+		{"too old", &greeterv1.HelloRequest{Name: "a", RequestTime: timestamppb.New(now.Add(-time.Hour))}, []string{"request_time"}},
+		{"in future", &greeterv1.HelloRequest{Name: "a", RequestTime: timestamppb.New(now.Add(time.Minute))}, []string{"request_time"}},
+	}
+	for _, tt := range tests {
+		err := validate.Validate(tt.req)
+		if tt.fields == nil {
+			if err != nil {
+				t.Errorf("%s: Validate() = %v, expect nil", tt.name, err)
+			}
+			continue
+		}
+		var verr *validate.Error
+		if !errors.As(err, &verr) {
+			t.Errorf("%s: Validate() = %v, expect *validate.Error", tt.name, err)
+			continue
+		}
+		var got []string
+		for _, v := range verr.Violations {
+			got = append(got, v.GetField())
+		}
+		if strings.Join(got, ",") != strings.Join(tt.fields, ",") {
+			t.Errorf("%s: violations on %v, expect %v", tt.name, got, tt.fields)
+		}
+	}
+}
+
+// isolatedHelloRequest 在独立的 registry 中重新构建 HelloRequest 和 Timestamp 的描述符，
+// 模拟代理通过服务端反射拿到的描述符：动态的 Timestamp 与 timestamppb 的描述符不是同一个
+func isolatedHelloRequest(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+	files := new(protoregistry.Files)
+	rebuild := func(fd protoreflect.FileDescriptor) {
+		f, err := protodesc.NewFile(protodesc.ToFileDescriptorProto(fd), files)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := files.RegisterFile(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	greeter := greeterv1.File_greeter_v1_greeter_proto
+	for i := 0; i < greeter.Imports().Len(); i++ {
+		imp := greeter.Imports().Get(i).FileDescriptor
+		if imp.Path() == timestamppb.File_google_protobuf_timestamp_proto.Path() {
+			rebuild(imp)
+		} else if err := files.RegisterFile(imp); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rebuild(greeter)
+	d, err := files.FindDescriptorByName("greeter.v1.HelloRequest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d.(protoreflect.MessageDescriptor)
+}
+
+func TestValidateDynamicMessage(t *testing.T) {
+	md := isolatedHelloRequest(t)
+	tsField := md.Fields().ByName("request_time")
+	if tsField.Message() == (&timestamppb.Timestamp{}).ProtoReflect().Descriptor() {
+		t.Fatal("request_time still uses the generated Timestamp descriptor")
+	}
+	request := func(at time.Time) *dynamicpb.Message {
+		ts := dynamicpb.NewMessage(tsField.Message())
+		ts.Set(ts.Descriptor().Fields().ByName("seconds"), protoreflect.ValueOfInt64(at.Unix()))
+		ts.Set(ts.Descriptor().Fields().ByName("nanos"), protoreflect.ValueOfInt32(int32(at.Nanosecond())))
+		m := dynamicpb.NewMessage(md)
+		m.Set(md.Fields().ByName("name"), protoreflect.ValueOfString("Junxi"))
+		m.Set(tsField, protoreflect.ValueOfMessage(ts))
+		return m
+	}
+
+	if err := validate.Validate(request(time.Now())); err != nil {
+		t.Errorf("valid dynamic request: Validate() = %v, expect nil", err)
+	}
+	var verr *validate.Error
+	if err := validate.Validate(request(time.Now().Add(-time.Hour))); !errors.As(err, &verr) ||
+		len(verr.Violations) != 1 || verr.Violations[0].GetField() != "request_time" {
+		t.Errorf("old dynamic request: Validate() = %v, expect a request_time violation", err)
+	}
+}